@@ -1,6 +1,46 @@
 package main
 
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"test-crud-user-orders/internal/config"
+)
+
 func main() {
-	server := NewServer()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+
+	// Configuration is read from the config file, the environment and the flags, see config.Load
+	loadConfig, err := config.Load(os.Args[1:])
+	if err != nil {
+		os.Exit(exitCode(err))
+	}
+
+	server := NewServer(loadConfig)
 	server.Start()
 }
+
+// configCommand runs "config print [-redacted] [flags]", printing the configuration the server would use
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: server config print [-redacted] [flags]")
+		return 2
+	}
+	if err := config.Print(os.Stdout, args[1:]); err != nil {
+		return exitCode(err)
+	}
+	return 0
+}
+
+// exitCode reports err on stderr, asking for the usage with -h is not a failure
+func exitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, err.Error())
+	return 2
+}
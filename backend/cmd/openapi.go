@@ -0,0 +1,530 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"test-crud-user-orders/internal/apiversion"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/handler"
+	"test-crud-user-orders/internal/openapi"
+)
+
+// newOpenAPIDocument describes every route mounted by registerRoutes, request
+// schemas come from the validate tags of the entity input structs
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.New("CRUD - User Orders", "2.0.0")
+
+	for _, version := range apiversion.Versions {
+		addAPIOperations(doc, version)
+	}
+
+	// Live updates
+	badRequest := doc.Envelope("Bad Request", nil)
+	doc.Add("GET", "/ws/users/:id/orders", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "WebSocket pushing the changes of the Orders of a User, after a subscribe message", OperationID: "userOrdersSocket",
+		Parameters: []*openapi.Parameter{
+			{Name: "Authorization", In: "header", Description: "Bearer token of the User, an HS256 JWT whose sub is the ID", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "Sec-WebSocket-Protocol", In: "header", Description: "bearer, <token> for browsers, which cannot set Authorization", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]*openapi.Response{
+			"101": {Description: "Switching to the WebSocket protocol"},
+			"400": badRequest,
+			"401": doc.Envelope("Invalid Token", nil),
+			"403": doc.Envelope("Token of another User", nil),
+		},
+	})
+
+	// GraphQL
+	graphqlResponse := &openapi.Response{Description: "GraphQL response, errors of the query are in its errors", Content: map[string]*openapi.MediaType{
+		"application/json": {Schema: &openapi.Schema{Type: "object"}},
+	}}
+	doc.Add("POST", "/graphql", &openapi.Operation{
+		Tags: []string{"GraphQL"}, Summary: "Query Users, Order Items and Order Histories with their relations in one request", OperationID: "postGraphQL",
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{
+			"application/json": {Schema: &openapi.Schema{Type: "object"}},
+		}},
+		Responses: map[string]*openapi.Response{
+			"200": graphqlResponse,
+			"400": badRequest,
+		},
+	})
+	doc.Add("GET", "/graphql", &openapi.Operation{
+		Tags: []string{"GraphQL"}, Summary: "Query Users, Order Items and Order Histories with their relations in one request", OperationID: "getGraphQL",
+		Parameters: []*openapi.Parameter{
+			openapi.Query("query", "string", "GraphQL query"),
+			openapi.Query("operationName", "string", "operation to execute when the query holds many"),
+			openapi.Query("variables", "string", "JSON object of the variables"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": graphqlResponse,
+			"400": badRequest,
+		},
+	})
+
+	// Monitoring
+	doc.Add("GET", "/healthz", &openapi.Operation{
+		Tags: []string{"Monitoring"}, Summary: "Liveness of the process, no dependency is checked", OperationID: "getHealthz",
+		Responses: map[string]*openapi.Response{
+			"200": doc.Envelope("OK", entity.Health{}),
+		},
+	})
+	doc.Add("GET", "/readyz", &openapi.Operation{
+		Tags: []string{"Monitoring"}, Summary: "Readiness with the status and latency of every dependency", OperationID: "getReadyz",
+		Responses: map[string]*openapi.Response{
+			"200": doc.Envelope("OK, or Degraded when only a non-critical dependency (Redis) is down", entity.Health{}),
+			"503": doc.Envelope("Starting, shutting down, or a critical dependency (database, migrations) is down", entity.Health{}),
+		},
+	})
+	doc.Add("GET", "/metrics", &openapi.Operation{
+		Tags: []string{"Monitoring"}, Summary: "Prometheus metrics in the text exposition format", OperationID: "getMetrics",
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "OK", Content: map[string]*openapi.MediaType{
+				"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+			}},
+		},
+	})
+
+	// Documentation
+	doc.Add("GET", "/openapi.json", &openapi.Operation{
+		Tags: []string{"Documentation"}, Summary: "This OpenAPI document", OperationID: "getOpenAPI",
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "OK", Content: map[string]*openapi.MediaType{
+				"application/json": {Schema: &openapi.Schema{Type: "object"}},
+			}},
+		},
+	})
+
+	// Every route but the exempted ones of rateLimits may answer 429, in the envelope of its version
+	tooManyRequests := doc.Envelope("Too Many Requests, retry after the Retry-After header", nil)
+	tooManyRequestsV2 := doc.EnvelopeV2("Too Many Requests, retry after the Retry-After header", nil)
+	for path, item := range doc.Paths {
+		version, echoPath := apiversion.Split(strings.ReplaceAll(strings.ReplaceAll(path, "{", ":"), "}", ""))
+		for method, operation := range item {
+			if limit, ok := rateLimits.Routes[strings.ToUpper(method)+" "+echoPath]; ok && limit.Unlimited() {
+				continue
+			}
+			operation.Responses["429"] = tooManyRequests
+			if version == apiversion.V2 {
+				operation.Responses["429"] = tooManyRequestsV2
+			}
+		}
+	}
+
+	return doc
+}
+
+// addAPIOperations describes the routes of the REST API mounted by registerAPIRoutes under version,
+// the operations of a deprecated version are marked so and keep the operationId of the first release
+func addAPIOperations(doc *openapi.Document, version apiversion.Version) {
+	envelope, tag, id := doc.Envelope, " (v1)", "V1"
+	switch version {
+	case apiversion.V2:
+		envelope, tag, id = doc.EnvelopeV2, " (v2)", "V2"
+	case apiversion.Unversioned:
+		tag, id = " (deprecated)", ""
+	}
+	_, deprecated := apiDeprecations(time.Time{})[version]
+	add := func(method, path string, op *openapi.Operation) {
+		for i := range op.Tags {
+			op.Tags[i] += tag
+		}
+		op.OperationID += id
+		op.Deprecated = deprecated
+		doc.Add(method, version.Prefix()+path, op)
+	}
+
+	pagination := []*openapi.Parameter{
+		openapi.Query("limit", "integer", "data per page, default 10"),
+		openapi.Query("page", "integer", "page number, default 1"),
+	}
+	bulkMode := openapi.Query("mode", "string", "atomic (default, all-or-nothing) or partial")
+	patchBody := func(v interface{}) *openapi.RequestBody {
+		return doc.Body(v, handler.MIMEMergePatch, "application/json")
+	}
+	badRequest := envelope("Bad Request", nil)
+	notFound := envelope("Not Found", nil)
+	serverError := envelope("Internal Server Error", nil)
+	bulkResponses := map[string]*openapi.Response{
+		"200": envelope("Every operation succeeded", []entity.BulkResult{}),
+		"207": envelope("Some operations failed (partial mode)", []entity.BulkResult{}),
+		"400": envelope("Invalid operations, nothing executed", []entity.BulkResult{}),
+		"422": envelope("An operation failed, everything rolled back (atomic mode)", []entity.BulkResult{}),
+	}
+
+	// Users
+	add("POST", "/users/", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "Create a User", OperationID: "createUser",
+		RequestBody: doc.Body(entity.CreateUser{}),
+		Responses: map[string]*openapi.Response{
+			"201": envelope("Created", entity.User{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("POST", "/users/bulk", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "Create, update and delete many Users", OperationID: "bulkUsers",
+		Parameters:  []*openapi.Parameter{bulkMode},
+		RequestBody: doc.Body([]entity.BulkOperation{}),
+		Responses:   bulkResponses,
+	})
+	add("GET", "/users/", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "List Users", OperationID: "listUsers",
+		Parameters: pagination,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.User{}),
+			"500": serverError,
+		},
+	})
+	add("GET", "/users/:id", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "Get a User", OperationID: "getUser",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.User{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PUT", "/users/:id", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "Replace a User", OperationID: "updateUser",
+		RequestBody: doc.Body(entity.CreateUser{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", nil),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PATCH", "/users/:id", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "Partially update a User (JSON Merge Patch)", OperationID: "patchUser",
+		RequestBody: patchBody(entity.PatchUser{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.User{}),
+			"400": badRequest,
+			"404": notFound,
+			"415": envelope("Unsupported Media Type", nil),
+			"500": serverError,
+		},
+	})
+	add("DELETE", "/users/:id", &openapi.Operation{
+		Tags: []string{"Users"}, Summary: "Soft-delete a User", OperationID: "deleteUser",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", nil),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("GET", "/users/:id/order-histories", &openapi.Operation{
+		Tags: []string{"Users", "Order Histories"}, Summary: "List Order Histories of a User", OperationID: "listUserOrderHistories",
+		Parameters: pagination,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.OrderHistory{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("GET", "/users/:id/stats", &openapi.Operation{
+		Tags: []string{"Users", "Reports"}, Summary: "Lifetime order figures of a User", OperationID: "getUserStats",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.UserStats{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+
+	// Order Items
+	add("POST", "/order-items/", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Create an Order Item", OperationID: "createOrderItem",
+		RequestBody: doc.Body(entity.CreateOrderItem{}),
+		Responses: map[string]*openapi.Response{
+			"201": envelope("Created", entity.OrderItem{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("POST", "/order-items/bulk", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Create, update and delete many Order Items", OperationID: "bulkOrderItems",
+		Parameters:  []*openapi.Parameter{bulkMode},
+		RequestBody: doc.Body([]entity.BulkOperation{}),
+		Responses:   bulkResponses,
+	})
+	add("POST", "/order-items/import", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Import Order Items from CSV (name, price, expired_days)", OperationID: "importOrderItems",
+		Parameters: []*openapi.Parameter{openapi.Query("dry_run", "boolean", "only validate the rows")},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{
+			"text/csv": {Schema: &openapi.Schema{Type: "string"}},
+			"multipart/form-data": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"file": {Type: "string", Format: "binary"},
+			}}},
+		}},
+		Responses: map[string]*openapi.Response{
+			"200": envelope("Dry-run result", entity.ImportResult{}),
+			"201": envelope("Imported", entity.ImportResult{}),
+			"400": envelope("Invalid rows, nothing imported", entity.ImportResult{}),
+			"413": envelope("Too many rows", nil),
+			"422": envelope("Import rolled back", entity.ImportResult{}),
+		},
+	})
+	add("GET", "/order-items/", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "List Order Items", OperationID: "listOrderItems",
+		Parameters: pagination,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.OrderItem{}),
+			"500": serverError,
+		},
+	})
+	add("GET", "/order-items/:id", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Get an Order Item", OperationID: "getOrderItem",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.OrderItem{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PUT", "/order-items/:id", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Replace an Order Item", OperationID: "updateOrderItem",
+		RequestBody: doc.Body(entity.CreateOrderItem{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.OrderItem{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PATCH", "/order-items/:id", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Partially update an Order Item (JSON Merge Patch)", OperationID: "patchOrderItem",
+		RequestBody: patchBody(entity.PatchOrderItem{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.OrderItem{}),
+			"400": badRequest,
+			"404": notFound,
+			"415": envelope("Unsupported Media Type", nil),
+			"500": serverError,
+		},
+	})
+	add("DELETE", "/order-items/:id", &openapi.Operation{
+		Tags: []string{"Order Items"}, Summary: "Soft-delete an Order Item", OperationID: "deleteOrderItem",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", nil),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+
+	// Order Histories
+	add("POST", "/order-histories/", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Create an Order History", OperationID: "createOrderHistory",
+		RequestBody: doc.Body(entity.CreateOrderHistory{}),
+		Responses: map[string]*openapi.Response{
+			"201": envelope("Created", entity.OrderHistory{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("POST", "/order-histories/bulk", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Create and update many Order Histories", OperationID: "bulkOrderHistories",
+		Parameters:  []*openapi.Parameter{bulkMode},
+		RequestBody: doc.Body([]entity.BulkOperation{}),
+		Responses:   bulkResponses,
+	})
+	add("GET", "/order-histories/", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "List Order Histories", OperationID: "listOrderHistories",
+		Parameters: append(pagination, openapi.Query("user_id", "integer", "only Order Histories of this User")),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.OrderHistory{}),
+			"500": serverError,
+		},
+	})
+	add("GET", "/order-histories/export", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Export Order Histories as CSV or XLSX", OperationID: "exportOrderHistories",
+		Parameters: []*openapi.Parameter{
+			openapi.Query("format", "string", "csv (default) or xlsx"),
+			openapi.Query("user_id", "integer", "only Order Histories of this User"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "OK", Content: map[string]*openapi.MediaType{
+				"text/csv": {Schema: &openapi.Schema{Type: "string"}},
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			}},
+			"400": badRequest,
+		},
+	})
+	add("GET", "/order-histories/stream", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Stream the new and updated Order Histories as Server-Sent Events", OperationID: "streamOrderHistories",
+		Parameters: []*openapi.Parameter{
+			openapi.Query("user_id", "integer", "only Order Histories of this User"),
+			{Name: "Last-Event-ID", In: "header", Description: "id of the last event received, the buffered events after it are sent first", Schema: &openapi.Schema{Type: "string"}},
+			openapi.Query("last_event_id", "string", "same as Last-Event-ID, for a client that cannot set headers"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "One OrderCreated or OrderUpdated event per change, its data is the Order History", Content: map[string]*openapi.MediaType{
+				"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+			}},
+			"400": badRequest,
+		},
+	})
+	add("GET", "/order-histories/:id", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Get an Order History", OperationID: "getOrderHistory",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.OrderHistory{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PUT", "/order-histories/:id", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Replace an Order History", OperationID: "updateOrderHistory",
+		RequestBody: doc.Body(entity.CreateOrderHistory{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", nil),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PATCH", "/order-histories/:id", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Partially update an Order History (JSON Merge Patch)", OperationID: "patchOrderHistory",
+		RequestBody: patchBody(entity.PatchOrderHistory{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.OrderHistory{}),
+			"400": badRequest,
+			"404": notFound,
+			"415": envelope("Unsupported Media Type", nil),
+			"500": serverError,
+		},
+	})
+	add("DELETE", "/order-histories/:id", &openapi.Operation{
+		Tags: []string{"Order Histories"}, Summary: "Deleting an Order History is not allowed", OperationID: "deleteOrderHistory",
+		Responses: map[string]*openapi.Response{
+			"403": envelope("Forbidden", nil),
+		},
+	})
+
+	// Reports
+	period := []*openapi.Parameter{
+		openapi.Query("from", "string", "first day (YYYY-MM-DD), default 29 days before today"),
+		openapi.Query("to", "string", "last day (YYYY-MM-DD), default today"),
+	}
+	top := append(period[:len(period):len(period)], openapi.Query("limit", "integer", "number of rows, 1 to 100, default 10"))
+	add("GET", "/reports/revenue", &openapi.Operation{
+		Tags: []string{"Reports"}, Summary: "Revenue per period", OperationID: "reportRevenue",
+		Parameters: append(period[:len(period):len(period)], openapi.Query("granularity", "string", "day (default), week or month")),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.RevenuePoint{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("GET", "/reports/top-items", &openapi.Operation{
+		Tags: []string{"Reports"}, Summary: "Order Items with the highest revenue", OperationID: "reportTopItems",
+		Parameters: top,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.TopItem{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("GET", "/reports/top-users", &openapi.Operation{
+		Tags: []string{"Reports"}, Summary: "Users with the highest revenue", OperationID: "reportTopUsers",
+		Parameters: top,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.TopUser{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("GET", "/reports/cohorts", &openapi.Operation{
+		Tags: []string{"Reports"}, Summary: "Monthly acquisition cohorts by first order with repeat-purchase retention", OperationID: "reportCohorts",
+		Parameters: []*openapi.Parameter{
+			openapi.Query("from", "string", "first day (YYYY-MM-DD) of first orders, default the first day 11 months ago"),
+			openapi.Query("to", "string", "last day (YYYY-MM-DD) of first orders, default today"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.Cohort{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+
+	// Webhooks
+	add("POST", "/webhooks/", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Subscribe a URL to domain events, signed with the secret", OperationID: "createWebhook",
+		RequestBody: doc.Body(entity.CreateWebhookSubscription{}),
+		Responses: map[string]*openapi.Response{
+			"201": envelope("Created", entity.WebhookSubscription{}),
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+	add("GET", "/webhooks/", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "List Webhook Subscriptions", OperationID: "listWebhooks",
+		Parameters: pagination,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.WebhookSubscription{}),
+			"500": serverError,
+		},
+	})
+	add("GET", "/webhooks/:id", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Get a Webhook Subscription", OperationID: "getWebhook",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.WebhookSubscription{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("PATCH", "/webhooks/:id", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Partially update a Webhook Subscription (JSON Merge Patch)", OperationID: "patchWebhook",
+		RequestBody: patchBody(entity.PatchWebhookSubscription{}),
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", entity.WebhookSubscription{}),
+			"400": badRequest,
+			"404": notFound,
+			"415": envelope("Unsupported Media Type", nil),
+			"500": serverError,
+		},
+	})
+	add("DELETE", "/webhooks/:id", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Delete a Webhook Subscription", OperationID: "deleteWebhook",
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", nil),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("GET", "/webhooks/:id/deliveries", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Delivery log of a Webhook Subscription, newest first", OperationID: "listWebhookDeliveries",
+		Parameters: pagination,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.WebhookDelivery{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("GET", "/webhooks/:id/deliveries/:delivery_id/attempts", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Every post of a delivery with its response code and error, newest first", OperationID: "listWebhookAttempts",
+		Parameters: pagination,
+		Responses: map[string]*openapi.Response{
+			"200": envelope("OK", []entity.WebhookAttempt{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+	add("POST", "/webhooks/:id/deliveries/:delivery_id/redeliver", &openapi.Operation{
+		Tags: []string{"Webhooks"}, Summary: "Send a delivery again, a dead one gets all its attempts back", OperationID: "redeliverWebhook",
+		Responses: map[string]*openapi.Response{
+			"202": envelope("Redelivery Scheduled", entity.WebhookDelivery{}),
+			"400": badRequest,
+			"404": notFound,
+			"500": serverError,
+		},
+	})
+}
@@ -3,128 +3,654 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	stdlog "log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"test-crud-user-orders/internal/handler"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
 
+	"test-crud-user-orders/internal/apiversion"
+	"test-crud-user-orders/internal/cache"
 	"test-crud-user-orders/internal/config"
+	"test-crud-user-orders/internal/graph"
+	"test-crud-user-orders/internal/handler"
+	"test-crud-user-orders/internal/live"
+	"test-crud-user-orders/internal/metrics"
+	"test-crud-user-orders/internal/outbox"
+	"test-crud-user-orders/internal/ratelimit"
 	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/internal/rpc"
+	"test-crud-user-orders/internal/stream"
+	"test-crud-user-orders/internal/tracing"
 	"test-crud-user-orders/internal/usecase"
+	"test-crud-user-orders/internal/webhook"
+	"test-crud-user-orders/pkg/logger"
 )
 
+// Server listens once Start is called, before setup has connected the dependencies: until setup
+// swapped in the handler of the whole API, it answers the health endpoints and 503 to everything else
 type Server struct {
-	e *echo.Echo
+	config  *config.Config
+	log     *zerolog.Logger
+	logFile *logger.RotatingWriter
+	health  *handler.HealthHandler
+	handler atomic.Value
+
+	// ctx is canceled as soon as the Server shuts down, setupDone is closed once setup returned
+	ctx       context.Context
+	cancel    context.CancelFunc
+	setupDone chan struct{}
+
+	// workers is the context of the background workers, canceled once the requests are drained
+	workers     context.Context
+	stopWorkers context.CancelFunc
+
+	hooksMu sync.Mutex
+	hooks   []shutdownHook
+}
+
+// shutdownHook stops a dependency or background worker of the Server
+type shutdownHook struct {
+	name string
+	stop func(context.Context) error
+}
+
+// NewServer sets up the logger and tracing of loadConfig and starts the Server in its "starting" stage
+func NewServer(loadConfig *config.Config) *Server {
+	// init Logger, written to stdout and to the rotating Logger File when LOG_FILE is set
+	var logOutput io.Writer = os.Stdout
+	var fileLog *logger.RotatingWriter
+	if loadConfig.Log.File != "" {
+		var errLog error
+		fileLog, errLog = config.SetupFileLog(loadConfig)
+		if errLog != nil {
+			stdlog.Fatalf("error opening log file: %s", errLog.Error())
+		}
+		logOutput = zerolog.MultiLevelWriter(os.Stdout, fileLog)
+	}
+	log, errLog := logger.NewLogger(loadConfig.Log.Level, loadConfig.Log.Format, logOutput)
+	if errLog != nil {
+		stdlog.Fatalf("error setting up logger: %s", errLog.Error())
+	}
+	// Code running outside of a request logs through the same Logger
+	zerolog.DefaultContextLogger = log
+
+	// Setup Tracing before any instrumented client is created
+	shutdownTracing, errTracing := tracing.Setup(context.Background(), loadConfig)
+	if errTracing != nil {
+		log.Fatal().Err(errTracing).Msg("error setting up tracing")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	workers, stopWorkers := context.WithCancel(context.Background())
+	s := &Server{
+		config:      loadConfig,
+		log:         log,
+		logFile:     fileLog,
+		health:      handler.NewHealthHandler(loadConfig.Health.Timeout),
+		ctx:         ctx,
+		cancel:      cancel,
+		setupDone:   make(chan struct{}),
+		workers:     workers,
+		stopWorkers: stopWorkers,
+	}
+	// Flush the spans still waiting in the batcher, after every other hook
+	s.OnShutdown("tracing", shutdownTracing)
+
+	// Only the health endpoints are served while the dependencies are set up
+	starting := s.newEcho()
+	starting.Use(s.health.ReadinessGate)
+	starting.GET("/healthz", s.health.Healthz)
+	starting.GET("/readyz", s.health.Readyz)
+	s.handler.Store(http.Handler(starting))
+
+	return s
+}
+
+// OnShutdown registers stop to be called on shutdown with the context of the drain timeout,
+// hooks run in the reverse order of their registration
+func (s *Server) OnShutdown(name string, stop func(context.Context) error) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.hooks = append(s.hooks, shutdownHook{name: name, stop: stop})
 }
 
-func NewServer() *Server {
-	// Load environment variables from .env file
-	loadConfig := config.LoadEnv()
+// ServeHTTP hands the request to the Echo instance of the current stage of the Server
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.Load().(http.Handler).ServeHTTP(w, r)
+}
 
+// newEcho returns an Echo instance with the middlewares shared by every stage of the Server
+func (s *Server) newEcho() *echo.Echo {
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	// The client address is the last one added to X-Forwarded-For by a proxy of a private network (nginx)
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
 
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+	e.Use(middleware.RequestID())
+	e.Use(tracing.Middleware())
+	e.Use(logger.Middleware(s.log))
+	e.Use(metrics.Middleware())
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		DisableStackAll: true,
+		LogErrorFunc:    logger.LogPanic,
+	}))
 	e.Validator = &CustomValidator{validator: validator.New()}
+	e.HTTPErrorHandler = handler.NewErrorHandler(e)
+	e.JSONSerializer = handler.JSONSerializer{}
+	return e
+}
+
+// setup connects the Database and Cache, builds the whole API and marks the Server ready
+func (s *Server) setup() {
+	log := s.log
+	loadConfig := s.config
 
-	// Setup Cache (redis) & Database (MariaDB)
-	cache := config.SetupCache(loadConfig)
-	db, errDB := config.SetupDatabase(loadConfig)
+	e := s.newEcho()
+
+	// Setup Redis & Database (MariaDB), both closed on shutdown after the other hooks
+	redisClient := config.SetupCache(loadConfig)
+	s.OnShutdown("redis", func(context.Context) error {
+		return redisClient.Close()
+	})
+	db, errDB := config.SetupDatabase(s.ctx, loadConfig, log)
 	if errDB != nil {
-		log.Fatalf("error connecting to database: %s", errDB.Error())
+		if s.ctx.Err() != nil {
+			// shutting down before the Database answered
+			return
+		}
+		log.Fatal().Err(errDB).Msg("error connecting to database")
+	}
+	sqlDB, errSQL := db.DB()
+	if errSQL != nil {
+		log.Fatal().Err(errSQL).Msg("error getting database pool")
 	}
+	s.OnShutdown("database", func(context.Context) error {
+		return sqlDB.Close()
+	})
+	// Expose query durations, pool statistics and cache hits on /metrics
+	if errMetrics := setupMetrics(db, redisClient); errMetrics != nil {
+		log.Fatal().Err(errMetrics).Msg("error registering metrics")
+	}
+	// Trace every query and Redis command under the span of its request
+	if errTracing := db.Use(tracing.GormPlugin{}); errTracing != nil {
+		log.Fatal().Err(errTracing).Msg("error registering tracing")
+	}
+	redisClient.AddHook(tracing.RedisHook{})
 	// Do AutoMigrate of Database
 	if errMigrate := config.AutoMigrate(db); errMigrate != nil {
-		log.Fatalf("error initializing table: %s", errMigrate.Error())
+		log.Fatal().Err(errMigrate).Msg("error initializing table")
+	}
+	// Only this process migrates the schema, so it is checked once instead of on every /readyz probe
+	errMigrations := config.CheckMigrations(s.ctx, db)
+
+	// Tell the version of every route of the REST API, the unversioned paths are announced deprecated
+	legacySunset, _ := time.Parse("2006-01-02", loadConfig.API.LegacySunset)
+	e.Use(apiversion.Middleware(apiversion.Config{
+		Resources:  apiResources,
+		Deprecated: apiDeprecations(legacySunset),
+	}))
+
+	// Limit the requests of every client, counted in Redis and in memory while Redis is down
+	if loadConfig.RateLimit.Enabled {
+		limits := rateLimits
+		limits.Limiter = ratelimit.NewRedisLimiter(redisClient, ratelimit.NewMemoryLimiter())
+		e.Use(ratelimit.Middleware(limits))
+	}
+
+	// Cache of the UseCases, selected by CACHE_DRIVER, read with the policy of every namespace
+	appCache := config.NewCache(loadConfig, redisClient)
+	if tiered, ok := appCache.(*cache.TieredCache); ok {
+		s.goWorker("cache invalidations", tiered.Run)
+	}
+	cacheLoader := config.NewCacheLoader(loadConfig, appCache, redisClient)
+	s.OnShutdown("cache refreshes", cacheLoader.Wait)
+
+	// Transactor shared by UseCases that write many rows at once
+	transactor := repository.NewTransactor(db)
+
+	// Background workers run on one instance at a time when Redis is shared
+	var workerLocker cache.Locker
+	if usesRedis(loadConfig) {
+		workerLocker = cache.NewRedisLocker(redisClient)
+	}
+
+	// Webhook Subscriptions, every event relayed from the outbox becomes one delivery per subscription
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
+	// the networks were validated with the configuration
+	webhookNetworks, _ := config.Networks(loadConfig.Webhook.AllowedNetworks)
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhookDeliveryRepo, webhook.Options{
+		Interval:        loadConfig.Webhook.PollInterval,
+		BatchSize:       loadConfig.Webhook.BatchSize,
+		Concurrency:     loadConfig.Webhook.Concurrency,
+		MaxAttempts:     loadConfig.Webhook.MaxAttempts,
+		RetryDelay:      loadConfig.Webhook.RetryDelay,
+		MaxRetryDelay:   loadConfig.Webhook.MaxRetryDelay,
+		Timeout:         loadConfig.Webhook.Timeout,
+		AllowedNetworks: webhookNetworks,
+		Locker:          workerLocker,
+	})
+	s.goWorker("webhook dispatcher", dispatcher.Run)
+
+	// Stream of the Order Histories, fanned out to every instance through Redis pub/sub when Redis is shared
+	orderStream := stream.NewHub(loadConfig.Stream.BufferSize)
+	var streamClient redis.UniversalClient
+	if usesRedis(loadConfig) {
+		streamClient = redisClient
 	}
+	streamSink := stream.NewSink(orderStream, streamClient)
+	s.goWorker("order stream", streamSink.Run)
+	// The streams never end on their own, they are closed as the drain begins so that it does not wait for them
+	go func() {
+		<-s.ctx.Done()
+		orderStream.Close()
+	}()
+
+	// Outbox of the domain events, written by the UseCases in the transaction of the change
+	outboxRepo := repository.NewOutboxRepository(db)
+	sinks := append([]outbox.Sink{webhook.NewSink(webhookRepo, webhookDeliveryRepo), streamSink}, config.OutboxSinks(loadConfig, redisClient)...)
+	relay := outbox.NewRelay(outboxRepo, sinks, outbox.Options{
+		Interval:    loadConfig.Outbox.PollInterval,
+		BatchSize:   loadConfig.Outbox.BatchSize,
+		MaxAttempts: loadConfig.Outbox.MaxAttempts,
+		Retention:   loadConfig.Outbox.Retention,
+		Locker:      workerLocker,
+	})
+	s.goWorker("outbox relay", relay.Run)
 
 	// init Repository, UseCase, and Handler of User table
 	userRepo := repository.NewUserRepository(db)
-	userUseCase := usecase.NewUserUseCase(userRepo)
+	userUseCase := usecase.WithTracingUserUseCase(usecase.NewUserUseCase(userRepo, outboxRepo, transactor))
 	userHandler := handler.NewUserHandler(userUseCase)
 
 	// init Repository, UseCase, and Handler of Order Item table
 	orderItemRepo := repository.NewOrderItemRepository(db)
-	orderItemUseCase := usecase.NewOrderItemUseCase(orderItemRepo, cache)
+	orderItemUseCase := usecase.WithTracingOrderItemUseCase(usecase.NewOrderItemUseCase(orderItemRepo, outboxRepo, transactor, cacheLoader))
 	orderItemHandler := handler.NewOrderItemHandler(orderItemUseCase)
 
 	// init Repository, UseCase, and Handler of Order History table
 	orderHistoryRepo := repository.NewOrderHistoryRepository(db)
-	orderHistoryUseCase := usecase.NewOrderHistoryUseCase(orderHistoryRepo, orderItemRepo, userRepo)
+	orderHistoryUseCase := usecase.WithTracingOrderHistoryUseCase(usecase.NewOrderHistoryUseCase(orderHistoryRepo, orderItemRepo, userRepo, outboxRepo, transactor))
 	orderHistoryHandler := handler.NewOrderHistoryHandler(orderHistoryUseCase)
+	orderStreamHandler := handler.NewOrderStreamHandler(orderStream, loadConfig.Stream.Heartbeat)
+	orderSocketHandler := handler.NewOrderSocketHandler(orderStream, loadConfig.WebSocket.AuthSecret, live.Options{
+		PingInterval: loadConfig.WebSocket.PingInterval,
+		PongTimeout:  loadConfig.WebSocket.PongTimeout,
+		WriteTimeout: loadConfig.WebSocket.WriteTimeout,
+	})
+
+	// init Repository, UseCase, and Handler of Reports
+	reportRepo := repository.NewReportRepository(db)
+	reportUseCase := usecase.WithTracingReportUseCase(usecase.NewReportUseCase(reportRepo, userRepo, cacheLoader))
+	reportHandler := handler.NewReportHandler(reportUseCase)
+
+	// init UseCase and Handler of Webhook Subscriptions
+	webhookUseCase := usecase.WithTracingWebhookUseCase(usecase.NewWebhookUseCase(webhookRepo, webhookDeliveryRepo))
+	webhookHandler := handler.NewWebhookHandler(webhookUseCase)
+
+	// gRPC API on its own port, served by the same UseCases as the REST API
+	s.serveGRPC(rpc.NewServer(log, rpc.UseCases{
+		User:         userUseCase,
+		OrderItem:    orderItemUseCase,
+		OrderHistory: orderHistoryUseCase,
+	}, loadConfig.GRPC.Reflection))
+
+	// GraphQL API, resolved by the same UseCases as the REST API
+	graphqlHandler := handler.NewGraphQLHandler(graph.NewExecutor(graph.UseCases{
+		User:         userUseCase,
+		OrderItem:    orderItemUseCase,
+		OrderHistory: orderHistoryUseCase,
+	}))
+
+	// init Handler of the API Documentation
+	docsHandler, errDocs := handler.NewDocsHandler(newOpenAPIDocument())
+	if errDocs != nil {
+		log.Fatal().Err(errDocs).Msg("error building OpenAPI document")
+	}
+
+	registerRoutes(e, handlers{
+		user:         userHandler,
+		orderItem:    orderItemHandler,
+		orderHistory: orderHistoryHandler,
+		orderStream:  orderStreamHandler,
+		orderSocket:  orderSocketHandler,
+		report:       reportHandler,
+		webhook:      webhookHandler,
+		graphql:      graphqlHandler,
+		docs:         docsHandler,
+		health:       s.health,
+	})
+
+	// Redis only backs caches and rate limits, the API keeps working (degraded) without it
+	checks := []handler.HealthCheck{
+		{Name: "database", Critical: true, Check: sqlDB.PingContext},
+		{Name: "migrations", Critical: true, Check: func(context.Context) error {
+			return errMigrations
+		}},
+	}
+	if usesRedis(loadConfig) {
+		checks = append(checks, handler.HealthCheck{Name: "redis", Check: func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}})
+	}
+	s.handler.Store(http.Handler(e))
+	s.health.SetReady(checks...)
+	log.Info().Msg("service ready")
+}
+
+// serveGRPC serves the gRPC API until shutdown, which lets the calls in flight finish within the drain
+// timeout before cutting the remaining ones
+func (s *Server) serveGRPC(server *grpc.Server) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.GRPC.Port))
+	if err != nil {
+		s.log.Fatal().Err(err).Msg("error listening for grpc")
+	}
+	go func() {
+		s.log.Info().Str("addr", listener.Addr().String()).Msg("grpc server started")
+		if err := server.Serve(listener); err != nil {
+			s.log.Error().Err(err).Msg("grpc server error")
+		}
+	}()
+	s.OnShutdown("grpc server", func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			server.GracefulStop()
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return ctx.Err()
+		}
+	})
+}
+
+// usesRedis reports whether the Cache, the rate limits or the events of cfg are stored in Redis
+func usesRedis(cfg *config.Config) bool {
+	return cfg.RateLimit.Enabled || cfg.Cache.Driver == "redis" || cfg.Cache.Driver == "tiered" ||
+		strings.Contains(cfg.Outbox.Sinks, "redis")
+}
+
+// goWorker runs work in the background until the requests are drained at shutdown, which then
+// waits for it to return within the drain timeout
+func (s *Server) goWorker(name string, work func(context.Context) error) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := work(s.workers); err != nil {
+			s.log.Error().Err(err).Str("worker", name).Msg("background worker stopped")
+		}
+	}()
+	s.OnShutdown(name, func(ctx context.Context) error {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// apiResources are the first segments of the paths of the REST API mounted by registerAPIRoutes
+var apiResources = []string{"users", "order-items", "order-histories", "reports", "webhooks"}
+
+// legacyDeprecated is when the unversioned paths of the REST API became deprecated aliases of /v1
+var legacyDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// apiDeprecations are the deprecated versions of the REST API, the unversioned paths are removed at
+// legacySunset (zero while it is not decided)
+func apiDeprecations(legacySunset time.Time) map[apiversion.Version]apiversion.Deprecation {
+	return map[apiversion.Version]apiversion.Deprecation{
+		apiversion.Unversioned: {Since: legacyDeprecated, Sunset: legacySunset, Successor: apiversion.V1},
+	}
+}
+
+// rateLimits are the budgets of a client (see ratelimit.ClientKey) per route, every route without
+// a limit of its own shares the Default one. A route of the REST API shares its budget with the same
+// route of every version.
+var rateLimits = ratelimit.Config{
+	Route: func(c echo.Context) string {
+		_, path := apiversion.Split(c.Path())
+		return c.Request().Method + " " + path
+	},
+	Default: ratelimit.Limit{Requests: 300, Window: time.Minute},
+	Routes: map[string]ratelimit.Limit{
+		// a script created 40k users through POST /users/
+		"POST /users/":                {Requests: 10, Window: time.Minute},
+		"POST /users/bulk":            {Requests: 5, Window: time.Minute},
+		"POST /order-items/bulk":      {Requests: 5, Window: time.Minute},
+		"POST /order-items/import":    {Requests: 5, Window: time.Minute},
+		"POST /order-histories/":      {Requests: 60, Window: time.Minute},
+		"POST /order-histories/bulk":  {Requests: 5, Window: time.Minute},
+		"GET /order-histories/export": {Requests: 5, Window: time.Minute},
+		// probes and scrapes are never limited
+		"GET /healthz": {},
+		"GET /readyz":  {},
+		"GET /metrics": {},
+	},
+}
+
+// handlers groups every Handler mounted by registerRoutes
+type handlers struct {
+	user         *handler.UserHandler
+	orderItem    *handler.OrderItemHandler
+	orderHistory *handler.OrderHistoryHandler
+	orderStream  *handler.OrderStreamHandler
+	orderSocket  *handler.OrderSocketHandler
+	report       *handler.ReportHandler
+	webhook      *handler.WebhookHandler
+	graphql      *handler.GraphQLHandler
+	docs         *handler.DocsHandler
+	health       *handler.HealthHandler
+}
+
+// registerRoutes mounts every route of the service, each one must be described in newOpenAPIDocument
+func registerRoutes(e *echo.Echo, h handlers) {
+	// init Paths of the REST API under every version, the unversioned ones are deprecated aliases of /v1
+	for _, version := range apiversion.Versions {
+		registerAPIRoutes(e.Group(version.Prefix()), h)
+	}
+
+	// init Path of the live updates of the Orders of a User
+	e.GET("/ws/users/:id/orders", h.orderSocket.Orders)
 
+	// init Path of the GraphQL API
+	e.GET("/graphql", h.graphql.Query)
+	e.POST("/graphql", h.graphql.Query)
+
+	// init Path of Health Checks
+	e.GET("/healthz", h.health.Healthz)
+	e.GET("/readyz", h.health.Readyz)
+
+	// init Path of Prometheus Metrics
+	e.GET("/metrics", metrics.Handler())
+
+	// init Path of API Documentation
+	e.GET("/openapi.json", h.docs.OpenAPI)
+	e.GET("/docs", h.docs.SwaggerUI)
+	e.GET("/docs/", h.docs.SwaggerUI)
+	e.GET("/docs/*", h.docs.Assets)
+}
+
+// registerAPIRoutes mounts the routes of the REST API under the prefix of g, which has no middleware
+// of its own since Echo would answer every unknown path of the prefix with it
+func registerAPIRoutes(g *echo.Group, h handlers) {
 	// init Path of User Table
-	pathUser := e.Group("/users")
-	pathUser.POST("/", userHandler.Create)
-	pathUser.GET("/", userHandler.GetAllPagination)
-	pathUser.GET("/:id", userHandler.GetByID)
-	pathUser.PUT("/:id", userHandler.Update)
-	pathUser.DELETE("/:id", userHandler.Delete)
+	pathUser := g.Group("/users")
+	pathUser.POST("/", h.user.Create)
+	pathUser.POST("/bulk", h.user.Bulk)
+	pathUser.GET("/", h.user.GetAllPagination)
+	pathUser.GET("/:id", h.user.GetByID)
+	pathUser.PUT("/:id", h.user.Update)
+	pathUser.PATCH("/:id", h.user.Patch)
+	pathUser.DELETE("/:id", h.user.Delete)
 
-	pathUser.GET("/:id/order-histories", orderHistoryHandler.GetHistoryByUserID)
+	pathUser.GET("/:id/order-histories", h.orderHistory.GetHistoryByUserID)
+	pathUser.GET("/:id/stats", h.report.UserStats)
 
 	// init Path of OrderItem Table
-	pathOrderItems := e.Group("/order-items")
-	pathOrderItems.POST("/", orderItemHandler.Create)
-	pathOrderItems.GET("/", orderItemHandler.GetAllPagination)
-	pathOrderItems.GET("/:id", orderItemHandler.GetByID)
-	pathOrderItems.PUT("/:id", orderItemHandler.Update)
-	pathOrderItems.DELETE("/:id", orderItemHandler.Delete)
+	pathOrderItems := g.Group("/order-items")
+	pathOrderItems.POST("/", h.orderItem.Create)
+	pathOrderItems.POST("/bulk", h.orderItem.Bulk)
+	pathOrderItems.POST("/import", h.orderItem.Import)
+	pathOrderItems.GET("/", h.orderItem.GetAllPagination)
+	pathOrderItems.GET("/:id", h.orderItem.GetByID)
+	pathOrderItems.PUT("/:id", h.orderItem.Update)
+	pathOrderItems.PATCH("/:id", h.orderItem.Patch)
+	pathOrderItems.DELETE("/:id", h.orderItem.Delete)
 
 	// init Path of OrderHistory Table
-	pathOrderHistory := e.Group("/order-histories")
-	pathOrderHistory.POST("/", orderHistoryHandler.Create)
-	pathOrderHistory.GET("/", orderHistoryHandler.GetAllPagination)
-	pathOrderHistory.GET("/:id", orderHistoryHandler.GetByID)
-	pathOrderHistory.PUT("/:id", orderHistoryHandler.Update)
-	pathOrderHistory.DELETE("/:id", orderHistoryHandler.Delete)
+	pathOrderHistory := g.Group("/order-histories")
+	pathOrderHistory.POST("/", h.orderHistory.Create)
+	pathOrderHistory.POST("/bulk", h.orderHistory.Bulk)
+	pathOrderHistory.GET("/", h.orderHistory.GetAllPagination)
+	pathOrderHistory.GET("/export", h.orderHistory.Export)
+	pathOrderHistory.GET("/stream", h.orderStream.Stream)
+	pathOrderHistory.GET("/:id", h.orderHistory.GetByID)
+	pathOrderHistory.PUT("/:id", h.orderHistory.Update)
+	pathOrderHistory.PATCH("/:id", h.orderHistory.Patch)
+	pathOrderHistory.DELETE("/:id", h.orderHistory.Delete)
+
+	// init Path of Reports
+	pathReports := g.Group("/reports")
+	pathReports.GET("/revenue", h.report.Revenue)
+	pathReports.GET("/top-items", h.report.TopItems)
+	pathReports.GET("/top-users", h.report.TopUsers)
+	pathReports.GET("/cohorts", h.report.Cohorts)
+
+	// init Path of Webhook Subscriptions
+	pathWebhooks := g.Group("/webhooks")
+	pathWebhooks.POST("/", h.webhook.Create)
+	pathWebhooks.GET("/", h.webhook.GetAllPagination)
+	pathWebhooks.GET("/:id", h.webhook.GetByID)
+	pathWebhooks.PATCH("/:id", h.webhook.Patch)
+	pathWebhooks.DELETE("/:id", h.webhook.Delete)
+	pathWebhooks.GET("/:id/deliveries", h.webhook.Deliveries)
+	pathWebhooks.GET("/:id/deliveries/:delivery_id/attempts", h.webhook.Attempts)
+	pathWebhooks.POST("/:id/deliveries/:delivery_id/redeliver", h.webhook.Redeliver)
+}
 
-	return &Server{e}
+// setupMetrics instruments the Database and Cache clients shared by every Repository and UseCase
+func setupMetrics(db *gorm.DB, redisClient *redis.Client) error {
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := metrics.RegisterDB("default", sqlDB); err != nil {
+		return err
+	}
+	redisClient.AddHook(metrics.RedisHook{})
+	return nil
 }
 
+// Start serves until SIGINT or SIGTERM, then shuts down gracefully and closes the Logger File last
 func (s *Server) Start() {
-	loadConfig := config.LoadEnv()
+	if s.logFile != nil {
+		defer func(fileLog *logger.RotatingWriter) {
+			if err := fileLog.Close(); err != nil {
+				stdlog.Printf("error closing log file: %s", err.Error())
+			}
+		}(s.logFile)
 
-	// init Logger File
-	fileLog, errLog := config.SetupFileLog(loadConfig.Log.File)
-	if errLog != nil {
-		log.Fatal(errLog)
+		// Reopen the Logger File on SIGHUP, after it was moved by an external tool
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		defer signal.Stop(hangup)
+		go func() {
+			for range hangup {
+				if err := s.logFile.Reopen(); err != nil {
+					s.log.Error().Err(err).Msg("error reopening log file")
+					continue
+				}
+				s.log.Info().Str("file", s.logFile.Filename()).Msg("log file reopened")
+			}
+		}()
 	}
-	defer func(fileLog *os.File) {
-		err := fileLog.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(fileLog)
-	// Log all Pre-Request and Post-Request to the Logger File
-	s.e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Output: fileLog,
-	}))
-
-	addr := fmt.Sprintf(":%s", loadConfig.Service.Port)
 
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.config.Service.Port),
+		Handler: s,
+	}
 	go func() {
-		if err := s.e.Start(addr); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %s\n", err.Error())
+		s.log.Info().Str("addr", server.Addr).Msg("http server started")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.log.Fatal().Err(err).Msg("server error")
 		}
 	}()
+	// The health endpoints answer while the Database is still being reached
+	go func() {
+		defer close(s.setupDone)
+		s.setup()
+	}()
+
+	// docker stop sends SIGTERM, a second signal stops the process without waiting for the drain
+	quit := make(chan os.Signal, 2)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	sig := <-quit
+	s.log.Info().Str("signal", sig.String()).Msg("shutting down")
+	go func() {
+		<-quit
+		s.log.Warn().Msg("second signal, exiting without draining")
+		os.Exit(1)
+	}()
+
+	s.shutdown(server)
+}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+// shutdown turns /readyz unhealthy, waits the shutdown Delay, drains the requests in flight, stops
+// the background workers and runs the shutdown hooks, all of it within the shutdown Timeout once the
+// Delay is over. The workers run until the drain is over, the requests in flight still write to the
+// outbox and read the caches they keep up to date.
+func (s *Server) shutdown(server *http.Server) {
+	s.health.SetDraining()
+	s.cancel()
+	time.Sleep(s.config.Shutdown.Delay)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Shutdown.Timeout)
 	defer cancel()
 
-	if err := s.e.Shutdown(ctx); err != nil {
-		log.Fatalf("server error: %s\n", err.Error())
+	if err := server.Shutdown(ctx); err != nil {
+		s.log.Error().Err(err).Msg("error draining requests")
+	}
+	// setup registers a hook for everything it started
+	select {
+	case <-s.setupDone:
+	case <-ctx.Done():
+		s.log.Warn().Msg("setup still running at shutdown")
+	}
+	s.stopWorkers()
+
+	s.hooksMu.Lock()
+	hooks := s.hooks
+	s.hooksMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].stop(ctx); err != nil {
+			s.log.Error().Err(err).Str("hook", hooks[i].name).Msg("error during shutdown")
+		}
 	}
+	s.log.Info().Msg("server stopped")
 }
 
 type CustomValidator struct {
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/apiversion"
+)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	e := echo.New()
+	registerRoutes(e, handlers{})
+	doc := newOpenAPIDocument()
+
+	for _, route := range e.Routes() {
+		// Swagger UI page and assets are not part of the API
+		if route.Path == "/docs" || strings.HasPrefix(route.Path, "/docs/") {
+			continue
+		}
+		if !doc.Has(route.Method, route.Path) {
+			t.Errorf("route %s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+}
+
+func TestOpenAPIDocumentsOnlyRegisteredRoutes(t *testing.T) {
+	e := echo.New()
+	registerRoutes(e, handlers{})
+	doc := newOpenAPIDocument()
+
+	registered := map[string]bool{}
+	for _, route := range e.Routes() {
+		registered[strings.ToLower(route.Method)+" "+route.Path] = true
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			echoPath := path
+			echoPath = strings.ReplaceAll(strings.ReplaceAll(echoPath, "{", ":"), "}", "")
+			if !registered[method+" "+echoPath] {
+				t.Errorf("OpenAPI document describes %s %s which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIRequestSchemasFollowValidateTags(t *testing.T) {
+	doc := newOpenAPIDocument()
+
+	schema, ok := doc.Components.Schemas["CreateOrderItem"]
+	if !ok {
+		t.Fatal("CreateOrderItem schema is missing")
+	}
+	required := strings.Join(schema.Required, ",")
+	if required != "name,price,expired_days" {
+		t.Errorf("CreateOrderItem required = %q, want name,price,expired_days", required)
+	}
+
+	patch, ok := doc.Components.Schemas["PatchOrderItem"]
+	if !ok {
+		t.Fatal("PatchOrderItem schema is missing")
+	}
+	if len(patch.Required) != 0 {
+		t.Errorf("PatchOrderItem must not require fields, got %v", patch.Required)
+	}
+	if price := patch.Properties["price"]; price.Minimum == nil || *price.Minimum != 1 {
+		t.Errorf("PatchOrderItem.price minimum = %v, want 1", price.Minimum)
+	}
+}
+
+func TestRateLimitsOnlyNameRegisteredRoutes(t *testing.T) {
+	e := echo.New()
+	registerRoutes(e, handlers{})
+
+	registered := map[string]bool{}
+	for _, route := range e.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for route := range rateLimits.Routes {
+		if !registered[route] {
+			t.Errorf("rate limit of %s names no registered route", route)
+		}
+	}
+}
+
+func TestOpenAPIMarksOnlyUnversionedRoutesDeprecated(t *testing.T) {
+	doc := newOpenAPIDocument()
+
+	for path, item := range doc.Paths {
+		version, _ := apiversion.Split(path)
+		for method, operation := range item {
+			legacy := version == apiversion.Unversioned && doc.Has(method, "/v1"+path)
+			if operation.Deprecated != legacy {
+				t.Errorf("%s %s deprecated = %v, want %v", strings.ToUpper(method), path, operation.Deprecated, legacy)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"test-crud-user-orders/internal/config"
+	"test-crud-user-orders/internal/handler"
+)
+
+func TestShutdownDrainsRequestsThenRunsHooksInReverse(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Shutdown.Delay = 200 * time.Millisecond
+	cfg.Shutdown.Timeout = 2 * time.Second
+	log := zerolog.Nop()
+	ctx, cancel := context.WithCancel(context.Background())
+	workers, stopWorkers := context.WithCancel(context.Background())
+	s := &Server{
+		config:      cfg,
+		log:         &log,
+		health:      handler.NewHealthHandler(time.Second),
+		ctx:         ctx,
+		cancel:      cancel,
+		setupDone:   make(chan struct{}),
+		workers:     workers,
+		stopWorkers: stopWorkers,
+	}
+	close(s.setupDone)
+	s.health.SetReady()
+
+	var stopped []string
+	for _, name := range []string{"tracing", "redis", "database", "worker"} {
+		name := name
+		s.OnShutdown(name, func(context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		})
+	}
+
+	inFlight := make(chan struct{})
+	var workersErr error
+	e := echo.New()
+	e.GET("/readyz", s.health.Readyz)
+	e.GET("/slow", func(c echo.Context) error {
+		close(inFlight)
+		time.Sleep(400 * time.Millisecond)
+		// the workers the request writes to still run at the end of the drain
+		workersErr = s.workers.Err()
+		return c.String(http.StatusOK, "done")
+	})
+	s.handler.Store(http.Handler(e))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: s}
+	go func() { _ = server.Serve(listener) }()
+	url := "http://" + listener.Addr().String()
+
+	slow := make(chan string, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		slow <- string(body)
+	}()
+	<-inFlight
+
+	done := make(chan struct{})
+	go func() {
+		s.shutdown(server)
+		close(done)
+	}()
+
+	// /readyz turns unhealthy during the delay while the listener still accepts requests
+	time.Sleep(50 * time.Millisecond)
+	res, err := http.Get(url + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), `"draining"`) {
+		t.Errorf("readyz = %d %s, want 503 draining", res.StatusCode, body)
+	}
+	if s.ctx.Err() == nil {
+		t.Error("setup context not canceled")
+	}
+
+	<-done
+	if got := <-slow; got != "done" {
+		t.Errorf("request in flight = %q, want it answered before the shutdown", got)
+	}
+	if workersErr != nil {
+		t.Errorf("workers stopped during the drain: %v", workersErr)
+	}
+	if s.workers.Err() == nil {
+		t.Error("workers not stopped after the drain")
+	}
+	if got := strings.Join(stopped, ","); got != "worker,database,redis,tracing" {
+		t.Errorf("hooks ran in order %s", got)
+	}
+}
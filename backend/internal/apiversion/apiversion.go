@@ -0,0 +1,110 @@
+// Package apiversion tells the version of the REST API a request was routed to, and announces the
+// deprecation of a version with the Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/metrics"
+)
+
+// Version of the REST API, the first segment of the path of its routes
+type Version string
+
+const (
+	// Unversioned are the paths of the first release, kept as aliases of V1
+	Unversioned Version = ""
+	// V1 is the API of the first release, frozen
+	V1 Version = "v1"
+	// V2 answers with the envelope of template.ResponseV2
+	V2 Version = "v2"
+)
+
+// Versions are the prefixes every route of the API is mounted under
+var Versions = []Version{V1, V2, Unversioned}
+
+// Prefix of the paths of v, empty for Unversioned
+func (v Version) Prefix() string {
+	if v == Unversioned {
+		return ""
+	}
+	return "/" + string(v)
+}
+
+// contextKey is the echo.Context key Middleware sets to the Version of a route of the API
+const contextKey = "api_version"
+
+// Deprecation of a Version
+type Deprecation struct {
+	// Since is when the Version was deprecated
+	Since time.Time
+	// Sunset is when the Version is removed, zero while it is not decided
+	Sunset time.Time
+	// Successor is the Version the clients should move to
+	Successor Version
+}
+
+// Config of Middleware
+type Config struct {
+	// Resources are the first segments of the paths of the API after the version (users for
+	// /v1/users/:id), the other routes have no Version
+	Resources []string
+	// Deprecated holds the Deprecation of every deprecated Version
+	Deprecated map[Version]Deprecation
+}
+
+// Split separates the Version from the path of a route: /v2/users/:id is V2 and /users/:id
+func Split(path string) (Version, string) {
+	for _, version := range []Version{V1, V2} {
+		if rest := strings.TrimPrefix(path, version.Prefix()); rest != path && strings.HasPrefix(rest, "/") {
+			return version, rest
+		}
+	}
+	return Unversioned, path
+}
+
+// FromContext is the Version of the route of c, ok is false on a route outside of the API
+func FromContext(c echo.Context) (version Version, ok bool) {
+	version, ok = c.Get(contextKey).(Version)
+	return version, ok
+}
+
+// Middleware sets the Version of every route of the API for FromContext. A request to a deprecated
+// Version carries the headers of its Deprecation and is counted by route, so the clients still
+// using it can be found before its sunset.
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	resources := make(map[string]bool, len(cfg.Resources))
+	for _, resource := range cfg.Resources {
+		resources[resource] = true
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			version, path := Split(c.Path())
+			if !resources[strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]] {
+				return next(c)
+			}
+			c.Set(contextKey, version)
+
+			if deprecation, ok := cfg.Deprecated[version]; ok {
+				_, requestPath := Split(c.Request().URL.Path)
+				deprecation.announce(c.Response().Header(), deprecation.Successor.Prefix()+requestPath)
+				metrics.DeprecatedRequests.WithLabelValues(c.Request().Method, c.Path()).Inc()
+			}
+			return next(c)
+		}
+	}
+}
+
+// announce writes the headers of d, successor is the same resource in the successor Version
+func (d Deprecation) announce(header http.Header, successor string) {
+	header.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+}
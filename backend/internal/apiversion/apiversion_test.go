@@ -0,0 +1,88 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"test-crud-user-orders/internal/metrics"
+)
+
+func TestSplit(t *testing.T) {
+	for _, tc := range []struct {
+		path    string
+		version Version
+		rest    string
+	}{
+		{"/v1/users/:id", V1, "/users/:id"},
+		{"/v2/order-histories/", V2, "/order-histories/"},
+		{"/users/:id", Unversioned, "/users/:id"},
+		{"/v1", Unversioned, "/v1"},
+		{"/v10/users", Unversioned, "/v10/users"},
+	} {
+		version, rest := Split(tc.path)
+		if version != tc.version || rest != tc.rest {
+			t.Errorf("Split(%q) = %q, %q, want %q, %q", tc.path, version, rest, tc.version, tc.rest)
+		}
+	}
+}
+
+func TestMiddlewareAnnouncesDeprecation(t *testing.T) {
+	since := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(Middleware(Config{
+		Resources:  []string{"users"},
+		Deprecated: map[Version]Deprecation{Unversioned: {Since: since, Sunset: sunset, Successor: V1}},
+	}))
+	handler := func(c echo.Context) error {
+		version, ok := FromContext(c)
+		if !ok {
+			return c.String(http.StatusOK, "none")
+		}
+		return c.String(http.StatusOK, "version "+string(version))
+	}
+	for _, prefix := range []string{"", "/v1", "/v2"} {
+		e.GET(prefix+"/users/:id", handler)
+	}
+	e.GET("/healthz", handler)
+
+	do := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	deprecated := metrics.DeprecatedRequests.WithLabelValues(http.MethodGet, "/users/:id")
+	before := testutil.ToFloat64(deprecated)
+
+	rec := do("/users/5")
+	for header, want := range map[string]string{
+		"Deprecation": "@1792368000",
+		"Sunset":      "Fri, 30 Apr 2027 00:00:00 GMT",
+		"Link":        `</v1/users/5>; rel="successor-version"`,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if body := rec.Body.String(); body != "version " {
+		t.Errorf("unversioned route answered %q", body)
+	}
+	if got := testutil.ToFloat64(deprecated) - before; got != 1 {
+		t.Errorf("deprecated requests counted %v, want 1", got)
+	}
+
+	for path, want := range map[string]string{"/v1/users/5": "version v1", "/v2/users/5": "version v2", "/healthz": "none"} {
+		rec := do(path)
+		if body := rec.Body.String(); body != want {
+			t.Errorf("%s answered %q, want %q", path, body, want)
+		}
+		if rec.Header().Get("Deprecation") != "" {
+			t.Errorf("%s is announced deprecated", path)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Package cache keeps short-lived copies of values that are expensive to compute. A Cache that fails
+// only costs the copy, its callers compute the value again from the Database.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when key holds no value, or its value expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores values by key, every implementation is safe for concurrent use
+type Cache interface {
+	// Get returns the value of key, ErrMiss when there is none
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value at key for ttl, a ttl of 0 keeps it until it is deleted or evicted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys, missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteByPrefix removes every key starting with prefix
+	DeleteByPrefix(ctx context.Context, prefix string) error
+}
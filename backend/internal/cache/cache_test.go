@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLRUCacheEvictsTheLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+	c := newLRUCache(2, func() time.Time { return now })
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), time.Minute)
+	_, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get of the least recently used = %v, want ErrMiss", err)
+	}
+	if value, err := c.Get(ctx, "a"); err != nil || string(value) != "1" {
+		t.Errorf("Get = %q, %v, want the entry read before the eviction", value, err)
+	}
+
+	_ = c.Set(ctx, "d", []byte("4"), time.Minute)
+	now = now.Add(time.Minute)
+	if _, err := c.Get(ctx, "d"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get of an expired entry = %v, want ErrMiss", err)
+	}
+	if c.Len() != 1 {
+		t.Errorf("entries = %d, want the expired one removed", c.Len())
+	}
+}
+
+func TestCachesDeleteByPrefix(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	for name, c := range map[string]Cache{
+		"lru":   NewLRUCache(100),
+		"redis": NewRedisCache(client),
+	} {
+		ctx := context.Background()
+		for _, key := range []string{"reports:*:1", "reports:*:2", "reports:a", "order_items:all"} {
+			if err := c.Set(ctx, key, []byte("[]"), time.Minute); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		if err := c.DeleteByPrefix(ctx, "reports:*"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for key, want := range map[string]bool{"reports:*:1": false, "reports:*:2": false, "reports:a": true, "order_items:all": true} {
+			if _, err := c.Get(ctx, key); (err == nil) != want {
+				t.Errorf("%s: Get(%q) = %v, want kept %v", name, key, err, want)
+			}
+		}
+		if err := c.Delete(ctx, "reports:a", "order_items:all", "missing"); err != nil {
+			t.Errorf("%s: Delete = %v", name, err)
+		}
+		if _, err := c.Get(ctx, "order_items:all"); !errors.Is(err, ErrMiss) {
+			t.Errorf("%s: Get after Delete = %v, want ErrMiss", name, err)
+		}
+	}
+}
+
+func TestTieredCacheInvalidatesEveryInstance(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// two instances of the service
+	first := NewTieredCache(client, NewLRUCache(100), time.Minute)
+	second := NewTieredCache(client, NewLRUCache(100), time.Minute)
+	for _, c := range []*TieredCache{first, second} {
+		go func(c *TieredCache) { _ = c.Run(ctx) }(c)
+	}
+	// an invalidation applied by each instance means both subscribed and cleared their cache already
+	for _, pair := range [][2]*TieredCache{{first, second}, {second, first}} {
+		publisher, subscriber := pair[0], pair[1]
+		waitFor(t, "the subscription", func() bool {
+			_ = subscriber.local.Set(ctx, "probe", nil, 0)
+			_ = publisher.Delete(ctx, "probe")
+			time.Sleep(10 * time.Millisecond)
+			_, err := subscriber.local.Get(ctx, "probe")
+			return errors.Is(err, ErrMiss)
+		})
+	}
+
+	if err := first.Set(ctx, "reports:1", []byte("old"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := second.Get(ctx, "reports:1"); err != nil || string(value) != "old" {
+		t.Fatalf("Get from the other instance = %q, %v", value, err)
+	}
+	// served from the process while Redis lost it
+	server.FlushAll()
+	if value, err := second.Get(ctx, "reports:1"); err != nil || string(value) != "old" {
+		t.Fatalf("local Get = %q, %v", value, err)
+	}
+
+	if err := first.Set(ctx, "reports:1", []byte("new"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the new value on the other instance", func() bool {
+		value, _ := second.Get(ctx, "reports:1")
+		return string(value) == "new"
+	})
+
+	if err := first.DeleteByPrefix(ctx, "reports:"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the deletion on the other instance", func() bool {
+		_, err := second.Get(ctx, "reports:1")
+		return errors.Is(err, ErrMiss)
+	})
+	if _, err := first.Get(ctx, "reports:1"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get on the deleting instance = %v, want ErrMiss", err)
+	}
+}
+
+func TestTieredCacheKeepsNothingWhileRedisFails(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	ctx := context.Background()
+	c := NewTieredCache(client, NewLRUCache(100), time.Minute)
+
+	server.Close()
+	if err := c.Set(ctx, "reports:1", []byte("[]"), time.Hour); err == nil {
+		t.Fatal("Set with Redis down succeeded")
+	}
+	if _, err := c.Get(ctx, "reports:1"); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("Get with Redis down = %v, want the Redis error", err)
+	}
+	if c.local.Len() != 0 {
+		t.Errorf("local entries = %d, want none", c.local.Len())
+	}
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if done() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errLoadPanicked is the error of the callers waiting for a load that panicked
+var errLoadPanicked = errors.New("cache load panicked")
+
+// call is one load in flight, shared by every caller of its key
+type call struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// group coalesces the concurrent loads of a key into one call, like golang.org/x/sync/singleflight
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do runs fn unless a call of key is in flight already, then it waits for the result of that call
+func (g *group) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	c, started := g.start(key)
+	if started {
+		g.run(key, c, fn)
+		return c.value, c.err
+	}
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// start registers a call of key, started is false when one is in flight already
+func (g *group) start(key string) (c *call, started bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c, false
+	}
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	c = &call{done: make(chan struct{})}
+	g.calls[key] = c
+	return c, true
+}
+
+// run fills c with the result of fn and releases its waiters
+func (g *group) run(key string, c *call, fn func() ([]byte, error)) {
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.err = errLoadPanicked
+	c.value, c.err = fn()
+}
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"test-crud-user-orders/pkg/logger"
+)
+
+const (
+	// lockTTL is the longest an instance holds the lock of a key it recomputes
+	lockTTL = 10 * time.Second
+	// lockPoll is the wait between two looks at a key recomputed by another instance
+	lockPoll = 50 * time.Millisecond
+	// loadTimeout bounds a load shared by many callers or run in the background
+	loadTimeout = 10 * time.Second
+)
+
+// Policy is how the keys of one namespace, the part of a key before its first colon, are loaded
+type Policy struct {
+	// TTL the value of a key is fresh, 0 disables the cache of the namespace
+	TTL time.Duration
+	// Stale is how long after its TTL a value is still served while one caller refreshes it
+	// in the background, 0 makes every caller wait for the fresh value
+	Stale time.Duration
+	// Lock recomputes a key on one instance at a time, the other instances wait for its value
+	Lock bool
+}
+
+// Loader reads values through a Cache and protects the source from stampedes: the concurrent
+// loads of a key are coalesced in the process, the Locker coalesces them across instances, and
+// a stale value may be served while it is refreshed. The values stored by a Loader carry the end
+// of their freshness, they are only read back by a Loader.
+type Loader struct {
+	cache    Cache
+	locker   Locker
+	policies map[string]Policy
+	now      func() time.Time
+
+	flights   group
+	refreshes sync.WaitGroup
+}
+
+// NewLoader loads through cache with the policies by namespace, a namespace without policy is not
+// cached. locker may be nil when the instances do not share the Cache.
+func NewLoader(cache Cache, locker Locker, policies map[string]Policy) *Loader {
+	return &Loader{cache: cache, locker: locker, policies: policies, now: time.Now}
+}
+
+// Load returns the value of key, from the Cache or from load. A Cache that fails only costs the copy.
+func (l *Loader) Load(ctx context.Context, key string, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	policy := l.policies[namespace(key)]
+	if policy.TTL <= 0 {
+		return load(ctx)
+	}
+
+	if value, fresh, ok := l.get(ctx, key); ok && (fresh || policy.Stale > 0) {
+		if !fresh {
+			l.refresh(ctx, key, policy, load)
+		}
+		return value, nil
+	}
+	return l.flights.do(ctx, key, func() ([]byte, error) {
+		// the callers waiting for the load must not fail with the request that started it
+		ctx, cancel := context.WithTimeout(detached{ctx}, loadTimeout)
+		defer cancel()
+		return l.compute(ctx, key, policy, load, true)
+	})
+}
+
+// Delete removes keys from the Cache
+func (l *Loader) Delete(ctx context.Context, keys ...string) error {
+	return l.cache.Delete(ctx, keys...)
+}
+
+// DeleteByPrefix removes every key starting with prefix from the Cache
+func (l *Loader) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return l.cache.DeleteByPrefix(ctx, prefix)
+}
+
+// Wait returns once the refreshes running in the background are over, or ctx is done
+func (l *Loader) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		l.refreshes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refresh recomputes key in the background, unless it is computed already
+func (l *Loader) refresh(ctx context.Context, key string, policy Policy, load func(context.Context) ([]byte, error)) {
+	c, started := l.flights.start(key)
+	if !started {
+		return
+	}
+	l.refreshes.Add(1)
+	go func() {
+		defer l.refreshes.Done()
+		// the refresh outlives the request that noticed the stale value
+		ctx, cancel := context.WithTimeout(detached{ctx}, loadTimeout)
+		defer cancel()
+		l.flights.run(key, c, func() (value []byte, err error) {
+			// nothing above this goroutine would recover a panic of load
+			defer func() {
+				if r := recover(); r != nil {
+					logger.FromContext(ctx).Error().Err(fmt.Errorf("%v", r)).Str("key", key).Str("stack", string(debug.Stack())).Msg("cache refresh panicked")
+					value, err = nil, errLoadPanicked
+				}
+			}()
+			return l.compute(ctx, key, policy, load, false)
+		})
+		if c.err != nil && !errors.Is(c.err, ErrLocked) && !errors.Is(c.err, errLoadPanicked) {
+			logger.FromContext(ctx).Warn().Err(c.err).Str("key", key).Msg("cache not refreshed")
+		}
+	}()
+}
+
+// compute loads key and stores its value. Under a Lock policy only the holder of the lock loads,
+// the other callers wait for its value when wait is set, or give up with ErrLocked.
+func (l *Loader) compute(ctx context.Context, key string, policy Policy, load func(context.Context) ([]byte, error), wait bool) ([]byte, error) {
+	if !policy.Lock || l.locker == nil {
+		return l.store(ctx, key, policy, load)
+	}
+
+	ticker := time.NewTicker(lockPoll)
+	defer ticker.Stop()
+	for {
+		unlock, err := l.locker.TryLock(ctx, "lock:"+key, lockTTL)
+		if err == nil {
+			defer unlock()
+			// the previous holder may have stored the value meanwhile
+			if value, fresh, ok := l.get(ctx, key); ok && fresh {
+				return value, nil
+			}
+			return l.store(ctx, key, policy, load)
+		}
+		if !errors.Is(err, ErrLocked) {
+			logger.FromContext(ctx).Warn().Err(err).Str("key", key).Msg("cache lock not taken")
+			return l.store(ctx, key, policy, load)
+		}
+		if !wait {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if value, fresh, ok := l.get(ctx, key); ok && fresh {
+			return value, nil
+		}
+	}
+}
+
+// store loads key and keeps its value for the TTL and Stale of policy
+func (l *Loader) store(ctx context.Context, key string, policy Policy, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(l.now().Add(policy.TTL).UnixNano()))
+	entry = append(entry, value...)
+	if err := l.cache.Set(ctx, key, entry, policy.TTL+policy.Stale); err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Str("key", key).Msg("value not cached")
+	}
+	return value, nil
+}
+
+// get reads the value of key and whether it is still fresh, ok is false when there is none
+func (l *Loader) get(ctx context.Context, key string) (value []byte, fresh, ok bool) {
+	entry, err := l.cache.Get(ctx, key)
+	if err != nil || len(entry) < 8 {
+		return nil, false, false
+	}
+	freshUntil := time.Unix(0, int64(binary.BigEndian.Uint64(entry)))
+	return entry[8:], l.now().Before(freshUntil), true
+}
+
+func namespace(key string) string {
+	return strings.SplitN(key, ":", 2)[0]
+}
+
+// detached keeps the values of a context, its logger and span, without its cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
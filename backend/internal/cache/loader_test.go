@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// countingLoad returns value after release is closed, and counts its calls
+type countingLoad struct {
+	calls   int32
+	value   string
+	release chan struct{}
+}
+
+func (l *countingLoad) load(ctx context.Context) ([]byte, error) {
+	atomic.AddInt32(&l.calls, 1)
+	if l.release != nil {
+		<-l.release
+	}
+	return []byte(l.value), nil
+}
+
+func TestLoaderCoalescesConcurrentLoads(t *testing.T) {
+	loader := NewLoader(NewLRUCache(100), nil, map[string]Policy{"reports": {TTL: time.Minute}})
+	load := &countingLoad{value: "report", release: make(chan struct{})}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := loader.Load(ctx, "reports:1", load.load); err != nil || string(value) != "report" {
+				t.Errorf("Load = %q, %v", value, err)
+			}
+		}()
+	}
+	waitFor(t, "the first load", func() bool { return atomic.LoadInt32(&load.calls) == 1 })
+	time.Sleep(20 * time.Millisecond)
+	close(load.release)
+	wg.Wait()
+
+	if _, err := loader.Load(ctx, "reports:1", load.load); err != nil || load.calls != 1 {
+		t.Errorf("loads = %d, %v, want a single one for 21 reads", load.calls, err)
+	}
+
+	// a namespace without policy is not cached
+	for i := 0; i < 2; i++ {
+		_, _ = loader.Load(ctx, "users:1", load.load)
+	}
+	if load.calls != 3 {
+		t.Errorf("loads = %d, want every read of an uncached namespace loaded", load.calls)
+	}
+}
+
+func TestLoaderOutlivesTheCallerStartingALoad(t *testing.T) {
+	loader := NewLoader(NewLRUCache(100), nil, map[string]Policy{"reports": {TTL: time.Minute}})
+	var calls int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte("report"), ctx.Err()
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	go func() { _, _ = loader.Load(first, "reports:1", load) }()
+	waitFor(t, "the first load", func() bool { return atomic.LoadInt32(&calls) == 1 })
+
+	type result struct {
+		value []byte
+		err   error
+	}
+	second := make(chan result, 1)
+	go func() {
+		value, err := loader.Load(context.Background(), "reports:1", load)
+		second <- result{value, err}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	close(release)
+
+	if res := <-second; res.err != nil || string(res.value) != "report" {
+		t.Errorf("waiting caller got %q, %v after the first one left, want the report", res.value, res.err)
+	}
+	if calls != 1 {
+		t.Errorf("loads = %d, want a single one", calls)
+	}
+}
+
+func TestLoaderServesStaleWhileRevalidating(t *testing.T) {
+	now := time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+	lru := newLRUCache(100, func() time.Time { return now })
+	loader := NewLoader(lru, nil, map[string]Policy{
+		"order_items": {TTL: time.Minute, Stale: time.Minute},
+		"reports":     {TTL: time.Minute},
+	})
+	loader.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"order_items:page:10:0", "reports:1"} {
+		if _, err := loader.Load(ctx, key, (&countingLoad{value: "old"}).load); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(90 * time.Second)
+
+	refreshed := &countingLoad{value: "new", release: make(chan struct{})}
+	if value, err := loader.Load(ctx, "order_items:page:10:0", refreshed.load); err != nil || string(value) != "old" {
+		t.Errorf("Load of a stale page = %q, %v, want the stale value", value, err)
+	}
+	// a single refresh runs for every stale read
+	_, _ = loader.Load(ctx, "order_items:page:10:0", refreshed.load)
+	close(refreshed.release)
+	if err := loader.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := loader.Load(ctx, "order_items:page:10:0", refreshed.load); string(value) != "new" || refreshed.calls != 1 {
+		t.Errorf("Load after the refresh = %q with %d refreshes, want new after one", value, refreshed.calls)
+	}
+
+	// without Stale, an expired report is never served
+	if value, _ := loader.Load(ctx, "reports:1", (&countingLoad{value: "new"}).load); string(value) != "new" {
+		t.Errorf("Load of an expired report = %q, want new", value)
+	}
+}
+
+func TestLoaderSurvivesARefreshPanicking(t *testing.T) {
+	now := time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+	lru := newLRUCache(100, func() time.Time { return now })
+	loader := NewLoader(lru, nil, map[string]Policy{"order_items": {TTL: time.Minute, Stale: time.Minute}})
+	loader.now = func() time.Time { return now }
+	ctx := context.Background()
+	key := "order_items:page:10:0"
+	if _, err := loader.Load(ctx, key, (&countingLoad{value: "old"}).load); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(90 * time.Second)
+
+	panicking := func(ctx context.Context) ([]byte, error) { panic("load failed") }
+	if value, err := loader.Load(ctx, key, panicking); err != nil || string(value) != "old" {
+		t.Errorf("Load of a stale page = %q, %v, want the stale value", value, err)
+	}
+	if err := loader.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := loader.Load(ctx, key, (&countingLoad{value: "new"}).load); err != nil || string(value) != "old" {
+		t.Errorf("Load after the panicking refresh = %q, %v, want the stale value kept", value, err)
+	}
+	if err := loader.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := loader.Load(ctx, key, (&countingLoad{value: "newer"}).load); string(value) != "new" {
+		t.Errorf("Load after another refresh = %q, want the key refreshed again", value)
+	}
+}
+
+func TestLoaderRecomputesOnOneInstance(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	policies := map[string]Policy{"reports": {TTL: time.Minute, Lock: true}}
+	ctx := context.Background()
+
+	// two instances of the service
+	first := NewLoader(NewRedisCache(client), NewRedisLocker(client), policies)
+	second := NewLoader(NewRedisCache(client), NewRedisLocker(client), policies)
+
+	firstLoad := &countingLoad{value: "first", release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = first.Load(ctx, "reports:1", firstLoad.load)
+	}()
+	waitFor(t, "the lock of the first instance", func() bool { return server.Exists("lock:reports:1") })
+
+	secondLoad := &countingLoad{value: "second"}
+	go func() {
+		time.Sleep(2 * lockPoll)
+		close(firstLoad.release)
+	}()
+	if value, err := second.Load(ctx, "reports:1", secondLoad.load); err != nil || string(value) != "first" {
+		t.Errorf("Load on the second instance = %q, %v, want the value of the first", value, err)
+	}
+	<-done
+	if secondLoad.calls != 0 || server.Exists("lock:reports:1") {
+		t.Errorf("second instance loaded %d times, lock left %v", secondLoad.calls, server.Exists("lock:reports:1"))
+	}
+
+	// a failed load releases the lock for the next instance
+	failing := func(context.Context) ([]byte, error) { return nil, errors.New("database down") }
+	if _, err := first.Load(ctx, "reports:2", failing); err == nil {
+		t.Fatal("failed load cached")
+	}
+	if value, err := second.Load(ctx, "reports:2", secondLoad.load); err != nil || string(value) != "second" {
+		t.Errorf("Load after a failure = %q, %v", value, err)
+	}
+
+	// Redis down, the value is still loaded
+	server.Close()
+	if value, err := first.Load(ctx, "reports:3", secondLoad.load); err != nil || string(value) != "second" {
+		t.Errorf("Load with Redis down = %q, %v", value, err)
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLocked is returned by TryLock while another holder has the lock
+var ErrLocked = errors.New("cache key locked")
+
+// unlockTimeout bounds the release of a lock, which runs after the context of its holder may be done
+const unlockTimeout = time.Second
+
+// Locker hands out locks shared by every instance of the service
+type Locker interface {
+	// TryLock takes the lock of key for ttl without waiting, unlock releases it before ttl
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), err error)
+}
+
+// compareAndDelete removes KEYS[1] only while it holds the token ARGV[1], so a holder whose lock
+// expired does not release the lock of the next holder
+var compareAndDelete = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker takes locks with SET NX, a lock expires after its ttl when its holder died
+type RedisLocker struct {
+	client redis.UniversalClient
+}
+
+func NewRedisLocker(client redis.UniversalClient) *RedisLocker {
+	return &RedisLocker{client: client}
+}
+
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	value := hex.EncodeToString(token)
+
+	ok, err := l.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLocked
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		_ = compareAndDelete.Run(ctx, l.client, []string{key}, value).Err()
+	}, nil
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key   string
+	value []byte
+	// expires is zero for an entry without ttl
+	expires time.Time
+}
+
+// LRUCache keeps up to size entries in the process and evicts the least recently used one
+// when it is full, each instance of the service caches on its own
+type LRUCache struct {
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recent holds the *lruEntry values, the most recently used first
+	recent *list.List
+}
+
+// NewLRUCache keeps up to size entries, at least one
+func NewLRUCache(size int) *LRUCache {
+	return newLRUCache(size, time.Now)
+}
+
+func newLRUCache(size int, now func() time.Time) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUCache{size: size, now: now, entries: map[string]*list.Element{}, recent: list.New()}
+}
+
+// Get never fails but with ErrMiss
+func (c *LRUCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.remove(element)
+		return nil, ErrMiss
+	}
+	c.recent.MoveToFront(element)
+	return append([]byte(nil), entry.value...), nil
+}
+
+// Set never fails
+func (c *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := &lruEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.recent.MoveToFront(element)
+		return nil
+	}
+	c.entries[key] = c.recent.PushFront(entry)
+	if c.recent.Len() > c.size {
+		c.remove(c.recent.Back())
+	}
+	return nil
+}
+
+// Delete never fails
+func (c *LRUCache) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+// DeleteByPrefix never fails, it walks every entry
+func (c *LRUCache) DeleteByPrefix(_ context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+// Len is the number of entries, the expired ones included until they are read or evicted
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
+
+// clear removes every entry
+func (c *LRUCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.recent.Init()
+}
+
+func (c *LRUCache) remove(element *list.Element) {
+	c.recent.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
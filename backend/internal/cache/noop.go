@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NoopCache stores nothing, every Get is a miss
+type NoopCache struct{}
+
+func NewNoopCache() NoopCache {
+	return NoopCache{}
+}
+
+func (NoopCache) Get(context.Context, string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+func (NoopCache) Set(context.Context, string, []byte, time.Duration) error {
+	return nil
+}
+
+func (NoopCache) Delete(context.Context, ...string) error {
+	return nil
+}
+
+func (NoopCache) DeleteByPrefix(context.Context, string) error {
+	return nil
+}
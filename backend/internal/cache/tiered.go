@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"test-crud-user-orders/pkg/logger"
+)
+
+// InvalidationChannel is the Redis pub/sub channel the TieredCaches of every instance publish
+// their changes on
+const InvalidationChannel = "cache:invalidations"
+
+// resubscribeDelay is the wait between two subscriptions while Redis does not answer
+const resubscribeDelay = time.Second
+
+// invalidation tells the other instances to drop entries from their local cache
+type invalidation struct {
+	// Origin is the instance that published it, which already dropped the entries
+	Origin string `json:"origin"`
+	// Keys to drop, or every key starting with Prefix when there is none
+	Keys   []string `json:"keys,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+}
+
+// TieredCache reads from an LRUCache in the process in front of Redis. Every change is written to
+// Redis and published on InvalidationChannel, Run drops the entries changed by other instances from
+// the local cache. An entry is kept locally at most localTTL, which bounds how stale it can be when
+// an invalidation is lost. While Redis fails nothing is cached locally either.
+type TieredCache struct {
+	local    *LRUCache
+	remote   *RedisCache
+	client   redis.UniversalClient
+	localTTL time.Duration
+	origin   string
+}
+
+func NewTieredCache(client redis.UniversalClient, local *LRUCache, localTTL time.Duration) *TieredCache {
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+	return &TieredCache{
+		local:    local,
+		remote:   NewRedisCache(client),
+		client:   client,
+		localTTL: localTTL,
+		origin:   hex.EncodeToString(origin),
+	}
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := c.local.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	value, err := c.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	_ = c.local.Set(ctx, key, value, c.localTTL)
+	return value, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	localTTL := c.localTTL
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	_ = c.local.Set(ctx, key, value, localTTL)
+	return c.publish(ctx, invalidation{Keys: []string{key}})
+}
+
+// Delete publishes the invalidation even when Redis failed to delete keys, for the other instances
+// not to keep serving them
+func (c *TieredCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_ = c.local.Delete(ctx, keys...)
+	errDelete := c.remote.Delete(ctx, keys...)
+	if err := c.publish(ctx, invalidation{Keys: keys}); err != nil {
+		return err
+	}
+	return errDelete
+}
+
+func (c *TieredCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	_ = c.local.DeleteByPrefix(ctx, prefix)
+	errDelete := c.remote.DeleteByPrefix(ctx, prefix)
+	if err := c.publish(ctx, invalidation{Prefix: prefix}); err != nil {
+		return err
+	}
+	return errDelete
+}
+
+func (c *TieredCache) publish(ctx context.Context, message invalidation) error {
+	message.Origin = c.origin
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.client.Publish(ctx, InvalidationChannel, data).Err()
+}
+
+// Run applies the invalidations of the other instances until ctx is done. The local cache is
+// cleared on every (re)subscription and every error, as invalidations may have been missed meanwhile.
+func (c *TieredCache) Run(ctx context.Context) error {
+	pubsub := c.client.Subscribe(ctx, InvalidationChannel)
+	defer pubsub.Close()
+
+	for {
+		received, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			c.local.clear()
+			logger.FromContext(ctx).Warn().Err(err).Msg("cache invalidations not received")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+
+		switch received := received.(type) {
+		case *redis.Subscription:
+			c.local.clear()
+		case *redis.Message:
+			c.apply(ctx, received.Payload)
+		}
+	}
+}
+
+func (c *TieredCache) apply(ctx context.Context, payload string) {
+	var message invalidation
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("invalid cache invalidation")
+		return
+	}
+	if message.Origin == c.origin {
+		return
+	}
+	if len(message.Keys) > 0 {
+		_ = c.local.Delete(ctx, message.Keys...)
+		return
+	}
+	_ = c.local.DeleteByPrefix(ctx, message.Prefix)
+}
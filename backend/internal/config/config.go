@@ -1,85 +1,267 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"github.com/glebarez/sqlite"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"os"
+	"net"
+	"net/url"
 	"strconv"
 	"strings"
+	"test-crud-user-orders/internal/cache"
 	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/outbox"
+	"test-crud-user-orders/pkg/logger"
 	"time"
 )
 
+// Config is layered by Load: the defaults below, then the config file, the environment and the
+// command line flags. Every setting is named after its environment variable, its flag is the same
+// name in lower case with dashes (DB_HOST is -db-host) and a secret can also be read from the file
+// named by <ENV>_FILE or -<flag>-file.
 type Config struct {
 	Database struct {
-		Host     string
-		Port     string
-		User     string
-		Password string
-		Name     string
-	}
+		// Driver is mysql (MariaDB), postgres or sqlite, the Name of a sqlite Database is its file
+		Driver string `yaml:"driver" toml:"driver" env:"DB_DRIVER" default:"mysql" validate:"oneof=mysql postgres sqlite"`
+		Host   string `yaml:"host" toml:"host" env:"DB_HOST" validate:"required_unless=Driver sqlite"`
+		// Port is the default of the Driver when not set, 3306 for mysql and 5432 for postgres
+		Port     int    `yaml:"port" toml:"port" env:"DB_PORT" validate:"min=0,max=65535"`
+		User     string `yaml:"user" toml:"user" env:"DB_USER" validate:"required_unless=Driver sqlite"`
+		Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+		Name     string `yaml:"name" toml:"name" env:"DB_NAME" validate:"required"`
+	} `yaml:"database" toml:"database"`
 	Redis struct {
-		Host     string
-		Port     string
-		Password string
-	}
+		Host     string `yaml:"host" toml:"host" env:"REDIS_HOST" default:"localhost" validate:"required"`
+		Port     int    `yaml:"port" toml:"port" env:"REDIS_PORT" default:"6379" validate:"min=1,max=65535"`
+		Password string `yaml:"password" toml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	} `yaml:"redis" toml:"redis"`
 	Log struct {
-		File string
-	}
+		// File rolls over daily and at MaxSize megabytes, rolled files are gzipped and kept MaxAge days
+		File     string `yaml:"file" toml:"file" env:"LOG_FILE"`
+		Level    string `yaml:"level" toml:"level" env:"LOG_LEVEL" default:"info" validate:"oneof=trace debug info warn error fatal panic"`
+		Format   string `yaml:"format" toml:"format" env:"LOG_FORMAT" default:"json" validate:"oneof=json console"`
+		MaxSize  int    `yaml:"max_size" toml:"max_size" env:"LOG_MAX_SIZE" default:"100" validate:"min=0"`
+		MaxAge   int    `yaml:"max_age" toml:"max_age" env:"LOG_MAX_AGE" default:"14" validate:"min=0"`
+		Compress bool   `yaml:"compress" toml:"compress" env:"LOG_COMPRESS" default:"true"`
+	} `yaml:"log" toml:"log"`
 	Service struct {
-		Port string
-	}
+		Port int `yaml:"port" toml:"port" env:"SERVICE_PORT" default:"8000" validate:"min=1,max=65535"`
+	} `yaml:"service" toml:"service"`
+	GRPC struct {
+		// Port of the gRPC API, served next to the REST API by the same UseCases
+		Port int `yaml:"port" toml:"port" env:"GRPC_PORT" default:"9000" validate:"min=1,max=65535"`
+		// Reflection lets clients like grpcurl list the services without the .proto files
+		Reflection bool `yaml:"reflection" toml:"reflection" env:"GRPC_REFLECTION" default:"true"`
+	} `yaml:"grpc" toml:"grpc"`
+	Cache struct {
+		// Driver of the caches: redis, lru (in the process), noop (nothing cached) or tiered
+		// (lru in front of redis, invalidated on every instance through Redis pub/sub)
+		Driver string `yaml:"driver" toml:"driver" env:"CACHE_DRIVER" default:"redis" validate:"oneof=redis lru noop tiered"`
+		// Size is the maximum number of entries kept in the process by lru and tiered
+		Size int `yaml:"size" toml:"size" env:"CACHE_SIZE" default:"10000" validate:"min=1"`
+		// LocalTTL bounds how long tiered serves an entry from the process without asking Redis
+		LocalTTL time.Duration `yaml:"local_ttl" toml:"local_ttl" env:"CACHE_LOCAL_TTL" default:"1m" validate:"gt=0"`
+	} `yaml:"cache" toml:"cache"`
+	OrderItem struct {
+		// CacheTTL of the pages of Order Items, 0 disables the cache
+		CacheTTL time.Duration `yaml:"cache_ttl" toml:"cache_ttl" env:"ORDER_ITEM_CACHE_TTL" default:"1m" validate:"min=0"`
+		// CacheStale is how long an expired page is still served while one request refreshes it
+		CacheStale time.Duration `yaml:"cache_stale" toml:"cache_stale" env:"ORDER_ITEM_CACHE_STALE" default:"30s" validate:"min=0"`
+		// CacheLock recomputes an expired page on one instance at a time
+		CacheLock bool `yaml:"cache_lock" toml:"cache_lock" env:"ORDER_ITEM_CACHE_LOCK" default:"true"`
+	} `yaml:"order_item" toml:"order_item"`
+	Report struct {
+		// CacheTTL of the reports, 0 disables the cache
+		CacheTTL time.Duration `yaml:"cache_ttl" toml:"cache_ttl" env:"REPORT_CACHE_TTL" default:"10m" validate:"min=0"`
+		// CacheStale is how long an expired report is still served while one request refreshes it
+		CacheStale time.Duration `yaml:"cache_stale" toml:"cache_stale" env:"REPORT_CACHE_STALE" default:"5m" validate:"min=0"`
+		// CacheLock recomputes an expired report on one instance at a time
+		CacheLock bool `yaml:"cache_lock" toml:"cache_lock" env:"REPORT_CACHE_LOCK" default:"true"`
+	} `yaml:"report" toml:"report"`
+	Health struct {
+		// Timeout given to every dependency check of /readyz
+		Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"HEALTH_TIMEOUT" default:"2s" validate:"gt=0"`
+	} `yaml:"health" toml:"health"`
+	API struct {
+		// LegacySunset is the day (YYYY-MM-DD) the unversioned paths, deprecated aliases of /v1, are removed
+		LegacySunset string `yaml:"legacy_sunset" toml:"legacy_sunset" env:"API_LEGACY_SUNSET" default:"2027-04-30" validate:"omitempty,datetime=2006-01-02"`
+	} `yaml:"api" toml:"api"`
+	RateLimit struct {
+		// Enabled limits the requests of every client with the rules of cmd/servers.go
+		Enabled bool `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	} `yaml:"rate_limit" toml:"rate_limit"`
+	Shutdown struct {
+		// Delay between /readyz turning unhealthy and the listener closing, for load balancers to notice
+		Delay time.Duration `yaml:"delay" toml:"delay" env:"SHUTDOWN_DELAY" default:"0s" validate:"min=0"`
+		// Timeout to drain the requests in flight and stop the background work
+		Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"SHUTDOWN_TIMEOUT" default:"10s" validate:"gt=0"`
+	} `yaml:"shutdown" toml:"shutdown"`
+	Outbox struct {
+		// Sinks receiving the domain events, a comma separated list of log, redis (a Redis Stream) and webhook
+		Sinks string `yaml:"sinks" toml:"sinks" env:"OUTBOX_SINKS" default:"log" validate:"listof=log redis webhook"`
+		// PollInterval between two looks of the relay at the outbox
+		PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" default:"1s" validate:"gt=0"`
+		// BatchSize is the number of events read from the outbox at once
+		BatchSize int `yaml:"batch_size" toml:"batch_size" env:"OUTBOX_BATCH_SIZE" default:"100" validate:"min=1"`
+		// MaxAttempts of an event on one sink before it is moved to the dead letters, 0 retries forever
+		MaxAttempts int `yaml:"max_attempts" toml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" default:"10" validate:"min=0"`
+		// Retention of the published events in the outbox, 0 keeps them
+		Retention time.Duration `yaml:"retention" toml:"retention" env:"OUTBOX_RETENTION" default:"168h" validate:"min=0"`
+		// RedisStream receiving the events of the redis sink, trimmed to about RedisStreamMaxLen entries (0 keeps all)
+		RedisStream       string `yaml:"redis_stream" toml:"redis_stream" env:"OUTBOX_REDIS_STREAM" default:"events" validate:"required"`
+		RedisStreamMaxLen int    `yaml:"redis_stream_max_len" toml:"redis_stream_max_len" env:"OUTBOX_REDIS_STREAM_MAX_LEN" default:"100000" validate:"min=0"`
+		// WebhookURL receiving a POST of every event by the webhook sink
+		WebhookURL     string        `yaml:"webhook_url" toml:"webhook_url" env:"OUTBOX_WEBHOOK_URL" validate:"required_if_listed=Sinks webhook,omitempty,url"`
+		WebhookTimeout time.Duration `yaml:"webhook_timeout" toml:"webhook_timeout" env:"OUTBOX_WEBHOOK_TIMEOUT" default:"5s" validate:"gt=0"`
+	} `yaml:"outbox" toml:"outbox"`
+	Webhook struct {
+		// PollInterval between two looks of the dispatcher at the due deliveries
+		PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" default:"1s" validate:"gt=0"`
+		// BatchSize is the number of deliveries read at once, Concurrency the number posted at once
+		BatchSize   int `yaml:"batch_size" toml:"batch_size" env:"WEBHOOK_BATCH_SIZE" default:"100" validate:"min=1"`
+		Concurrency int `yaml:"concurrency" toml:"concurrency" env:"WEBHOOK_CONCURRENCY" default:"4" validate:"min=1"`
+		// MaxAttempts of a delivery before it is dead, only a redelivery attempts it again
+		MaxAttempts int `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" default:"8" validate:"min=1"`
+		// RetryDelay after the first failed attempt, doubled after every other one up to MaxRetryDelay
+		RetryDelay    time.Duration `yaml:"retry_delay" toml:"retry_delay" env:"WEBHOOK_RETRY_DELAY" default:"30s" validate:"gt=0"`
+		MaxRetryDelay time.Duration `yaml:"max_retry_delay" toml:"max_retry_delay" env:"WEBHOOK_MAX_RETRY_DELAY" default:"1h" validate:"gt=0"`
+		// Timeout of one post to a subscription
+		Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOK_TIMEOUT" default:"5s" validate:"gt=0"`
+		// AllowedNetworks the subscriptions may post to although they are private, a comma separated list of
+		// CIDRs, every other loopback, private and link-local address is refused
+		AllowedNetworks string `yaml:"allowed_networks" toml:"allowed_networks" env:"WEBHOOK_ALLOWED_NETWORKS" validate:"cidrs"`
+	} `yaml:"webhook" toml:"webhook"`
+	Stream struct {
+		// BufferSize is the number of events kept per instance for the clients resuming with Last-Event-ID
+		BufferSize int `yaml:"buffer_size" toml:"buffer_size" env:"STREAM_BUFFER_SIZE" default:"1000" validate:"min=1"`
+		// Heartbeat between two comments keeping an idle stream open through the proxies
+		Heartbeat time.Duration `yaml:"heartbeat" toml:"heartbeat" env:"STREAM_HEARTBEAT" default:"15s" validate:"gt=0"`
+	} `yaml:"stream" toml:"stream"`
+	WebSocket struct {
+		// AuthSecret verifies the HS256 tokens of the clients, whose sub is the ID of the User, empty refuses every client
+		AuthSecret string `yaml:"auth_secret" toml:"auth_secret" env:"WS_AUTH_SECRET" validate:"omitempty,min=16"`
+		// PingInterval between two pings of the server, a client not answering within PongTimeout is disconnected
+		PingInterval time.Duration `yaml:"ping_interval" toml:"ping_interval" env:"WS_PING_INTERVAL" default:"30s" validate:"gt=0"`
+		PongTimeout  time.Duration `yaml:"pong_timeout" toml:"pong_timeout" env:"WS_PONG_TIMEOUT" default:"60s" validate:"gtfield=PingInterval"`
+		// WriteTimeout of one message, a client not reading it in time is disconnected
+		WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WS_WRITE_TIMEOUT" default:"10s" validate:"gt=0"`
+	} `yaml:"websocket" toml:"websocket"`
+	Tracing struct {
+		// Exporter of the spans, otlp is configured by the OTEL_EXPORTER_OTLP_* variables
+		Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" default:"none" validate:"oneof=none stdout file otlp"`
+		File     string `yaml:"file" toml:"file" env:"TRACING_FILE" validate:"required_if=Exporter file"`
+	} `yaml:"tracing" toml:"tracing"`
 }
 
-func LoadEnv() *Config {
-	cfg := &Config{}
-
-	// Database
-	cfg.Database.Name = os.Getenv("DB_NAME")
-	cfg.Database.Host = os.Getenv("DB_HOST")
-	cfg.Database.Port = os.Getenv("DB_PORT")
-	cfg.Database.User = os.Getenv("DB_USER")
-	cfg.Database.Password = os.Getenv("DB_PASSWORD")
-
-	// Redis
-	cfg.Redis.Host = os.Getenv("REDIS_HOST")
-	cfg.Redis.Port = os.Getenv("REDIS_PORT")
-	cfg.Redis.Password = os.Getenv("REDIS_PASSWORD")
+// models are the tables managed by AutoMigrate
+var models = []interface{}{
+	&entity.User{}, &entity.OrderItem{}, &entity.OrderHistory{},
+	&entity.OutboxEvent{}, &entity.OutboxCursor{}, &entity.OutboxDeadLetter{},
+	&entity.WebhookSubscription{}, &entity.WebhookDelivery{}, &entity.WebhookAttempt{},
+}
 
-	// Log
-	cfg.Log.File = os.Getenv("LOG_FILE")
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(models...)
+}
 
-	// Service
-	cfg.Service.Port = os.Getenv("SERVICE_PORT")
+// CheckMigrations reports the first table or column of the models missing from the database
+func CheckMigrations(ctx context.Context, db *gorm.DB) error {
+	migrator := db.WithContext(ctx).Migrator()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table %s is missing", stmt.Schema.Table)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !migrator.HasColumn(model, field.DBName) {
+				return fmt.Errorf("column %s.%s is missing", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	return nil
+}
 
-	return cfg
+// Dialector returns the gorm driver of the Database configured in cfg
+func Dialector(cfg *Config) gorm.Dialector {
+	config := cfg.Database
+	switch config.Driver {
+	case "postgres":
+		port := config.Port
+		if port == 0 {
+			port = 5432
+		}
+		dsn := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(config.User, config.Password),
+			Host:   net.JoinHostPort(config.Host, strconv.Itoa(port)),
+			Path:   "/" + config.Name,
+		}
+		return postgres.Open(dsn.String())
+	case "sqlite":
+		// the dates are written in a format the date functions of SQLite read
+		separator := "?"
+		if strings.Contains(config.Name, "?") {
+			separator = "&"
+		}
+		return sqlite.Open(config.Name + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	default:
+		port := config.Port
+		if port == 0 {
+			port = 3306
+		}
+		return mysql.Open(fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			config.User, config.Password, config.Host, port, config.Name))
+	}
 }
 
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&entity.User{}, &entity.OrderItem{}, &entity.OrderHistory{})
+// OpenDatabase connects once to the Database configured in cfg
+func OpenDatabase(cfg *Config) (*gorm.DB, error) {
+	db, err := gorm.Open(Dialector(cfg), &gorm.Config{Logger: logger.GormLogger{}})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Database.Driver == "sqlite" {
+		// SQLite writes one statement at a time, a single connection also shares a :memory: Database
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
 }
 
-func SetupDatabase(cfg *Config) (*gorm.DB, error) {
+// SetupDatabase connects to the Database, retrying every 5 seconds until it answers or ctx is done
+func SetupDatabase(ctx context.Context, cfg *Config, log *zerolog.Logger) (*gorm.DB, error) {
 	var db *gorm.DB
 	var err error
 
 	maxRetry := 10
-	config := cfg.Database
-	// Initialize database connection
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.User, config.Password, config.Host, config.Port, config.Name)
 	for i := 0; i < maxRetry; i++ {
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		db, err = OpenDatabase(cfg)
 
 		if i > 0 {
-			fmt.Println("DB Connection : Retry Mechanism [" + strconv.Itoa(i) + "x]")
+			log.Warn().Int("retry", i).Msg("DB Connection : Retry Mechanism")
 		}
 		if err == nil {
 			break
 		}
-		time.Sleep(5 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
 	}
 	return db, err
 }
@@ -88,19 +270,63 @@ func SetupCache(cfg *Config) *redis.Client {
 	config := cfg.Redis
 	// Initialize Redis client
 	return redis.NewClient(&redis.Options{
-		Addr:     config.Host,
+		Addr:     net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
 		Password: config.Password,
 		DB:       0,
 	})
 }
 
-func SetupFileLog(path string) (*os.File, error) {
-	now := time.Now()
-	// Format the time in YYYY-MM-DD format
-	dateString := now.Format("2006-01-02")
-	// Replace "dateformat" with the date in the filename
-	newFilename := strings.Replace(path, "dateformat", dateString, 1)
+// NewCache returns the Cache of the Driver configured in cfg, redis and tiered store in client.
+// The invalidations of a tiered Cache are only applied while its Run is running.
+func NewCache(cfg *Config, client *redis.Client) cache.Cache {
+	config := cfg.Cache
+	switch config.Driver {
+	case "lru":
+		return cache.NewLRUCache(config.Size)
+	case "noop":
+		return cache.NewNoopCache()
+	case "tiered":
+		return cache.NewTieredCache(client, cache.NewLRUCache(config.Size), config.LocalTTL)
+	default:
+		return cache.NewRedisCache(client)
+	}
+}
+
+// NewCacheLoader loads through cache with the policy of every cache namespace of cfg, the lock of
+// a policy is taken in Redis when the instances share the Cache
+func NewCacheLoader(cfg *Config, c cache.Cache, client *redis.Client) *cache.Loader {
+	var locker cache.Locker
+	if cfg.Cache.Driver == "redis" || cfg.Cache.Driver == "tiered" {
+		locker = cache.NewRedisLocker(client)
+	}
+	return cache.NewLoader(c, locker, map[string]cache.Policy{
+		"order_items": {TTL: cfg.OrderItem.CacheTTL, Stale: cfg.OrderItem.CacheStale, Lock: cfg.OrderItem.CacheLock},
+		"reports":     {TTL: cfg.Report.CacheTTL, Stale: cfg.Report.CacheStale, Lock: cfg.Report.CacheLock},
+	})
+}
+
+// OutboxSinks lists the sinks of the Outbox configured in cfg, in the order they receive the events
+func OutboxSinks(cfg *Config, client *redis.Client) []outbox.Sink {
+	config := cfg.Outbox
+	var sinks []outbox.Sink
+	for _, name := range listed(config.Sinks) {
+		switch name {
+		case "log":
+			sinks = append(sinks, outbox.LogSink{})
+		case "redis":
+			sinks = append(sinks, outbox.NewRedisStreamSink(client, config.RedisStream, int64(config.RedisStreamMaxLen)))
+		case "webhook":
+			sinks = append(sinks, outbox.NewWebhookSink(config.WebhookURL, config.WebhookTimeout))
+		}
+	}
+	return sinks
+}
 
-	// Create a log file
-	return os.Create(newFilename)
+// SetupFileLog appends to the Log File of the day, "dateformat" in the path is replaced with the date
+func SetupFileLog(cfg *Config) (*logger.RotatingWriter, error) {
+	return logger.NewRotatingWriter(cfg.Log.File, logger.RotateOptions{
+		MaxSize:  int64(cfg.Log.MaxSize) << 20,
+		MaxAge:   cfg.Log.MaxAge,
+		Compress: cfg.Log.Compress,
+	})
 }
@@ -0,0 +1,413 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of a secret printed with Print
+const redacted = "[REDACTED]"
+
+// setting is one leaf field of Config
+type setting struct {
+	env       string
+	flag      string
+	key       string
+	namespace string
+	secret    bool
+	def       string
+	index     []int
+}
+
+var settings = collectSettings(reflect.TypeOf(Config{}), nil, "", "Config")
+
+func collectSettings(t reflect.Type, index []int, key, namespace string) []setting {
+	var result []setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		fieldKey := strings.TrimPrefix(key+"."+field.Tag.Get("yaml"), ".")
+		fieldNamespace := namespace + "." + field.Name
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			result = append(result, collectSettings(field.Type, fieldIndex, fieldKey, fieldNamespace)...)
+			continue
+		}
+		env := field.Tag.Get("env")
+		result = append(result, setting{
+			env:       env,
+			flag:      strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+			key:       fieldKey,
+			namespace: fieldNamespace,
+			secret:    field.Tag.Get("secret") == "true",
+			def:       field.Tag.Get("default"),
+			index:     fieldIndex,
+		})
+	}
+	return result
+}
+
+// Load builds the Config of the service from, by increasing priority, the defaults, the config file
+// given by -config or CONFIG_FILE (YAML or TOML), the environment and the flags in args.
+// Empty environment variables are ignored. The result is validated before it is returned.
+func Load(args []string) (*Config, error) {
+	return load("server", args, os.LookupEnv, nil)
+}
+
+// Print writes the Config loaded from args as YAML to w, -redacted hides the secrets
+func Print(w io.Writer, args []string) error {
+	var hide bool
+	cfg, err := load("config print", args, os.LookupEnv, func(fs *flag.FlagSet) {
+		fs.BoolVar(&hide, "redacted", false, "replace the secrets with "+redacted)
+	})
+	if err != nil {
+		return err
+	}
+	if hide {
+		cfg = cfg.Redacted()
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Redacted returns a copy of cfg with every secret that is set replaced
+func (cfg *Config) Redacted() *Config {
+	copied := *cfg
+	value := reflect.ValueOf(&copied).Elem()
+	for _, s := range settings {
+		field := value.FieldByIndex(s.index)
+		if s.secret && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+	return &copied
+}
+
+// Validate reports every invalid setting of cfg at once, each one named after its environment variable
+func (cfg *Config) Validate() error {
+	validate := validator.New()
+	_ = validate.RegisterValidation("listof", listOf)
+	_ = validate.RegisterValidation("required_if_listed", requiredIfListed, true)
+	_ = validate.RegisterValidation("cidrs", func(fl validator.FieldLevel) bool {
+		_, err := Networks(fl.Field().String())
+		return err == nil
+	})
+	err := validate.Struct(cfg)
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, describe(fieldError))
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(messages, "\n  - "))
+}
+
+// listed splits a comma separated setting, blanks ignored
+func listed(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Networks parses a comma separated list of CIDRs
+func Networks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, item := range listed(value) {
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// listOf accepts a comma separated list of the words of the param
+func listOf(fl validator.FieldLevel) bool {
+	allowed := strings.Fields(fl.Param())
+	for _, item := range listed(fl.Field().String()) {
+		found := false
+		for _, word := range allowed {
+			found = found || item == word
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// requiredIfListed requires the field when the list named by the param holds its word,
+// "Sinks webhook" requires it when Sinks lists webhook
+func requiredIfListed(fl validator.FieldLevel) bool {
+	params := strings.Fields(fl.Param())
+	if len(params) != 2 {
+		panic("required_if_listed takes a field and a word")
+	}
+	for _, item := range listed(fl.Parent().FieldByName(params[0]).String()) {
+		if item == params[1] {
+			return !fl.Field().IsZero()
+		}
+	}
+	return true
+}
+
+func describe(fieldError validator.FieldError) string {
+	name := fieldError.StructNamespace()
+	for _, s := range settings {
+		if s.namespace == fieldError.StructNamespace() {
+			name = fmt.Sprintf("%s (%s)", s.env, s.key)
+			break
+		}
+	}
+
+	param := fieldError.Param()
+	switch fieldError.Tag() {
+	case "required":
+		return name + " is required"
+	case "required_if":
+		fields := strings.Fields(param)
+		return fmt.Sprintf("%s is required when %s is %s", name, strings.ToLower(fields[0]), strings.Join(fields[1:], " "))
+	case "required_unless":
+		fields := strings.Fields(param)
+		return fmt.Sprintf("%s is required unless %s is %s", name, strings.ToLower(fields[0]), strings.Join(fields[1:], " "))
+	case "required_if_listed":
+		fields := strings.Fields(param)
+		return fmt.Sprintf("%s is required when %s lists %s", name, strings.ToLower(fields[0]), fields[1])
+	case "listof":
+		return fmt.Sprintf("%s must list some of %s, got %q", name, strings.Join(strings.Fields(param), ", "), fmt.Sprint(fieldError.Value()))
+	case "cidrs":
+		return fmt.Sprintf("%s must list CIDRs like 10.0.0.0/8, got %q", name, fmt.Sprint(fieldError.Value()))
+	case "url":
+		return fmt.Sprintf("%s must be a URL, got %q", name, fmt.Sprint(fieldError.Value()))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s, got %q", name, strings.Join(strings.Fields(param), ", "), fmt.Sprint(fieldError.Value()))
+	case "min":
+		return fmt.Sprintf("%s must be at least %s, got %v", name, param, fieldError.Value())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s, got %v", name, param, fieldError.Value())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s, got %v", name, param, fieldError.Value())
+	default:
+		return fmt.Sprintf("%s failed the %s check", name, fieldError.Tag())
+	}
+}
+
+// load applies the layers of Load, extraFlags registers flags of a command beside the settings
+func load(name string, args []string, lookupEnv func(string) (string, bool), extraFlags func(*flag.FlagSet)) (*Config, error) {
+	cfg := &Config{}
+	value := reflect.ValueOf(cfg).Elem()
+
+	// The flags are parsed first to know the config file, they are applied last
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", "", "path of the YAML or TOML config file (CONFIG_FILE)")
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		usage := s.env
+		if s.def != "" {
+			usage += ", default " + s.def
+		}
+		fs.Var(&flagValue{values: flagValues, name: s.flag, isBool: value.FieldByIndex(s.index).Kind() == reflect.Bool}, s.flag, usage)
+		if s.secret {
+			fs.Var(&flagValue{values: flagValues, name: s.flag + "-file"}, s.flag+"-file", "file holding "+s.env)
+		}
+	}
+	if extraFlags != nil {
+		extraFlags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	for _, s := range settings {
+		if s.def == "" {
+			continue
+		}
+		if err := setField(value.FieldByIndex(s.index), s.def); err != nil {
+			return nil, fmt.Errorf("default of %s: %s", s.env, err.Error())
+		}
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		if err := decodeFile(*configFile, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// environment overrides
+	for _, s := range settings {
+		raw, ok := lookupEnv(s.env)
+		raw, source, err := readSecret(s, raw, ok && raw != "", lookupEnv, s.env, s.env+"_FILE")
+		if err != nil {
+			return nil, err
+		}
+		if source == "" {
+			continue
+		}
+		if err := setField(value.FieldByIndex(s.index), raw); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err.Error())
+		}
+	}
+
+	// flag overrides
+	lookupFlag := func(name string) (string, bool) {
+		raw, ok := flagValues[strings.TrimPrefix(name, "-")]
+		return raw, ok
+	}
+	for _, s := range settings {
+		raw, ok := flagValues[s.flag]
+		raw, source, err := readSecret(s, raw, ok, lookupFlag, "-"+s.flag, "-"+s.flag+"-file")
+		if err != nil {
+			return nil, err
+		}
+		if source == "" {
+			continue
+		}
+		if err := setField(value.FieldByIndex(s.index), raw); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err.Error())
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readSecret returns the value of s set in a layer and where it came from, a secret may instead
+// be read from the file named by fileKey, whose trailing newline is dropped. source is empty when
+// the layer does not set s.
+func readSecret(s setting, raw string, set bool, lookup func(string) (string, bool), name, fileKey string) (string, string, error) {
+	if !s.secret {
+		if !set {
+			return "", "", nil
+		}
+		return raw, name, nil
+	}
+
+	path, fromFile := lookup(fileKey)
+	fromFile = fromFile && path != ""
+	switch {
+	case set && fromFile:
+		return "", "", fmt.Errorf("%s and %s are both set, use only one", name, fileKey)
+	case fromFile:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %s", fileKey, err.Error())
+		}
+		return strings.TrimRight(string(content), "\r\n"), fileKey, nil
+	case set:
+		return raw, name, nil
+	default:
+		return "", "", nil
+	}
+}
+
+// decodeFile merges the YAML or TOML file at path into cfg, unknown keys are rejected
+func decodeFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %s", err.Error())
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("error parsing config file %s: %s", path, err.Error())
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %s", path, err.Error())
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error parsing config file %s: unknown key %s", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// setField parses raw into the kind of field
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration (e.g. 30s, 5m)", raw)
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		field.SetInt(int64(number))
+	case field.Kind() == reflect.Bool:
+		boolean, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean (true or false)", raw)
+		}
+		field.SetBool(boolean)
+	default:
+		field.SetString(raw)
+	}
+	return nil
+}
+
+// flagValue records the raw value of a flag, it is parsed once the lower layers are applied
+type flagValue struct {
+	values map[string]string
+	name   string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.values == nil {
+		return ""
+	}
+	return v.values[v.name]
+}
+
+func (v *flagValue) Set(raw string) error {
+	v.values[v.name] = raw
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func TestLoadLayersFileEnvAndFlags(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	content := "database:\n  host: db-from-file\n  port: 3307\n  user: app\n  name: orders\nreport:\n  cache_ttl: 1m\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := load("test", []string{"-db-port", "3308", "-log-compress=false"}, env(map[string]string{
+		"CONFIG_FILE": file,
+		"DB_HOST":     "db-from-env",
+		"DB_PORT":     "3309",
+		"REDIS_PORT":  "",
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Database.Host != "db-from-env" {
+		t.Errorf("DB_HOST = %q, the environment must override the file", cfg.Database.Host)
+	}
+	if cfg.Database.Port != 3308 {
+		t.Errorf("DB_PORT = %d, the flag must override the environment", cfg.Database.Port)
+	}
+	if cfg.Database.User != "app" || cfg.Report.CacheTTL != time.Minute {
+		t.Errorf("user = %q, cache ttl = %s, want the values of the file", cfg.Database.User, cfg.Report.CacheTTL)
+	}
+	if cfg.Redis.Port != 6379 || cfg.Health.Timeout != 2*time.Second || cfg.Log.MaxSize != 100 {
+		t.Errorf("redis port = %d, health timeout = %s, log max size = %d, want the defaults",
+			cfg.Redis.Port, cfg.Health.Timeout, cfg.Log.MaxSize)
+	}
+	if cfg.Log.Compress {
+		t.Error("-log-compress=false ignored")
+	}
+}
+
+func TestLoadReadsTOMLFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	content := "[database]\nhost = \"db\"\nuser = \"root\"\nname = \"orders\"\n\n[health]\ntimeout = \"5s\"\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := load("test", []string{"-config", file}, env(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Host != "db" || cfg.Health.Timeout != 5*time.Second {
+		t.Errorf("host = %q, timeout = %s, want the values of the file", cfg.Database.Host, cfg.Health.Timeout)
+	}
+}
+
+func TestLoadReadsSecretsFromFiles(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "password.txt")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	required := map[string]string{"DB_HOST": "db", "DB_USER": "root", "DB_NAME": "orders", "DB_PASSWORD_FILE": secret}
+
+	cfg, err := load("test", nil, env(required), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("password = %q, want the content of the file without its newline", cfg.Database.Password)
+	}
+	if redacted := cfg.Redacted(); redacted.Database.Password != "[REDACTED]" || redacted.Redis.Password != "" {
+		t.Errorf("redacted passwords = %q, %q", redacted.Database.Password, redacted.Redis.Password)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Error("Redacted changed the original Config")
+	}
+
+	required["DB_PASSWORD"] = "inline"
+	if _, err := load("test", nil, env(required), nil); err == nil || !strings.Contains(err.Error(), "use only one") {
+		t.Errorf("err = %v, want DB_PASSWORD and DB_PASSWORD_FILE to conflict", err)
+	}
+}
+
+func TestLoadReportsEveryInvalidSetting(t *testing.T) {
+	_, err := load("test", nil, env(map[string]string{
+		"DB_HOST":          "db",
+		"DB_PORT":          "70000",
+		"LOG_LEVEL":        "loud",
+		"TRACING_EXPORTER": "file",
+		"OUTBOX_SINKS":     "log, webhook,kafka",
+	}), nil)
+	if err == nil {
+		t.Fatal("invalid configuration accepted")
+	}
+	for _, want := range []string{
+		"DB_USER (database.user) is required unless driver is sqlite",
+		"DB_NAME (database.name) is required",
+		"DB_PORT (database.port) must be at most 65535, got 70000",
+		`LOG_LEVEL (log.level) must be one of trace, debug, info, warn, error, fatal, panic, got "loud"`,
+		"TRACING_FILE (tracing.file) is required when exporter is file",
+		`OUTBOX_SINKS (outbox.sinks) must list some of log, redis, webhook, got "log, webhook,kafka"`,
+		"OUTBOX_WEBHOOK_URL (outbox.webhook_url) is required when sinks lists webhook",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q:\n%s", want, err.Error())
+		}
+	}
+
+	if _, err := load("test", nil, env(map[string]string{"HEALTH_TIMEOUT": "soon"}), nil); err == nil ||
+		err.Error() != `HEALTH_TIMEOUT: "soon" is not a duration (e.g. 30s, 5m)` {
+		t.Errorf("err = %v, want the variable and the expected type", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("DB_USER", "root")
+	t.Setenv("DB_NAME", "orders")
+	t.Setenv("DB_PASSWORD", "s3cret")
+
+	var out bytes.Buffer
+	if err := Print(&out, []string{"-redacted"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cret") || !strings.Contains(out.String(), "password: '[REDACTED]'") {
+		t.Errorf("printed configuration leaks the secret:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "cache_ttl: 10m0s") {
+		t.Errorf("durations must be printed readable:\n%s", out.String())
+	}
+}
@@ -0,0 +1,46 @@
+package entity
+
+import "encoding/json"
+
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkOperation is one element of a bulk request, Data holds the same body as the single-item endpoint
+type BulkOperation struct {
+	Op   string          `json:"op" validate:"required,oneof=create update delete"`
+	ID   int             `json:"id" validate:"required_unless=Op create"`
+	Data json.RawMessage `json:"data"`
+}
+
+type BulkResult struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	ID      int    `json:"id,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkUser struct {
+	Index    int
+	Op       string
+	ID       int
+	FullName string
+}
+
+type BulkOrderItem struct {
+	Index     int
+	Op        string
+	OrderItem OrderItem
+}
+
+type BulkOrderHistory struct {
+	Index        int
+	Op           string
+	ID           int
+	UserID       int
+	OrderItemID  int
+	Descriptions string
+}
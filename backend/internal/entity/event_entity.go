@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the domain events
+const (
+	EventUserCreated           = "UserCreated"
+	EventUserDeleted           = "UserDeleted"
+	EventOrderItemPriceChanged = "OrderItemPriceChanged"
+	EventOrderCreated          = "OrderCreated"
+	EventOrderUpdated          = "OrderUpdated"
+	EventOrderStatusChanged    = "OrderStatusChanged"
+)
+
+// OutboxEvent is a domain event written in the transaction of its change, the relay publishes it
+// at least once to every sink and sets PublishedAt once all of them are past it.
+// IDs are taken when a row is written, not when its transaction commits, so a smaller ID can show
+// up after a greater one: the relay orders the events on the Sequence it gives them once visible.
+type OutboxEvent struct {
+	ID int `gorm:"primaryKey"`
+	// EventID is unique per event, consumers deduplicate the events published again on it
+	EventID     string    `gorm:"size:36;not null;uniqueIndex"`
+	Type        string    `gorm:"size:64;not null"`
+	AggregateID int       `gorm:"not null"`
+	Payload     string    `gorm:"type:text;not null"`
+	OccurredAt  time.Time `gorm:"not null"`
+	// Sequence is given by the relay in the order the events became visible, nil until then
+	Sequence    *int       `gorm:"uniqueIndex"`
+	PublishedAt *time.Time `gorm:"index"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// OutboxCursor is the position of one sink in the outbox, the sink got every event up to LastSequence
+type OutboxCursor struct {
+	Sink string `gorm:"primaryKey;size:64"`
+	// LastSequence is the Sequence of the last outbox event the sink got or gave up on
+	LastSequence int `gorm:"not null"`
+	// Attempts counts the failed publications of the next event, LastError is the error of the last one
+	Attempts  int
+	LastError string `gorm:"size:255"`
+	// NextAttemptAt is when the next event is tried again after a failure
+	NextAttemptAt *time.Time
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (OutboxCursor) TableName() string {
+	return "outbox_cursors"
+}
+
+// OutboxDeadLetter is an event a sink failed to publish too many times, the relay moved past it
+// for that sink only
+type OutboxDeadLetter struct {
+	ID          int       `gorm:"primaryKey"`
+	Sink        string    `gorm:"size:64;not null;index"`
+	EventID     string    `gorm:"size:36;not null"`
+	Type        string    `gorm:"size:64;not null"`
+	AggregateID int       `gorm:"not null"`
+	Payload     string    `gorm:"type:text;not null"`
+	OccurredAt  time.Time `gorm:"not null"`
+	Attempts    int       `gorm:"not null"`
+	LastError   string    `gorm:"size:255"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (OutboxDeadLetter) TableName() string {
+	return "outbox_dead_letters"
+}
+
+// Event returns the event as published to the sinks
+func (e *OutboxEvent) Event() Event {
+	return Event{
+		ID:          e.EventID,
+		Type:        e.Type,
+		AggregateID: e.AggregateID,
+		OccurredAt:  e.OccurredAt,
+		Payload:     json.RawMessage(e.Payload),
+	}
+}
+
+// Event is a domain event as published to the sinks
+type Event struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID int             `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// UserDeletedEvent is the payload of UserDeleted, the payload of UserCreated is the User
+type UserDeletedEvent struct {
+	ID int `json:"id"`
+}
+
+// OrderItemPriceChangedEvent is the payload of OrderItemPriceChanged
+type OrderItemPriceChangedEvent struct {
+	OrderItemID int `json:"order_item_id"`
+	OldPrice    int `json:"old_price"`
+	NewPrice    int `json:"new_price"`
+}
+
+// OrderStatusChangedEvent is the payload of OrderStatusChanged
+type OrderStatusChangedEvent struct {
+	OrderID   int    `json:"order_id"`
+	UserID    int    `json:"user_id"`
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+}
+
+// OrderEvent is the payload of the OrderCreated and OrderUpdated events of an Order History
+type OrderEvent struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	OrderItemID  int       `json:"order_item_id"`
+	Descriptions string    `json:"descriptions"`
+	Price        *int      `json:"price"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewOrderEvent returns the payload of the events of orderHistory
+func NewOrderEvent(orderHistory *OrderHistory) OrderEvent {
+	return OrderEvent{
+		ID:           orderHistory.ID,
+		UserID:       orderHistory.UserID,
+		OrderItemID:  orderHistory.OrderItemID,
+		Descriptions: orderHistory.Descriptions,
+		Price:        orderHistory.Price,
+		Status:       orderHistory.Status,
+		CreatedAt:    orderHistory.CreatedAt,
+	}
+}
@@ -0,0 +1,23 @@
+package entity
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+	HealthStarting = "starting"
+	HealthDraining = "draining"
+)
+
+// Health is the body of the health and readiness endpoints
+type Health struct {
+	Status string                       `json:"status"`
+	Uptime string                       `json:"uptime"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
@@ -4,15 +4,26 @@ import (
 	"time"
 )
 
+// States of an Order History, every order starts pending
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderCompleted = "completed"
+	OrderCancelled = "cancelled"
+)
+
 type OrderHistory struct {
 	ID           int        `json:"id" gorm:"primaryKey"`
 	UserID       int        `json:"-" gorm:"not null;foreignkey:UserID"`
 	OrderItemID  int        `json:"-" gorm:"not null;foreignkey:OrderItemID"`
 	Descriptions string     `json:"descriptions" gorm:"size:255"`
+	Price        *int       `json:"price" gorm:"null"`
+	Status       string     `json:"status" gorm:"size:16;not null;default:pending"`
 	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-	User         *User      `json:"user,omitempty" gorm:"foreignkey:UserID`
-	OrderItem    *OrderItem `json:"order_item,omitempty" gorm:"foreignkey:OrderItemID`
+	User         *User      `json:"user,omitempty" gorm:"foreignkey:UserID"`
+	OrderItem    *OrderItem `json:"order_item,omitempty" gorm:"foreignkey:OrderItemID"`
 }
 
 type CreateOrderHistory struct {
@@ -21,6 +32,30 @@ type CreateOrderHistory struct {
 	Descriptions string `json:"descriptions" validate:"required"`
 }
 
+type PatchOrderHistory struct {
+	UserID       *int    `json:"user_id" validate:"omitempty,min=1"`
+	OrderItemID  *int    `json:"order_item_id" validate:"omitempty,min=1"`
+	Descriptions *string `json:"descriptions" validate:"omitempty,min=1,max=255"`
+	Status       *string `json:"status" validate:"omitempty,oneof=pending paid shipped completed cancelled"`
+}
+
 func (OrderHistory) TableName() string {
 	return "order_histories"
 }
+
+// OrderHistoryFilter holds the optional filters of the list and export endpoints
+type OrderHistoryFilter struct {
+	UserID int
+}
+
+// OrderHistoryExport is one flattened row of the Order History export
+type OrderHistoryExport struct {
+	ID             int       `gorm:"column:id"`
+	CreatedAt      time.Time `gorm:"column:created_at"`
+	UserID         int       `gorm:"column:user_id"`
+	UserName       string    `gorm:"column:user_name"`
+	OrderItemID    int       `gorm:"column:order_item_id"`
+	OrderItemName  string    `gorm:"column:order_item_name"`
+	OrderItemPrice int       `gorm:"column:order_item_price"`
+	Descriptions   string    `gorm:"column:descriptions"`
+}
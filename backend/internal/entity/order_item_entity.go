@@ -21,3 +21,23 @@ type CreateOrderItem struct {
 	Price      int    `json:"price" validate:"required"`
 	ExpiredDay int    `json:"expired_days" validate:"required"`
 }
+
+type PatchOrderItem struct {
+	Name       *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Price      *int    `json:"price" validate:"omitempty,min=1"`
+	ExpiredDay *int    `json:"expired_days" validate:"omitempty,min=1"`
+}
+
+// ImportRowError reports why one row of an import file was rejected
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportResult is the outcome of an import, Imported stays 0 on dry-run
+type ImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
@@ -0,0 +1,67 @@
+package entity
+
+import "time"
+
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// ReportFilter is the period of a report, From is inclusive and To exclusive
+type ReportFilter struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Limit       int
+}
+
+type RevenuePoint struct {
+	Period  string `json:"period" gorm:"column:period"`
+	Orders  int64  `json:"orders" gorm:"column:orders"`
+	Revenue int64  `json:"revenue" gorm:"column:revenue"`
+}
+
+type TopItem struct {
+	OrderItemID int    `json:"order_item_id" gorm:"column:order_item_id"`
+	Name        string `json:"name" gorm:"column:name"`
+	Orders      int64  `json:"orders" gorm:"column:orders"`
+	Revenue     int64  `json:"revenue" gorm:"column:revenue"`
+}
+
+type TopUser struct {
+	UserID  int    `json:"user_id" gorm:"column:user_id"`
+	Name    string `json:"name" gorm:"column:name"`
+	Orders  int64  `json:"orders" gorm:"column:orders"`
+	Revenue int64  `json:"revenue" gorm:"column:revenue"`
+}
+
+// Cohort is the Users acquired (first order) in one month and how many of them ordered again
+type Cohort struct {
+	Cohort    string            `json:"cohort"`
+	Users     int64             `json:"users"`
+	Retention []CohortRetention `json:"retention"`
+}
+
+// CohortRetention is the share of a Cohort that ordered Month months after its acquisition month
+type CohortRetention struct {
+	Month      int     `json:"month"`
+	Users      int64   `json:"users"`
+	Percentage float64 `json:"percentage"`
+}
+
+// CohortActivity is one row of the cohort query, Users of Cohort who ordered MonthOffset months later
+type CohortActivity struct {
+	Cohort      string `gorm:"column:cohort"`
+	MonthOffset int    `gorm:"column:month_offset"`
+	Users       int64  `gorm:"column:users"`
+}
+
+type UserStats struct {
+	UserID               int        `json:"user_id"`
+	Orders               int64      `json:"orders" gorm:"column:orders"`
+	LifetimeValue        int64      `json:"lifetime_value" gorm:"column:lifetime_value"`
+	FirstOrder           *time.Time `json:"first_order" gorm:"column:first_order"`
+	LastOrder            *time.Time `json:"last_order" gorm:"column:last_order"`
+	AvgDaysBetweenOrders *float64   `json:"avg_days_between_orders"`
+}
@@ -18,3 +18,7 @@ type User struct {
 type CreateUser struct {
 	FullName string `json:"name" validate:"required"`
 }
+
+type PatchUser struct {
+	FullName *string `json:"name" validate:"omitempty,min=1"`
+}
@@ -0,0 +1,120 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AllEvents subscribes a Webhook to every type of event
+const AllEvents = "*"
+
+// States of a Webhook Delivery, a failed one is retried until it is dead
+const (
+	DeliveryPending   = "pending"
+	DeliveryFailed    = "failed"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// EventTypes is a list of event types stored as one comma separated column
+type EventTypes []string
+
+func (EventTypes) GormDataType() string {
+	return "string"
+}
+
+func (t EventTypes) Value() (driver.Value, error) {
+	return strings.Join(t, ","), nil
+}
+
+func (t *EventTypes) Scan(value interface{}) error {
+	var joined string
+	switch v := value.(type) {
+	case string:
+		joined = v
+	case []byte:
+		joined = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into EventTypes", value)
+	}
+	*t = nil
+	if joined != "" {
+		*t = strings.Split(joined, ",")
+	}
+	return nil
+}
+
+// Has reports whether eventType is in the list, AllEvents matches every type
+func (t EventTypes) Has(eventType string) bool {
+	for _, listed := range t {
+		if listed == eventType || listed == AllEvents {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription posts the events of its Events to URL, signed with Secret
+type WebhookSubscription struct {
+	ID     int        `json:"id" gorm:"primaryKey"`
+	URL    string     `json:"url" gorm:"size:2048;not null"`
+	Events EventTypes `json:"events" gorm:"size:255;not null"`
+	// Secret signs the payloads, it is never shown again once set
+	Secret    string         `json:"-" gorm:"size:255;not null"`
+	Active    bool           `json:"active" gorm:"not null"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+type CreateWebhookSubscription struct {
+	URL    string   `json:"url" validate:"required,url,max=2048,startswith=http://|startswith=https://"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=* UserCreated UserDeleted OrderItemPriceChanged OrderCreated OrderUpdated OrderStatusChanged"`
+	Secret string   `json:"secret" validate:"required,min=16,max=255"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+type PatchWebhookSubscription struct {
+	URL    *string  `json:"url" validate:"omitempty,url,max=2048,startswith=http://|startswith=https://"`
+	Events []string `json:"events" validate:"omitempty,min=1,dive,oneof=* UserCreated UserDeleted OrderItemPriceChanged OrderCreated OrderUpdated OrderStatusChanged"`
+	Secret *string  `json:"secret" validate:"omitempty,min=16,max=255"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookDelivery is one event to post to one Webhook Subscription and the outcome of its last attempt
+type WebhookDelivery struct {
+	ID             int    `json:"id" gorm:"primaryKey"`
+	SubscriptionID int    `json:"subscription_id" gorm:"not null;uniqueIndex:idx_webhook_deliveries_event"`
+	EventID        string `json:"event_id" gorm:"size:36;not null;uniqueIndex:idx_webhook_deliveries_event"`
+	EventType      string `json:"event_type" gorm:"size:64;not null"`
+	// Payload is the Event posted, as JSON
+	Payload string `json:"-" gorm:"type:text;not null"`
+	Status  string `json:"status" gorm:"size:16;not null;index:idx_webhook_deliveries_due"`
+	// Attempts counts the posts since the delivery was created or redelivered
+	Attempts      int        `json:"attempts" gorm:"not null"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" gorm:"index:idx_webhook_deliveries_due"`
+	// ResponseCode of the last attempt, 0 when no answer was received, every attempt is kept as a WebhookAttempt
+	ResponseCode int        `json:"response_code,omitempty"`
+	LastError    string     `json:"last_error,omitempty" gorm:"size:255"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// WebhookAttempt is one post of a Webhook Delivery, the attempts are only ever added
+type WebhookAttempt struct {
+	ID         int `json:"id" gorm:"primaryKey"`
+	DeliveryID int `json:"delivery_id" gorm:"not null;index"`
+	// Attempt is the number of the post since the delivery was created or redelivered
+	Attempt int `json:"attempt" gorm:"not null"`
+	// ResponseCode is 0 when no answer was received
+	ResponseCode int       `json:"response_code,omitempty"`
+	Error        string    `json:"error,omitempty" gorm:"size:255"`
+	AttemptedAt  time.Time `json:"attempted_at" gorm:"not null"`
+}
@@ -0,0 +1,183 @@
+// Package graph serves the GraphQL API of schema.graphql. Like the gRPC API it only goes through
+// the UseCases of the REST handlers, the relations of a response are batched by per-request loaders
+// so a list of Order Histories with their User and Order Item costs one query per relation.
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/usecase"
+	"test-crud-user-orders/pkg/logger"
+)
+
+//go:embed schema.graphql
+var schema string
+
+const (
+	// maxLimit caps the limit of every list, like the limit of the reports
+	maxLimit = 100
+	// maxDepth rejects queries nesting relations deeper than any client needs
+	maxDepth = 8
+	// batchWait is how long a loader collects the keys of the resolvers running in parallel
+	batchWait = 2 * time.Millisecond
+)
+
+// UseCases served by the GraphQL API
+type UseCases struct {
+	User         usecase.UserUseCase
+	OrderItem    usecase.OrderItemUseCase
+	OrderHistory usecase.OrderHistoryUseCase
+}
+
+// Request is a GraphQL request as sent in the body of a POST or the query of a GET
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Executor executes GraphQL requests on the UseCases
+type Executor struct {
+	schema   *graphql.Schema
+	useCases UseCases
+}
+
+func NewExecutor(useCases UseCases) *Executor {
+	return &Executor{
+		schema: graphql.MustParseSchema(schema, &resolver{useCases},
+			graphql.MaxDepth(maxDepth),
+			// a whole list resolves its relations at once, so a loader gets the keys of every row in one batch
+			graphql.MaxParallelism(maxLimit),
+			graphql.Logger(panicLogger{}),
+			graphql.PanicHandler(panicLogger{}),
+		),
+		useCases: useCases,
+	}
+}
+
+// Exec executes req with loaders living as long as the request
+func (e *Executor) Exec(ctx context.Context, req Request) *graphql.Response {
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders(e.useCases))
+	return e.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+// panicLogger logs a resolver that panicked and answers it like the Recover middleware of Echo
+type panicLogger struct{}
+
+func (panicLogger) LogPanic(ctx context.Context, value interface{}) {
+	logger.FromContext(ctx).Error().Err(fmt.Errorf("%v", value)).Str("stack", string(debug.Stack())).Msg("panic recovered")
+}
+
+func (panicLogger) MakePanicError(context.Context, interface{}) *gqlerrors.QueryError {
+	return gqlerrors.Errorf("Internal Server Error")
+}
+
+type loadersKey struct{}
+
+// loaders batch the relations of the Order Histories and Users of one request
+type loaders struct {
+	users          *loader[int, *entity.User]
+	orderItems     *loader[int, *entity.OrderItem]
+	orderHistories *loader[userOrders, []*entity.OrderHistory]
+}
+
+// userOrders are the first Limit Order Histories of a User
+type userOrders struct {
+	UserID int
+	Limit  int
+}
+
+func newLoaders(useCases UseCases) *loaders {
+	return &loaders{
+		users: newLoader(batchWait, maxLimit, func(ctx context.Context, ids []int) (map[int]*entity.User, error) {
+			users, err := useCases.User.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[int]*entity.User, len(users))
+			for _, user := range users {
+				byID[user.ID] = user
+			}
+			return byID, nil
+		}),
+		orderItems: newLoader(batchWait, maxLimit, func(ctx context.Context, ids []int) (map[int]*entity.OrderItem, error) {
+			orderItems, err := useCases.OrderItem.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[int]*entity.OrderItem, len(orderItems))
+			for _, orderItem := range orderItems {
+				byID[orderItem.ID] = orderItem
+			}
+			return byID, nil
+		}),
+		orderHistories: newLoader(batchWait, maxLimit, func(ctx context.Context, keys []userOrders) (map[userOrders][]*entity.OrderHistory, error) {
+			// one query per distinct limit, a single one unless aliases ask for different limits
+			userIDs := map[int][]int{}
+			for _, key := range keys {
+				userIDs[key.Limit] = append(userIDs[key.Limit], key.UserID)
+			}
+			byKey := make(map[userOrders][]*entity.OrderHistory, len(keys))
+			for limit, ids := range userIDs {
+				orderHistories, err := useCases.OrderHistory.GetByUserIDs(ctx, ids, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, orderHistory := range orderHistories {
+					key := userOrders{UserID: orderHistory.UserID, Limit: limit}
+					byKey[key] = append(byKey[key], orderHistory)
+				}
+			}
+			return byKey, nil
+		}),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// pageOf reads the limit and page arguments of a list with the defaults of ?limit=&page= on the REST API
+func pageOf(limit, page int32) (int32, int32, int64) {
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	if page < 1 {
+		page = 1
+	}
+	return limit, page, int64(page-1) * int64(limit)
+}
+
+// idOf reads an ID argument, an ID that is not a number matches nothing
+func idOf(id graphql.ID) (int, bool) {
+	n, err := strconv.Atoi(string(id))
+	return n, err == nil && n > 0
+}
+
+// notFound tells whether err only means the record is missing, resolved as null
+func notFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// failure logs err and hides it behind the message of the REST API, a canceled request is kept as is
+func failure(ctx context.Context, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	logger.FromContext(ctx).Error().Err(err).Msg("resolver failed")
+	return errors.New("Internal Server Error")
+}
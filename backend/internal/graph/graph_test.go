@@ -0,0 +1,253 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository/memory"
+	"test-crud-user-orders/internal/usecase"
+)
+
+// countingUserUseCase counts the batches of Users
+type countingUserUseCase struct {
+	usecase.UserUseCase
+	batches int32
+}
+
+func (uc *countingUserUseCase) GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error) {
+	atomic.AddInt32(&uc.batches, 1)
+	return uc.UserUseCase.GetByIDs(ctx, ids)
+}
+
+// countingOrderItemUseCase counts the batches of Order Items
+type countingOrderItemUseCase struct {
+	usecase.OrderItemUseCase
+	batches int32
+}
+
+func (uc *countingOrderItemUseCase) GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error) {
+	atomic.AddInt32(&uc.batches, 1)
+	return uc.OrderItemUseCase.GetByIDs(ctx, ids)
+}
+
+// countingOrderHistoryUseCase counts the batches of Order Histories of Users
+type countingOrderHistoryUseCase struct {
+	usecase.OrderHistoryUseCase
+	batches int32
+}
+
+func (uc *countingOrderHistoryUseCase) GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error) {
+	atomic.AddInt32(&uc.batches, 1)
+	return uc.OrderHistoryUseCase.GetByUserIDs(ctx, userIDs, limit)
+}
+
+// fixture is an Executor on in-memory repositories holding 3 Users with 2 Order Histories each
+type fixture struct {
+	executor     *Executor
+	store        *memory.Store
+	users        *countingUserUseCase
+	orderItems   *countingOrderItemUseCase
+	orderHistory *countingOrderHistoryUseCase
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	store := memory.NewStore()
+	userRepo := memory.NewUserRepository(store)
+	orderItemRepo := memory.NewOrderItemRepository(store)
+	outboxRepo := memory.NewOutboxRepository(store)
+	transactor := memory.NewTransactor(store)
+
+	f := &fixture{
+		store:        store,
+		users:        &countingUserUseCase{UserUseCase: usecase.NewUserUseCase(userRepo, outboxRepo, transactor)},
+		orderItems:   &countingOrderItemUseCase{OrderItemUseCase: usecase.NewOrderItemUseCase(orderItemRepo, outboxRepo, transactor, cache.NewLoader(cache.NewNoopCache(), nil, nil))},
+		orderHistory: &countingOrderHistoryUseCase{OrderHistoryUseCase: usecase.NewOrderHistoryUseCase(memory.NewOrderHistoryRepository(store), orderItemRepo, userRepo, outboxRepo, transactor)},
+	}
+	f.executor = NewExecutor(UseCases{User: f.users, OrderItem: f.orderItems, OrderHistory: f.orderHistory})
+
+	ctx := context.Background()
+	for _, name := range []string{"Ann", "Bob", "Cid"} {
+		user, err := f.users.Create(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, itemName := range []string{"Kopi", "Teh"} {
+			orderItem := &entity.OrderItem{Name: itemName, Price: 100, ExpiredAt: time.Now().AddDate(0, 0, 1)}
+			if err := f.orderItems.Create(ctx, orderItem); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.orderHistory.Create(ctx, user.ID, orderItem.ID, name+" "+itemName); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return f
+}
+
+// exec executes query and decodes its data into data, returning the messages of its errors
+func (f *fixture) exec(t *testing.T, query string, variables map[string]interface{}, data interface{}) []string {
+	t.Helper()
+	res := f.executor.Exec(context.Background(), Request{Query: query, Variables: variables})
+	var messages []string
+	for _, err := range res.Errors {
+		messages = append(messages, err.Message)
+	}
+	if len(res.Data) > 0 && data != nil {
+		if err := json.Unmarshal(res.Data, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return messages
+}
+
+func TestNestedRelationsAreBatched(t *testing.T) {
+	f := newFixture(t)
+
+	var data struct {
+		Users struct {
+			Nodes []struct {
+				Name           string
+				OrderHistories []struct {
+					Descriptions string
+					Status       string
+					User         struct{ Name string }
+					OrderItem    struct{ Name string }
+				}
+			}
+			Page struct{ Show, Total int }
+		}
+	}
+	errs := f.exec(t, `{
+		users(limit: 10) {
+			nodes { name orderHistories(limit: 1) { descriptions status user { name } orderItem { name } } }
+			page { show total }
+		}
+	}`, nil, &data)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	if len(data.Users.Nodes) != 3 || data.Users.Page.Show != 3 || data.Users.Page.Total != 3 {
+		t.Fatalf("users = %+v, want the 3 Users", data.Users)
+	}
+	for _, user := range data.Users.Nodes {
+		if len(user.OrderHistories) != 1 {
+			t.Fatalf("Order Histories of %s = %+v, want the first one", user.Name, user.OrderHistories)
+		}
+		orderHistory := user.OrderHistories[0]
+		if orderHistory.Descriptions != user.Name+" Kopi" || orderHistory.Status != entity.OrderPending || orderHistory.User.Name != user.Name || orderHistory.OrderItem.Name != "Kopi" {
+			t.Errorf("first Order History of %s = %+v", user.Name, orderHistory)
+		}
+	}
+
+	// one fetch per relation instead of one per User and per Order History
+	if f.orderHistory.batches != 1 || f.users.batches != 1 || f.orderItems.batches != 1 {
+		t.Errorf("batches of Order Histories, Users and Order Items = %d, %d, %d, want 1 each",
+			f.orderHistory.batches, f.users.batches, f.orderItems.batches)
+	}
+}
+
+func TestPreloadedRelationsAreNotLoadedAgain(t *testing.T) {
+	f := newFixture(t)
+
+	var data struct {
+		OrderHistories struct {
+			Nodes []struct {
+				User      struct{ Name string }
+				OrderItem struct{ Name string }
+			}
+			Page struct{ Limit, Page, Show, Total int }
+		}
+	}
+	errs := f.exec(t, `query($userId: ID) {
+		orderHistories(userId: $userId, limit: 1, page: 2) { nodes { user { name } orderItem { name } } page { limit page show total } }
+	}`, map[string]interface{}{"userId": "2"}, &data)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	page := data.OrderHistories.Page
+	if len(data.OrderHistories.Nodes) != 1 || page.Limit != 1 || page.Page != 2 || page.Show != 1 || page.Total != 2 {
+		t.Fatalf("second page of the Order Histories of Bob = %+v", data.OrderHistories)
+	}
+	if node := data.OrderHistories.Nodes[0]; node.User.Name != "Bob" || node.OrderItem.Name != "Teh" {
+		t.Errorf("Order History = %+v, want the Teh of Bob", node)
+	}
+	// the Order Item comes preloaded with the Order Histories of a User, only the User is batched
+	if f.orderItems.batches != 0 || f.users.batches != 1 {
+		t.Errorf("batches of Order Items and Users = %d, %d, want 0 and 1", f.orderItems.batches, f.users.batches)
+	}
+}
+
+func TestMissingRecordsResolveToNull(t *testing.T) {
+	f := newFixture(t)
+
+	var data map[string]interface{}
+	errs := f.exec(t, `{ user(id: "99") { name } orderItem(id: "abc") { name } orderHistory(id: "99") { id } }`, nil, &data)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	for _, field := range []string{"user", "orderItem", "orderHistory"} {
+		if value, ok := data[field]; !ok || value != nil {
+			t.Errorf("%s = %v, want null", field, value)
+		}
+	}
+
+	if errs := f.exec(t, `{ users { nodes { password } } }`, nil, nil); len(errs) != 1 {
+		t.Errorf("query of an unknown field = %v, want one validation error", errs)
+	}
+}
+
+func TestFailuresAreHidden(t *testing.T) {
+	f := newFixture(t)
+	f.store.FailWith(errors.New("connection refused"))
+
+	errs := f.exec(t, `{ user(id: "1") { name } }`, nil, nil)
+	if len(errs) != 1 || errs[0] != "Internal Server Error" {
+		t.Errorf("errors = %v, want Internal Server Error without the cause", errs)
+	}
+}
+
+func TestLoaderBatchesAndCachesKeys(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+	l := newLoader(10*time.Millisecond, 3, func(ctx context.Context, keys []int) (map[int]int, error) {
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+		values := map[int]int{}
+		for _, key := range keys {
+			values[key] = key * 10
+		}
+		return values, nil
+	})
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for _, key := range []int{1, 2, 3, 4, 1} {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			if value, err := l.Load(ctx, key); err != nil || value != key*10 {
+				t.Errorf("Load(%d) = %d, %v", key, value, err)
+			}
+		}(key)
+	}
+	wg.Wait()
+
+	// 4 distinct keys with batches of at most 3
+	if len(batches) != 2 || len(batches[0])+len(batches[1]) != 4 {
+		t.Errorf("batches = %v, want 2 holding the 4 keys once", batches)
+	}
+	if value, err := l.Load(ctx, 2); err != nil || value != 20 || len(batches) != 2 {
+		t.Errorf("Load of a cached key = %d, %v after %d batches, want 20 without a fetch", value, err, len(batches))
+	}
+}
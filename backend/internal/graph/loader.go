@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// loader batches the keys asked by resolvers running in parallel into one fetch, like a DataLoader:
+// the first key opens a batch that is fetched after wait, or as soon as it holds maxBatch keys.
+// Every value is kept for the rest of the request, so a key is never fetched twice.
+type loader[K comparable, V any] struct {
+	fetch    func(ctx context.Context, keys []K) (map[K]V, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	batch *batch[K, V]
+	seen  map[K]*batch[K, V]
+}
+
+// batch is one fetch of a loader, done is closed once values and err are set
+type batch[K comparable, V any] struct {
+	keys   []K
+	values map[K]V
+	err    error
+	done   chan struct{}
+}
+
+func newLoader[K comparable, V any](wait time.Duration, maxBatch int, fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, wait: wait, maxBatch: maxBatch, seen: map[K]*batch[K, V]{}}
+}
+
+// Load waits for the batch of key, a key missing from the fetched values gives the zero value of V
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.seen[key]
+	if !ok {
+		if l.batch == nil {
+			l.batch = &batch[K, V]{done: make(chan struct{})}
+			opened := l.batch
+			time.AfterFunc(l.wait, func() { l.dispatch(ctx, opened) })
+		}
+		b = l.batch
+		b.keys = append(b.keys, key)
+		l.seen[key] = b
+		if len(b.keys) >= l.maxBatch {
+			l.batch = nil
+			go b.run(ctx, l.fetch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.values[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches b when its wait is over, unless it was already fetched once full
+func (l *loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	if l.batch != b {
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+
+	b.run(ctx, l.fetch)
+}
+
+func (b *batch[K, V]) run(ctx context.Context, fetch func(ctx context.Context, keys []K) (map[K]V, error)) {
+	b.values, b.err = fetch(ctx, b.keys)
+	close(b.done)
+}
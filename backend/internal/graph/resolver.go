@@ -0,0 +1,266 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+// resolver resolves the fields of Query
+type resolver struct {
+	useCases UseCases
+}
+
+type pageArgs struct {
+	Limit int32
+	Page  int32
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, ok := idOf(args.ID)
+	if !ok {
+		return nil, nil
+	}
+	user, err := r.useCases.User.GetByID(ctx, id)
+	if notFound(err) || (err == nil && user == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, failure(ctx, err)
+	}
+	return &userResolver{user}, nil
+}
+
+func (r *resolver) Users(ctx context.Context, args pageArgs) (*list[*userResolver], error) {
+	limit, page, offset := pageOf(args.Limit, args.Page)
+	total := r.useCases.User.CountData(ctx)
+	var users []*entity.User
+	if offset < total {
+		var err error
+		if users, err = r.useCases.User.GetAllPagination(ctx, int(limit), int(offset)); err != nil {
+			return nil, failure(ctx, err)
+		}
+	}
+
+	nodes := make([]*userResolver, len(users))
+	for i, user := range users {
+		nodes[i] = &userResolver{user}
+	}
+	return newList(nodes, limit, page, total), nil
+}
+
+func (r *resolver) OrderItem(ctx context.Context, args struct{ ID graphql.ID }) (*orderItemResolver, error) {
+	id, ok := idOf(args.ID)
+	if !ok {
+		return nil, nil
+	}
+	orderItem, err := r.useCases.OrderItem.GetByID(ctx, id)
+	if notFound(err) || (err == nil && orderItem == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, failure(ctx, err)
+	}
+	return &orderItemResolver{orderItem}, nil
+}
+
+func (r *resolver) OrderItems(ctx context.Context, args pageArgs) (*list[*orderItemResolver], error) {
+	limit, page, offset := pageOf(args.Limit, args.Page)
+	total := r.useCases.OrderItem.CountData(ctx)
+	var orderItems []*entity.OrderItem
+	if offset < total {
+		var err error
+		if orderItems, err = r.useCases.OrderItem.GetAllPagination(ctx, int(limit), int(offset)); err != nil {
+			return nil, failure(ctx, err)
+		}
+	}
+
+	nodes := make([]*orderItemResolver, len(orderItems))
+	for i, orderItem := range orderItems {
+		nodes[i] = &orderItemResolver{orderItem}
+	}
+	return newList(nodes, limit, page, total), nil
+}
+
+func (r *resolver) OrderHistory(ctx context.Context, args struct{ ID graphql.ID }) (*orderHistoryResolver, error) {
+	id, ok := idOf(args.ID)
+	if !ok {
+		return nil, nil
+	}
+	orderHistory, err := r.useCases.OrderHistory.GetByID(ctx, id)
+	if notFound(err) || (err == nil && orderHistory == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, failure(ctx, err)
+	}
+	return &orderHistoryResolver{orderHistory}, nil
+}
+
+func (r *resolver) OrderHistories(ctx context.Context, args struct {
+	UserID *graphql.ID
+	Limit  int32
+	Page   int32
+}) (*list[*orderHistoryResolver], error) {
+	limit, page, offset := pageOf(args.Limit, args.Page)
+	userID := 0
+	if args.UserID != nil {
+		var ok bool
+		if userID, ok = idOf(*args.UserID); !ok {
+			return newList([]*orderHistoryResolver{}, limit, page, 0), nil
+		}
+	}
+
+	total := r.useCases.OrderHistory.CountData(ctx, userID)
+	var orderHistories []*entity.OrderHistory
+	if offset < total {
+		var err error
+		if userID > 0 {
+			orderHistories, err = r.useCases.OrderHistory.GetByUserID(ctx, userID, int(limit), int(offset))
+		} else {
+			orderHistories, err = r.useCases.OrderHistory.GetAllPagination(ctx, int(limit), int(offset))
+		}
+		if err != nil {
+			return nil, failure(ctx, err)
+		}
+	}
+
+	nodes := make([]*orderHistoryResolver, len(orderHistories))
+	for i, orderHistory := range orderHistories {
+		nodes[i] = &orderHistoryResolver{orderHistory}
+	}
+	return newList(nodes, limit, page, total), nil
+}
+
+// list resolves UserList, OrderItemList and OrderHistoryList
+type list[T any] struct {
+	nodes []T
+	page  *pageResolver
+}
+
+func newList[T any](nodes []T, limit, page int32, total int64) *list[T] {
+	return &list[T]{nodes, &pageResolver{limit: limit, page: page, show: int32(len(nodes)), total: int32(total)}}
+}
+
+func (l *list[T]) Nodes() []T {
+	return l.nodes
+}
+
+func (l *list[T]) Page() *pageResolver {
+	return l.page
+}
+
+type pageResolver struct {
+	limit, page, show, total int32
+}
+
+func (p *pageResolver) Limit() int32 { return p.limit }
+func (p *pageResolver) Page() int32  { return p.page }
+func (p *pageResolver) Show() int32  { return p.show }
+func (p *pageResolver) Total() int32 { return p.total }
+
+func toID(id int) graphql.ID {
+	return graphql.ID(strconv.Itoa(id))
+}
+
+type userResolver struct {
+	user *entity.User
+}
+
+func (r *userResolver) ID() graphql.ID          { return toID(r.user.ID) }
+func (r *userResolver) Name() string            { return r.user.FullName }
+func (r *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.user.CreatedAt} }
+func (r *userResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.user.UpdatedAt} }
+func (r *userResolver) FirstOrder() *graphql.Time {
+	if r.user.FirstOrder == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.user.FirstOrder}
+}
+
+// OrderHistories waits for the Order Histories of every User of the response, fetched at once
+func (r *userResolver) OrderHistories(ctx context.Context, args struct{ Limit int32 }) ([]*orderHistoryResolver, error) {
+	limit, _, _ := pageOf(args.Limit, 1)
+	orderHistories, err := loadersFrom(ctx).orderHistories.Load(ctx, userOrders{UserID: r.user.ID, Limit: int(limit)})
+	if err != nil {
+		return nil, failure(ctx, err)
+	}
+	nodes := make([]*orderHistoryResolver, len(orderHistories))
+	for i, orderHistory := range orderHistories {
+		nodes[i] = &orderHistoryResolver{orderHistory}
+	}
+	return nodes, nil
+}
+
+type orderItemResolver struct {
+	orderItem *entity.OrderItem
+}
+
+func (r *orderItemResolver) ID() graphql.ID { return toID(r.orderItem.ID) }
+func (r *orderItemResolver) Name() string   { return r.orderItem.Name }
+func (r *orderItemResolver) Price() int32   { return int32(r.orderItem.Price) }
+func (r *orderItemResolver) ExpiredAt() graphql.Time {
+	return graphql.Time{Time: r.orderItem.ExpiredAt}
+}
+func (r *orderItemResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.orderItem.CreatedAt}
+}
+func (r *orderItemResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.orderItem.UpdatedAt}
+}
+
+type orderHistoryResolver struct {
+	orderHistory *entity.OrderHistory
+}
+
+func (r *orderHistoryResolver) ID() graphql.ID          { return toID(r.orderHistory.ID) }
+func (r *orderHistoryResolver) UserID() graphql.ID      { return toID(r.orderHistory.UserID) }
+func (r *orderHistoryResolver) OrderItemID() graphql.ID { return toID(r.orderHistory.OrderItemID) }
+func (r *orderHistoryResolver) Descriptions() string    { return r.orderHistory.Descriptions }
+func (r *orderHistoryResolver) Status() string          { return r.orderHistory.Status }
+func (r *orderHistoryResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.orderHistory.CreatedAt}
+}
+func (r *orderHistoryResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.orderHistory.UpdatedAt}
+}
+func (r *orderHistoryResolver) Price() *int32 {
+	if r.orderHistory.Price == nil {
+		return nil
+	}
+	price := int32(*r.orderHistory.Price)
+	return &price
+}
+
+// User is the User preloaded with the Order History, or else the one of the batch of the response
+func (r *orderHistoryResolver) User(ctx context.Context) (*userResolver, error) {
+	user := r.orderHistory.User
+	if user == nil {
+		var err error
+		if user, err = loadersFrom(ctx).users.Load(ctx, r.orderHistory.UserID); err != nil {
+			return nil, failure(ctx, err)
+		}
+	}
+	if user == nil {
+		return nil, nil
+	}
+	return &userResolver{user}, nil
+}
+
+// OrderItem is the Order Item preloaded with the Order History, or else the one of the batch of the response
+func (r *orderHistoryResolver) OrderItem(ctx context.Context) (*orderItemResolver, error) {
+	orderItem := r.orderHistory.OrderItem
+	if orderItem == nil {
+		var err error
+		if orderItem, err = loadersFrom(ctx).orderItems.Load(ctx, r.orderHistory.OrderItemID); err != nil {
+			return nil, failure(ctx, err)
+		}
+	}
+	if orderItem == nil {
+		return nil, nil
+	}
+	return &orderItemResolver{orderItem}, nil
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/template"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxBulkOperations limits the number of operations in one bulk request
+const maxBulkOperations = 1000
+
+// bindBulk reads the array of operations and the execution mode from ?mode=atomic|partial,
+// atomic (all-or-nothing) is the default
+func bindBulk(c echo.Context) ([]entity.BulkOperation, bool, error) {
+	var atomic bool
+	switch c.QueryParam("mode") {
+	case "", "atomic":
+		atomic = true
+	case "partial":
+		atomic = false
+	default:
+		return nil, false, echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "Mode Must Be atomic or partial",
+		})
+	}
+
+	var operations []entity.BulkOperation
+	if err := json.NewDecoder(c.Request().Body).Decode(&operations); err != nil {
+		return nil, false, echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Invalid Request",
+		})
+	}
+	if len(operations) < 1 {
+		return nil, false, echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "Zero Operations",
+		})
+	}
+	if len(operations) > maxBulkOperations {
+		return nil, false, echo.NewHTTPError(http.StatusRequestEntityTooLarge, template.ResponseHTTP{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("Maximum %d Operations per Request", maxBulkOperations),
+		})
+	}
+
+	return operations, atomic, nil
+}
+
+// bindBulkData validates one operation and decodes its data into dst, dst is nil for operations without data
+func bindBulkData(c echo.Context, operation entity.BulkOperation, dst interface{}) error {
+	if err := c.Validate(&operation); err != nil {
+		return validationError(err)
+	}
+	if dst == nil {
+		return nil
+	}
+	if len(operation.Data) == 0 {
+		return errors.New("data is required")
+	}
+	if err := json.Unmarshal(operation.Data, dst); err != nil {
+		return err
+	}
+	if err := c.Validate(dst); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
+
+// bulkResponse executes the valid operations and answers with one result per operation.
+// An atomic request with any invalid operation is rejected without executing anything.
+func bulkResponse(c echo.Context, atomic bool, invalid []entity.BulkResult, valid []entity.BulkResult, execute func() []entity.BulkResult) error {
+	if atomic && len(invalid) > 0 {
+		for _, result := range valid {
+			result.Error = "not executed"
+			invalid = append(invalid, result)
+		}
+		sortBulkResults(invalid)
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Data:    invalid,
+			Message: "Bulk Request Has Invalid Operations",
+		})
+	}
+
+	var results []entity.BulkResult
+	if len(valid) > 0 {
+		results = execute()
+	}
+	results = append(results, invalid...)
+	sortBulkResults(results)
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	status := http.StatusOK
+	message := "OK"
+	if failed > 0 && atomic {
+		status = http.StatusUnprocessableEntity
+		message = "Bulk Request Rolled Back"
+	} else if failed > 0 {
+		status = http.StatusMultiStatus
+		message = fmt.Sprintf("%d of %d Operations Failed", failed, len(results))
+	}
+
+	response := template.ResponseHTTP{
+		Status:  status,
+		Data:    results,
+		Message: message,
+	}
+	if status == http.StatusUnprocessableEntity {
+		return echo.NewHTTPError(status, response)
+	}
+	return c.JSON(status, response)
+}
+
+func sortBulkResults(results []entity.BulkResult) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+}
+
+// validationError unwraps the message of the CustomValidator error
+func validationError(err error) error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return fmt.Errorf("%v", httpErr.Message)
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>CRUD - User Orders API</title>
+  <link rel="stylesheet" type="text/css" href="/docs/swagger-ui.css">
+  <link rel="icon" type="image/png" href="/docs/favicon-32x32.png" sizes="32x32">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js" charset="UTF-8"></script>
+  <script src="/docs/swagger-ui-standalone-preset.js" charset="UTF-8"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({
+        url: "/openapi.json",
+        dom_id: "#swagger-ui",
+        deepLinking: true,
+        presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+        layout: "StandaloneLayout"
+      });
+    };
+  </script>
+</body>
+</html>`
+
+type DocsHandler struct {
+	spec   []byte
+	assets http.Handler
+}
+
+// NewDocsHandler serves spec (an OpenAPI document) and the bundled Swagger UI
+func NewDocsHandler(spec interface{}) (*DocsHandler, error) {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{
+		spec:   specJSON,
+		assets: http.StripPrefix("/docs", http.FileServer(swaggerFiles.HTTP)),
+	}, nil
+}
+
+// OpenAPI Func for Get the OpenAPI Document of this Service
+func (h *DocsHandler) OpenAPI(c echo.Context) error {
+	return c.JSONBlob(http.StatusOK, h.spec)
+}
+
+// SwaggerUI Func for Get the Swagger UI page reading /openapi.json
+func (h *DocsHandler) SwaggerUI(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUIPage)
+}
+
+// Assets Func for Get the static files of the Swagger UI
+func (h *DocsHandler) Assets(c echo.Context) error {
+	h.assets.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
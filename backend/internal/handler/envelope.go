@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/apiversion"
+	"test-crud-user-orders/internal/template"
+)
+
+// JSONSerializer writes the template.ResponseHTTP of the routes of apiversion.V2 as a template.ResponseV2,
+// whose status always is the one of HTTP. The older versions keep the envelope of their first release.
+type JSONSerializer struct {
+	echo.DefaultJSONSerializer
+}
+
+func (s JSONSerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	if version, ok := apiversion.FromContext(c); ok && version == apiversion.V2 {
+		switch response := i.(type) {
+		case template.ResponseHTTP:
+			i = responseV2(c, response)
+		case *template.ResponseHTTP:
+			i = responseV2(c, *response)
+		}
+	}
+	return s.DefaultJSONSerializer.Serialize(c, i, indent)
+}
+
+// responseV2 moves the message and the cause of response to the error of a failed request,
+// the cause of a server error stays in the logs
+func responseV2(c echo.Context, response template.ResponseHTTP) template.ResponseV2 {
+	status := c.Response().Status
+	v2 := template.ResponseV2{
+		Data:      response.Data,
+		Page:      response.Page,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+	if status < http.StatusBadRequest {
+		return v2
+	}
+
+	v2.Error = &template.ErrorV2{
+		Status:  status,
+		Code:    errorCode(status),
+		Message: response.Message,
+	}
+	if status < http.StatusInternalServerError && response.Error != nil {
+		v2.Error.Details = response.Error.Error()
+	}
+	return v2
+}
+
+// errorCode is the text of status in snake case, not_found for 404
+func errorCode(status int) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		case r == '\'':
+			return -1
+		}
+		return '_'
+	}, http.StatusText(status))
+}
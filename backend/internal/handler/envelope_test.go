@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"test-crud-user-orders/internal/template"
+)
+
+func TestV2Envelope(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+	api.createOrderItem(t, "Tea", 100)
+	api.run(t, []request{
+		{"order", http.MethodPost, "/order-histories/", "", `{"user_id":1,"order_item_id":1,"descriptions":"first"}`, http.StatusCreated, "OK"},
+	})
+
+	v2 := func(method, path, body string) (int, template.ResponseV2, map[string]json.RawMessage) {
+		t.Helper()
+		rec := api.do(method, path, "", body)
+		var res template.ResponseV2
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s %s is not a v2 envelope: %v\n%s", method, path, err, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, res, fields
+	}
+
+	// v1 answers the status of its first release in the body, v2 the one of HTTP
+	if res := decode(t, api.do(http.MethodPut, "/order-histories/1", "", `{"user_id":1,"order_item_id":1,"descriptions":"again"}`)); res.Status != http.StatusBadRequest {
+		t.Errorf("v1 update status = %d, the frozen envelope answers 400", res.Status)
+	}
+	code, res, fields := v2(http.MethodPut, "/v2/order-histories/1", `{"user_id":1,"order_item_id":1,"descriptions":"again"}`)
+	if code != http.StatusOK || res.Error != nil {
+		t.Errorf("v2 update = %d with error %+v, want 200 without error", code, res.Error)
+	}
+	if _, ok := fields["data"]; !ok {
+		t.Error("v2 answer without data, it is always present")
+	}
+	if _, ok := fields["status"]; ok {
+		t.Error("v2 answer with the status of v1")
+	}
+
+	code, res, _ = v2(http.MethodGet, "/v2/order-histories/", "")
+	if code != http.StatusOK || res.Page == nil || res.Data == nil {
+		t.Errorf("v2 list = %d with data %v and page %v", code, res.Data, res.Page)
+	}
+
+	for _, tc := range []struct {
+		name, method, path, body string
+		want                     template.ErrorV2
+		details                  bool
+	}{
+		{"not found", http.MethodGet, "/v2/order-histories/9", "", template.ErrorV2{Status: http.StatusNotFound, Code: "not_found", Message: "Order History Not Found"}, false},
+		{"unknown ID", http.MethodGet, "/v2/users/abc", "", template.ErrorV2{Status: http.StatusBadRequest, Code: "bad_request", Message: "Unknown ID"}, true},
+		{"invalid body", http.MethodPost, "/v2/order-histories/", `{"user_id":1}`, template.ErrorV2{Status: http.StatusBadRequest, Code: "bad_request", Message: "Bad Request"}, true},
+		{"forbidden", http.MethodDelete, "/v2/order-histories/1", "", template.ErrorV2{Status: http.StatusForbidden, Code: "forbidden", Message: "Delete Transaction Not Allowed"}, false},
+	} {
+		code, res, _ := v2(tc.method, tc.path, tc.body)
+		if code != tc.want.Status || res.Error == nil {
+			t.Errorf("%s = %d with error %+v, want %d", tc.name, code, res.Error, tc.want.Status)
+			continue
+		}
+		got := *res.Error
+		if (got.Details != "") != tc.details {
+			t.Errorf("%s details = %q, want some: %v", tc.name, got.Details, tc.details)
+		}
+		got.Details = ""
+		if got != tc.want {
+			t.Errorf("%s error = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
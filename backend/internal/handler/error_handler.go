@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/template"
+	"test-crud-user-orders/pkg/logger"
+)
+
+// NewErrorHandler answers every error as a template.ResponseHTTP carrying the request ID,
+// so a client can quote it when reporting a failure. The cause of a 5xx is logged with the request.
+func NewErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) {
+			httpErr = echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+				Status:  http.StatusInternalServerError,
+				Error:   err,
+				Message: "Internal Server Error",
+			})
+		}
+
+		response, ok := httpErr.Message.(template.ResponseHTTP)
+		if !ok {
+			response = template.ResponseHTTP{Status: httpErr.Code, Message: fmt.Sprint(httpErr.Message)}
+		}
+		if httpErr.Code >= http.StatusInternalServerError && response.Error != nil {
+			logger.SetError(c, response.Error)
+		}
+		response.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+		e.DefaultHTTPErrorHandler(&echo.HTTPError{Code: httpErr.Code, Message: response, Internal: httpErr.Internal}, c)
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"test-crud-user-orders/pkg/xlsx"
+)
+
+// exportFlushRows is the number of rows written between two flushes to the client
+const exportFlushRows = 100
+
+// formulaPrefixes start a text a spreadsheet evaluates as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// rowWriter is implemented by every export format
+type rowWriter interface {
+	WriteRow(cells ...interface{}) error
+	Flush() error
+	Close() error
+}
+
+// newRowWriter returns the writer and Content-Type of an export format (csv or xlsx), the text
+// cells of both are escaped with escapeFormula
+func newRowWriter(format string, w io.Writer, sheetName string) (rowWriter, string, error) {
+	switch format {
+	case "", "csv":
+		return formulaSafeWriter{&csvRowWriter{csv.NewWriter(w)}}, "text/csv; charset=utf-8", nil
+	case "xlsx":
+		writer, err := xlsx.NewStreamWriter(w, sheetName)
+		if err != nil {
+			return nil, "", err
+		}
+		return formulaSafeWriter{writer}, xlsx.ContentType, nil
+	}
+	return nil, "", fmt.Errorf("unknown export format %q", format)
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+		case int:
+			record[i] = strconv.Itoa(v)
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
+
+// escapeFormula prefixes with a quote a text a spreadsheet would evaluate as a formula, so a name
+// like =HYPERLINK(...) entered by a user is shown as typed instead of run
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// formulaSafeWriter escapes the text cells of every row before writing them
+type formulaSafeWriter struct {
+	rowWriter
+}
+
+func (f formulaSafeWriter) WriteRow(cells ...interface{}) error {
+	escaped := make([]interface{}, len(cells))
+	for i, cell := range cells {
+		if text, ok := cell.(string); ok {
+			cell = escapeFormula(text)
+		}
+		escaped[i] = cell
+	}
+	return f.rowWriter.WriteRow(escaped...)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/graph"
+	"test-crud-user-orders/internal/template"
+)
+
+type GraphQLHandler struct {
+	executor *graph.Executor
+}
+
+func NewGraphQLHandler(executor *graph.Executor) *GraphQLHandler {
+	return &GraphQLHandler{executor}
+}
+
+// Query Func for Executing a GraphQL query, sent as a JSON body on POST or as ?query=&variables= on GET.
+// Errors of the query itself are answered with 200 in the errors of the GraphQL response.
+func (h *GraphQLHandler) Query(c echo.Context) error {
+	var req graph.Request
+	if c.Request().Method == http.MethodGet {
+		req.Query = c.QueryParam("query")
+		req.OperationName = c.QueryParam("operationName")
+		if variables := c.QueryParam("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+					Status:  http.StatusBadRequest,
+					Message: "variables Must Be a JSON Object",
+				})
+			}
+		}
+	} else if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Bad Request",
+		})
+	}
+
+	if req.Query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "query Is Required",
+		})
+	}
+
+	return c.JSON(http.StatusOK, h.executor.Exec(c.Request().Context(), req))
+}
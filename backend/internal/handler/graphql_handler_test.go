@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGraphQLRequests(t *testing.T) {
+	api := newTestAPI(t)
+	api.run(t, []request{
+		{name: "POST without a query", method: http.MethodPost, path: "/graphql", body: `{"variables":{}}`, wantStatus: http.StatusBadRequest, wantMessage: "query Is Required"},
+		{name: "POST of invalid JSON", method: http.MethodPost, path: "/graphql", body: `{"query":`, wantStatus: http.StatusBadRequest, wantMessage: "Bad Request"},
+		{name: "GET without a query", method: http.MethodGet, path: "/graphql", wantStatus: http.StatusBadRequest, wantMessage: "query Is Required"},
+		{name: "GET with variables that are not JSON", method: http.MethodGet, path: "/graphql?query=%7Busers%7Bpage%7Btotal%7D%7D%7D&variables=x", wantStatus: http.StatusBadRequest, wantMessage: "variables Must Be a JSON Object"},
+	})
+}
+
+func TestGraphQLQueriesNestedRelations(t *testing.T) {
+	api := newTestAPI(t)
+	userID := api.createUser(t, "Budi")
+	orderItemID := api.createOrderItem(t, "Kopi", 15000)
+	body := fmt.Sprintf(`{"user_id":%d,"order_item_id":%d,"descriptions":"pagi"}`, userID, orderItemID)
+	if rec := api.do(http.MethodPost, "/order-histories/", "", body); rec.Code != http.StatusCreated {
+		t.Fatalf("create order history = %d\n%s", rec.Code, rec.Body.String())
+	}
+
+	var res struct {
+		Data struct {
+			User struct {
+				Name           string
+				OrderHistories []struct {
+					Price     int
+					OrderItem struct{ Name string }
+				}
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	query := `query($id: ID!) { user(id: $id) { name orderHistories { price orderItem { name } } } }`
+	variables := fmt.Sprintf(`{"id":"%d"}`, userID)
+	for _, rec := range []*httptest.ResponseRecorder{
+		api.do(http.MethodGet, "/graphql?"+url.Values{"query": {query}, "variables": {variables}}.Encode(), "", ""),
+		api.do(http.MethodPost, "/graphql", "", `{"query":"`+query+`","variables":`+variables+`}`),
+	} {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200\n%s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		user := res.Data.User
+		if len(res.Errors) > 0 || user.Name != "Budi" || len(user.OrderHistories) != 1 ||
+			user.OrderHistories[0].Price != 15000 || user.OrderHistories[0].OrderItem.Name != "Kopi" {
+			t.Errorf("response = %+v, want Budi with the Kopi ordered", res)
+		}
+	}
+
+	rec := api.do(http.MethodPost, "/graphql", "", `{"query":"{ users { nodes { password } } }"}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK || len(res.Errors) != 1 {
+		t.Errorf("query of an unknown field = %d %s, want 200 with one error", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/apiversion"
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/graph"
+	"test-crud-user-orders/internal/live"
+	"test-crud-user-orders/internal/repository/memory"
+	"test-crud-user-orders/internal/stream"
+	"test-crud-user-orders/internal/usecase"
+)
+
+// testValidator validates like the CustomValidator of the Server
+type testValidator struct {
+	validator *validator.Validate
+}
+
+func (v *testValidator) Validate(i interface{}) error {
+	if err := v.validator.Struct(i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// testAPI serves the CRUD, GraphQL and webhook routes on in-memory repositories, the report routes on a stub
+// and the order stream and socket on a Hub of its own
+type testAPI struct {
+	e      *echo.Echo
+	store  *memory.Store
+	report *stubReportUseCase
+	stream *stream.Hub
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	store := memory.NewStore()
+	userRepo := memory.NewUserRepository(store)
+	orderItemRepo := memory.NewOrderItemRepository(store)
+	orderHistoryRepo := memory.NewOrderHistoryRepository(store)
+	outboxRepo := memory.NewOutboxRepository(store)
+	transactor := memory.NewTransactor(store)
+
+	userUseCase := usecase.NewUserUseCase(userRepo, outboxRepo, transactor)
+	orderItemUseCase := usecase.NewOrderItemUseCase(orderItemRepo, outboxRepo, transactor, cache.NewLoader(cache.NewNoopCache(), nil, nil))
+	orderHistoryUseCase := usecase.NewOrderHistoryUseCase(orderHistoryRepo, orderItemRepo, userRepo, outboxRepo, transactor)
+	user := NewUserHandler(userUseCase)
+	orderItem := NewOrderItemHandler(orderItemUseCase)
+	orderHistory := NewOrderHistoryHandler(orderHistoryUseCase)
+	reportUseCase := &stubReportUseCase{}
+	report := NewReportHandler(reportUseCase)
+	hub := stream.NewHub(10)
+	orderStream := NewOrderStreamHandler(hub, time.Minute)
+	orderSocket := NewOrderSocketHandler(hub, socketSecret, live.Options{PingInterval: time.Minute, PongTimeout: 2 * time.Minute, WriteTimeout: time.Second})
+	graphql := NewGraphQLHandler(graph.NewExecutor(graph.UseCases{User: userUseCase, OrderItem: orderItemUseCase, OrderHistory: orderHistoryUseCase}))
+	webhook := NewWebhookHandler(usecase.NewWebhookUseCase(memory.NewWebhookRepository(store), memory.NewWebhookDeliveryRepository(store)))
+
+	e := echo.New()
+	e.Validator = &testValidator{validator: validator.New()}
+	e.HTTPErrorHandler = NewErrorHandler(e)
+	e.JSONSerializer = JSONSerializer{}
+
+	// the REST API under /v2 too, told apart by its envelope
+	e.Use(apiversion.Middleware(apiversion.Config{Resources: []string{"users", "order-items", "order-histories", "reports", "webhooks"}}))
+	for _, prefix := range []string{"", "/v2"} {
+		g := e.Group(prefix)
+		g.POST("/users/", user.Create)
+		g.POST("/users/bulk", user.Bulk)
+		g.GET("/users/", user.GetAllPagination)
+		g.GET("/users/:id", user.GetByID)
+		g.PUT("/users/:id", user.Update)
+		g.PATCH("/users/:id", user.Patch)
+		g.DELETE("/users/:id", user.Delete)
+		g.GET("/users/:id/order-histories", orderHistory.GetHistoryByUserID)
+		g.GET("/users/:id/stats", report.UserStats)
+
+		g.POST("/order-items/", orderItem.Create)
+		g.POST("/order-items/bulk", orderItem.Bulk)
+		g.POST("/order-items/import", orderItem.Import)
+		g.GET("/order-items/", orderItem.GetAllPagination)
+		g.GET("/order-items/:id", orderItem.GetByID)
+		g.PUT("/order-items/:id", orderItem.Update)
+		g.PATCH("/order-items/:id", orderItem.Patch)
+		g.DELETE("/order-items/:id", orderItem.Delete)
+
+		g.POST("/order-histories/", orderHistory.Create)
+		g.POST("/order-histories/bulk", orderHistory.Bulk)
+		g.GET("/order-histories/", orderHistory.GetAllPagination)
+		g.GET("/order-histories/export", orderHistory.Export)
+		g.GET("/order-histories/stream", orderStream.Stream)
+		g.GET("/order-histories/:id", orderHistory.GetByID)
+		g.PUT("/order-histories/:id", orderHistory.Update)
+		g.PATCH("/order-histories/:id", orderHistory.Patch)
+		g.DELETE("/order-histories/:id", orderHistory.Delete)
+
+		g.GET("/reports/revenue", report.Revenue)
+		g.GET("/reports/top-items", report.TopItems)
+		g.GET("/reports/top-users", report.TopUsers)
+		g.GET("/reports/cohorts", report.Cohorts)
+
+		g.POST("/webhooks/", webhook.Create)
+		g.GET("/webhooks/", webhook.GetAllPagination)
+		g.GET("/webhooks/:id", webhook.GetByID)
+		g.PATCH("/webhooks/:id", webhook.Patch)
+		g.DELETE("/webhooks/:id", webhook.Delete)
+		g.GET("/webhooks/:id/deliveries", webhook.Deliveries)
+		g.GET("/webhooks/:id/deliveries/:delivery_id/attempts", webhook.Attempts)
+		g.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhook.Redeliver)
+	}
+
+	e.GET("/ws/users/:id/orders", orderSocket.Orders)
+	e.GET("/graphql", graphql.Query)
+	e.POST("/graphql", graphql.Query)
+
+	return &testAPI{e: e, store: store, report: reportUseCase, stream: hub}
+}
+
+// request is one call of a table test and the answer it expects
+type request struct {
+	name        string
+	method      string
+	path        string
+	contentType string
+	body        string
+	wantStatus  int
+	wantMessage string
+}
+
+// run sends every request in order and checks the status and message of each answer
+func (api *testAPI) run(t *testing.T, requests []request) {
+	t.Helper()
+	for _, r := range requests {
+		rec := api.do(r.method, r.path, r.contentType, r.body)
+		if rec.Code != r.wantStatus {
+			t.Errorf("%s: %s %s = %d, want %d\n%s", r.name, r.method, r.path, rec.Code, r.wantStatus, rec.Body.String())
+			continue
+		}
+		if r.wantMessage != "" {
+			if message := decode(t, rec).Message; message != r.wantMessage {
+				t.Errorf("%s: message = %q, want %q", r.name, message, r.wantMessage)
+			}
+		}
+	}
+}
+
+func (api *testAPI) do(method, path, contentType, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+		if contentType == "" {
+			contentType = echo.MIMEApplicationJSON
+		}
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	api.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// response is template.ResponseHTTP as read by a client
+type response struct {
+	Status  int             `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Page    struct {
+		Limit int64 `json:"limit"`
+		Page  int64 `json:"page"`
+		Show  int   `json:"show"`
+		Total int64 `json:"total"`
+	} `json:"page"`
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) response {
+	t.Helper()
+	var res response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("answer is not a JSON response: %v\n%s", err, rec.Body.String())
+	}
+	return res
+}
+
+func (api *testAPI) createUser(t *testing.T, name string) int {
+	t.Helper()
+	rec := api.do(http.MethodPost, "/users/", "", `{"name":"`+name+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create user = %d\n%s", rec.Code, rec.Body.String())
+	}
+	var user entity.User
+	if err := json.Unmarshal(decode(t, rec).Data, &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func (api *testAPI) createOrderItem(t *testing.T, name string, price int) int {
+	t.Helper()
+	orderItem := &entity.OrderItem{Name: name, Price: price, ExpiredAt: generateTime(1)}
+	if err := memory.NewOrderItemRepository(api.store).Create(context.Background(), orderItem); err != nil {
+		t.Fatal(err)
+	}
+	return orderItem.ID
+}
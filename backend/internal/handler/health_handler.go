@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/template"
+)
+
+// HealthCheck probes one dependency, a failing Critical check makes the service not ready
+// while any other failing check only reports it as degraded
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	started time.Time
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	ready    bool
+	draining bool
+	checks   []HealthCheck
+}
+
+// NewHealthHandler reports "starting" on /readyz until SetReady is called, every check gets timeout to answer
+func NewHealthHandler(timeout time.Duration) *HealthHandler {
+	return &HealthHandler{started: time.Now(), timeout: timeout}
+}
+
+// SetReady ends the startup of the service, /readyz runs checks from now on
+func (h *HealthHandler) SetReady(checks ...HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ready = true
+	h.checks = checks
+}
+
+// Ready reports whether the startup of the service is over
+func (h *HealthHandler) Ready() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.ready
+}
+
+// SetDraining marks the service as shutting down, /readyz answers 503 from now on so that no new
+// traffic is sent while the requests in flight are still served
+func (h *HealthHandler) SetDraining() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.draining = true
+}
+
+// Healthz Func for Get the liveness of the process, it never checks a dependency
+func (h *HealthHandler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Message: "OK",
+		Data:    entity.Health{Status: entity.HealthOK, Uptime: h.uptime()},
+	})
+}
+
+// Readyz Func for Get the readiness of the service with the status of every dependency
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	h.mu.RLock()
+	ready, draining, checks := h.ready, h.draining, h.checks
+	h.mu.RUnlock()
+
+	if draining {
+		return c.JSON(http.StatusServiceUnavailable, template.ResponseHTTP{
+			Status:  http.StatusServiceUnavailable,
+			Message: "Service Is Shutting Down",
+			Data:    entity.Health{Status: entity.HealthDraining, Uptime: h.uptime()},
+		})
+	}
+	if !ready {
+		return c.JSON(http.StatusServiceUnavailable, template.ResponseHTTP{
+			Status:  http.StatusServiceUnavailable,
+			Message: "Service Is Starting",
+			Data:    entity.Health{Status: entity.HealthStarting, Uptime: h.uptime()},
+		})
+	}
+
+	health := entity.Health{
+		Status: entity.HealthOK,
+		Uptime: h.uptime(),
+		Checks: h.run(c.Request().Context(), checks),
+	}
+	for _, result := range health.Checks {
+		if result.Status == entity.HealthOK {
+			continue
+		}
+		if result.Critical {
+			health.Status = entity.HealthDown
+			break
+		}
+		health.Status = entity.HealthDegraded
+	}
+
+	status, message := http.StatusOK, "OK"
+	switch health.Status {
+	case entity.HealthDown:
+		status, message = http.StatusServiceUnavailable, "Service Unavailable"
+	case entity.HealthDegraded:
+		message = "Degraded"
+	}
+	return c.JSON(status, template.ResponseHTTP{
+		Status:  status,
+		Message: message,
+		Data:    health,
+	})
+}
+
+// ReadinessGate answers 503 to every request but the health endpoints until the service is ready
+func (h *HealthHandler) ReadinessGate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.Ready() || c.Path() == "/healthz" || c.Path() == "/readyz" {
+			return next(c)
+		}
+		c.Response().Header().Set(echo.HeaderRetryAfter, "5")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, template.ResponseHTTP{
+			Status:  http.StatusServiceUnavailable,
+			Message: "Service Is Starting",
+		})
+	}
+}
+
+// run probes every dependency at once, each within the timeout of the handler
+func (h *HealthHandler) run(ctx context.Context, checks []HealthCheck) map[string]entity.HealthCheckResult {
+	results := make(map[string]entity.HealthCheckResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			result := entity.HealthCheckResult{
+				Status:    entity.HealthOK,
+				Critical:  check.Critical,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = entity.HealthDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	return results
+}
+
+func (h *HealthHandler) uptime() string {
+	return time.Since(h.started).Round(time.Second).String()
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHealthRoutesFollowTheLifecycle(t *testing.T) {
+	health := NewHealthHandler(time.Second)
+	e := echo.New()
+	e.Use(health.ReadinessGate)
+	e.GET("/healthz", health.Healthz)
+	e.GET("/readyz", health.Readyz)
+	e.GET("/users/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	api := &testAPI{e: e}
+
+	api.run(t, []request{
+		{"alive while starting", http.MethodGet, "/healthz", "", "", http.StatusOK, "OK"},
+		{"not ready while starting", http.MethodGet, "/readyz", "", "", http.StatusServiceUnavailable, "Service Is Starting"},
+	})
+	if rec := api.do(http.MethodGet, "/users/", "", ""); rec.Code != http.StatusServiceUnavailable || rec.Header().Get(echo.HeaderRetryAfter) != "5" {
+		t.Errorf("API while starting = %d with Retry-After %q, want 503 and 5", rec.Code, rec.Header().Get(echo.HeaderRetryAfter))
+	}
+
+	failing := errors.New("unreachable")
+	var cacheErr, databaseErr error
+	health.SetReady(
+		HealthCheck{Name: "database", Critical: true, Check: func(ctx context.Context) error { return databaseErr }},
+		HealthCheck{Name: "redis", Check: func(ctx context.Context) error { return cacheErr }},
+	)
+	api.run(t, []request{
+		{"ready", http.MethodGet, "/readyz", "", "", http.StatusOK, "OK"},
+		{"API once ready", http.MethodGet, "/users/", "", "", http.StatusOK, ""},
+	})
+
+	cacheErr = failing
+	api.run(t, []request{{"cache down", http.MethodGet, "/readyz", "", "", http.StatusOK, "Degraded"}})
+	databaseErr = failing
+	api.run(t, []request{{"database down", http.MethodGet, "/readyz", "", "", http.StatusServiceUnavailable, "Service Unavailable"}})
+
+	health.SetDraining()
+	api.run(t, []request{
+		{"draining", http.MethodGet, "/readyz", "", "", http.StatusServiceUnavailable, "Service Is Shutting Down"},
+		{"alive while draining", http.MethodGet, "/healthz", "", "", http.StatusOK, "OK"},
+	})
+}
+
+func TestDocsRoutes(t *testing.T) {
+	docs, err := NewDocsHandler(map[string]string{"openapi": "3.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.GET("/openapi.json", docs.OpenAPI)
+	e.GET("/docs", docs.SwaggerUI)
+	e.GET("/docs/*", docs.Assets)
+
+	for path, want := range map[string]string{
+		"/openapi.json":              echo.MIMEApplicationJSONCharsetUTF8,
+		"/docs":                      echo.MIMETextHTMLCharsetUTF8,
+		"/docs/swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, rec.Code, rec.Header().Get(echo.HeaderContentType), want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"test-crud-user-orders/internal/entity"
 	"test-crud-user-orders/internal/template"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -85,13 +86,19 @@ func (h *OrderHistoryHandler) GetAllPagination(c echo.Context) error {
 	// Calculate offset by limit per page and number of page
 	offsetData := (page - 1) * limitData
 
+	filter := orderHistoryFilter(c)
+
 	// Count Users Data, Return int64
-	countData := h.orderHistoryUseCase.CountData(c.Request().Context(), -1)
+	countData := h.orderHistoryUseCase.CountData(c.Request().Context(), filter.UserID)
 	var orderHistory []*entity.OrderHistory
 	if offsetData < countData {
-		orderHistory, err = h.orderHistoryUseCase.GetAllPagination(c.Request().Context(), int(limitData), int(offsetData))
+		if filter.UserID > 0 {
+			orderHistory, err = h.orderHistoryUseCase.GetByUserID(c.Request().Context(), filter.UserID, int(limitData), int(offsetData))
+		} else {
+			orderHistory, err = h.orderHistoryUseCase.GetAllPagination(c.Request().Context(), int(limitData), int(offsetData))
+		}
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, template.ResponseHTTP{
+			return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
 				Status:  http.StatusInternalServerError,
 				Error:   err,
 				Message: "Internal Server Error",
@@ -119,6 +126,53 @@ func (h *OrderHistoryHandler) GetAllPagination(c echo.Context) error {
 	})
 }
 
+// Export Func for Download All Data as CSV or XLSX, streamed row by row
+func (h *OrderHistoryHandler) Export(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	filter := orderHistoryFilter(c)
+
+	res := c.Response()
+	writer, contentType, err := newRowWriter(format, res, "Order Histories")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "Format Must Be csv or xlsx",
+		})
+	}
+
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="order-histories-%s.%s"`, time.Now().Format("2006-01-02"), format))
+	res.WriteHeader(http.StatusOK)
+
+	if err := writer.WriteRow("id", "created_at", "user_id", "user_name", "order_item_id", "order_item_name", "order_item_price", "descriptions"); err != nil {
+		return err
+	}
+
+	rows := 0
+	err = h.orderHistoryUseCase.Export(c.Request().Context(), filter, func(row *entity.OrderHistoryExport) error {
+		if err := writer.WriteRow(row.ID, row.CreatedAt, row.UserID, row.UserName, row.OrderItemID, row.OrderItemName, row.OrderItemPrice, row.Descriptions); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			res.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, the client notices the truncated file
+		return err
+	}
+
+	return writer.Close()
+}
+
 // GetByID Func for Get 1 Data by primaryKey
 func (h *OrderHistoryHandler) GetByID(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -132,7 +186,6 @@ func (h *OrderHistoryHandler) GetByID(c echo.Context) error {
 
 	orderHistory, err := h.orderHistoryUseCase.GetByID(c.Request().Context(), id)
 	if err != nil {
-		fmt.Println("Error : " + err.Error())
 		if err.Error() == "record not found" {
 			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
 				Status:  http.StatusNotFound,
@@ -214,6 +267,69 @@ func (h *OrderHistoryHandler) Update(c echo.Context) error {
 	})
 }
 
+// Patch Func for Partial Update of 1 Data by primaryKey (JSON Merge Patch)
+func (h *OrderHistoryHandler) Patch(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	var input entity.PatchOrderHistory
+	if err := bindMergePatch(c, &input); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{}
+	if input.UserID != nil {
+		fields["user_id"] = *input.UserID
+	}
+	if input.OrderItemID != nil {
+		fields["order_item_id"] = *input.OrderItemID
+	}
+	if input.Descriptions != nil {
+		fields["descriptions"] = *input.Descriptions
+	}
+	if input.Status != nil {
+		fields["status"] = *input.Status
+	}
+
+	orderHistory, err := h.orderHistoryUseCase.Patch(c.Request().Context(), id, fields)
+	if err != nil {
+		var errDB error
+		status := http.StatusInternalServerError
+		message := "Internal Server Error"
+
+		if err.Error() == "record not found" || err.Error() == "order history not found" {
+			status = http.StatusNotFound
+			message = "Order History Not Found"
+		} else if err.Error() == "user data not found" {
+			status = http.StatusNotFound
+			message = "UserID Not Found"
+		} else if err.Error() == "order item data not found" {
+			status = http.StatusNotFound
+			message = "OrderItemID Not Found"
+		} else {
+			errDB = err
+		}
+
+		return echo.NewHTTPError(status, template.ResponseHTTP{
+			Status:  status,
+			Error:   errDB,
+			Message: message,
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    orderHistory,
+		Message: "OK",
+	})
+}
+
 // Delete Func for Delete 1 Data by primaryKey
 func (h *OrderHistoryHandler) Delete(c echo.Context) error {
 	return echo.NewHTTPError(http.StatusForbidden, template.ResponseHTTP{
@@ -251,7 +367,7 @@ func (h *OrderHistoryHandler) GetHistoryByUserID(c echo.Context) error {
 	if offsetData < countData {
 		orderHistory, err = h.orderHistoryUseCase.GetByUserID(c.Request().Context(), userID, int(limitData), int(offsetData))
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, template.ResponseHTTP{
+			return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
 				Status:  http.StatusInternalServerError,
 				Error:   err,
 				Message: "Internal Server Error",
@@ -278,3 +394,51 @@ func (h *OrderHistoryHandler) GetHistoryByUserID(c echo.Context) error {
 		},
 	})
 }
+
+// Bulk Func for Create and Update of many Data in one Request
+func (h *OrderHistoryHandler) Bulk(c echo.Context) error {
+	operations, atomic, err := bindBulk(c)
+	if err != nil {
+		return err
+	}
+
+	var ops []entity.BulkOrderHistory
+	var valid, invalid []entity.BulkResult
+	for i, operation := range operations {
+		result := entity.BulkResult{Index: i, Op: operation.Op, ID: operation.ID}
+
+		var input entity.CreateOrderHistory
+		var data interface{}
+		if operation.Op != entity.BulkDelete {
+			data = &input
+		}
+		if err := bindBulkData(c, operation, data); err != nil {
+			result.Error = err.Error()
+			invalid = append(invalid, result)
+			continue
+		}
+
+		ops = append(ops, entity.BulkOrderHistory{
+			Index:        i,
+			Op:           operation.Op,
+			ID:           operation.ID,
+			UserID:       input.UserID,
+			OrderItemID:  input.OrderItemID,
+			Descriptions: input.Descriptions,
+		})
+		valid = append(valid, result)
+	}
+
+	return bulkResponse(c, atomic, invalid, valid, func() []entity.BulkResult {
+		return h.orderHistoryUseCase.Bulk(c.Request().Context(), ops, atomic)
+	})
+}
+
+// orderHistoryFilter reads the filters shared by the list and export endpoints
+func orderHistoryFilter(c echo.Context) entity.OrderHistoryFilter {
+	var filter entity.OrderHistoryFilter
+	if userID, err := strconv.Atoi(c.QueryParam("user_id")); err == nil && userID > 0 {
+		filter.UserID = userID
+	}
+	return filter
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/pkg/xlsx"
+)
+
+func TestOrderHistoryRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	ann, bob, deletedUser := api.createUser(t, "Ann"), api.createUser(t, "Bob"), api.createUser(t, "Cid")
+	tea, coffee, deletedItem := api.createOrderItem(t, "Tea", 100), api.createOrderItem(t, "Coffee", 200), api.createOrderItem(t, "Cake", 300)
+	api.run(t, []request{
+		{"first order", http.MethodPost, "/order-histories/", "", `{"user_id":1,"order_item_id":1,"descriptions":"first"}`, http.StatusCreated, "OK"},
+		{"second order", http.MethodPost, "/order-histories/", "", `{"user_id":2,"order_item_id":2,"descriptions":"second"}`, http.StatusCreated, "OK"},
+		{"delete Cid", http.MethodDelete, "/users/3", "", "", http.StatusOK, ""},
+		{"delete Cake", http.MethodDelete, "/order-items/3", "", "", http.StatusOK, ""},
+	})
+	if ann != 1 || bob != 2 || deletedUser != 3 || tea != 1 || coffee != 2 || deletedItem != 3 {
+		t.Fatal("fixtures do not have the IDs the requests use")
+	}
+
+	api.run(t, []request{
+		{"create with a broken body", http.MethodPost, "/order-histories/", "", `{`, http.StatusBadRequest, "Invalid Request"},
+		{"create without descriptions", http.MethodPost, "/order-histories/", "", `{"user_id":1,"order_item_id":1}`, http.StatusBadRequest, "Bad Request"},
+		{"create for a deleted user", http.MethodPost, "/order-histories/", "", `{"user_id":3,"order_item_id":1,"descriptions":"x"}`, http.StatusNotFound, "UserID 3 Not Found or Deleted"},
+		{"create of a deleted item", http.MethodPost, "/order-histories/", "", `{"user_id":1,"order_item_id":3,"descriptions":"x"}`, http.StatusNotFound, "OrderItemID 3 Not Found or Deleted"},
+
+		{"list", http.MethodGet, "/order-histories/", "", "", http.StatusOK, "OK"},
+		{"list of a user", http.MethodGet, "/order-histories/?user_id=2", "", "", http.StatusOK, "OK"},
+		{"list of a user without orders", http.MethodGet, "/order-histories/?user_id=3", "", "", http.StatusOK, "Zero Data"},
+		{"history of a user", http.MethodGet, "/users/1/order-histories", "", "", http.StatusOK, "OK"},
+		{"history past the last page", http.MethodGet, "/users/1/order-histories?page=2", "", "", http.StatusOK, "Zero Data"},
+		{"history of an unknown ID", http.MethodGet, "/users/abc/order-histories", "", "", http.StatusBadRequest, "Unknown ID"},
+
+		{"get", http.MethodGet, "/order-histories/1", "", "", http.StatusOK, "OK"},
+		{"get an unknown ID", http.MethodGet, "/order-histories/abc", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"get a missing order", http.MethodGet, "/order-histories/99", "", "", http.StatusNotFound, "Order History Not Found"},
+
+		{"update", http.MethodPut, "/order-histories/1", "", `{"user_id":1,"order_item_id":2,"descriptions":"changed"}`, http.StatusOK, "OK"},
+		{"update an unknown ID", http.MethodPut, "/order-histories/abc", "", `{}`, http.StatusBadRequest, "Unknown ID"},
+		{"update with a broken body", http.MethodPut, "/order-histories/1", "", `{`, http.StatusBadRequest, "Invalid Request"},
+		{"update without descriptions", http.MethodPut, "/order-histories/1", "", `{"user_id":1,"order_item_id":2}`, http.StatusBadRequest, "Bad Request"},
+		{"update a missing order", http.MethodPut, "/order-histories/99", "", `{"user_id":1,"order_item_id":1,"descriptions":"x"}`, http.StatusNotFound, "Order History Not Found"},
+		{"update to a deleted user", http.MethodPut, "/order-histories/1", "", `{"user_id":3,"order_item_id":2,"descriptions":"x"}`, http.StatusNotFound, "UserID Not Found"},
+		{"update to a deleted item", http.MethodPut, "/order-histories/1", "", `{"user_id":1,"order_item_id":3,"descriptions":"x"}`, http.StatusNotFound, "OrderItemID Not Found"},
+
+		{"patch", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"descriptions":"patched"}`, http.StatusOK, "OK"},
+		{"patch the status", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"status":"shipped"}`, http.StatusOK, "OK"},
+		{"patch to an unknown status", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"status":"lost"}`, http.StatusBadRequest, "Bad Request"},
+		{"patch an unknown ID", http.MethodPatch, "/order-histories/abc", MIMEMergePatch, `{}`, http.StatusBadRequest, "Unknown ID"},
+		{"patch with a zero user", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"user_id":0}`, http.StatusBadRequest, "Bad Request"},
+		{"patch a missing order", http.MethodPatch, "/order-histories/99", MIMEMergePatch, `{"descriptions":"x"}`, http.StatusNotFound, "Order History Not Found"},
+		{"patch to a deleted user", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"user_id":3}`, http.StatusNotFound, "UserID Not Found"},
+		{"patch to a deleted item", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"order_item_id":3}`, http.StatusNotFound, "OrderItemID Not Found"},
+
+		{"delete", http.MethodDelete, "/order-histories/1", "", "", http.StatusForbidden, "Delete Transaction Not Allowed"},
+	})
+
+	for id, want := range map[int]string{1: entity.OrderShipped, 2: entity.OrderPending} {
+		var orderHistory entity.OrderHistory
+		if err := json.Unmarshal(decode(t, api.do(http.MethodGet, fmt.Sprintf("/order-histories/%d", id), "", "")).Data, &orderHistory); err != nil {
+			t.Fatal(err)
+		}
+		if orderHistory.Status != want {
+			t.Errorf("status of order %d = %q, want %q", id, orderHistory.Status, want)
+		}
+	}
+}
+
+func TestOrderHistoryRoutesAnswer500WhenTheDatabaseFails(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+	api.createOrderItem(t, "Tea", 100)
+	api.run(t, []request{
+		{"order", http.MethodPost, "/order-histories/", "", `{"user_id":1,"order_item_id":1,"descriptions":"x"}`, http.StatusCreated, "OK"},
+	})
+	api.store.FailWith(errors.New("database down"))
+
+	api.run(t, []request{
+		{"get", http.MethodGet, "/order-histories/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"update", http.MethodPut, "/order-histories/1", "", `{"user_id":1,"order_item_id":1,"descriptions":"x"}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"patch", http.MethodPatch, "/order-histories/1", MIMEMergePatch, `{"descriptions":"x"}`, http.StatusInternalServerError, "Internal Server Error"},
+	})
+}
+
+func TestOrderHistoryBulkRoute(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+	api.createOrderItem(t, "Tea", 100)
+
+	api.run(t, []request{
+		{"create and update", http.MethodPost, "/order-histories/bulk", "",
+			`[{"op":"create","data":{"user_id":1,"order_item_id":1,"descriptions":"a"}},` +
+				`{"op":"create","data":{"user_id":1,"order_item_id":1,"descriptions":"b"}}]`, http.StatusOK, "OK"},
+		{"delete is refused", http.MethodPost, "/order-histories/bulk?mode=partial", "",
+			`[{"op":"update","id":1,"data":{"user_id":1,"order_item_id":1,"descriptions":"c"}},{"op":"delete","id":2}]`,
+			http.StatusMultiStatus, "1 of 2 Operations Failed"},
+	})
+}
+
+func TestOrderHistoryExportRoute(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+	api.createUser(t, "=1+1")
+	api.createOrderItem(t, "Tea", 100)
+	api.run(t, []request{
+		{"order of Ann", http.MethodPost, "/order-histories/", "", `{"user_id":1,"order_item_id":1,"descriptions":"a, b"}`, http.StatusCreated, ""},
+		{"order of a formula", http.MethodPost, "/order-histories/", "", `{"user_id":2,"order_item_id":1,"descriptions":"@SUM(A1) <b>"}`, http.StatusCreated, ""},
+		{"unknown format", http.MethodGet, "/order-histories/export?format=pdf", "", "", http.StatusBadRequest, "Format Must Be csv or xlsx"},
+	})
+
+	rec := api.do(http.MethodGet, "/order-histories/export?user_id=1", "", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), "text/csv") {
+		t.Fatalf("csv export = %d %q", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][3] != "Ann" || records[1][5] != "Tea" || records[1][6] != "100" || records[1][7] != "a, b" {
+		t.Errorf("csv export = %q, want the header and the order of Ann", records)
+	}
+
+	// a text a spreadsheet would run as a formula is exported behind a quote
+	records, err = csv.NewReader(api.do(http.MethodGet, "/order-histories/export?user_id=2", "", "").Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][3] != "'=1+1" || records[1][7] != "'@SUM(A1) <b>" {
+		t.Errorf("csv export = %q, want the formulas escaped", records)
+	}
+
+	rec = api.do(http.MethodGet, "/order-histories/export?format=xlsx", "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != xlsx.ContentType {
+		t.Fatalf("xlsx export = %d %q", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+	sheet := readSheet(t, rec.Body.Bytes())
+	for _, cell := range []string{
+		`<c r="D2" t="inlineStr"><is><t xml:space="preserve">Ann</t></is></c>`,
+		`<c r="G2"><v>100</v></c>`,
+		`<c r="D3" t="inlineStr"><is><t xml:space="preserve">&#39;=1+1</t></is></c>`,
+		`<c r="H3" t="inlineStr"><is><t xml:space="preserve">&#39;@SUM(A1) &lt;b&gt;</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("xlsx sheet lacks %s\n%s", cell, sheet)
+		}
+	}
+}
+
+// readSheet unzips the only sheet of an XLSX workbook
+func readSheet(t *testing.T, workbook []byte) string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatalf("xlsx export is not a zip archive: %v", err)
+	}
+	f, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(sheet)
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"encoding/csv"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"test-crud-user-orders/internal/entity"
 	"test-crud-user-orders/internal/template"
 	"test-crud-user-orders/internal/usecase"
@@ -82,7 +85,7 @@ func (h *OrderItemHandler) GetAllPagination(c echo.Context) error {
 	if offsetData < countData {
 		orderItem, err = h.orderItemUseCase.GetAllPagination(c.Request().Context(), int(limitData), int(offsetData))
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, template.ResponseHTTP{
+			return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
 				Status:  http.StatusInternalServerError,
 				Error:   err,
 				Message: "Internal Server Error",
@@ -123,7 +126,6 @@ func (h *OrderItemHandler) GetByID(c echo.Context) error {
 
 	orderItem, err := h.orderItemUseCase.GetByID(c.Request().Context(), int(id))
 	if err != nil {
-		fmt.Println("Error : " + err.Error())
 		if err.Error() == "record not found" {
 			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
 				Status:  http.StatusNotFound,
@@ -212,6 +214,57 @@ func (h *OrderItemHandler) Update(c echo.Context) error {
 	})
 }
 
+// Patch Func for Partial Update of 1 Data by primaryKey (JSON Merge Patch)
+func (h *OrderItemHandler) Patch(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	var input entity.PatchOrderItem
+	if err := bindMergePatch(c, &input); err != nil {
+		return err
+	}
+
+	// Only the supplied fields are written, ExpiredAt is kept unless expired_days is sent
+	fields := map[string]interface{}{}
+	if input.Name != nil {
+		fields["name"] = *input.Name
+	}
+	if input.Price != nil {
+		fields["price"] = *input.Price
+	}
+	if input.ExpiredDay != nil {
+		fields["expired_at"] = generateTime(*input.ExpiredDay)
+	}
+
+	orderItem, err := h.orderItemUseCase.Patch(c.Request().Context(), id, fields)
+	if err != nil {
+		if err.Error() == "record not found" || err.Error() == "order item not found" {
+			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
+				Status:  http.StatusNotFound,
+				Message: fmt.Sprintf("OrderItemID #%d Not Found or Deleted", id),
+			})
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Message: "OK",
+		Data:    orderItem,
+	})
+}
+
 // Delete Func for Delete 1 Data by primaryKey
 func (h *OrderItemHandler) Delete(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -243,3 +296,190 @@ func (h *OrderItemHandler) Delete(c echo.Context) error {
 		Message: fmt.Sprintf("OrderItemID #%d Has Been Deleted", id),
 	})
 }
+
+// Bulk Func for Create, Update and Delete of many Data in one Request
+func (h *OrderItemHandler) Bulk(c echo.Context) error {
+	operations, atomic, err := bindBulk(c)
+	if err != nil {
+		return err
+	}
+
+	var ops []entity.BulkOrderItem
+	var valid, invalid []entity.BulkResult
+	for i, operation := range operations {
+		result := entity.BulkResult{Index: i, Op: operation.Op, ID: operation.ID}
+
+		var input entity.CreateOrderItem
+		var data interface{}
+		if operation.Op != entity.BulkDelete {
+			data = &input
+		}
+		if err := bindBulkData(c, operation, data); err != nil {
+			result.Error = err.Error()
+			invalid = append(invalid, result)
+			continue
+		}
+
+		op := entity.BulkOrderItem{Index: i, Op: operation.Op}
+		op.OrderItem.ID = operation.ID
+		if data != nil {
+			if input.ExpiredDay < 1 {
+				input.ExpiredDay = 1
+			}
+			op.OrderItem.Name = input.Name
+			op.OrderItem.Price = input.Price
+			op.OrderItem.ExpiredAt = generateTime(input.ExpiredDay)
+		}
+		ops = append(ops, op)
+		valid = append(valid, result)
+	}
+
+	return bulkResponse(c, atomic, invalid, valid, func() []entity.BulkResult {
+		return h.orderItemUseCase.Bulk(c.Request().Context(), ops, atomic)
+	})
+}
+
+// Import Func for Inserting many Data from a CSV file (columns name, price, expired_days)
+func (h *OrderItemHandler) Import(c echo.Context) error {
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+
+	// The CSV is either the "file" field of a multipart form or the raw request body
+	var source io.Reader = c.Request().Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+				Status:  http.StatusBadRequest,
+				Error:   err,
+				Message: "Invalid Request",
+			})
+		}
+		defer f.Close()
+		source = f
+	}
+
+	reader := csv.NewReader(source)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "CSV Header Not Found",
+		})
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "price", "expired_days"} {
+		if _, ok := columns[name]; !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("CSV Column %s Not Found", name),
+			})
+		}
+	}
+
+	result := entity.ImportResult{DryRun: dryRun}
+	var ops []entity.BulkOrderItem
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		result.Rows++
+		if result.Rows > maxImportRows {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, template.ResponseHTTP{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("Maximum %d Rows per Import", maxImportRows),
+			})
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, entity.ImportRowError{Row: row, Error: err.Error()})
+			continue
+		}
+
+		input, err := parseOrderItemRecord(c, record, columns)
+		if err != nil {
+			result.Errors = append(result.Errors, entity.ImportRowError{Row: row, Error: err.Error()})
+			continue
+		}
+		if input.ExpiredDay < 1 {
+			input.ExpiredDay = 1
+		}
+
+		op := entity.BulkOrderItem{Index: row, Op: entity.BulkCreate}
+		op.OrderItem.Name = input.Name
+		op.OrderItem.Price = input.Price
+		op.OrderItem.ExpiredAt = generateTime(input.ExpiredDay)
+		ops = append(ops, op)
+	}
+
+	if len(result.Errors) > 0 && !dryRun {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Data:    result,
+			Message: "Import Has Invalid Rows, Nothing Imported",
+		})
+	}
+	if dryRun {
+		message := "OK"
+		if len(result.Errors) > 0 {
+			message = fmt.Sprintf("%d of %d Rows Are Invalid", len(result.Errors), result.Rows)
+		}
+		return c.JSON(http.StatusOK, template.ResponseHTTP{
+			Status:  http.StatusOK,
+			Data:    result,
+			Message: message,
+		})
+	}
+
+	// All rows are valid, they are written all-or-nothing
+	for _, bulkResult := range h.orderItemUseCase.Bulk(c.Request().Context(), ops, true) {
+		if bulkResult.Success {
+			result.Imported++
+		} else if bulkResult.Error != "rolled back" {
+			result.Errors = append(result.Errors, entity.ImportRowError{Row: bulkResult.Index, Error: bulkResult.Error})
+		}
+	}
+	if len(result.Errors) > 0 {
+		result.Imported = 0
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, template.ResponseHTTP{
+			Status:  http.StatusUnprocessableEntity,
+			Data:    result,
+			Message: "Import Rolled Back",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, template.ResponseHTTP{
+		Status:  http.StatusCreated,
+		Data:    result,
+		Message: "OK",
+	})
+}
+
+// maxImportRows limits the number of data rows in one import file
+const maxImportRows = 10000
+
+func parseOrderItemRecord(c echo.Context, record []string, columns map[string]int) (*entity.CreateOrderItem, error) {
+	field := func(name string) string {
+		if i := columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	input := &entity.CreateOrderItem{Name: field("name")}
+	var err error
+	if input.Price, err = strconv.Atoi(field("price")); err != nil {
+		return nil, fmt.Errorf("price %q is not a number", field("price"))
+	}
+	if input.ExpiredDay, err = strconv.Atoi(field("expired_days")); err != nil {
+		return nil, fmt.Errorf("expired_days %q is not a number", field("expired_days"))
+	}
+	if err := c.Validate(input); err != nil {
+		return nil, validationError(err)
+	}
+	return input, nil
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+func TestOrderItemRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	api.createOrderItem(t, "Tea", 100)
+	api.createOrderItem(t, "Coffee", 200)
+
+	api.run(t, []request{
+		{"create", http.MethodPost, "/order-items/", "", `{"name":"Cake","price":300,"expired_days":7}`, http.StatusCreated, "OK"},
+		{"create with a broken body", http.MethodPost, "/order-items/", "", `{`, http.StatusBadRequest, "Invalid Request"},
+		{"create without a price", http.MethodPost, "/order-items/", "", `{"name":"Cake","expired_days":7}`, http.StatusBadRequest, "Bad Request"},
+
+		{"list a page", http.MethodGet, "/order-items/?limit=2&page=2", "", "", http.StatusOK, "OK"},
+		{"list past the last page", http.MethodGet, "/order-items/?limit=5&page=2", "", "", http.StatusOK, "Zero Data"},
+
+		{"get", http.MethodGet, "/order-items/1", "", "", http.StatusOK, "OK"},
+		{"get an unknown ID", http.MethodGet, "/order-items/abc", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"get a missing order item", http.MethodGet, "/order-items/99", "", "", http.StatusNotFound, "OrderItemID #99 Not Found or Deleted"},
+
+		{"update", http.MethodPut, "/order-items/1", "", `{"name":"Green Tea","price":120,"expired_days":3}`, http.StatusOK, "OK"},
+		{"update an unknown ID", http.MethodPut, "/order-items/abc", "", `{}`, http.StatusBadRequest, "Unknown ID"},
+		{"update with a broken body", http.MethodPut, "/order-items/1", "", `{`, http.StatusBadRequest, "Invalid Request"},
+		{"update without a name", http.MethodPut, "/order-items/1", "", `{"price":1,"expired_days":1}`, http.StatusBadRequest, "Error Validate Request"},
+		{"update a missing order item", http.MethodPut, "/order-items/99", "", `{"name":"Ghost","price":1,"expired_days":1}`, http.StatusNotFound, "OrderItemID #99 Not Found or Deleted"},
+
+		{"patch", http.MethodPatch, "/order-items/1", MIMEMergePatch, `{"price":130,"expired_days":10}`, http.StatusOK, "OK"},
+		{"patch an unknown ID", http.MethodPatch, "/order-items/abc", MIMEMergePatch, `{}`, http.StatusBadRequest, "Unknown ID"},
+		{"patch with a negative price", http.MethodPatch, "/order-items/1", MIMEMergePatch, `{"price":-1}`, http.StatusBadRequest, "Bad Request"},
+		{"patch a missing order item", http.MethodPatch, "/order-items/99", MIMEMergePatch, `{"price":1}`, http.StatusNotFound, "OrderItemID #99 Not Found or Deleted"},
+
+		{"delete", http.MethodDelete, "/order-items/2", "", "", http.StatusOK, "OrderItemID #2 Has Been Deleted"},
+		{"delete again", http.MethodDelete, "/order-items/2", "", "", http.StatusNotFound, "OrderItemID #2 Not Found"},
+		{"delete an unknown ID", http.MethodDelete, "/order-items/abc", "", "", http.StatusBadRequest, "Unknown ID"},
+	})
+
+	var orderItem entity.OrderItem
+	if err := json.Unmarshal(decode(t, api.do(http.MethodGet, "/order-items/1", "", "")).Data, &orderItem); err != nil {
+		t.Fatal(err)
+	}
+	if orderItem.Name != "Green Tea" || orderItem.Price != 130 {
+		t.Errorf("order item = %+v, want the patched price on the updated name", orderItem)
+	}
+}
+
+func TestOrderItemRoutesAnswer500WhenTheDatabaseFails(t *testing.T) {
+	api := newTestAPI(t)
+	api.createOrderItem(t, "Tea", 100)
+	api.store.FailWith(errors.New("database down"))
+
+	api.run(t, []request{
+		{"create", http.MethodPost, "/order-items/", "", `{"name":"Cake","price":300,"expired_days":7}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"get", http.MethodGet, "/order-items/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"update", http.MethodPut, "/order-items/1", "", `{"name":"Tea","price":1,"expired_days":1}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"patch", http.MethodPatch, "/order-items/1", MIMEMergePatch, `{"price":1}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"delete", http.MethodDelete, "/order-items/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+	})
+}
+
+func TestOrderItemBulkRoute(t *testing.T) {
+	api := newTestAPI(t)
+	api.createOrderItem(t, "Tea", 100)
+
+	api.run(t, []request{
+		{"every operation succeeds", http.MethodPost, "/order-items/bulk", "",
+			`[{"op":"create","data":{"name":"Cake","price":300,"expired_days":7}},{"op":"delete","id":1}]`, http.StatusOK, "OK"},
+		{"atomic with a failure", http.MethodPost, "/order-items/bulk", "",
+			`[{"op":"update","id":1,"data":{"name":"Tea","price":1,"expired_days":1}}]`, http.StatusUnprocessableEntity, "Bulk Request Rolled Back"},
+		{"partial with an invalid operation", http.MethodPost, "/order-items/bulk?mode=partial", "",
+			`[{"op":"create","data":{"name":"Pie"}},{"op":"update","id":2,"data":{"name":"Cheesecake","price":350,"expired_days":7}}]`,
+			http.StatusMultiStatus, "1 of 2 Operations Failed"},
+	})
+}
+
+func TestOrderItemImportRoute(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.run(t, []request{
+		{"dry run with an invalid row", http.MethodPost, "/order-items/import?dry_run=true", "text/csv",
+			"name,price,expired_days\nTea,100,7\nCake,cheap,7\n", http.StatusOK, "1 of 2 Rows Are Invalid"},
+		{"invalid row", http.MethodPost, "/order-items/import", "text/csv",
+			"name,price,expired_days\nTea,100,7\n,100,7\n", http.StatusBadRequest, "Import Has Invalid Rows, Nothing Imported"},
+		{"missing column", http.MethodPost, "/order-items/import", "text/csv",
+			"name,price\nTea,100\n", http.StatusBadRequest, "CSV Column expired_days Not Found"},
+		{"empty file", http.MethodPost, "/order-items/import", "text/csv", "", http.StatusBadRequest, "CSV Header Not Found"},
+		{"dry run", http.MethodPost, "/order-items/import?dry_run=1", "text/csv",
+			"name,price,expired_days\nTea,100,7\n", http.StatusOK, "OK"},
+		{"import", http.MethodPost, "/order-items/import", "text/csv",
+			"Name, Price, Expired_Days\nTea,100,7\nCake,300,1\n", http.StatusCreated, "OK"},
+	})
+
+	// the file field of a multipart form
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "items.csv")
+	_, _ = file.Write([]byte("name,price,expired_days\nPie,250,2\n"))
+	_ = form.Close()
+	rec := api.do(http.MethodPost, "/order-items/import", form.FormDataContentType(), body.String())
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("multipart import = %d\n%s", rec.Code, rec.Body.String())
+	}
+
+	var result entity.ImportResult
+	if err := json.Unmarshal(decode(t, rec).Data, &result); err != nil || result.Imported != 1 {
+		t.Errorf("multipart import = %+v, %v, want one row imported", result, err)
+	}
+	if total := decode(t, api.do(http.MethodGet, "/order-items/", "", "")).Page.Total; total != 3 {
+		t.Errorf("order items = %d, want the 3 imported ones", total)
+	}
+
+	rows := "name,price,expired_days\n" + strings.Repeat("Tea,1,1\n", maxImportRows+1)
+	if rec := api.do(http.MethodPost, "/order-items/import", "text/csv", rows); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("import of %d rows = %d, want 413", maxImportRows+1, rec.Code)
+	}
+}
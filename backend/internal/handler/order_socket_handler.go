@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/live"
+	"test-crud-user-orders/internal/stream"
+	"test-crud-user-orders/internal/template"
+)
+
+// socketProtocol is the subprotocol a browser, which cannot set the Authorization header of a WebSocket,
+// offers before its token in Sec-WebSocket-Protocol
+const socketProtocol = "bearer"
+
+type OrderSocketHandler struct {
+	hub        *stream.Hub
+	authSecret string
+	options    live.Options
+	upgrader   websocket.Upgrader
+}
+
+// NewOrderSocketHandler serves the changes of hub to the clients holding a token signed with authSecret
+func NewOrderSocketHandler(hub *stream.Hub, authSecret string, options live.Options) *OrderSocketHandler {
+	return &OrderSocketHandler{
+		hub:        hub,
+		authSecret: authSecret,
+		options:    options,
+		upgrader: websocket.Upgrader{
+			// clients authenticate with a token instead of cookies, a page of another origin gains nothing
+			CheckOrigin: func(*http.Request) bool { return true },
+			// a browser drops the connection when none of the subprotocols it offered is selected
+			Subprotocols: []string{socketProtocol},
+		},
+	}
+}
+
+// Orders Func for Pushing the changes of the Orders of 1 User over a WebSocket, the token of the User
+// is read from the Authorization header or, for browsers, the Sec-WebSocket-Protocol header. It is never
+// read from the URL, which is written to the access logs and the traces.
+func (h *OrderSocketHandler) Orders(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if token == "" {
+		token = protocolToken(c.Request())
+	}
+	userID, err := live.VerifyToken(h.authSecret, token)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, template.ResponseHTTP{
+			Status:  http.StatusUnauthorized,
+			Message: "Invalid Token",
+		})
+	}
+	if userID != id {
+		return echo.NewHTTPError(http.StatusForbidden, template.ResponseHTTP{
+			Status:  http.StatusForbidden,
+			Message: "Token Not Valid for This User",
+		})
+	}
+
+	// the upgrader answers a request that is not a WebSocket handshake itself
+	conn, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return nil
+	}
+	live.NewSession(conn, h.hub, userID, h.options).Serve()
+	return nil
+}
+
+// protocolToken is the token offered after socketProtocol in the Sec-WebSocket-Protocol header of r
+func protocolToken(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == socketProtocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/live"
+	"test-crud-user-orders/internal/stream"
+)
+
+const socketSecret = "0123456789abcdef"
+
+func TestOrderSocketAuthenticatesTheUser(t *testing.T) {
+	api := newTestAPI(t)
+	token, err := live.SignToken(socketSecret, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _ := live.SignToken("another secret!!", 1, time.Hour)
+
+	api.run(t, []request{
+		{"without a token", http.MethodGet, "/ws/users/1/orders", "", "", http.StatusUnauthorized, "Invalid Token"},
+		{"token in the URL", http.MethodGet, "/ws/users/1/orders?access_token=" + token, "", "", http.StatusUnauthorized, "Invalid Token"},
+	})
+	for _, tc := range []struct {
+		name, path, protocol string
+		want                 int
+	}{
+		{"unknown ID", "/ws/users/abc/orders", "bearer, " + token, http.StatusBadRequest},
+		{"forged token", "/ws/users/1/orders", "bearer, " + forged, http.StatusUnauthorized},
+		{"token without its subprotocol", "/ws/users/1/orders", token, http.StatusUnauthorized},
+		{"token of another user", "/ws/users/2/orders", "bearer, " + token, http.StatusForbidden},
+		{"not a handshake", "/ws/users/1/orders", "bearer, " + token, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Sec-WebSocket-Protocol", tc.protocol)
+		rec := httptest.NewRecorder()
+		api.e.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s = %d, want %d\n%s", tc.name, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+
+	server := httptest.NewServer(api.e)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/users/1/orders"
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// a browser offers its token as a subprotocol and needs the server to select one
+	browser, _, err := (&websocket.Dialer{Subprotocols: []string{"bearer", token}}).Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protocol := browser.Subprotocol(); protocol != "bearer" {
+		t.Errorf("subprotocol = %q, want bearer", protocol)
+	}
+	browser.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	if err := conn.WriteJSON(live.ClientMessage{Action: live.ActionSubscribe}); err != nil {
+		t.Fatal(err)
+	}
+	var reply live.ServerMessage
+	if err := conn.ReadJSON(&reply); err != nil || reply.Type != live.TypeSubscribed {
+		t.Fatalf("reply = %+v, %v, want subscribed", reply, err)
+	}
+	api.stream.Broadcast(stream.Message{ID: "a", Type: entity.EventOrderCreated, OrderID: 3, UserID: 2, Data: []byte(`{"id":3}`)})
+	api.stream.Broadcast(stream.Message{ID: "b", Type: entity.EventOrderCreated, OrderID: 4, UserID: 1, Data: []byte(`{"id":4}`)})
+	if err := conn.ReadJSON(&reply); err != nil || reply.ID != "b" || reply.OrderID != 4 {
+		t.Errorf("pushed %+v, %v, want the order of the user only", reply, err)
+	}
+}
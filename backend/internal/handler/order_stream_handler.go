@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/stream"
+	"test-crud-user-orders/internal/template"
+)
+
+// streamRetry is the wait a client lets pass before reconnecting a stream, in milliseconds
+const streamRetry = 3000
+
+type OrderStreamHandler struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+// NewOrderStreamHandler streams the messages of hub, sending a comment every heartbeat to keep an
+// idle stream open through the proxies
+func NewOrderStreamHandler(hub *stream.Hub, heartbeat time.Duration) *OrderStreamHandler {
+	return &OrderStreamHandler{hub: hub, heartbeat: heartbeat}
+}
+
+// Stream Func for Pushing the new and updated Order Histories as Server-Sent Events, filtered by user_id.
+// A client resuming with Last-Event-ID first receives the events it missed that are still buffered.
+func (h *OrderStreamHandler) Stream(c echo.Context) error {
+	var userID int
+	if param := c.QueryParam("user_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil || id < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+				Status:  http.StatusBadRequest,
+				Message: "user_id Must Be a Positive Integer",
+			})
+		}
+		userID = id
+	}
+	// EventSource sends the header when it reconnects, the query parameter lets a new one resume
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+
+	subscriber, missed := h.hub.Subscribe(lastEventID, userID)
+	defer h.hub.Unsubscribe(subscriber)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	// nginx passes every event on instead of buffering the answer
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", streamRetry); err != nil {
+		return nil
+	}
+	for _, message := range missed {
+		if err := writeEvent(res, message); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case message, ok := <-subscriber.Messages:
+			if !ok {
+				// dropped behind or shutting down, the client reconnects with Last-Event-ID
+				return nil
+			}
+			if err := writeEvent(res, message); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// writeEvent writes message as one Server-Sent Event, its data is JSON on a single line
+func writeEvent(res *echo.Response, message stream.Message) error {
+	_, err := fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", message.ID, message.Type, message.Data)
+	return err
+}
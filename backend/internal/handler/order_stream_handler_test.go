@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/stream"
+)
+
+// sseClient reads the Server-Sent Events of one stream
+type sseClient struct {
+	res    *http.Response
+	reader *bufio.Reader
+}
+
+func openStream(t *testing.T, server *httptest.Server, query, lastEventID string) *sseClient {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/order-histories/stream"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return &sseClient{res: res, reader: bufio.NewReader(res.Body)}
+}
+
+// next returns the id, event and data of the next event, skipping the comments and retry hint
+func (c *sseClient) next(t *testing.T) (id, event, data string) {
+	t.Helper()
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && id != "":
+			return id, event, data
+		}
+	}
+}
+
+func orderMessage(id string, userID int) stream.Message {
+	data, _ := json.Marshal(entity.OrderEvent{ID: 1, UserID: userID, Descriptions: "order " + id})
+	return stream.Message{ID: id, Type: entity.EventOrderCreated, UserID: userID, Data: data}
+}
+
+// checkStream fails unless client opened a stream, the Handler subscribed before answering
+func checkStream(t *testing.T, client *sseClient) {
+	t.Helper()
+	if client.res.StatusCode != http.StatusOK || client.res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %s, want 200 text/event-stream", client.res.StatusCode, client.res.Header.Get("Content-Type"))
+	}
+}
+
+func TestOrderStreamPushesTheOrdersOfTheUser(t *testing.T) {
+	api := newTestAPI(t)
+	server := httptest.NewServer(api.e)
+	defer server.Close()
+
+	api.run(t, []request{
+		{"stream of a bad user", http.MethodGet, "/order-histories/stream?user_id=abc", "", "", http.StatusBadRequest, "user_id Must Be a Positive Integer"},
+	})
+
+	all := openStream(t, server, "", "")
+	checkStream(t, all)
+	ann := openStream(t, server, "?user_id=1", "")
+	checkStream(t, ann)
+
+	api.stream.Broadcast(orderMessage("a", 2))
+	api.stream.Broadcast(orderMessage("b", 1))
+
+	for _, want := range []string{"a", "b"} {
+		if id, event, _ := all.next(t); id != want || event != entity.EventOrderCreated {
+			t.Errorf("stream of every user sent %s %s, want %s", event, id, want)
+		}
+	}
+	id, _, data := ann.next(t)
+	var order entity.OrderEvent
+	if err := json.Unmarshal([]byte(data), &order); err != nil || id != "b" || order.UserID != 1 {
+		t.Errorf("stream of user 1 sent %s %s, want the order of user 1", id, data)
+	}
+
+	// resumed after a and only for user 2, b is not sent again
+	api.stream.Broadcast(orderMessage("c", 2))
+	resumed := openStream(t, server, "?user_id=2", "a")
+	checkStream(t, resumed)
+	if id, _, _ := resumed.next(t); id != "c" {
+		t.Errorf("resumed stream sent %s first, want c", id)
+	}
+
+	// ended on shutdown
+	api.stream.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := all.reader.ReadString('\n'); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("stream not ended once the hub was closed")
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"test-crud-user-orders/internal/template"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEMergePatch is the media type of RFC 7386 JSON Merge Patch documents
+const MIMEMergePatch = "application/merge-patch+json"
+
+// bindMergePatch reads an RFC 7386 merge patch from the request body into dst.
+// Members of dst are expected to be pointers, so a member left nil was not
+// supplied by the client. A null member asks for the field to be removed,
+// which none of our columns allow, so it is rejected instead.
+func bindMergePatch(c echo.Context, dst interface{}) error {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != MIMEMergePatch && mediaType != echo.MIMEApplicationJSON) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, template.ResponseHTTP{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("Content-Type must be %s or %s", MIMEMergePatch, echo.MIMEApplicationJSON),
+		})
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Invalid Request",
+		})
+	}
+
+	// A merge patch that is not an object would replace the whole resource
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "Merge Patch Must Be a JSON Object",
+		})
+	}
+	for name, value := range members {
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("Field %s Cannot Be Removed", name),
+			})
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		message := "Invalid Request"
+		if errors.As(err, &typeErr) {
+			message = fmt.Sprintf("Field %s Has Invalid Type", typeErr.Field)
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: message,
+		})
+	}
+
+	if err := c.Validate(dst); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Bad Request",
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/template"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/usecase"
+)
+
+const (
+	reportDateFormat = "2006-01-02"
+	reportMaxLimit   = 100
+)
+
+type ReportHandler struct {
+	reportUseCase usecase.ReportUseCase
+}
+
+func NewReportHandler(reportUseCase usecase.ReportUseCase) *ReportHandler {
+	return &ReportHandler{reportUseCase}
+}
+
+// Revenue Func for Get Revenue per day, week or month
+func (h *ReportHandler) Revenue(c echo.Context) error {
+	filter, err := reportFilter(c, last30Days)
+	if err != nil {
+		return err
+	}
+
+	points, err := h.reportUseCase.Revenue(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    points,
+		Message: reportMessage(len(points)),
+	})
+}
+
+// TopItems Func for Get the Order Items with the highest Revenue
+func (h *ReportHandler) TopItems(c echo.Context) error {
+	filter, err := reportFilter(c, last30Days)
+	if err != nil {
+		return err
+	}
+
+	items, err := h.reportUseCase.TopItems(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    items,
+		Message: reportMessage(len(items)),
+	})
+}
+
+// TopUsers Func for Get the Users with the highest Revenue
+func (h *ReportHandler) TopUsers(c echo.Context) error {
+	filter, err := reportFilter(c, last30Days)
+	if err != nil {
+		return err
+	}
+
+	users, err := h.reportUseCase.TopUsers(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    users,
+		Message: reportMessage(len(users)),
+	})
+}
+
+// Cohorts Func for Get monthly acquisition Cohorts with their repeat-purchase Retention
+func (h *ReportHandler) Cohorts(c echo.Context) error {
+	// Cohorts are whole months, by default the last 12 of them
+	filter, err := reportFilter(c, func(today time.Time) time.Time {
+		return time.Date(today.Year(), today.Month()-11, 1, 0, 0, 0, 0, time.Local)
+	})
+	if err != nil {
+		return err
+	}
+	filter.Granularity = entity.GranularityMonth
+
+	cohorts, err := h.reportUseCase.Cohorts(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    cohorts,
+		Message: reportMessage(len(cohorts)),
+	})
+}
+
+// UserStats Func for Get the lifetime Order figures of 1 User
+func (h *ReportHandler) UserStats(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	stats, err := h.reportUseCase.UserStats(c.Request().Context(), id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
+				Status:  http.StatusNotFound,
+				Message: fmt.Sprintf("UserID %d Not Found or Deleted", id),
+			})
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    stats,
+		Message: "OK",
+	})
+}
+
+// reportFilter reads from and to (YYYY-MM-DD, both inclusive, from defaults to defaultFrom of today
+// and to to today), granularity (day, week or month, default day) and limit (default 10)
+func reportFilter(c echo.Context, defaultFrom func(today time.Time) time.Time) (entity.ReportFilter, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	filter := entity.ReportFilter{
+		From:        defaultFrom(today),
+		To:          today.AddDate(0, 0, 1),
+		Granularity: entity.GranularityDay,
+		Limit:       10,
+	}
+
+	if from := c.QueryParam("from"); from != "" {
+		date, err := time.ParseInLocation(reportDateFormat, from, time.Local)
+		if err != nil {
+			return filter, reportBadRequest("from Must Be YYYY-MM-DD")
+		}
+		filter.From = date
+	}
+	if to := c.QueryParam("to"); to != "" {
+		date, err := time.ParseInLocation(reportDateFormat, to, time.Local)
+		if err != nil {
+			return filter, reportBadRequest("to Must Be YYYY-MM-DD")
+		}
+		filter.To = date.AddDate(0, 0, 1)
+	}
+	if !filter.From.Before(filter.To) {
+		if c.QueryParam("from") == "" {
+			return filter, reportBadRequest("from Must Be Set When to Is Before " + filter.From.Format(reportDateFormat))
+		}
+		return filter, reportBadRequest("from Must Not Be After to")
+	}
+
+	switch granularity := c.QueryParam("granularity"); granularity {
+	case "":
+	case entity.GranularityDay, entity.GranularityWeek, entity.GranularityMonth:
+		filter.Granularity = granularity
+	default:
+		return filter, reportBadRequest("granularity Must Be day, week or month")
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > reportMaxLimit {
+			return filter, reportBadRequest("limit Must Be Between 1 and " + strconv.Itoa(reportMaxLimit))
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// last30Days is the default from of the reports, 30 days up to today
+func last30Days(today time.Time) time.Time {
+	return today.AddDate(0, 0, -29)
+}
+
+func reportBadRequest(message string) error {
+	return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+		Status:  http.StatusBadRequest,
+		Message: message,
+	})
+}
+
+func reportMessage(length int) string {
+	if length < 1 {
+		return "Zero Data"
+	}
+	return "OK"
+}
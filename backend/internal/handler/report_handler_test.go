@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+// stubReportUseCase answers one point of every report, or err, and keeps the last filter
+type stubReportUseCase struct {
+	filter entity.ReportFilter
+	err    error
+}
+
+func (s *stubReportUseCase) Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error) {
+	s.filter = filter
+	return []*entity.RevenuePoint{{Period: "2023-01-01", Orders: 1, Revenue: 100}}, s.err
+}
+
+func (s *stubReportUseCase) TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error) {
+	s.filter = filter
+	return []*entity.TopItem{{OrderItemID: 1, Name: "Tea", Orders: 1, Revenue: 100}}, s.err
+}
+
+func (s *stubReportUseCase) TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error) {
+	s.filter = filter
+	return nil, s.err
+}
+
+func (s *stubReportUseCase) Cohorts(ctx context.Context, filter entity.ReportFilter) ([]*entity.Cohort, error) {
+	s.filter = filter
+	return []*entity.Cohort{{Cohort: "2023-01", Users: 1}}, s.err
+}
+
+func (s *stubReportUseCase) UserStats(ctx context.Context, userID int) (*entity.UserStats, error) {
+	if userID == 99 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entity.UserStats{UserID: userID}, s.err
+}
+
+func TestReportRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	now := time.Now()
+	cohortsFrom := time.Date(now.Year(), now.Month()-11, 1, 0, 0, 0, 0, time.Local)
+
+	api.run(t, []request{
+		{"revenue", http.MethodGet, "/reports/revenue?from=2023-01-01&to=2023-01-31&granularity=week", "", "", http.StatusOK, "OK"},
+		{"top items", http.MethodGet, "/reports/top-items?limit=5", "", "", http.StatusOK, "OK"},
+		{"top users without data", http.MethodGet, "/reports/top-users", "", "", http.StatusOK, "Zero Data"},
+		{"cohorts", http.MethodGet, "/reports/cohorts", "", "", http.StatusOK, "OK"},
+		{"bad from", http.MethodGet, "/reports/revenue?from=01-01-2023", "", "", http.StatusBadRequest, "from Must Be YYYY-MM-DD"},
+		{"bad to", http.MethodGet, "/reports/top-items?to=tomorrow", "", "", http.StatusBadRequest, "to Must Be YYYY-MM-DD"},
+		{"from after to", http.MethodGet, "/reports/top-users?from=2023-02-01&to=2023-01-01", "", "", http.StatusBadRequest, "from Must Not Be After to"},
+		{"bad granularity", http.MethodGet, "/reports/revenue?granularity=year", "", "", http.StatusBadRequest, "granularity Must Be day, week or month"},
+		{"limit too high", http.MethodGet, "/reports/top-items?limit=101", "", "", http.StatusBadRequest, "limit Must Be Between 1 and 100"},
+		{"bad cohorts filter", http.MethodGet, "/reports/cohorts?limit=0", "", "", http.StatusBadRequest, "limit Must Be Between 1 and 100"},
+		{"cohorts up to a to before the default from", http.MethodGet, "/reports/cohorts?to=2000-01-31", "", "", http.StatusBadRequest,
+			"from Must Be Set When to Is Before " + cohortsFrom.Format(reportDateFormat)},
+		{"cohorts with from before an old to", http.MethodGet, "/reports/cohorts?from=2000-01-01&to=2000-12-31", "", "", http.StatusOK, "OK"},
+		{"cohorts up to a to in the default months", http.MethodGet, "/reports/cohorts?to=" + cohortsFrom.AddDate(0, 1, 0).Format(reportDateFormat), "", "", http.StatusOK, "OK"},
+		{"revenue up to a to before the default from", http.MethodGet, "/reports/revenue?to=2000-01-31", "", "", http.StatusBadRequest,
+			"from Must Be Set When to Is Before " + time.Date(now.Year(), now.Month(), now.Day()-29, 0, 0, 0, 0, time.Local).Format(reportDateFormat)},
+
+		{"user stats", http.MethodGet, "/users/1/stats", "", "", http.StatusOK, "OK"},
+		{"user stats of an unknown ID", http.MethodGet, "/users/abc/stats", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"user stats of a missing user", http.MethodGet, "/users/99/stats", "", "", http.StatusNotFound, "UserID 99 Not Found or Deleted"},
+	})
+
+	// to is inclusive, the filter ends the day after
+	api.do(http.MethodGet, "/reports/revenue?from=2023-01-01&to=2023-01-31&granularity=month", "", "")
+	filter := api.report.filter
+	if filter.Granularity != entity.GranularityMonth || !filter.To.Equal(time.Date(2023, 2, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("filter = %+v, want month granularity up to 2023-02-01", filter)
+	}
+
+	api.do(http.MethodGet, "/reports/cohorts?granularity=day", "", "")
+	if filter := api.report.filter; filter.Granularity != entity.GranularityMonth || filter.From.Day() != 1 {
+		t.Errorf("cohorts filter = %+v, want whole months", filter)
+	}
+
+	api.report.err = errors.New("database down")
+	api.run(t, []request{
+		{"revenue failing", http.MethodGet, "/reports/revenue", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"top items failing", http.MethodGet, "/reports/top-items", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"top users failing", http.MethodGet, "/reports/top-users", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"cohorts failing", http.MethodGet, "/reports/cohorts", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"user stats failing", http.MethodGet, "/users/1/stats", "", "", http.StatusInternalServerError, "Internal Server Error"},
+	})
+}
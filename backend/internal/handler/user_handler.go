@@ -77,7 +77,7 @@ func (h *UserHandler) GetAllPagination(c echo.Context) error {
 	if offsetData < countData {
 		users, err = h.userUseCase.GetAllPagination(c.Request().Context(), int(limitData), int(offsetData))
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, template.ResponseHTTP{
+			return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
 				Status:  http.StatusInternalServerError,
 				Error:   err,
 				Message: "Internal Server Error",
@@ -196,6 +196,52 @@ func (h *UserHandler) Update(c echo.Context) error {
 	})
 }
 
+// Patch Func for Partial Update of 1 Data by primaryKey (JSON Merge Patch)
+func (h *UserHandler) Patch(c echo.Context) error {
+	// Condition IF client send unformatted PrimaryKey
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	var input entity.PatchUser
+	if err := bindMergePatch(c, &input); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{}
+	if input.FullName != nil {
+		fields["full_name"] = *input.FullName
+	}
+
+	// Execute Partial Update data of User by PrimaryKey
+	user, err := h.userUseCase.Patch(c.Request().Context(), id, fields)
+	if err != nil {
+		if err.Error() == "record not found" || err.Error() == "user not found" {
+			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
+				Status:  http.StatusNotFound,
+				Message: fmt.Sprintf("UserID %d Not Found", id),
+			})
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    user,
+		Message: fmt.Sprintf("UserID %d Has Been Updated", id),
+	})
+}
+
 // Delete Func for Delete 1 Data by primaryKey
 func (h *UserHandler) Delete(c echo.Context) error {
 	// Condition IF client send unformatted PrimaryKey
@@ -234,3 +280,35 @@ func generateTime(days int) time.Time {
 	today := time.Now()
 	return today.AddDate(0, 0, days)
 }
+
+// Bulk Func for Create, Update and Delete of many Data in one Request
+func (h *UserHandler) Bulk(c echo.Context) error {
+	operations, atomic, err := bindBulk(c)
+	if err != nil {
+		return err
+	}
+
+	var ops []entity.BulkUser
+	var valid, invalid []entity.BulkResult
+	for i, operation := range operations {
+		result := entity.BulkResult{Index: i, Op: operation.Op, ID: operation.ID}
+
+		var input entity.CreateUser
+		var data interface{}
+		if operation.Op != entity.BulkDelete {
+			data = &input
+		}
+		if err := bindBulkData(c, operation, data); err != nil {
+			result.Error = err.Error()
+			invalid = append(invalid, result)
+			continue
+		}
+
+		ops = append(ops, entity.BulkUser{Index: i, Op: operation.Op, ID: operation.ID, FullName: input.FullName})
+		valid = append(valid, result)
+	}
+
+	return bulkResponse(c, atomic, invalid, valid, func() []entity.BulkResult {
+		return h.userUseCase.Bulk(c.Request().Context(), ops, atomic)
+	})
+}
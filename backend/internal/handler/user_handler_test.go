@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUserRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+	api.createUser(t, "Bob")
+
+	api.run(t, []request{
+		{"create", http.MethodPost, "/users/", "", `{"name":"Cid"}`, http.StatusCreated, "Create User Success"},
+		{"create with a broken body", http.MethodPost, "/users/", "", `{`, http.StatusBadRequest, "Bad Request"},
+		{"create without a name", http.MethodPost, "/users/", "", `{}`, http.StatusBadRequest, "Bad Request"},
+
+		{"list a page", http.MethodGet, "/users/?limit=2&page=2", "", "", http.StatusOK, "OK"},
+		{"list past the last page", http.MethodGet, "/users/?page=9", "", "", http.StatusOK, "Zero Data"},
+
+		{"get", http.MethodGet, "/users/1", "", "", http.StatusOK, "OK"},
+		{"get an unknown ID", http.MethodGet, "/users/abc", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"get a missing user", http.MethodGet, "/users/99", "", "", http.StatusNotFound, "UserID 99 Not Found or Deleted"},
+
+		{"update", http.MethodPut, "/users/1", "", `{"name":"Annie"}`, http.StatusOK, "UserID 1 Has Been Updated"},
+		{"update an unknown ID", http.MethodPut, "/users/abc", "", `{"name":"Annie"}`, http.StatusBadRequest, "Unknown ID"},
+		{"update with a broken body", http.MethodPut, "/users/1", "", `{`, http.StatusBadRequest, "Bad Request"},
+		{"update without a name", http.MethodPut, "/users/1", "", `{}`, http.StatusBadRequest, "Bad Request"},
+		{"update a missing user", http.MethodPut, "/users/99", "", `{"name":"Ghost"}`, http.StatusNotFound, "UserID 99 Not Found"},
+
+		{"patch", http.MethodPatch, "/users/1", MIMEMergePatch, `{"name":"Anne"}`, http.StatusOK, "UserID 1 Has Been Updated"},
+		{"patch an unknown ID", http.MethodPatch, "/users/abc", MIMEMergePatch, `{}`, http.StatusBadRequest, "Unknown ID"},
+		{"patch as text", http.MethodPatch, "/users/1", "text/plain", `{"name":"Anne"}`, http.StatusUnsupportedMediaType, ""},
+		{"patch with an array", http.MethodPatch, "/users/1", MIMEMergePatch, `[]`, http.StatusBadRequest, "Merge Patch Must Be a JSON Object"},
+		{"patch removing the name", http.MethodPatch, "/users/1", MIMEMergePatch, `{"name":null}`, http.StatusBadRequest, "Field name Cannot Be Removed"},
+		{"patch an unknown field", http.MethodPatch, "/users/1", MIMEMergePatch, `{"age":30}`, http.StatusBadRequest, "Invalid Request"},
+		{"patch with a number", http.MethodPatch, "/users/1", MIMEMergePatch, `{"name":1}`, http.StatusBadRequest, "Field name Has Invalid Type"},
+		{"patch with an empty name", http.MethodPatch, "/users/1", MIMEMergePatch, `{"name":""}`, http.StatusBadRequest, "Bad Request"},
+		{"patch a missing user", http.MethodPatch, "/users/99", MIMEMergePatch, `{"name":"Ghost"}`, http.StatusNotFound, "UserID 99 Not Found"},
+
+		{"delete", http.MethodDelete, "/users/2", "", "", http.StatusOK, "UserID 2 Has Been Deleted"},
+		{"delete again", http.MethodDelete, "/users/2", "", "", http.StatusNotFound, "UserID 2 Not Found"},
+		{"get a deleted user", http.MethodGet, "/users/2", "", "", http.StatusNotFound, "UserID 2 Not Found or Deleted"},
+		{"delete an unknown ID", http.MethodDelete, "/users/abc", "", "", http.StatusBadRequest, "Unknown ID"},
+	})
+
+	rec := api.do(http.MethodGet, "/users/?limit=1&page=2", "", "")
+	if page := decode(t, rec).Page; page.Limit != 1 || page.Page != 2 || page.Show != 1 || page.Total != 2 {
+		t.Errorf("page = %+v, want the second of 2 users", page)
+	}
+}
+
+func TestUserRoutesAnswer500WhenTheDatabaseFails(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+	api.store.FailWith(errors.New("database down"))
+
+	api.run(t, []request{
+		{"create", http.MethodPost, "/users/", "", `{"name":"Bob"}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"get", http.MethodGet, "/users/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"update", http.MethodPut, "/users/1", "", `{"name":"Annie"}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"patch", http.MethodPatch, "/users/1", MIMEMergePatch, `{"name":"Annie"}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"delete", http.MethodDelete, "/users/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+	})
+}
+
+func TestUserBulkRoute(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser(t, "Ann")
+
+	api.run(t, []request{
+		{"every operation succeeds", http.MethodPost, "/users/bulk", "",
+			`[{"op":"create","data":{"name":"Bob"}},{"op":"update","id":1,"data":{"name":"Annie"}}]`, http.StatusOK, "OK"},
+		{"partial with a failure", http.MethodPost, "/users/bulk?mode=partial", "",
+			`[{"op":"create","data":{"name":"Cid"}},{"op":"delete","id":99}]`, http.StatusMultiStatus, "1 of 2 Operations Failed"},
+		{"partial with an invalid operation", http.MethodPost, "/users/bulk?mode=partial", "",
+			`[{"op":"create","data":{}},{"op":"delete","id":3}]`, http.StatusMultiStatus, "1 of 2 Operations Failed"},
+		{"atomic with a failure", http.MethodPost, "/users/bulk", "",
+			`[{"op":"create","data":{"name":"Dan"}},{"op":"update","id":99,"data":{"name":"Ghost"}}]`, http.StatusUnprocessableEntity, "Bulk Request Rolled Back"},
+		{"atomic with an invalid operation", http.MethodPost, "/users/bulk?mode=atomic", "",
+			`[{"op":"create","data":{"name":"Dan"}},{"op":"upsert"}]`, http.StatusBadRequest, "Bulk Request Has Invalid Operations"},
+		{"unknown mode", http.MethodPost, "/users/bulk?mode=best", "", `[]`, http.StatusBadRequest, "Mode Must Be atomic or partial"},
+		{"broken body", http.MethodPost, "/users/bulk", "", `{`, http.StatusBadRequest, "Invalid Request"},
+		{"no operation", http.MethodPost, "/users/bulk", "", `[]`, http.StatusBadRequest, "Zero Operations"},
+		{"too many operations", http.MethodPost, "/users/bulk", "",
+			"[" + strings.TrimSuffix(strings.Repeat(`{"op":"delete","id":1},`, maxBulkOperations+1), ",") + "]",
+			http.StatusRequestEntityTooLarge, "Maximum 1000 Operations per Request"},
+	})
+
+	if rec := api.do(http.MethodGet, "/users/", "", ""); decode(t, rec).Page.Total != 2 {
+		t.Errorf("users = %s, want Annie and Bob, Cid was deleted", rec.Body.String())
+	}
+}
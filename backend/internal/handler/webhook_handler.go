@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/template"
+	"test-crud-user-orders/internal/usecase"
+)
+
+type WebhookHandler struct {
+	webhookUseCase usecase.WebhookUseCase
+}
+
+func NewWebhookHandler(webhookUseCase usecase.WebhookUseCase) *WebhookHandler {
+	return &WebhookHandler{webhookUseCase}
+}
+
+// Create Func for Registering a new Webhook Subscription
+func (h *WebhookHandler) Create(c echo.Context) error {
+	var input entity.CreateWebhookSubscription
+
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Invalid Request",
+		})
+	}
+	if err := c.Validate(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Bad Request",
+		})
+	}
+
+	subscription := entity.WebhookSubscription{
+		URL:    input.URL,
+		Events: entity.EventTypes(input.Events),
+		Secret: input.Secret,
+		Active: input.Active == nil || *input.Active,
+	}
+	if err := h.webhookUseCase.Create(c.Request().Context(), &subscription); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+			Status:  http.StatusInternalServerError,
+			Error:   err,
+			Message: "Internal Server Error",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, template.ResponseHTTP{
+		Status:  http.StatusCreated,
+		Message: "OK",
+		Data:    subscription,
+	})
+}
+
+// GetAllPagination Func for Get All Webhook Subscriptions with Pagination
+func (h *WebhookHandler) GetAllPagination(c echo.Context) error {
+	limitData, page, offsetData := pagination(c)
+
+	countData := h.webhookUseCase.CountData(c.Request().Context())
+	var subscriptions []*entity.WebhookSubscription
+	if offsetData < countData {
+		var err error
+		subscriptions, err = h.webhookUseCase.GetAllPagination(c.Request().Context(), int(limitData), int(offsetData))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+				Status:  http.StatusInternalServerError,
+				Error:   err,
+				Message: "Internal Server Error",
+			})
+		}
+	}
+
+	messageResult := "OK"
+	if len(subscriptions) < 1 {
+		messageResult = "Zero Data"
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    subscriptions,
+		Message: messageResult,
+		Page: template.PagePagination{
+			Limit: limitData,
+			Page:  page,
+			Show:  len(subscriptions),
+			Total: countData,
+		},
+	})
+}
+
+// GetByID Func for Get 1 Webhook Subscription by primaryKey
+func (h *WebhookHandler) GetByID(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	subscription, err := h.webhookUseCase.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return webhookError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Message: "OK",
+		Data:    subscription,
+	})
+}
+
+// Patch Func for Partial Update of 1 Webhook Subscription by primaryKey (JSON Merge Patch)
+func (h *WebhookHandler) Patch(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	var input entity.PatchWebhookSubscription
+	if err := bindMergePatch(c, &input); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{}
+	if input.URL != nil {
+		fields["url"] = *input.URL
+	}
+	if input.Events != nil {
+		fields["events"] = entity.EventTypes(input.Events)
+	}
+	if input.Secret != nil {
+		fields["secret"] = *input.Secret
+	}
+	if input.Active != nil {
+		fields["active"] = *input.Active
+	}
+
+	subscription, err := h.webhookUseCase.Patch(c.Request().Context(), id, fields)
+	if err != nil {
+		return webhookError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Message: "OK",
+		Data:    subscription,
+	})
+}
+
+// Delete Func for Delete 1 Webhook Subscription by primaryKey
+func (h *WebhookHandler) Delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+
+	if err := h.webhookUseCase.Delete(c.Request().Context(), id); err != nil {
+		return webhookError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Message: fmt.Sprintf("WebhookID #%d Has Been Deleted", id),
+	})
+}
+
+// Deliveries Func for Get the Delivery Log of 1 Webhook Subscription with Pagination, newest first
+func (h *WebhookHandler) Deliveries(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Error:   err,
+			Message: "Unknown ID",
+		})
+	}
+	limitData, page, offsetData := pagination(c)
+
+	deliveries, err := h.webhookUseCase.Deliveries(c.Request().Context(), id, int(limitData), int(offsetData))
+	if err != nil {
+		return webhookError(err, id)
+	}
+
+	messageResult := "OK"
+	if len(deliveries) < 1 {
+		messageResult = "Zero Data"
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    deliveries,
+		Message: messageResult,
+		Page: template.PagePagination{
+			Limit: limitData,
+			Page:  page,
+			Show:  len(deliveries),
+			Total: h.webhookUseCase.CountDeliveries(c.Request().Context(), id),
+		},
+	})
+}
+
+// Attempts Func for Get the Attempts of 1 Delivery of a Webhook Subscription with Pagination, newest first
+func (h *WebhookHandler) Attempts(c echo.Context) error {
+	id, errID := strconv.Atoi(c.Param("id"))
+	deliveryID, errDeliveryID := strconv.Atoi(c.Param("delivery_id"))
+	if errID != nil || errDeliveryID != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "Unknown ID",
+		})
+	}
+	limitData, page, offsetData := pagination(c)
+
+	attempts, err := h.webhookUseCase.Attempts(c.Request().Context(), id, deliveryID, int(limitData), int(offsetData))
+	if err != nil {
+		if err.Error() == "delivery data not found" {
+			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
+				Status:  http.StatusNotFound,
+				Message: fmt.Sprintf("DeliveryID #%d of WebhookID #%d Not Found", deliveryID, id),
+			})
+		}
+		return webhookError(err, id)
+	}
+
+	messageResult := "OK"
+	if len(attempts) < 1 {
+		messageResult = "Zero Data"
+	}
+
+	return c.JSON(http.StatusOK, template.ResponseHTTP{
+		Status:  http.StatusOK,
+		Data:    attempts,
+		Message: messageResult,
+		Page: template.PagePagination{
+			Limit: limitData,
+			Page:  page,
+			Show:  len(attempts),
+			Total: h.webhookUseCase.CountAttempts(c.Request().Context(), deliveryID),
+		},
+	})
+}
+
+// Redeliver Func for Sending 1 Delivery of a Webhook Subscription again
+func (h *WebhookHandler) Redeliver(c echo.Context) error {
+	id, errID := strconv.Atoi(c.Param("id"))
+	deliveryID, errDeliveryID := strconv.Atoi(c.Param("delivery_id"))
+	if errID != nil || errDeliveryID != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, template.ResponseHTTP{
+			Status:  http.StatusBadRequest,
+			Message: "Unknown ID",
+		})
+	}
+
+	delivery, err := h.webhookUseCase.Redeliver(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		if err.Error() == "delivery data not found" {
+			return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
+				Status:  http.StatusNotFound,
+				Message: fmt.Sprintf("DeliveryID #%d of WebhookID #%d Not Found", deliveryID, id),
+			})
+		}
+		return webhookError(err, id)
+	}
+
+	return c.JSON(http.StatusAccepted, template.ResponseHTTP{
+		Status:  http.StatusAccepted,
+		Message: "Redelivery Scheduled",
+		Data:    delivery,
+	})
+}
+
+// pagination reads the limit and page query parameters, defaulting to the first 10 rows
+func pagination(c echo.Context) (limit, page, offset int64) {
+	limit, err := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	page, err = strconv.ParseInt(c.QueryParam("page"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	return limit, page, (page - 1) * limit
+}
+
+// webhookError answers 404 for a missing Webhook Subscription and 500 otherwise
+func webhookError(err error, id int) error {
+	if err.Error() == "record not found" {
+		return echo.NewHTTPError(http.StatusNotFound, template.ResponseHTTP{
+			Status:  http.StatusNotFound,
+			Message: fmt.Sprintf("WebhookID #%d Not Found or Deleted", id),
+		})
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, template.ResponseHTTP{
+		Status:  http.StatusInternalServerError,
+		Error:   err,
+		Message: "Internal Server Error",
+	})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository/memory"
+)
+
+const webhookBody = `{"url":"https://partner.test/hooks","events":["OrderCreated"],"secret":"0123456789abcdef"}`
+
+// deadDelivery adds a delivery of subscriptionID that ran out of attempts
+func (api *testAPI) deadDelivery(t *testing.T, subscriptionID int) int {
+	t.Helper()
+	delivery := &entity.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        "a",
+		EventType:      entity.EventOrderCreated,
+		Payload:        `{}`,
+		Status:         entity.DeliveryDead,
+		Attempts:       8,
+		ResponseCode:   500,
+		LastError:      "webhook answered 500",
+	}
+	deliveries := memory.NewWebhookDeliveryRepository(api.store)
+	if err := deliveries.Add(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+	last := &entity.WebhookAttempt{Attempt: 8, ResponseCode: 500, Error: "webhook answered 500", AttemptedAt: time.Now()}
+	if err := deliveries.Save(context.Background(), delivery, last); err != nil {
+		t.Fatal(err)
+	}
+	return delivery.ID
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	api := newTestAPI(t)
+
+	rec := api.do(http.MethodPost, "/webhooks/", "", webhookBody)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create webhook = %d\n%s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "0123456789abcdef") {
+		t.Errorf("answer shows the secret: %s", rec.Body.String())
+	}
+	var subscription entity.WebhookSubscription
+	if err := json.Unmarshal(decode(t, rec).Data, &subscription); err != nil {
+		t.Fatal(err)
+	}
+	if !subscription.Active || len(subscription.Events) != 1 || subscription.Events[0] != entity.EventOrderCreated {
+		t.Errorf("subscription = %+v, want active on OrderCreated", subscription)
+	}
+	delivery := api.deadDelivery(t, subscription.ID)
+
+	api.run(t, []request{
+		{"create with a broken body", http.MethodPost, "/webhooks/", "", `{`, http.StatusBadRequest, "Invalid Request"},
+		{"create with a relative URL", http.MethodPost, "/webhooks/", "", `{"url":"/hooks","events":["*"],"secret":"0123456789abcdef"}`, http.StatusBadRequest, "Bad Request"},
+		{"create with another scheme", http.MethodPost, "/webhooks/", "", `{"url":"redis://redis:6379","events":["*"],"secret":"0123456789abcdef"}`, http.StatusBadRequest, "Bad Request"},
+		{"create with an unknown event", http.MethodPost, "/webhooks/", "", `{"url":"https://partner.test/","events":["OrderShipped"],"secret":"0123456789abcdef"}`, http.StatusBadRequest, "Bad Request"},
+		{"create with a short secret", http.MethodPost, "/webhooks/", "", `{"url":"https://partner.test/","events":["*"],"secret":"short"}`, http.StatusBadRequest, "Bad Request"},
+
+		{"list", http.MethodGet, "/webhooks/", "", "", http.StatusOK, "OK"},
+		{"list past the last page", http.MethodGet, "/webhooks/?page=2", "", "", http.StatusOK, "Zero Data"},
+
+		{"get", http.MethodGet, "/webhooks/1", "", "", http.StatusOK, "OK"},
+		{"get an unknown ID", http.MethodGet, "/webhooks/abc", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"get a missing webhook", http.MethodGet, "/webhooks/99", "", "", http.StatusNotFound, "WebhookID #99 Not Found or Deleted"},
+
+		{"patch", http.MethodPatch, "/webhooks/1", MIMEMergePatch, `{"events":["*"],"active":false}`, http.StatusOK, "OK"},
+		{"patch an unknown ID", http.MethodPatch, "/webhooks/abc", MIMEMergePatch, `{}`, http.StatusBadRequest, "Unknown ID"},
+		{"patch with no event", http.MethodPatch, "/webhooks/1", MIMEMergePatch, `{"events":[]}`, http.StatusBadRequest, "Bad Request"},
+		{"patch a missing webhook", http.MethodPatch, "/webhooks/99", MIMEMergePatch, `{"active":true}`, http.StatusNotFound, "WebhookID #99 Not Found or Deleted"},
+
+		{"deliveries", http.MethodGet, "/webhooks/1/deliveries", "", "", http.StatusOK, "OK"},
+		{"deliveries of a missing webhook", http.MethodGet, "/webhooks/99/deliveries", "", "", http.StatusNotFound, "WebhookID #99 Not Found or Deleted"},
+
+		{"attempts", http.MethodGet, "/webhooks/1/deliveries/1/attempts", "", "", http.StatusOK, "OK"},
+		{"attempts of an unknown ID", http.MethodGet, "/webhooks/1/deliveries/abc/attempts", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"attempts of a missing delivery", http.MethodGet, "/webhooks/1/deliveries/99/attempts", "", "", http.StatusNotFound, "DeliveryID #99 of WebhookID #1 Not Found"},
+		{"attempts of a missing webhook", http.MethodGet, "/webhooks/99/deliveries/1/attempts", "", "", http.StatusNotFound, "WebhookID #99 Not Found or Deleted"},
+
+		{"redeliver", http.MethodPost, "/webhooks/1/deliveries/1/redeliver", "", "", http.StatusAccepted, "Redelivery Scheduled"},
+		{"redeliver an unknown ID", http.MethodPost, "/webhooks/1/deliveries/abc/redeliver", "", "", http.StatusBadRequest, "Unknown ID"},
+		{"redeliver a missing delivery", http.MethodPost, "/webhooks/1/deliveries/99/redeliver", "", "", http.StatusNotFound, "DeliveryID #99 of WebhookID #1 Not Found"},
+	})
+
+	var patched entity.WebhookSubscription
+	if err := json.Unmarshal(decode(t, api.do(http.MethodGet, "/webhooks/1", "", "")).Data, &patched); err != nil {
+		t.Fatal(err)
+	}
+	if patched.Active || patched.URL != subscription.URL || len(patched.Events) != 1 || patched.Events[0] != entity.AllEvents {
+		t.Errorf("webhook = %+v, want every event, disabled, on the same URL", patched)
+	}
+
+	res := decode(t, api.do(http.MethodGet, "/webhooks/1/deliveries", "", ""))
+	var deliveries []entity.WebhookDelivery
+	if err := json.Unmarshal(res.Data, &deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if res.Page.Total != 1 || len(deliveries) != 1 || deliveries[0].ID != delivery {
+		t.Fatalf("deliveries = %+v, want the delivery added", deliveries)
+	}
+	due := deliveries[0]
+	if due.Status != entity.DeliveryPending || due.Attempts != 0 || due.ResponseCode != 500 || due.NextAttemptAt == nil || time.Since(*due.NextAttemptAt) > time.Minute {
+		t.Errorf("redelivered delivery = %+v, want pending now with the last response code kept", due)
+	}
+
+	res = decode(t, api.do(http.MethodGet, "/webhooks/1/deliveries/1/attempts", "", ""))
+	var attempts []entity.WebhookAttempt
+	if err := json.Unmarshal(res.Data, &attempts); err != nil {
+		t.Fatal(err)
+	}
+	if res.Page.Total != 1 || len(attempts) != 1 || attempts[0].DeliveryID != delivery || attempts[0].ResponseCode != 500 || attempts[0].Error != "webhook answered 500" {
+		t.Errorf("attempts = %+v, want the attempt kept after the redelivery", attempts)
+	}
+
+	api.run(t, []request{
+		{"delete", http.MethodDelete, "/webhooks/1", "", "", http.StatusOK, "WebhookID #1 Has Been Deleted"},
+		{"delete again", http.MethodDelete, "/webhooks/1", "", "", http.StatusNotFound, "WebhookID #1 Not Found or Deleted"},
+		{"redeliver of a deleted webhook", http.MethodPost, "/webhooks/1/deliveries/1/redeliver", "", "", http.StatusNotFound, "WebhookID #1 Not Found or Deleted"},
+	})
+}
+
+func TestWebhookRoutesAnswer500WhenTheDatabaseFails(t *testing.T) {
+	api := newTestAPI(t)
+	if rec := api.do(http.MethodPost, "/webhooks/", "", webhookBody); rec.Code != http.StatusCreated {
+		t.Fatalf("create webhook = %d\n%s", rec.Code, rec.Body.String())
+	}
+	api.store.FailWith(errors.New("database down"))
+
+	api.run(t, []request{
+		{"create", http.MethodPost, "/webhooks/", "", webhookBody, http.StatusInternalServerError, "Internal Server Error"},
+		{"get", http.MethodGet, "/webhooks/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"patch", http.MethodPatch, "/webhooks/1", MIMEMergePatch, `{"active":false}`, http.StatusInternalServerError, "Internal Server Error"},
+		{"delete", http.MethodDelete, "/webhooks/1", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"deliveries", http.MethodGet, "/webhooks/1/deliveries", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"attempts", http.MethodGet, "/webhooks/1/deliveries/1/attempts", "", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"redeliver", http.MethodPost, "/webhooks/1/deliveries/1/redeliver", "", "", http.StatusInternalServerError, "Internal Server Error"},
+	})
+}
@@ -0,0 +1,84 @@
+// Package live pushes the changes of the orders of a User to the customer apps over a WebSocket.
+// A client authenticates with a token of the User when it connects, then subscribes to every order
+// of the User or to some of them. The changes come from the stream.Hub fed by the outbox, the same
+// notifications as the Server-Sent Events of the Order Histories.
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Actions of the messages of a client
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+// Types of the messages of the server
+const (
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	TypeEvent        = "event"
+	TypeError        = "error"
+)
+
+// ClientMessage subscribes to or unsubscribes from the changes of one order, or of every order of the
+// User when OrderID is 0
+type ClientMessage struct {
+	Action  string `json:"action"`
+	OrderID int    `json:"order_id,omitempty"`
+}
+
+// ServerMessage acknowledges a ClientMessage, carries the change of an order or reports an error
+type ServerMessage struct {
+	Type    string `json:"type"`
+	OrderID int    `json:"order_id,omitempty"`
+	// Event is the type of the change and ID the ID of its event, the same on every replica
+	Event string `json:"event,omitempty"`
+	ID    string `json:"id,omitempty"`
+	// Data is the OrderEvent payload of the change
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// ErrInvalidToken is returned for a token not signed with the secret, expired or without a User
+var ErrInvalidToken = errors.New("invalid token")
+
+// VerifyToken returns the ID of the User of token, an HS256 JWT signed with secret whose sub is the ID
+// of the User and exp is required. An empty secret accepts no token.
+func VerifyToken(secret, token string) (int, error) {
+	if secret == "" || token == "" {
+		return 0, ErrInvalidToken
+	}
+	claims := &jwt.StandardClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || claims.ExpiresAt == 0 {
+		return 0, ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID < 1 {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// SignToken returns a token of userID valid for ttl, for the services issuing the tokens and the tests
+func SignToken(secret string, userID int, ttl time.Duration) (string, error) {
+	claims := jwt.StandardClaims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
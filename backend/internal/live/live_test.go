@@ -0,0 +1,208 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/websocket"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/stream"
+)
+
+const secret = "0123456789abcdef"
+
+var testOptions = Options{PingInterval: 20 * time.Millisecond, PongTimeout: 100 * time.Millisecond, WriteTimeout: time.Second}
+
+// dial serves a Session of user 1 on hub and returns the connection of its client
+func dial(t *testing.T, hub *stream.Hub) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		NewSession(conn, hub, 1, testOptions).Serve()
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// send writes message and returns the reply of the server
+func send(t *testing.T, conn *websocket.Conn, message string) ServerMessage {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	return next(t, conn)
+}
+
+func next(t *testing.T, conn *websocket.Conn) ServerMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var message ServerMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	return message
+}
+
+func orderChange(id string, orderID int) stream.Message {
+	data, _ := json.Marshal(entity.OrderEvent{ID: orderID, UserID: 1})
+	return stream.Message{ID: id, Type: entity.EventOrderUpdated, OrderID: orderID, UserID: 1, Data: data}
+}
+
+func TestSessionPushesTheSubscribedOrders(t *testing.T) {
+	hub := stream.NewHub(10)
+	conn := dial(t, hub)
+
+	for message, want := range map[string]ServerMessage{
+		`{"action":"subscribe","order_id":5}`:   {Type: TypeSubscribed, OrderID: 5},
+		`{"action":"subscribe","order_id":6}`:   {Type: TypeSubscribed, OrderID: 6},
+		`{"action":"unsubscribe","order_id":6}`: {Type: TypeUnsubscribed, OrderID: 6},
+		`{"action":"subscribe","order_id":-1}`:  {Type: TypeError, Message: "order_id must not be negative"},
+		`{"action":"watch"}`:                    {Type: TypeError, Message: "unknown action"},
+		`not json`:                              {Type: TypeError, Message: "invalid message"},
+	} {
+		if got := send(t, conn, message); got.Type != want.Type || got.OrderID != want.OrderID || got.Message != want.Message {
+			t.Errorf("reply to %s = %+v, want %+v", message, got, want)
+		}
+	}
+
+	hub.Broadcast(orderChange("a", 6))
+	hub.Broadcast(orderChange("b", 5))
+	got := next(t, conn)
+	var order entity.OrderEvent
+	if err := json.Unmarshal(got.Data, &order); err != nil || got.Type != TypeEvent || got.ID != "b" || got.OrderID != 5 ||
+		got.Event != entity.EventOrderUpdated || order.ID != 5 {
+		t.Fatalf("pushed %+v, want the change of the subscribed order only", got)
+	}
+
+	if got := send(t, conn, `{"action":"subscribe"}`); got.Type != TypeSubscribed || got.OrderID != 0 {
+		t.Fatalf("reply to a subscription to every order = %+v", got)
+	}
+	hub.Broadcast(orderChange("c", 6))
+	if got := next(t, conn); got.ID != "c" {
+		t.Errorf("pushed %+v, want every order once subscribed to all", got)
+	}
+
+	if got := send(t, conn, `{"action":"unsubscribe"}`); got.Type != TypeUnsubscribed {
+		t.Fatalf("reply to unsubscribe = %+v", got)
+	}
+	hub.Broadcast(orderChange("d", 5))
+	// the reply proves d was handled before it, and not pushed
+	if got := send(t, conn, `{"action":"subscribe","order_id":7}`); got.Type != TypeSubscribed {
+		t.Errorf("pushed %+v after unsubscribing from everything", got)
+	}
+}
+
+func TestSessionPushesTheStatusChangesOfTheSubscribedOrders(t *testing.T) {
+	hub := stream.NewHub(10)
+	sink := stream.NewSink(hub, nil)
+	conn := dial(t, hub)
+	if got := send(t, conn, `{"action":"subscribe","order_id":5}`); got.Type != TypeSubscribed {
+		t.Fatalf("reply to subscribe = %+v", got)
+	}
+
+	payload, _ := json.Marshal(entity.OrderStatusChangedEvent{OrderID: 5, UserID: 1, OldStatus: entity.OrderPending, NewStatus: entity.OrderPaid})
+	event := entity.Event{ID: "a", Type: entity.EventOrderStatusChanged, Payload: payload}
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	got := next(t, conn)
+	var change entity.OrderStatusChangedEvent
+	if err := json.Unmarshal(got.Data, &change); err != nil || got.ID != "a" || got.OrderID != 5 ||
+		got.Event != entity.EventOrderStatusChanged || change.NewStatus != entity.OrderPaid {
+		t.Errorf("pushed %+v, want the status change of the subscribed order", got)
+	}
+}
+
+func TestSessionKeepsAliveTheClientsAnsweringPings(t *testing.T) {
+	hub := stream.NewHub(10)
+
+	// the client answers the pings while it reads
+	alive := dial(t, hub)
+	send(t, alive, `{"action":"subscribe"}`)
+	_ = alive.SetReadDeadline(time.Now().Add(5 * testOptions.PongTimeout))
+	if _, _, err := alive.ReadMessage(); !isTimeout(err) {
+		t.Fatalf("connection of a client answering the pings = %v, want it kept open", err)
+	}
+
+	// the client does not read, so it never answers
+	silent := dial(t, hub)
+	send(t, silent, `{"action":"subscribe"}`)
+	time.Sleep(3 * testOptions.PongTimeout)
+	hub.Broadcast(orderChange("a", 1))
+	_ = silent.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		_, data, err := silent.ReadMessage()
+		if err != nil {
+			if isTimeout(err) {
+				t.Error("connection of a client not answering the pings still open")
+			}
+			break
+		}
+		if strings.Contains(string(data), `"id":"a"`) {
+			t.Error("change pushed to a client not answering the pings")
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr interface{ Timeout() bool }
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func TestSessionClosesWhenTheServerShutsDown(t *testing.T) {
+	hub := stream.NewHub(10)
+	conn := dial(t, hub)
+	send(t, conn, `{"action":"subscribe"}`)
+
+	hub.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("read after shutdown = %v, want close going away", err)
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	valid, _ := SignToken(secret, 7, time.Hour)
+	expired, _ := SignToken(secret, 7, -time.Hour)
+	forged, _ := SignToken("another secret!!", 7, time.Hour)
+	withoutExpiry, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "7"}).SignedString([]byte(secret))
+	withoutUser, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}).SignedString([]byte(secret))
+
+	if userID, err := VerifyToken(secret, valid); err != nil || userID != 7 {
+		t.Errorf("VerifyToken(valid) = %d, %v, want user 7", userID, err)
+	}
+	for name, token := range map[string]string{
+		"empty":          "",
+		"garbage":        "not.a.token",
+		"expired":        expired,
+		"forged":         forged,
+		"without expiry": withoutExpiry,
+		"without user":   withoutUser,
+	} {
+		if _, err := VerifyToken(secret, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("VerifyToken(%s) = %v, want ErrInvalidToken", name, err)
+		}
+	}
+	if _, err := VerifyToken("", valid); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken without a secret = %v, want ErrInvalidToken", err)
+	}
+}
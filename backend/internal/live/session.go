@@ -0,0 +1,188 @@
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"test-crud-user-orders/internal/stream"
+)
+
+const (
+	// maxMessageSize of a ClientMessage, a bigger one closes the connection
+	maxMessageSize = 1024
+	// replyBuffer is the number of replies waiting to be written, a client sending more messages
+	// without reading the replies is disconnected
+	replyBuffer = 16
+	// maxSubscriptions to single orders per connection
+	maxSubscriptions = 100
+)
+
+var errTooManyMessages = errors.New("too many messages")
+
+// Options of the Sessions
+type Options struct {
+	// PingInterval between two pings of the server, a client not answering within PongTimeout is gone
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	// WriteTimeout of one message, a client not reading it in time is disconnected
+	WriteTimeout time.Duration
+}
+
+// Session serves the WebSocket of one client of a User. Only the goroutine of Serve writes to the
+// connection, the changes of the orders come from the Hub which drops a client falling behind.
+type Session struct {
+	conn    *websocket.Conn
+	hub     *stream.Hub
+	userID  int
+	options Options
+
+	mu sync.Mutex
+	// all is set by a subscription to every order, orders holds the subscriptions to single orders
+	all     bool
+	orders  map[int]bool
+	readErr error
+}
+
+func NewSession(conn *websocket.Conn, hub *stream.Hub, userID int, options Options) *Session {
+	return &Session{conn: conn, hub: hub, userID: userID, options: options, orders: map[int]bool{}}
+}
+
+// Serve answers the messages of the client and pushes it the changes it subscribed to until the
+// client leaves, falls behind or the Hub is closed. It closes the connection.
+func (s *Session) Serve() {
+	subscriber, _ := s.hub.Subscribe("", s.userID)
+	defer s.hub.Unsubscribe(subscriber)
+	defer s.conn.Close()
+
+	replies := make(chan ServerMessage, replyBuffer)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		s.read(replies)
+	}()
+
+	ping := time.NewTicker(s.options.PingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-readDone:
+			s.mu.Lock()
+			err := s.readErr
+			s.mu.Unlock()
+			if errors.Is(err, errTooManyMessages) {
+				s.close(websocket.ClosePolicyViolation, "replies not read")
+			}
+			return
+		case reply := <-replies:
+			if err := s.write(reply); err != nil {
+				return
+			}
+		case message, ok := <-subscriber.Messages:
+			if !ok {
+				if s.hub.Closed() {
+					s.close(websocket.CloseGoingAway, "server shutting down")
+				} else {
+					s.close(websocket.CloseTryAgainLater, "too slow, reconnect")
+				}
+				return
+			}
+			if !s.subscribed(message.OrderID) {
+				continue
+			}
+			err := s.write(ServerMessage{Type: TypeEvent, OrderID: message.OrderID, Event: message.Type, ID: message.ID, Data: message.Data})
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			deadline := time.Now().Add(s.options.WriteTimeout)
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// read applies the messages of the client and queues the replies until the connection fails
+func (s *Session) read(replies chan<- ServerMessage) {
+	s.conn.SetReadLimit(maxMessageSize)
+	alive := func() error {
+		return s.conn.SetReadDeadline(time.Now().Add(s.options.PongTimeout))
+	}
+	_ = alive()
+	s.conn.SetPongHandler(func(string) error { return alive() })
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = alive()
+
+		var message ClientMessage
+		reply := ServerMessage{Type: TypeError, Message: "invalid message"}
+		if err := json.Unmarshal(data, &message); err == nil {
+			reply = s.apply(message)
+		}
+		select {
+		case replies <- reply:
+		default:
+			s.mu.Lock()
+			s.readErr = errTooManyMessages
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// apply changes the subscriptions of the Session as asked by message. The Hub only hands over the
+// orders of the User, subscribing to the order of another User receives nothing.
+func (s *Session) apply(message ClientMessage) ServerMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case message.OrderID < 0:
+		return ServerMessage{Type: TypeError, Message: "order_id must not be negative"}
+	case message.Action == ActionSubscribe && message.OrderID == 0:
+		s.all = true
+		return ServerMessage{Type: TypeSubscribed}
+	case message.Action == ActionSubscribe:
+		if !s.orders[message.OrderID] && len(s.orders) >= maxSubscriptions {
+			return ServerMessage{Type: TypeError, OrderID: message.OrderID, Message: "too many subscriptions"}
+		}
+		s.orders[message.OrderID] = true
+		return ServerMessage{Type: TypeSubscribed, OrderID: message.OrderID}
+	case message.Action == ActionUnsubscribe && message.OrderID == 0:
+		// leaves every subscription, to single orders included
+		s.all = false
+		s.orders = map[int]bool{}
+		return ServerMessage{Type: TypeUnsubscribed}
+	case message.Action == ActionUnsubscribe:
+		delete(s.orders, message.OrderID)
+		return ServerMessage{Type: TypeUnsubscribed, OrderID: message.OrderID}
+	}
+	return ServerMessage{Type: TypeError, Message: "unknown action"}
+}
+
+func (s *Session) subscribed(orderID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.all || s.orders[orderID]
+}
+
+func (s *Session) write(message ServerMessage) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.options.WriteTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(message)
+}
+
+// close tells the client why the connection ends, the client may not be reading anymore
+func (s *Session) close(code int, reason string) {
+	deadline := time.Now().Add(s.options.WriteTimeout)
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
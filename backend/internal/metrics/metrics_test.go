@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareLabelsRequestsByRoute(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/users/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/unknown"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/users/:id", "200")); got != 2 {
+		t.Errorf("GET /users/:id 200 = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/users/:id", "404")); got != 1 {
+		t.Errorf("GET /users/:id 404 = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(HTTPRequests); got != 3 {
+		t.Errorf("request series = %d, want 3 (unmatched paths share one series)", got)
+	}
+}
+
+func TestHandlerServesTextFormat(t *testing.T) {
+	UsersCreated.Inc()
+
+	e := echo.New()
+	e.GET("/metrics", Handler())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if contentType := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", contentType)
+	}
+	for _, name := range []string{"users_created_total", "orders_created_total", "go_goroutines"} {
+		if !strings.Contains(rec.Body.String(), name) {
+			t.Errorf("%s is missing from the exposition", name)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/template"
+)
+
+// Document is the subset of an OpenAPI 3.0 document this service needs
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	types      map[reflect.Type]string
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem maps a lower-case HTTP method to its Operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	AllOf      []*Schema          `json:"allOf,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+)
+
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI:    "3.0.3",
+		Info:       Info{Title: title, Version: version},
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+		types:      map[reflect.Type]string{},
+	}
+}
+
+// Add documents an operation of an Echo route, path parameters (:id) are added automatically
+func (d *Document) Add(method, echoPath string, op *Operation) {
+	path := PathFromEcho(echoPath)
+	for _, segment := range strings.Split(echoPath, "/") {
+		if strings.HasPrefix(segment, ":") {
+			op.Parameters = append([]*Parameter{{
+				Name:     segment[1:],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "integer"},
+			}}, op.Parameters...)
+		}
+	}
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// Has reports whether method and Echo path are documented
+func (d *Document) Has(method, echoPath string) bool {
+	_, ok := d.Paths[PathFromEcho(echoPath)][strings.ToLower(method)]
+	return ok
+}
+
+// PathFromEcho converts an Echo route path (/users/:id) to an OpenAPI path (/users/{id})
+func PathFromEcho(echoPath string) string {
+	segments := strings.Split(echoPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Query describes an optional query parameter
+func Query(name, typ, description string) *Parameter {
+	return &Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
+
+// Body describes a required request body of v (a struct value or a slice of them)
+func (d *Document) Body(v interface{}, contentTypes ...string) *RequestBody {
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"application/json"}
+	}
+	body := &RequestBody{Required: true, Content: map[string]*MediaType{}}
+	schema := d.Schema(v)
+	for _, contentType := range contentTypes {
+		body.Content[contentType] = &MediaType{Schema: schema}
+	}
+	return body
+}
+
+// Envelope describes a template.ResponseHTTP response with data of v, v is nil when data is omitted
+func (d *Document) Envelope(description string, v interface{}) *Response {
+	schema := d.Schema(template.ResponseHTTP{})
+	if v != nil {
+		schema = &Schema{AllOf: []*Schema{schema, {
+			Type:       "object",
+			Properties: map[string]*Schema{"data": d.Schema(v)},
+		}}}
+	}
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// EnvelopeV2 describes a template.ResponseV2 response with data of v, v is nil when data is null
+func (d *Document) EnvelopeV2(description string, v interface{}) *Response {
+	schema := d.Schema(template.ResponseV2{})
+	if v != nil {
+		schema = &Schema{AllOf: []*Schema{schema, {
+			Type:       "object",
+			Properties: map[string]*Schema{"data": d.Schema(v)},
+		}}}
+	}
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// Schema returns the schema of v, structs are registered as components and referenced
+func (d *Document) Schema(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case rawJSONType:
+		return &Schema{Type: "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		return d.component(t)
+	}
+	// interface{} and error may hold anything
+	return &Schema{}
+}
+
+// component registers t under components/schemas and returns a reference to it
+func (d *Document) component(t reflect.Type) *Schema {
+	name, ok := d.types[t]
+	if !ok {
+		name = t.Name()
+		d.types[t] = name
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		d.Components.Schemas[name] = schema
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if jsonName == "-" {
+				continue
+			}
+			if jsonName == "" {
+				jsonName = field.Name
+			}
+
+			// A pointer is null in responses, but only means "not supplied" in validated inputs
+			property := d.schemaOf(field.Type)
+			if field.Type.Kind() == reflect.Ptr && property.Ref == "" && field.Tag.Get("validate") == "" {
+				property.Nullable = true
+			}
+			if applyValidate(property, field.Tag.Get("validate")) {
+				schema.Required = append(schema.Required, jsonName)
+			}
+			schema.Properties[jsonName] = property
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// applyValidate translates go-playground/validator tags into schema constraints,
+// it returns true when the field is required. The rules after dive constrain the items of an array.
+func applyValidate(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch {
+		case name == "required":
+			required = true
+		case name == "dive" && schema.Items != nil:
+			schema = schema.Items
+		case name == "url":
+			schema.Format = "uri"
+		case schema.Ref != "":
+			// constraints of a referenced component live in the component itself
+		case name == "oneof":
+			schema.Enum = strings.Fields(param)
+		case name == "min" || name == "max":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				setBound(schema, name == "min", n)
+			}
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, lower bool, n float64) {
+	switch schema.Type {
+	case "string":
+		length := int(n)
+		if lower {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package outbox publishes the domain events written to the outbox table by the use cases.
+// The Relay keeps a cursor per Sink and publishes every committed event to each Sink at least
+// once, in the order their transactions made them visible. A Sink failing does not hold back the others, and an
+// event a Sink failed on MaxAttempts times is moved to the dead letters of that Sink. An event
+// may be published again after a failure, consumers deduplicate on its ID.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/pkg/logger"
+)
+
+// lockTTL is the longest one instance relays while the others wait
+const lockTTL = 30 * time.Second
+
+// maxRetryDelay caps the wait before a failed sink is tried again
+const maxRetryDelay = 10 * time.Minute
+
+// Sink receives the published events, Publish must fail unless event was delivered.
+// Name identifies the cursor of the Sink, it must not change between two runs.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event entity.Event) error
+}
+
+// Options of a Relay
+type Options struct {
+	// Interval between two looks at the outbox, also the wait before a failed sink is tried
+	// again, doubled after every other failure
+	Interval time.Duration
+	// BatchSize is the number of events read from the outbox at once
+	BatchSize int
+	// MaxAttempts of an event on one sink before it is a dead letter, 0 retries forever
+	MaxAttempts int
+	// Retention of the published events, 0 keeps them
+	Retention time.Duration
+	// Locker lets one instance relay at a time, nil relays on every instance
+	Locker cache.Locker
+}
+
+// Relay moves the events of the outbox to its sinks
+type Relay struct {
+	outboxRepo repository.OutboxRepository
+	sinks      []Sink
+	options    Options
+	now        func() time.Time
+}
+
+func NewRelay(outboxRepo repository.OutboxRepository, sinks []Sink, options Options) *Relay {
+	return &Relay{outboxRepo: outboxRepo, sinks: sinks, options: options, now: time.Now}
+}
+
+// Run relays the outbox every Interval until ctx is done
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.options.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RelayPending(ctx); err != nil && ctx.Err() == nil {
+			logger.FromContext(ctx).Warn().Err(err).Msg("outbox not relayed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending moves every sink through the outbox until it is at the end or a publication
+// fails, the failed event is tried again on a later call once its retry delay is over. It
+// returns the number of events now published to every sink and the first error of a sink.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	if r.options.Locker != nil {
+		unlock, err := r.options.Locker.TryLock(ctx, "lock:outbox", lockTTL)
+		if errors.Is(err, cache.ErrLocked) {
+			return 0, nil
+		}
+		if err == nil {
+			defer unlock()
+		} else {
+			logger.FromContext(ctx).Warn().Err(err).Msg("outbox lock not taken")
+		}
+	}
+
+	// the events are relayed in the order they became visible, a transaction committing after
+	// another one that wrote later does not leave its events behind the cursors
+	for {
+		sequenced, err := r.outboxRepo.Sequence(ctx, r.options.BatchSize)
+		if err != nil {
+			return 0, err
+		}
+		if sequenced < r.options.BatchSize {
+			break
+		}
+	}
+
+	// published is the sequence of the last event every sink is past
+	published := math.MaxInt
+	var firstErr error
+	for _, sink := range r.sinks {
+		cursor, err := r.outboxRepo.Cursor(ctx, sink.Name())
+		if err != nil {
+			return 0, err
+		}
+		if err := r.relay(ctx, sink, cursor); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if cursor.LastSequence < published {
+			published = cursor.LastSequence
+		}
+	}
+
+	marked, err := r.outboxRepo.MarkPublished(ctx, published, r.now())
+	if err != nil {
+		return int(marked), err
+	}
+	if r.options.Retention > 0 {
+		if _, err := r.outboxRepo.DeletePublished(ctx, r.now().Add(-r.options.Retention)); err != nil {
+			return int(marked), err
+		}
+	}
+	return int(marked), firstErr
+}
+
+// relay publishes the events after cursor to sink and moves cursor past them
+func (r *Relay) relay(ctx context.Context, sink Sink, cursor *entity.OutboxCursor) error {
+	if cursor.NextAttemptAt != nil && r.now().Before(*cursor.NextAttemptAt) {
+		return nil
+	}
+	for {
+		events, err := r.outboxRepo.After(ctx, cursor.LastSequence, r.options.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			errPublish := sink.Publish(ctx, event.Event())
+			if errPublish != nil && ctx.Err() != nil {
+				// stopped, not a failure of the sink
+				return ctx.Err()
+			}
+			if errPublish == nil {
+				advance(cursor, *event.Sequence)
+				continue
+			}
+
+			errPublish = fmt.Errorf("%s sink: %w", sink.Name(), errPublish)
+			cursor.Attempts++
+			cursor.LastError = errPublish.Error()
+			if r.options.MaxAttempts == 0 || cursor.Attempts < r.options.MaxAttempts {
+				next := r.now().Add(r.backoff(cursor.Attempts))
+				cursor.NextAttemptAt = &next
+				if err := r.outboxRepo.SaveCursor(ctx, cursor); err != nil {
+					logger.FromContext(ctx).Warn().Err(err).Str("sink", sink.Name()).Msg("outbox failure not recorded")
+				}
+				return errPublish
+			}
+
+			logger.FromContext(ctx).Error().Err(errPublish).
+				Str("sink", sink.Name()).
+				Str("event_id", event.EventID).
+				Int("attempts", cursor.Attempts).
+				Msg("outbox event moved to the dead letters")
+			deadLetter := &entity.OutboxDeadLetter{
+				Sink:        sink.Name(),
+				EventID:     event.EventID,
+				Type:        event.Type,
+				AggregateID: event.AggregateID,
+				Payload:     event.Payload,
+				OccurredAt:  event.OccurredAt,
+				Attempts:    cursor.Attempts,
+				LastError:   cursor.LastError,
+			}
+			advance(cursor, *event.Sequence)
+			if err := r.outboxRepo.SaveCursor(ctx, cursor, deadLetter); err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			if err := r.outboxRepo.SaveCursor(ctx, cursor); err != nil {
+				return err
+			}
+		}
+		if len(events) < r.options.BatchSize {
+			return nil
+		}
+	}
+}
+
+// advance moves cursor past the event of sequence and clears the failures of the sink
+func advance(cursor *entity.OutboxCursor, sequence int) {
+	cursor.LastSequence = sequence
+	cursor.Attempts = 0
+	cursor.LastError = ""
+	cursor.NextAttemptAt = nil
+}
+
+// backoff is the wait after the attempts-th failed publication on a sink
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.options.Interval
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,326 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/internal/repository/memory"
+)
+
+// recordingSink keeps the IDs of the published events, it fails while failing is set and on
+// the event refused
+type recordingSink struct {
+	name      string
+	mu        sync.Mutex
+	published []string
+	failing   bool
+	refused   string
+}
+
+func (s *recordingSink) Name() string {
+	if s.name == "" {
+		return "recording"
+	}
+	return s.name
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event entity.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing || event.ID == s.refused {
+		return errors.New("sink down")
+	}
+	s.published = append(s.published, event.ID)
+	return nil
+}
+
+// deadLetterOutbox keeps the dead letters saved with the cursors
+type deadLetterOutbox struct {
+	repository.OutboxRepository
+	deadLetters []*entity.OutboxDeadLetter
+}
+
+func (o *deadLetterOutbox) SaveCursor(ctx context.Context, cursor *entity.OutboxCursor, deadLetters ...*entity.OutboxDeadLetter) error {
+	o.deadLetters = append(o.deadLetters, deadLetters...)
+	return o.OutboxRepository.SaveCursor(ctx, cursor, deadLetters...)
+}
+
+func addEvents(t *testing.T, outboxRepo repository.OutboxRepository, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		err := outboxRepo.Add(context.Background(), &entity.OutboxEvent{
+			EventID:     id,
+			Type:        entity.EventUserCreated,
+			AggregateID: 1,
+			Payload:     `{"id":1}`,
+			OccurredAt:  time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func cursorOf(t *testing.T, outboxRepo repository.OutboxRepository, sink string) *entity.OutboxCursor {
+	t.Helper()
+	cursor, err := outboxRepo.Cursor(context.Background(), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cursor
+}
+
+func equalIDs(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRelayPublishesInOrderAndRetries(t *testing.T) {
+	outboxRepo := memory.NewOutboxRepository(memory.NewStore())
+	sink := &recordingSink{}
+	relay := NewRelay(outboxRepo, []Sink{sink}, Options{BatchSize: 2})
+	ctx := context.Background()
+	addEvents(t, outboxRepo, "a", "b", "c")
+
+	if published, err := relay.RelayPending(ctx); err != nil || published != 3 {
+		t.Fatalf("RelayPending = %d, %v, want the 3 events over two batches", published, err)
+	}
+	addEvents(t, outboxRepo, "d", "e")
+	sink.failing = true
+	if _, err := relay.RelayPending(ctx); err == nil {
+		t.Fatal("RelayPending with the sink down succeeded")
+	}
+	if cursor := cursorOf(t, outboxRepo, "recording"); cursor.LastSequence != 3 || cursor.Attempts != 1 || cursor.LastError != "recording sink: sink down" {
+		t.Fatalf("cursor = %+v, want the failure counted on the event after c", cursor)
+	}
+
+	sink.failing = false
+	if published, err := relay.RelayPending(ctx); err != nil || published != 2 {
+		t.Fatalf("RelayPending = %d, %v, want the 2 events retried", published, err)
+	}
+	if !equalIDs(sink.published, "a", "b", "c", "d", "e") {
+		t.Errorf("published = %v, want every event once and in order", sink.published)
+	}
+	if cursor := cursorOf(t, outboxRepo, "recording"); cursor.LastSequence != 5 || cursor.Attempts != 0 || cursor.LastError != "" {
+		t.Errorf("cursor = %+v, want the failure cleared", cursor)
+	}
+}
+
+func TestRelayPublishesEventsInCommitOrder(t *testing.T) {
+	outboxRepo := memory.NewOutboxRepository(memory.NewStore())
+	sink := &recordingSink{}
+	relay := NewRelay(outboxRepo, []Sink{sink}, Options{BatchSize: 10})
+	ctx := context.Background()
+	// the transaction of id 1 took its ID first but commits after the one of id 2
+	add := func(id int, eventID string) {
+		err := outboxRepo.Add(ctx, &entity.OutboxEvent{
+			ID: id, EventID: eventID, Type: entity.EventUserCreated, AggregateID: 1, Payload: `{"id":1}`, OccurredAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(2, "second")
+	if published, err := relay.RelayPending(ctx); err != nil || published != 1 {
+		t.Fatalf("RelayPending = %d, %v, want id 2", published, err)
+	}
+	add(1, "first")
+	if published, err := relay.RelayPending(ctx); err != nil || published != 1 {
+		t.Fatalf("RelayPending = %d, %v, want id 1 committed after id 2 relayed", published, err)
+	}
+	if !equalIDs(sink.published, "second", "first") {
+		t.Errorf("published = %v, want both events in the order they committed", sink.published)
+	}
+}
+
+func TestRelayKeepsRelayingToTheOtherSinks(t *testing.T) {
+	outboxRepo := memory.NewOutboxRepository(memory.NewStore())
+	healthy, broken := &recordingSink{name: "healthy"}, &recordingSink{name: "broken", failing: true}
+	relay := NewRelay(outboxRepo, []Sink{broken, healthy}, Options{BatchSize: 10})
+	ctx := context.Background()
+	addEvents(t, outboxRepo, "a", "b")
+
+	for i := 0; i < 2; i++ {
+		if published, err := relay.RelayPending(ctx); err == nil || published != 0 {
+			t.Fatalf("RelayPending with a sink down = %d, %v, want its error and nothing published to every sink", published, err)
+		}
+		addEvents(t, outboxRepo, strconv.Itoa(i))
+	}
+	if !equalIDs(healthy.published, "a", "b", "0") {
+		t.Errorf("healthy sink got %v, want the events while the other sink is down", healthy.published)
+	}
+
+	broken.failing = false
+	if published, err := relay.RelayPending(ctx); err != nil || published != 4 {
+		t.Fatalf("RelayPending = %d, %v, want the 4 events published once the sink is back", published, err)
+	}
+	if !equalIDs(broken.published, "a", "b", "0", "1") || !equalIDs(healthy.published, "a", "b", "0", "1") {
+		t.Errorf("published = %v and %v, want every event once and in order on both sinks", broken.published, healthy.published)
+	}
+
+	// a sink added later starts after the events published to every sink
+	late := &recordingSink{name: "late"}
+	addEvents(t, outboxRepo, "c")
+	relay = NewRelay(outboxRepo, []Sink{broken, healthy, late}, Options{BatchSize: 10})
+	if published, err := relay.RelayPending(ctx); err != nil || published != 1 || !equalIDs(late.published, "c") {
+		t.Errorf("RelayPending = %d, %v, new sink got %v, want c only", published, err, late.published)
+	}
+}
+
+func TestRelayMovesPastAnEventASinkKeepsRefusing(t *testing.T) {
+	outboxRepo := &deadLetterOutbox{OutboxRepository: memory.NewOutboxRepository(memory.NewStore())}
+	sink := &recordingSink{refused: "b"}
+	relay := NewRelay(outboxRepo, []Sink{sink}, Options{BatchSize: 10, Interval: time.Minute, MaxAttempts: 3})
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	relay.now = func() time.Time { return now }
+	ctx := context.Background()
+	addEvents(t, outboxRepo, "a", "b", "c")
+
+	for attempt, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		if _, err := relay.RelayPending(ctx); err == nil {
+			t.Fatalf("RelayPending on attempt %d succeeded, want the failure of b", attempt+1)
+		}
+		cursor := cursorOf(t, outboxRepo, "recording")
+		if cursor.Attempts != attempt+1 || cursor.NextAttemptAt == nil || !cursor.NextAttemptAt.Equal(now.Add(wantDelay)) {
+			t.Fatalf("cursor after attempt %d = %+v, want b tried again in %s", attempt+1, cursor, wantDelay)
+		}
+		// not tried again before its retry delay
+		if _, err := relay.RelayPending(ctx); err != nil {
+			t.Fatalf("RelayPending before the retry delay = %v, want the sink left alone", err)
+		}
+		now = *cursor.NextAttemptAt
+	}
+
+	if published, err := relay.RelayPending(ctx); err != nil || published != 2 {
+		t.Fatalf("RelayPending on the last attempt = %d, %v, want b dead and both b and c published", published, err)
+	}
+	if !equalIDs(sink.published, "a", "c") {
+		t.Errorf("published = %v, want the relay past b", sink.published)
+	}
+	if len(outboxRepo.deadLetters) != 1 {
+		t.Fatalf("dead letters = %+v, want b", outboxRepo.deadLetters)
+	}
+	if deadLetter := outboxRepo.deadLetters[0]; deadLetter.EventID != "b" || deadLetter.Sink != "recording" ||
+		deadLetter.Attempts != 3 || deadLetter.LastError != "recording sink: sink down" || deadLetter.Payload != `{"id":1}` {
+		t.Errorf("dead letter = %+v, want b after 3 attempts", deadLetter)
+	}
+	if cursor := cursorOf(t, outboxRepo, "recording"); cursor.LastSequence != 3 || cursor.Attempts != 0 || cursor.NextAttemptAt != nil {
+		t.Errorf("cursor = %+v, want it past c with the failures cleared", cursor)
+	}
+}
+
+func TestRelayDeletesPublishedEventsAfterRetention(t *testing.T) {
+	outboxRepo := memory.NewOutboxRepository(memory.NewStore())
+	relay := NewRelay(outboxRepo, []Sink{&recordingSink{}}, Options{BatchSize: 10, Retention: time.Hour})
+	ctx := context.Background()
+	addEvents(t, outboxRepo, "a")
+
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+	if _, err := relay.RelayPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, _ := outboxRepo.DeletePublished(ctx, now.Add(time.Second)); deleted != 1 {
+		t.Fatalf("published events = %d, want the event kept within the retention", deleted)
+	}
+
+	addEvents(t, outboxRepo, "b")
+	if _, err := relay.RelayPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	relay.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, err := relay.RelayPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, _ := outboxRepo.DeletePublished(ctx, now.Add(time.Second)); deleted != 0 {
+		t.Errorf("published events = %d, want the event deleted after the retention", deleted)
+	}
+}
+
+func TestRelaySkipsWhileAnotherInstanceRelays(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	locker := cache.NewRedisLocker(client)
+	outboxRepo := memory.NewOutboxRepository(memory.NewStore())
+	sink := &recordingSink{}
+	relay := NewRelay(outboxRepo, []Sink{sink}, Options{BatchSize: 10, Locker: locker})
+	ctx := context.Background()
+	addEvents(t, outboxRepo, "a")
+
+	unlock, err := locker.TryLock(ctx, "lock:outbox", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published, err := relay.RelayPending(ctx); err != nil || published != 0 {
+		t.Errorf("RelayPending while locked = %d, %v, want nothing published", published, err)
+	}
+	unlock()
+	if published, err := relay.RelayPending(ctx); err != nil || published != 1 {
+		t.Errorf("RelayPending = %d, %v, want the event published", published, err)
+	}
+}
+
+func TestRedisStreamSink(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	sink := NewRedisStreamSink(client, "events", 100)
+	ctx := context.Background()
+	event := entity.Event{ID: "a", Type: entity.EventUserCreated, AggregateID: 7, OccurredAt: time.Now(), Payload: json.RawMessage(`{"id":7}`)}
+
+	if err := sink.Publish(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := client.XRange(ctx, "events", "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("stream = %+v, %v", entries, err)
+	}
+	values := entries[0].Values
+	if values["id"] != "a" || values["type"] != entity.EventUserCreated || values["aggregate_id"] != "7" || values["payload"] != `{"id":7}` {
+		t.Errorf("entry = %v", values)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received entity.Event
+	var idempotencyKey string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	sink := NewWebhookSink(server.URL, time.Second)
+	ctx := context.Background()
+	event := entity.Event{ID: "a", Type: entity.EventUserDeleted, AggregateID: 7, OccurredAt: time.Now(), Payload: json.RawMessage(`{"id":7}`)}
+
+	if err := sink.Publish(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if idempotencyKey != "a" || received.Type != entity.EventUserDeleted || string(received.Payload) != `{"id":7}` {
+		t.Errorf("received %+v with Idempotency-Key %q", received, idempotencyKey)
+	}
+
+	status = http.StatusBadGateway
+	if err := sink.Publish(ctx, event); err == nil || err.Error() != "webhook answered 502" {
+		t.Errorf("Publish to a failing webhook = %v, want the status", err)
+	}
+}
@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/pkg/logger"
+)
+
+// LogSink writes every event to the logger of the context
+type LogSink struct{}
+
+func (LogSink) Name() string {
+	return "log"
+}
+
+func (LogSink) Publish(ctx context.Context, event entity.Event) error {
+	logger.FromContext(ctx).Info().
+		Str("event_id", event.ID).
+		Str("event_type", event.Type).
+		Int("aggregate_id", event.AggregateID).
+		RawJSON("payload", event.Payload).
+		Msg("domain event")
+	return nil
+}
+
+// RedisStreamSink appends every event to a Redis Stream, consumer groups read it with XREADGROUP
+type RedisStreamSink struct {
+	client redis.UniversalClient
+	stream string
+	// maxLen trims the stream to about maxLen entries, 0 keeps every entry
+	maxLen int64
+}
+
+func NewRedisStreamSink(client redis.UniversalClient, stream string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Name() string {
+	return "redis"
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, event entity.Event) error {
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: s.maxLen > 0,
+		Values: map[string]interface{}{
+			"id":           event.ID,
+			"type":         event.Type,
+			"aggregate_id": event.AggregateID,
+			"occurred_at":  event.OccurredAt.Format(time.RFC3339Nano),
+			"payload":      string(event.Payload),
+		},
+	}).Err()
+}
+
+// WebhookSink posts every event as JSON to url, any answer but 2xx is a failure.
+// The Idempotency-Key header carries the ID of the event.
+type WebhookSink struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{client: &http.Client{Timeout: timeout}, url: url}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event entity.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %d", res.StatusCode)
+	}
+	return nil
+}
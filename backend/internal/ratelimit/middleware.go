@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"test-crud-user-orders/internal/metrics"
+	"test-crud-user-orders/internal/template"
+	"test-crud-user-orders/pkg/logger"
+)
+
+// HeaderAPIKey carries the API key of a client, see APIKeyContextKey
+const HeaderAPIKey = "X-API-Key"
+
+// UserContextKey is the echo.Context key an authentication middleware sets to the ID of the user
+const UserContextKey = "user_id"
+
+// APIKeyContextKey is the echo.Context key an authentication middleware sets to the API key of
+// HeaderAPIKey once it verified it
+const APIKeyContextKey = "api_key"
+
+// KeyFunc identifies the client of a request
+type KeyFunc func(c echo.Context) string
+
+// RouteFunc names the route of a request in Config.Routes and in its bucket
+type RouteFunc func(c echo.Context) string
+
+// Route names the route of a request "METHOD /route/template"
+func Route(c echo.Context) string {
+	return c.Request().Method + " " + c.Path()
+}
+
+// ClientKey identifies the client by its user when authenticated, by its API key when verified
+// and by its IP address otherwise. An API key nobody verified is ignored, a client sending a new
+// key on every request would have a new bucket each time. The API key is hashed so it is never
+// stored in Redis.
+func ClientKey(c echo.Context) string {
+	if user := c.Get(UserContextKey); user != nil {
+		return fmt.Sprintf("user:%v", user)
+	}
+	if apiKey, _ := c.Get(APIKeyContextKey).(string); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + c.RealIP()
+}
+
+// Config of Middleware
+type Config struct {
+	Limiter Limiter
+	// Default is shared by every route of a client without a limit of its own
+	Default Limit
+	// Routes holds the limits by "METHOD /route/template", a zero Limit exempts the route
+	Routes map[string]Limit
+	// Key identifies the client, ClientKey when nil
+	Key KeyFunc
+	// Route names the route of a request, Route when nil. Routes sharing a name share their buckets.
+	Route RouteFunc
+}
+
+// Middleware answers 429 Too Many Requests with Retry-After once a client used its budget on a route.
+// Every limited response carries the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers. A failing Limiter lets the request through.
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	if cfg.Key == nil {
+		cfg.Key = ClientKey
+	}
+	if cfg.Route == nil {
+		cfg.Route = Route
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := cfg.Route(c)
+			limit, ok := cfg.Routes[route]
+			bucket := route
+			if !ok {
+				limit, bucket = cfg.Default, "default"
+			}
+			if limit.Unlimited() {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+			result, err := cfg.Limiter.Take(ctx, "ratelimit:"+bucket+":"+cfg.Key(c), limit)
+			if err != nil {
+				logger.FromContext(ctx).Error().Err(err).Msg("rate limiter failed")
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", seconds(result.Reset))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, seconds(limit.Window)))
+			if result.Allowed {
+				return next(c)
+			}
+
+			metrics.RateLimited.WithLabelValues(bucket).Inc()
+			header.Set(echo.HeaderRetryAfter, seconds(result.RetryAfter))
+			return echo.NewHTTPError(http.StatusTooManyRequests, template.ResponseHTTP{
+				Status:  http.StatusTooManyRequests,
+				Message: "Too Many Requests",
+			})
+		}
+	}
+}
+
+// seconds rounds d up to whole seconds, as the headers expect
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Window to one client. The budget is a token bucket refilled continuously,
+// so a client may burst up to Requests at once and then gets one request every Window/Requests.
+// A Limit with no Requests does not limit anything.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Unlimited reports whether l lets every request through
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Window <= 0
+}
+
+// Result is the state of a bucket after one request was taken from it
+type Result struct {
+	Allowed bool
+	// Remaining requests allowed right away
+	Remaining int
+	// RetryAfter is how long to wait for the next allowed request, zero when Allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Limiter takes one request from the bucket of key
+type Limiter interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take refills tokens for elapsed, then takes one request from them
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	capacity := float64(limit.Requests)
+	// tokens per nanosecond
+	rate := capacity / float64(limit.Window)
+	if elapsed > 0 {
+		tokens = math.Min(capacity, tokens+float64(elapsed)*rate)
+	}
+
+	result := Result{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+	}
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - tokens) / rate))
+	return tokens, result
+}
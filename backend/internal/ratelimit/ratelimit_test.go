@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemoryLimiterRefillsOverTheWindow(t *testing.T) {
+	now := time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+	limiter := newMemoryLimiter(func() time.Time { return now })
+	limit := Limit{Requests: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		result, _ := limiter.Take(ctx, "client", limit)
+		if result.Allowed != want {
+			t.Fatalf("request %d allowed = %v, want %v", i+1, result.Allowed, want)
+		}
+	}
+	result, _ := limiter.Take(ctx, "client", limit)
+	if result.RetryAfter != 30*time.Second || result.Remaining != 0 {
+		t.Errorf("retry after = %s, remaining = %d, want 30s and 0", result.RetryAfter, result.Remaining)
+	}
+	if other, _ := limiter.Take(ctx, "other", limit); !other.Allowed {
+		t.Error("another client shares the bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if result, _ := limiter.Take(ctx, "client", limit); !result.Allowed {
+		t.Error("bucket not refilled after Window/Requests")
+	}
+}
+
+func TestRedisLimiterSharesTheBucket(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	limit := Limit{Requests: 3, Window: time.Minute}
+
+	// two instances of the service
+	first := NewRedisLimiter(client, NewMemoryLimiter())
+	second := NewRedisLimiter(client, NewMemoryLimiter())
+	ctx := context.Background()
+
+	for i, limiter := range []*RedisLimiter{first, second, first, second} {
+		result, err := limiter.Take(ctx, "ratelimit:test:ip:1", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i < 3; result.Allowed != want {
+			t.Fatalf("request %d allowed = %v, want %v", i+1, result.Allowed, want)
+		}
+		if !result.Allowed && (result.RetryAfter <= 0 || result.RetryAfter > 20*time.Second) {
+			t.Errorf("retry after = %s, want at most Window/Requests", result.RetryAfter)
+		}
+	}
+	if ttl := server.TTL("ratelimit:test:ip:1"); ttl <= 0 || ttl > time.Minute+time.Second {
+		t.Errorf("bucket ttl = %s, want it to expire once refilled", ttl)
+	}
+}
+
+func TestRedisLimiterFallsBackToMemory(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	server.Close()
+
+	now := time.Now()
+	limiter := NewRedisLimiter(client, NewMemoryLimiter())
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	for i, want := range []bool{true, false} {
+		result, err := limiter.Take(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatalf("error = %v, the fallback must answer", err)
+		}
+		if result.Allowed != want {
+			t.Fatalf("request %d allowed = %v, want %v", i+1, result.Allowed, want)
+		}
+	}
+}
+
+// verifyAPIKey stands for an authentication middleware accepting the API key "key-1" only
+func verifyAPIKey(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if apiKey := c.Request().Header.Get(HeaderAPIKey); apiKey == "key-1" {
+			c.Set(APIKeyContextKey, apiKey)
+		}
+		return next(c)
+	}
+}
+
+func TestMiddlewareAnswers429WithHeaders(t *testing.T) {
+	e := echo.New()
+	e.Use(verifyAPIKey)
+	e.Use(Middleware(Config{
+		Limiter: NewMemoryLimiter(),
+		Default: Limit{Requests: 100, Window: time.Minute},
+		Routes: map[string]Limit{
+			"POST /users/": {Requests: 1, Window: time.Minute},
+			"GET /healthz": {},
+		},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/users/", ok)
+	e.GET("/healthz", ok)
+
+	do := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if apiKey != "" {
+			req.Header.Set(HeaderAPIKey, apiKey)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/users/", ""); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("first request = %d, remaining %q", rec.Code, rec.Header().Get("RateLimit-Remaining"))
+	}
+	rec := do(http.MethodPost, "/users/", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request = %d, want 429", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Retry-After":      "60",
+		"RateLimit-Limit":  "1",
+		"RateLimit-Reset":  "60",
+		"RateLimit-Policy": "1;w=60",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	for _, apiKey := range []string{"key-2", "key-3"} {
+		if rec := do(http.MethodPost, "/users/", apiKey); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("client rotating unverified API keys = %d, want the 429 of its IP address", rec.Code)
+		}
+	}
+	if rec := do(http.MethodPost, "/users/", "key-1"); rec.Code != http.StatusOK {
+		t.Errorf("client with a verified API key = %d, it has a bucket of its own", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := do(http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("exempted route = %d with RateLimit-Limit %q", rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+}
+
+func TestMiddlewareSharesTheBucketOfARoute(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(Config{
+		Limiter: NewMemoryLimiter(),
+		Default: Limit{Requests: 100, Window: time.Minute},
+		Routes:  map[string]Limit{"POST /users/": {Requests: 1, Window: time.Minute}},
+		Route: func(c echo.Context) string {
+			return c.Request().Method + " " + strings.TrimPrefix(c.Path(), "/v1")
+		},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/users/", ok)
+	e.POST("/v1/users/", ok)
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/v1/users/", http.StatusOK},
+		{"/users/", http.StatusTooManyRequests},
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Fatalf("POST %s = %d, want %d", tc.path, rec.Code, tc.want)
+		}
+	}
+}
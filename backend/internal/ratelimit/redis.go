@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"test-crud-user-orders/pkg/logger"
+)
+
+// fallbackPeriod is how long the fallback answers alone after Redis failed, so that a Redis down
+// does not cost every request a connection timeout
+const fallbackPeriod = 5 * time.Second
+
+// tokenBucket refills and takes from the bucket hash KEYS[1] atomically with the clock of Redis,
+// shared by every instance. ARGV: requests, window in milliseconds.
+// Returns allowed (0 or 1), remaining, retry after and reset in milliseconds.
+var tokenBucket = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local rate = capacity / window
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = capacity
+if bucket[1] then
+  tokens = math.min(capacity, tonumber(bucket[1]) + math.max(0, now - tonumber(bucket[2])) * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((capacity - tokens) / rate)
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// RedisLimiter shares the buckets between every instance of the service through Redis,
+// it answers with fallback while Redis fails
+type RedisLimiter struct {
+	client   redis.Scripter
+	fallback Limiter
+	now      func() time.Time
+
+	// failedUntil is the end of the fallback period in Unix nanoseconds, 0 while Redis answers
+	failedUntil int64
+}
+
+func NewRedisLimiter(client redis.Scripter, fallback Limiter) *RedisLimiter {
+	return &RedisLimiter{client: client, fallback: fallback, now: time.Now}
+}
+
+func (r *RedisLimiter) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	failedUntil := atomic.LoadInt64(&r.failedUntil)
+	if failedUntil != 0 && r.now().UnixNano() < failedUntil {
+		return r.fallback.Take(ctx, key, limit)
+	}
+
+	result, err := r.take(ctx, key, limit)
+	if err != nil {
+		if atomic.SwapInt64(&r.failedUntil, r.now().Add(fallbackPeriod).UnixNano()) == 0 {
+			logger.FromContext(ctx).Warn().Err(err).Msg("rate limiter falls back to memory, redis failed")
+		}
+		return r.fallback.Take(ctx, key, limit)
+	}
+	if failedUntil != 0 && atomic.CompareAndSwapInt64(&r.failedUntil, failedUntil, 0) {
+		logger.FromContext(ctx).Info().Msg("rate limiter uses redis again")
+	}
+	return result, nil
+}
+
+func (r *RedisLimiter) take(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := tokenBucket.Run(ctx, r.client, []string{key}, limit.Requests, limit.Window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("rate limit script returned %d values", len(values))
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,941 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/internal/repository/memory"
+)
+
+// repositories are the repositories of one storage
+type repositories struct {
+	users          repository.UserRepository
+	orderItems     repository.OrderItemRepository
+	orderHistories repository.OrderHistoryRepository
+	outbox         repository.OutboxRepository
+	webhooks       repository.WebhookRepository
+	deliveries     repository.WebhookDeliveryRepository
+	transactor     repository.Transactor
+}
+
+// storages opens empty repositories of every implementation, each must pass the same tests
+var storages = map[string]func(t *testing.T) repositories{
+	"memory": func(t *testing.T) repositories {
+		store := memory.NewStore()
+		return repositories{
+			users:          memory.NewUserRepository(store),
+			orderItems:     memory.NewOrderItemRepository(store),
+			orderHistories: memory.NewOrderHistoryRepository(store),
+			outbox:         memory.NewOutboxRepository(store),
+			webhooks:       memory.NewWebhookRepository(store),
+			deliveries:     memory.NewWebhookDeliveryRepository(store),
+			transactor:     memory.NewTransactor(store),
+		}
+	},
+	"gorm/sqlite": func(t *testing.T) repositories {
+		db := openSQLite(t)
+		return repositories{
+			users:          repository.NewUserRepository(db),
+			orderItems:     repository.NewOrderItemRepository(db),
+			orderHistories: repository.NewOrderHistoryRepository(db),
+			outbox:         repository.NewOutboxRepository(db),
+			webhooks:       repository.NewWebhookRepository(db),
+			deliveries:     repository.NewWebhookDeliveryRepository(db),
+			transactor:     repository.NewTransactor(db),
+		}
+	},
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	tests := map[string]func(t *testing.T, repos repositories){
+		"UserSoftDelete":                   testUserSoftDelete,
+		"UserUpdates":                      testUserUpdates,
+		"UserPagination":                   testUserPagination,
+		"OrderItemSoftDelete":              testOrderItemSoftDelete,
+		"OrderItemUpdates":                 testOrderItemUpdates,
+		"OrderItemPagination":              testOrderItemPagination,
+		"OrderHistoryFirstOrder":           testOrderHistoryFirstOrder,
+		"OrderHistoryPreloads":             testOrderHistoryPreloads,
+		"OrderHistoryUpdates":              testOrderHistoryUpdates,
+		"OrderHistoryPagination":           testOrderHistoryPagination,
+		"OrderHistoryExport":               testOrderHistoryExport,
+		"BatchReads":                       testBatchReads,
+		"OutboxRelayCycle":                 testOutboxRelayCycle,
+		"OutboxWrittenInTransaction":       testOutboxWrittenInTransaction,
+		"WebhookSubscriptions":             testWebhookSubscriptions,
+		"WebhookDeliveries":                testWebhookDeliveries,
+		"TransactionRollsBack":             testTransactionRollsBack,
+		"NestedTransactionRollsBackItself": testNestedTransactionRollsBackItself,
+	}
+	for storage, open := range storages {
+		open := open
+		t.Run(storage, func(t *testing.T) {
+			for name, test := range tests {
+				test := test
+				t.Run(name, func(t *testing.T) {
+					test(t, open(t))
+				})
+			}
+		})
+	}
+}
+
+func createUsers(t *testing.T, repos repositories, names ...string) []*entity.User {
+	t.Helper()
+	var users []*entity.User
+	for _, name := range names {
+		user, err := repos.users.Create(context.Background(), &entity.User{FullName: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func createOrderItems(t *testing.T, repos repositories, prices ...int) []*entity.OrderItem {
+	t.Helper()
+	var orderItems []*entity.OrderItem
+	for _, price := range prices {
+		orderItem := &entity.OrderItem{Name: "Item", Price: price, ExpiredAt: date("2030-01-01 00:00")}
+		if err := repos.orderItems.Create(context.Background(), orderItem); err != nil {
+			t.Fatal(err)
+		}
+		orderItems = append(orderItems, orderItem)
+	}
+	return orderItems
+}
+
+func createOrderHistory(t *testing.T, repos repositories, userID, orderItemID int, price *int) *entity.OrderHistory {
+	t.Helper()
+	orderHistory, err := repos.orderHistories.Create(context.Background(), &entity.OrderHistory{
+		UserID:       userID,
+		OrderItemID:  orderItemID,
+		Descriptions: "order",
+		Price:        price,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orderHistory
+}
+
+func isNotFound(err error) bool {
+	return err != nil && err.Error() == "record not found"
+}
+
+func testUserSoftDelete(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob")
+	if users[0].ID < 1 || users[1].ID <= users[0].ID || users[0].CreatedAt.IsZero() {
+		t.Fatalf("created users = %+v, want increasing IDs and timestamps", users)
+	}
+
+	if err := repos.users.SoftDelete(ctx, users[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := repos.users.GetByID(ctx, users[0].ID); !isNotFound(err) || user != nil {
+		t.Errorf("GetByID of a deleted user = %+v, %v, want record not found", user, err)
+	}
+	if err := repos.users.SoftDelete(ctx, users[0].ID); err != nil {
+		t.Errorf("deleting twice = %v, want no error", err)
+	}
+	if err := repos.users.SoftDelete(ctx, 999); err != nil {
+		t.Errorf("deleting a missing user = %v, want no error", err)
+	}
+	if _, err := repos.users.GetByID(ctx, 999); !isNotFound(err) {
+		t.Errorf("GetByID of a missing user = %v, want record not found", err)
+	}
+	if count := repos.users.CountData(ctx); count != 1 {
+		t.Errorf("CountData = %d, want the deleted user left out", count)
+	}
+}
+
+func testUserUpdates(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob")
+
+	// zero fields are left as they are
+	if err := repos.users.Update(ctx, &entity.User{ID: users[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.users.Update(ctx, &entity.User{ID: users[1].ID, FullName: "Bobby"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.users.UpdateFields(ctx, users[0].ID, map[string]interface{}{"full_name": "Annie"}); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[int]string{users[0].ID: "Annie", users[1].ID: "Bobby"} {
+		user, err := repos.users.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.FullName != want {
+			t.Errorf("name of user %d = %q, want %q", id, user.FullName, want)
+		}
+	}
+
+	// a deleted user is not written, nor is a missing one
+	if err := repos.users.SoftDelete(ctx, users[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.users.Update(ctx, &entity.User{ID: users[1].ID, FullName: "Ghost"}); err != nil {
+		t.Errorf("updating a deleted user = %v, want no error", err)
+	}
+	if err := repos.users.UpdateFields(ctx, users[1].ID, map[string]interface{}{"full_name": "Ghost"}); err != nil {
+		t.Errorf("updating fields of a deleted user = %v, want no error", err)
+	}
+	if err := repos.users.UpdateFields(ctx, 999, map[string]interface{}{"full_name": "Ghost"}); err != nil {
+		t.Errorf("updating fields of a missing user = %v, want no error", err)
+	}
+	orderItem := createOrderItems(t, repos, 100)[0]
+	orderHistory := createOrderHistory(t, repos, users[1].ID, orderItem.ID, nil)
+	deleted, err := repos.orderHistories.GetByID(ctx, orderHistory.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.User == nil || deleted.User.FullName != "Bobby" || !deleted.User.DeletedAt.Valid {
+		t.Errorf("deleted user = %+v, want Bobby unchanged", deleted.User)
+	}
+}
+
+func testUserPagination(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "A", "B", "C", "D", "E")
+	if err := repos.users.SoftDelete(ctx, users[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, page := range []struct {
+		limit, offset int
+		want          []string
+	}{
+		{2, 0, []string{"A", "C"}},
+		{2, 2, []string{"D", "E"}},
+		{2, 4, nil},
+		{10, 0, []string{"A", "C", "D", "E"}},
+	} {
+		got, err := repos.users.GetAllPagination(ctx, page.limit, page.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, user := range got {
+			names = append(names, user.FullName)
+		}
+		if !equalStrings(names, page.want) {
+			t.Errorf("limit %d offset %d = %v, want %v", page.limit, page.offset, names, page.want)
+		}
+	}
+	if count := repos.users.CountData(ctx); count != 4 {
+		t.Errorf("CountData = %d, want 4", count)
+	}
+}
+
+func testOrderItemSoftDelete(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	orderItems := createOrderItems(t, repos, 100, 200)
+	if orderItems[0].ID < 1 || orderItems[1].ID <= orderItems[0].ID || orderItems[0].UpdatedAt.IsZero() {
+		t.Fatalf("created order items = %+v, want increasing IDs and timestamps", orderItems)
+	}
+
+	if err := repos.orderItems.SoftDelete(ctx, orderItems[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if orderItem, err := repos.orderItems.GetByID(ctx, orderItems[0].ID); !isNotFound(err) || orderItem != nil {
+		t.Errorf("GetByID of a deleted order item = %+v, %v, want record not found", orderItem, err)
+	}
+	if err := repos.orderItems.SoftDelete(ctx, 999); err != nil {
+		t.Errorf("deleting a missing order item = %v, want no error", err)
+	}
+	if count := repos.orderItems.CountData(ctx); count != 1 {
+		t.Errorf("CountData = %d, want the deleted order item left out", count)
+	}
+}
+
+func testOrderItemUpdates(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	orderItem := createOrderItems(t, repos, 100)[0]
+
+	expiredAt := date("2031-06-01 12:00")
+	if err := repos.orderItems.Update(ctx, &entity.OrderItem{ID: orderItem.ID, Name: "Tea", ExpiredAt: expiredAt}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.orderItems.GetByID(ctx, orderItem.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Tea" || got.Price != 100 || !got.ExpiredAt.Equal(expiredAt) {
+		t.Errorf("updated order item = %+v, want the new name and expiry with the price kept", got)
+	}
+
+	if err := repos.orderItems.UpdateFields(ctx, orderItem.ID, map[string]interface{}{"price": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = repos.orderItems.GetByID(ctx, orderItem.ID); err != nil || got.Price != 0 || got.Name != "Tea" {
+		t.Errorf("order item = %+v, %v, want only the price set to zero", got, err)
+	}
+	if err := repos.orderItems.UpdateFields(ctx, orderItem.ID, map[string]interface{}{"colour": "red"}); err == nil {
+		t.Error("updating an unknown column succeeded")
+	}
+}
+
+func testOrderItemPagination(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	orderItems := createOrderItems(t, repos, 1, 2, 3)
+	if err := repos.orderItems.SoftDelete(ctx, orderItems[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repos.orderItems.GetAllPagination(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Price != 3 {
+		t.Errorf("second page of one = %+v, want the order item of price 3", got)
+	}
+	if count := repos.orderItems.CountData(ctx); count != 2 {
+		t.Errorf("CountData = %d, want 2", count)
+	}
+}
+
+func testOrderHistoryFirstOrder(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob")
+	orderItem := createOrderItems(t, repos, 100)[0]
+
+	createOrderHistory(t, repos, users[0].ID, orderItem.ID, nil)
+	first, err := repos.users.GetByID(ctx, users[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.FirstOrder == nil {
+		t.Fatal("first order not recorded")
+	}
+
+	createOrderHistory(t, repos, users[0].ID, orderItem.ID, nil)
+	again, err := repos.users.GetByID(ctx, users[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.FirstOrder == nil || !again.FirstOrder.Equal(*first.FirstOrder) {
+		t.Errorf("first order = %v, want it kept at %v", again.FirstOrder, first.FirstOrder)
+	}
+	if other, err := repos.users.GetByID(ctx, users[1].ID); err != nil || other.FirstOrder != nil {
+		t.Errorf("user without orders = %+v, %v", other, err)
+	}
+}
+
+func testOrderHistoryPreloads(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	user := createUsers(t, repos, "Ann")[0]
+	orderItem := createOrderItems(t, repos, 100)[0]
+	orderHistory := createOrderHistory(t, repos, user.ID, orderItem.ID, nil)
+
+	// preloads include soft-deleted rows
+	if err := repos.users.SoftDelete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.orderItems.SoftDelete(ctx, orderItem.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repos.orderHistories.GetByID(ctx, orderHistory.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.User == nil || got.User.FullName != "Ann" || got.OrderItem == nil || got.OrderItem.Price != 100 {
+		t.Errorf("GetByID preloads user %+v and order item %+v", got.User, got.OrderItem)
+	}
+
+	all, err := repos.orderHistories.GetAllPagination(ctx, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].User == nil || all[0].OrderItem == nil {
+		t.Errorf("GetAllPagination = %+v, want the user and order item preloaded", all)
+	}
+
+	byUser, err := repos.orderHistories.GetByUserID(ctx, user.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byUser) != 1 || byUser[0].User != nil || byUser[0].OrderItem == nil {
+		t.Errorf("GetByUserID = %+v, want only the order item preloaded", byUser)
+	}
+
+	if _, err := repos.orderHistories.GetByID(ctx, 999); !isNotFound(err) {
+		t.Errorf("GetByID of a missing order history = %v, want record not found", err)
+	}
+}
+
+func testOrderHistoryUpdates(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob")
+	orderItems := createOrderItems(t, repos, 100, 200)
+	orderHistory := createOrderHistory(t, repos, users[0].ID, orderItems[0].ID, nil)
+
+	price := 200
+	if err := repos.orderHistories.Update(ctx, &entity.OrderHistory{
+		ID:          orderHistory.ID,
+		UserID:      users[1].ID,
+		OrderItemID: orderItems[1].ID,
+		Price:       &price,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repos.orderHistories.GetByID(ctx, orderHistory.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != users[1].ID || got.OrderItemID != orderItems[1].ID || got.Price == nil || *got.Price != 200 || got.Descriptions != "order" {
+		t.Errorf("updated order history = %+v, want Bob, the second item and the descriptions kept", got)
+	}
+
+	if err := repos.orderHistories.UpdateFields(ctx, orderHistory.ID, map[string]interface{}{"descriptions": "gift", "price": 150}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = repos.orderHistories.GetByID(ctx, orderHistory.ID); err != nil || got.Descriptions != "gift" || got.Price == nil || *got.Price != 150 {
+		t.Errorf("order history = %+v, %v, want the descriptions and price written", got, err)
+	}
+
+	if err := repos.users.SoftDelete(ctx, users[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	err = repos.orderHistories.Update(ctx, &entity.OrderHistory{ID: orderHistory.ID, UserID: users[0].ID, OrderItemID: orderItems[1].ID})
+	if err == nil || err.Error() != "user data not found" {
+		t.Errorf("update to a deleted user = %v, want user data not found", err)
+	}
+	if err := repos.orderItems.SoftDelete(ctx, orderItems[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	err = repos.orderHistories.Update(ctx, &entity.OrderHistory{ID: orderHistory.ID, UserID: users[1].ID, OrderItemID: orderItems[0].ID})
+	if err == nil || err.Error() != "order item data not found" {
+		t.Errorf("update to a deleted order item = %v, want order item data not found", err)
+	}
+
+	// Order Histories have no deleted_at, they are removed
+	if err := repos.orderHistories.SoftDelete(ctx, orderHistory.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.orderHistories.GetByID(ctx, orderHistory.ID); !isNotFound(err) {
+		t.Errorf("GetByID of a deleted order history = %v, want record not found", err)
+	}
+}
+
+func testOrderHistoryPagination(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob")
+	orderItem := createOrderItems(t, repos, 100)[0]
+	var ids []int
+	for _, user := range []*entity.User{users[0], users[1], users[0], users[0]} {
+		ids = append(ids, createOrderHistory(t, repos, user.ID, orderItem.ID, nil).ID)
+	}
+
+	if count := repos.orderHistories.CountData(ctx, 0); count != 4 {
+		t.Errorf("CountData of every user = %d, want 4", count)
+	}
+	if count := repos.orderHistories.CountData(ctx, users[0].ID); count != 3 {
+		t.Errorf("CountData of Ann = %d, want 3", count)
+	}
+
+	page, err := repos.orderHistories.GetByUserID(ctx, users[0].ID, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[3] {
+		t.Errorf("second page of Ann = %+v, want orders %d and %d", page, ids[2], ids[3])
+	}
+	if none, err := repos.orderHistories.GetByUserID(ctx, 0, 10, 0); err != nil || len(none) != 0 {
+		t.Errorf("GetByUserID of user 0 = %+v, %v, want nothing", none, err)
+	}
+
+	all, err := repos.orderHistories.GetAllPagination(ctx, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != ids[3] {
+		t.Errorf("last page = %+v, want order %d", all, ids[3])
+	}
+}
+
+func testBatchReads(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob", "Cid")
+	orderItems := createOrderItems(t, repos, 100, 200)
+	var ids []int
+	for _, user := range []*entity.User{users[0], users[1], users[0], users[0], users[1]} {
+		ids = append(ids, createOrderHistory(t, repos, user.ID, orderItems[0].ID, nil).ID)
+	}
+
+	// batches include soft-deleted rows like the preloads
+	if err := repos.users.SoftDelete(ctx, users[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.orderItems.SoftDelete(ctx, orderItems[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	gotUsers, err := repos.users.GetByIDs(ctx, []int{users[1].ID, 999, users[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotUsers) != 2 || gotUsers[0].FullName != "Ann" || gotUsers[1].FullName != "Bob" {
+		t.Errorf("GetByIDs of users = %+v, want Ann and the deleted Bob", gotUsers)
+	}
+	gotOrderItems, err := repos.orderItems.GetByIDs(ctx, []int{orderItems[1].ID, orderItems[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotOrderItems) != 2 || gotOrderItems[0].Price != 100 || gotOrderItems[1].Price != 200 {
+		t.Errorf("GetByIDs of order items = %+v, want both, the deleted one included", gotOrderItems)
+	}
+
+	byUsers, err := repos.orderHistories.GetByUserIDs(ctx, []int{users[1].ID, users[0].ID, users[2].ID}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, orderHistory := range byUsers {
+		got = append(got, orderHistory.ID)
+		if orderHistory.User != nil || orderHistory.OrderItem != nil {
+			t.Errorf("GetByUserIDs preloaded %+v, want no relation", orderHistory)
+		}
+	}
+	if want := []int{ids[0], ids[2], ids[1], ids[4]}; !equalInts(got, want) {
+		t.Errorf("GetByUserIDs limited to 2 = %v, want %v", got, want)
+	}
+}
+
+func testOrderHistoryExport(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	users := createUsers(t, repos, "Ann", "Bob")
+	orderItem := createOrderItems(t, repos, 100)[0]
+	price := 80
+	createOrderHistory(t, repos, users[0].ID, orderItem.ID, &price)
+	createOrderHistory(t, repos, users[1].ID, orderItem.ID, nil)
+	createOrderHistory(t, repos, users[0].ID, orderItem.ID, nil)
+
+	var rows []entity.OrderHistoryExport
+	collect := func(row *entity.OrderHistoryExport) error {
+		rows = append(rows, *row)
+		return nil
+	}
+	if err := repos.orderHistories.Export(ctx, entity.OrderHistoryFilter{UserID: users[0].ID}, collect); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("exported rows of Ann = %+v, want 2", rows)
+	}
+	if rows[0].UserName != "Ann" || rows[0].OrderItemName != "Item" || rows[0].OrderItemPrice != 80 || rows[0].Descriptions != "order" {
+		t.Errorf("first row = %+v, want Ann, Item and the price of the order", rows[0])
+	}
+	if rows[1].OrderItemPrice != 100 || rows[1].ID <= rows[0].ID {
+		t.Errorf("second row = %+v, want the price of the item and a later ID", rows[1])
+	}
+
+	rows = nil
+	if err := repos.orderHistories.Export(ctx, entity.OrderHistoryFilter{}, collect); err != nil || len(rows) != 3 {
+		t.Errorf("export of every user = %d rows, %v, want 3", len(rows), err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := repos.orderHistories.Export(ctx, entity.OrderHistoryFilter{}, func(row *entity.OrderHistoryExport) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("export = %v after %d rows, want the error of fn after the first row", err, calls)
+	}
+}
+
+func outboxEvent(eventID string) *entity.OutboxEvent {
+	return &entity.OutboxEvent{
+		EventID:     eventID,
+		Type:        entity.EventUserCreated,
+		AggregateID: 1,
+		Payload:     `{"id":1}`,
+		OccurredAt:  date("2023-01-01 10:00"),
+	}
+}
+
+func eventIDsAfter(t *testing.T, repos repositories, id, limit int) []string {
+	t.Helper()
+	events, err := repos.outbox.After(context.Background(), id, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, event := range events {
+		ids = append(ids, event.EventID)
+	}
+	return ids
+}
+
+// sequence gives a sequence to every visible event of the outbox of repos
+func sequence(t *testing.T, repos repositories) int {
+	t.Helper()
+	sequenced, err := repos.outbox.Sequence(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sequenced
+}
+
+func testOutboxRelayCycle(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	// b took its ID after a but committed first
+	a, b := outboxEvent("a"), outboxEvent("b")
+	a.ID, b.ID = 5, 10
+	if err := repos.outbox.Add(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if sequenced := sequence(t, repos); sequenced != 1 {
+		t.Errorf("Sequence = %d, want b", sequenced)
+	}
+	if err := repos.outbox.Add(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDsAfter(t, repos, 0, 10); !equalStrings(got, []string{"b"}) {
+		t.Errorf("After(0) = %v, want the events without a sequence left out", got)
+	}
+	c, d := outboxEvent("c"), outboxEvent("d")
+	if err := repos.outbox.Add(ctx, c, d); err != nil {
+		t.Fatal(err)
+	}
+	if sequenced, err := repos.outbox.Sequence(ctx, 2); err != nil || sequenced != 2 {
+		t.Errorf("Sequence(2) = %d, %v, want 2", sequenced, err)
+	}
+	if sequenced := sequence(t, repos); sequenced != 1 {
+		t.Errorf("Sequence = %d, want the last event", sequenced)
+	}
+	if got := eventIDsAfter(t, repos, 0, 10); !equalStrings(got, []string{"b", "a", "c", "d"}) {
+		t.Errorf("After(0) = %v, want the events in the order they were sequenced", got)
+	}
+	if got := eventIDsAfter(t, repos, 1, 2); !equalStrings(got, []string{"a", "c"}) {
+		t.Errorf("After(1, 2) = %v, want a and c", got)
+	}
+
+	cursor, err := repos.outbox.Cursor(ctx, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.Sink != "log" || cursor.LastSequence != 0 {
+		t.Errorf("Cursor of a new sink = %+v, want it before every event", cursor)
+	}
+	next := date("2023-01-01 10:01")
+	cursor.Attempts = 2
+	cursor.LastError = strings.Repeat("x", 300)
+	cursor.NextAttemptAt = &next
+	deadLetter := &entity.OutboxDeadLetter{
+		Sink: "log", EventID: "a", Type: entity.EventUserCreated, AggregateID: 1, Payload: `{"id":1}`,
+		OccurredAt: date("2023-01-01 10:00"), Attempts: 2, LastError: strings.Repeat("x", 300),
+	}
+	if err := repos.outbox.SaveCursor(ctx, cursor, deadLetter); err != nil {
+		t.Fatal(err)
+	}
+	if deadLetter.ID < 1 || len(deadLetter.LastError) != 255 {
+		t.Errorf("dead letter = %+v, want it written with the error cut to 255 bytes", deadLetter)
+	}
+	cursor.LastSequence = 2
+	if err := repos.outbox.SaveCursor(ctx, cursor); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := repos.outbox.Cursor(ctx, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.LastSequence != 2 || saved.Attempts != 2 || len(saved.LastError) != 255 || saved.NextAttemptAt == nil || !saved.NextAttemptAt.Equal(next) {
+		t.Errorf("Cursor = %+v, want the saved position and failure", saved)
+	}
+
+	publishedAt := date("2023-01-02 10:00")
+	if marked, err := repos.outbox.MarkPublished(ctx, 2, publishedAt); err != nil || marked != 2 {
+		t.Errorf("MarkPublished(2) = %d, %v, want b and a", marked, err)
+	}
+	if marked, err := repos.outbox.MarkPublished(ctx, 2, date("2023-01-02 11:00")); err != nil || marked != 0 {
+		t.Errorf("MarkPublished(2) again = %d, %v, want the published events left alone", marked, err)
+	}
+	if cursor, err := repos.outbox.Cursor(ctx, "redis"); err != nil || cursor.LastSequence != 2 {
+		t.Errorf("Cursor of a sink added later = %+v, %v, want it after the published events", cursor, err)
+	}
+
+	if deleted, err := repos.outbox.DeletePublished(ctx, publishedAt); err != nil || deleted != 0 {
+		t.Errorf("DeletePublished(publication time) = %d, %v, want nothing deleted", deleted, err)
+	}
+	if deleted, err := repos.outbox.DeletePublished(ctx, date("2023-01-03 10:00")); err != nil || deleted != 2 {
+		t.Errorf("DeletePublished = %d, %v, want the 2 published events", deleted, err)
+	}
+	if got := eventIDsAfter(t, repos, 0, 10); !equalStrings(got, []string{"c", "d"}) {
+		t.Errorf("After(0) after DeletePublished = %v, want c and d", got)
+	}
+
+	// the sequences go on once every event is deleted, the cursors are past them
+	cursor.LastSequence = 4
+	if err := repos.outbox.SaveCursor(ctx, cursor); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.outbox.MarkPublished(ctx, 4, publishedAt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.outbox.DeletePublished(ctx, date("2023-01-03 10:00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.outbox.Add(ctx, outboxEvent("e")); err != nil {
+		t.Fatal(err)
+	}
+	sequence(t, repos)
+	if got := eventIDsAfter(t, repos, 4, 10); !equalStrings(got, []string{"e"}) {
+		t.Errorf("After(4) = %v, want e sequenced after the deleted events", got)
+	}
+}
+
+func testOutboxWrittenInTransaction(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	_ = repos.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := repos.outbox.Add(ctx, outboxEvent("undone")); err != nil {
+			t.Fatal(err)
+		}
+		return errors.New("failure")
+	})
+	err := repos.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return repos.outbox.Add(ctx, outboxEvent("kept"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence(t, repos)
+	if got := eventIDsAfter(t, repos, 0, 10); !equalStrings(got, []string{"kept"}) {
+		t.Errorf("After(0) = %v, want the event of the committed transaction only", got)
+	}
+}
+
+func testWebhookSubscriptions(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	all := &entity.WebhookSubscription{URL: "http://a.test/", Events: entity.EventTypes{entity.AllEvents}, Secret: "secret", Active: true}
+	orders := &entity.WebhookSubscription{URL: "http://b.test/", Events: entity.EventTypes{entity.EventOrderCreated, entity.EventUserDeleted}, Secret: "secret", Active: true}
+	for _, subscription := range []*entity.WebhookSubscription{all, orders} {
+		if err := repos.webhooks.Create(ctx, subscription); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subscribed := func(eventType string) []int {
+		t.Helper()
+		subscriptions, err := repos.webhooks.Subscribed(ctx, eventType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, subscription := range subscriptions {
+			ids = append(ids, subscription.ID)
+		}
+		return ids
+	}
+	if got := subscribed(entity.EventUserDeleted); len(got) != 2 {
+		t.Errorf("Subscribed(UserDeleted) = %v, want both subscriptions", got)
+	}
+	if got := subscribed(entity.EventUserCreated); len(got) != 1 || got[0] != all.ID {
+		t.Errorf("Subscribed(UserCreated) = %v, want the subscription to every event", got)
+	}
+
+	err := repos.webhooks.UpdateFields(ctx, orders.ID, map[string]interface{}{
+		"events": entity.EventTypes{entity.EventUserCreated},
+		"active": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := repos.webhooks.GetByID(ctx, orders.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(updated.Events, []string{entity.EventUserCreated}) || updated.Active || updated.Secret != "secret" {
+		t.Errorf("updated subscription = %+v", updated)
+	}
+	if got := subscribed(entity.EventUserCreated); len(got) != 1 || got[0] != all.ID {
+		t.Errorf("Subscribed(UserCreated) = %v, want the inactive subscription skipped", got)
+	}
+
+	if err := repos.webhooks.SoftDelete(ctx, all.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.webhooks.GetByID(ctx, all.ID); !isNotFound(err) {
+		t.Errorf("GetByID of a deleted subscription = %v, want record not found", err)
+	}
+	if got := subscribed(entity.EventUserCreated); len(got) != 0 {
+		t.Errorf("Subscribed(UserCreated) = %v, want the deleted subscription skipped", got)
+	}
+	if count := repos.webhooks.CountData(ctx); count != 1 {
+		t.Errorf("CountData = %d, want 1", count)
+	}
+}
+
+func testWebhookDeliveries(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	now := date("2023-01-01 10:00")
+	later := now.Add(time.Hour)
+	delivery := func(subscriptionID int, eventID string, next time.Time) *entity.WebhookDelivery {
+		return &entity.WebhookDelivery{
+			SubscriptionID: subscriptionID,
+			EventID:        eventID,
+			EventType:      entity.EventOrderCreated,
+			Payload:        `{}`,
+			Status:         entity.DeliveryPending,
+			NextAttemptAt:  &next,
+		}
+	}
+	first, second, other := delivery(1, "a", now), delivery(1, "b", later), delivery(2, "a", now)
+	if err := repos.deliveries.Add(ctx, first, second, other); err != nil {
+		t.Fatal(err)
+	}
+	// the same event relayed again is skipped
+	if err := repos.deliveries.Add(ctx, delivery(1, "a", now)); err != nil {
+		t.Fatal(err)
+	}
+	if count := repos.deliveries.CountBySubscription(ctx, 1); count != 2 {
+		t.Errorf("CountBySubscription = %d, want the duplicate skipped", count)
+	}
+
+	due, err := repos.deliveries.Due(ctx, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 2 || due[0].ID != first.ID || due[1].ID != other.ID {
+		t.Fatalf("Due = %+v, want the two deliveries due now", due)
+	}
+
+	first.Status = entity.DeliveryDead
+	first.NextAttemptAt = nil
+	first.ResponseCode = 500
+	refused := &entity.WebhookAttempt{Attempt: 1, Error: "connection refused", AttemptedAt: now}
+	answered := &entity.WebhookAttempt{Attempt: 2, ResponseCode: 500, Error: "webhook answered 500", AttemptedAt: later}
+	if err := repos.deliveries.Save(ctx, first, refused, answered); err != nil {
+		t.Fatal(err)
+	}
+	// a save without attempts keeps the history
+	if err := repos.deliveries.Save(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err := repos.deliveries.Attempts(ctx, first.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0].ID != answered.ID || attempts[0].DeliveryID != first.ID ||
+		attempts[0].ResponseCode != 500 || attempts[1].Error != "connection refused" || !attempts[1].AttemptedAt.Equal(now) {
+		t.Errorf("Attempts = %+v, want both attempts, the newest first", attempts)
+	}
+	if count := repos.deliveries.CountAttempts(ctx, first.ID); count != 2 {
+		t.Errorf("CountAttempts = %d, want 2", count)
+	}
+	if count := repos.deliveries.CountAttempts(ctx, other.ID); count != 0 {
+		t.Errorf("CountAttempts of another delivery = %d, want 0", count)
+	}
+	if due, _ := repos.deliveries.Due(ctx, later, 10); len(due) != 2 || due[0].ID != other.ID || due[1].ID != second.ID {
+		t.Errorf("Due later = %+v, want the dead delivery skipped", due)
+	}
+
+	log, err := repos.deliveries.GetBySubscription(ctx, 1, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 || log[0].ID != second.ID || log[1].ResponseCode != 500 || log[1].Status != entity.DeliveryDead {
+		t.Errorf("GetBySubscription = %+v, want the newest first and the saved outcome", log)
+	}
+	if _, err := repos.deliveries.GetByID(ctx, 2, first.ID); !isNotFound(err) {
+		t.Errorf("GetByID of a delivery of another subscription = %v, want record not found", err)
+	}
+}
+
+func testTransactionRollsBack(t *testing.T, repos repositories) {
+	ctx := context.Background()
+	failure := errors.New("failure")
+
+	err := repos.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repos.users.Create(ctx, &entity.User{FullName: "Inside"}); err != nil {
+			return err
+		}
+		if count := repos.users.CountData(ctx); count != 1 {
+			t.Errorf("CountData inside the transaction = %d, want 1", count)
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithinTransaction = %v, want the error of fn", err)
+	}
+	if count := repos.users.CountData(ctx); count != 0 {
+		t.Errorf("CountData after rollback = %d, want 0", count)
+	}
+
+	err = repos.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := repos.users.Create(ctx, &entity.User{FullName: "Committed"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := repos.users.CountData(ctx); count != 1 {
+		t.Errorf("CountData after commit = %d, want 1", count)
+	}
+}
+
+func testNestedTransactionRollsBackItself(t *testing.T, repos repositories) {
+	ctx := context.Background()
+
+	err := repos.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repos.users.Create(ctx, &entity.User{FullName: "Kept"}); err != nil {
+			return err
+		}
+		nested := repos.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if _, err := repos.users.Create(ctx, &entity.User{FullName: "Undone"}); err != nil {
+				return err
+			}
+			return errors.New("nested failure")
+		})
+		if nested == nil {
+			t.Error("nested WithinTransaction = nil, want its error")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := repos.users.GetAllPagination(ctx, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].FullName != "Kept" {
+		t.Errorf("users = %+v, want only the one of the outer transaction", users)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dialect writes the SQL functions that differ between the drivers of the Database,
+// every function returns the same text on each driver
+type dialect interface {
+	// Day formats the date of column as YYYY-MM-DD
+	Day(column string) string
+	// Week formats the Monday of the week of column as YYYY-MM-DD
+	Week(column string) string
+	// Month formats the month of column as YYYY-MM
+	Month(column string) string
+	// MonthsBetween counts the calendar months from the month of from to the month of to
+	MonthsBetween(from, to string) string
+}
+
+// dialectOf picks the dialect of the driver db was opened with, MariaDB (mysql) by default
+func dialectOf(db *gorm.DB) dialect {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return postgresDialect{}
+	case "sqlite":
+		return sqliteDialect{}
+	default:
+		return mysqlDialect{}
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Day(column string) string {
+	return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", column)
+}
+
+func (mysqlDialect) Week(column string) string {
+	return fmt.Sprintf("DATE_FORMAT(DATE_SUB(%[1]s, INTERVAL WEEKDAY(%[1]s) DAY), '%%Y-%%m-%%d')", column)
+}
+
+func (mysqlDialect) Month(column string) string {
+	return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m')", column)
+}
+
+func (mysqlDialect) MonthsBetween(from, to string) string {
+	return fmt.Sprintf("PERIOD_DIFF(DATE_FORMAT(%s, '%%Y%%m'), DATE_FORMAT(%s, '%%Y%%m'))", to, from)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Day(column string) string {
+	return fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM-DD')", column)
+}
+
+// Week truncates to the ISO week, which starts on Monday
+func (postgresDialect) Week(column string) string {
+	return fmt.Sprintf("TO_CHAR(DATE_TRUNC('week', %s), 'YYYY-MM-DD')", column)
+}
+
+func (postgresDialect) Month(column string) string {
+	return fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM')", column)
+}
+
+func (postgresDialect) MonthsBetween(from, to string) string {
+	return fmt.Sprintf("CAST((EXTRACT(YEAR FROM %[2]s) - EXTRACT(YEAR FROM %[1]s)) * 12 + "+
+		"EXTRACT(MONTH FROM %[2]s) - EXTRACT(MONTH FROM %[1]s) AS INTEGER)", from, to)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Day(column string) string {
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", column)
+}
+
+// Week moves to the next Sunday (the same day on a Sunday) then back to the Monday before it
+func (sqliteDialect) Week(column string) string {
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s, 'weekday 0', '-6 days')", column)
+}
+
+func (sqliteDialect) Month(column string) string {
+	return fmt.Sprintf("strftime('%%Y-%%m', %s)", column)
+}
+
+func (sqliteDialect) MonthsBetween(from, to string) string {
+	return fmt.Sprintf("((CAST(strftime('%%Y', %[2]s) AS INTEGER) - CAST(strftime('%%Y', %[1]s) AS INTEGER)) * 12 + "+
+		"CAST(strftime('%%m', %[2]s) AS INTEGER) - CAST(strftime('%%m', %[1]s) AS INTEGER))", from, to)
+}
+
+// aggregateTime scans the MIN or MAX of a date column, which SQLite returns as text
+type aggregateTime struct {
+	Time *time.Time
+}
+
+// aggregateTimeLayouts are the text forms of a date written by the SQLite driver
+var aggregateTimeLayouts = []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05"}
+
+func (t *aggregateTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = nil
+		return nil
+	case time.Time:
+		t.Time = &v
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("cannot scan %T into a date", value)
+}
+
+// Value lets gorm map the type, aggregateTime is only ever read
+func (t aggregateTime) Value() (driver.Value, error) {
+	if t.Time == nil {
+		return nil, nil
+	}
+	return *t.Time, nil
+}
+
+func (t *aggregateTime) parse(text string) error {
+	for _, layout := range aggregateTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = &parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a date", text)
+}
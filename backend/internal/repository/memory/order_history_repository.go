@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type orderHistoryRepository struct {
+	store *Store
+}
+
+func NewOrderHistoryRepository(store *Store) repository.OrderHistoryRepository {
+	return &orderHistoryRepository{store}
+}
+
+func (r *orderHistoryRepository) Create(ctx context.Context, orderHistory *entity.OrderHistory) (*entity.OrderHistory, error) {
+	err := r.store.run(ctx, func(t *tables) error {
+		_, exists := t.orderHistories[orderHistory.ID]
+		if err := nextID(&orderHistory.ID, &t.lastOrderHistoryID, exists); err != nil {
+			return err
+		}
+		timestamps(&orderHistory.CreatedAt, &orderHistory.UpdatedAt)
+
+		row := *orderHistory
+		row.User, row.OrderItem = nil, nil
+		t.orderHistories[row.ID] = row
+
+		// The first order of a User is only recorded once
+		if user, ok := t.users[row.UserID]; ok && !user.DeletedAt.Valid && user.FirstOrder == nil {
+			now := time.Now()
+			user.FirstOrder = &now
+			user.UpdatedAt = now
+			t.users[user.ID] = user
+		}
+		return nil
+	})
+	return orderHistory, err
+}
+
+func (r *orderHistoryRepository) Update(ctx context.Context, orderHistory *entity.OrderHistory) error {
+	return r.store.run(ctx, func(t *tables) error {
+		// Check if the related User and OrderItem are not soft-deleted
+		if user, ok := t.users[orderHistory.UserID]; !ok || user.DeletedAt.Valid {
+			return fmt.Errorf("user data not found")
+		}
+		if orderItem, ok := t.orderItems[orderHistory.OrderItemID]; !ok || orderItem.DeletedAt.Valid {
+			return fmt.Errorf("order item data not found")
+		}
+
+		row, ok := t.orderHistories[orderHistory.ID]
+		if !ok {
+			return nil
+		}
+		orderHistory.UpdatedAt = time.Now()
+		if err := updateNonZero(&row, orderHistory); err != nil {
+			return err
+		}
+		t.orderHistories[row.ID] = row
+		return nil
+	})
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *orderHistoryRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.orderHistories[id]
+		if !ok {
+			return nil
+		}
+		if err := updateColumns(&row, fields); err != nil {
+			return err
+		}
+		row.UpdatedAt = time.Now()
+		t.orderHistories[id] = row
+		return nil
+	})
+}
+
+// SoftDelete removes the row, Order Histories have no deleted_at column
+func (r *orderHistoryRepository) SoftDelete(ctx context.Context, id int) error {
+	return r.store.run(ctx, func(t *tables) error {
+		delete(t.orderHistories, id)
+		return nil
+	})
+}
+
+func (r *orderHistoryRepository) GetByID(ctx context.Context, id int) (*entity.OrderHistory, error) {
+	var orderHistory entity.OrderHistory
+	err := r.store.run(ctx, func(t *tables) error {
+		row, ok := t.orderHistories[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		orderHistory = t.preload(row, true)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &orderHistory, nil
+}
+
+func (r *orderHistoryRepository) GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.OrderHistory, error) {
+	return r.list(ctx, func(orderHistory entity.OrderHistory) bool {
+		return orderHistory.UserID == userID
+	}, false, limit, offset)
+}
+
+// GetByUserIDs copies the first limit Order Histories of every User of userIDs, ordered by User then by ID
+func (r *orderHistoryRepository) GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error) {
+	var orderHistories []*entity.OrderHistory
+	err := r.store.run(ctx, func(t *tables) error {
+		byUser := map[int][]int{}
+		ids := sortedIDs(t.orderHistories, inIDs(userIDs, func(orderHistory entity.OrderHistory) int { return orderHistory.UserID }))
+		for _, id := range ids {
+			userID := t.orderHistories[id].UserID
+			byUser[userID] = append(byUser[userID], id)
+		}
+		for _, userID := range sortedIDs(byUser, func([]int) bool { return true }) {
+			for _, id := range paginate(byUser[userID], limit, 0) {
+				orderHistory := t.orderHistories[id]
+				orderHistories = append(orderHistories, &orderHistory)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderHistories, nil
+}
+
+func (r *orderHistoryRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderHistory, error) {
+	return r.list(ctx, ofUser(0), true, limit, offset)
+}
+
+// list pages through the Order Histories accepted by keep
+func (r *orderHistoryRepository) list(ctx context.Context, keep func(orderHistory entity.OrderHistory) bool, withUser bool, limit, offset int) ([]*entity.OrderHistory, error) {
+	var orderHistories []*entity.OrderHistory
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range paginate(sortedIDs(t.orderHistories, keep), limit, offset) {
+			orderHistory := t.preload(t.orderHistories[id], withUser)
+			orderHistories = append(orderHistories, &orderHistory)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderHistories, nil
+}
+
+func (r *orderHistoryRepository) CountData(ctx context.Context, userID int) int64 {
+	var count int64
+	_ = r.store.run(ctx, func(t *tables) error {
+		count = int64(len(sortedIDs(t.orderHistories, ofUser(userID))))
+		return nil
+	})
+	return count
+}
+
+// Export copies the filtered Order Histories joined with their User and Order Item,
+// fn is called once the Store is unlocked so it may use the repositories itself
+func (r *orderHistoryRepository) Export(ctx context.Context, filter entity.OrderHistoryFilter, fn func(row *entity.OrderHistoryExport) error) error {
+	var rows []entity.OrderHistoryExport
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range sortedIDs(t.orderHistories, ofUser(filter.UserID)) {
+			orderHistory := t.orderHistories[id]
+			row := entity.OrderHistoryExport{
+				ID:           orderHistory.ID,
+				CreatedAt:    orderHistory.CreatedAt,
+				UserID:       orderHistory.UserID,
+				UserName:     t.users[orderHistory.UserID].FullName,
+				OrderItemID:  orderHistory.OrderItemID,
+				Descriptions: orderHistory.Descriptions,
+			}
+			if orderItem, ok := t.orderItems[orderHistory.OrderItemID]; ok {
+				row.OrderItemName = orderItem.Name
+				row.OrderItemPrice = orderItem.Price
+			}
+			if orderHistory.Price != nil {
+				row.OrderItemPrice = *orderHistory.Price
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range rows {
+		if err := fn(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preload attaches the Order Item and, withUser, the User of row, soft-deleted ones included
+func (t *tables) preload(row entity.OrderHistory, withUser bool) entity.OrderHistory {
+	if orderItem, ok := t.orderItems[row.OrderItemID]; ok {
+		row.OrderItem = &orderItem
+	}
+	if user, ok := t.users[row.UserID]; ok && withUser {
+		row.User = &user
+	}
+	return row
+}
+
+// ofUser keeps the Order Histories of userID, every one when userID is below 1
+func ofUser(userID int) func(orderHistory entity.OrderHistory) bool {
+	return func(orderHistory entity.OrderHistory) bool {
+		return userID < 1 || orderHistory.UserID == userID
+	}
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type orderItemRepository struct {
+	store *Store
+}
+
+func NewOrderItemRepository(store *Store) repository.OrderItemRepository {
+	return &orderItemRepository{store}
+}
+
+func (r *orderItemRepository) Create(ctx context.Context, orderItem *entity.OrderItem) error {
+	return r.store.run(ctx, func(t *tables) error {
+		_, exists := t.orderItems[orderItem.ID]
+		if err := nextID(&orderItem.ID, &t.lastOrderItemID, exists); err != nil {
+			return err
+		}
+		timestamps(&orderItem.CreatedAt, &orderItem.UpdatedAt)
+
+		row := *orderItem
+		row.OrderHistories = nil
+		t.orderItems[row.ID] = row
+		return nil
+	})
+}
+
+func (r *orderItemRepository) Update(ctx context.Context, orderItem *entity.OrderItem) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.orderItems[orderItem.ID]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		orderItem.UpdatedAt = time.Now()
+		if err := updateNonZero(&row, orderItem); err != nil {
+			return err
+		}
+		t.orderItems[row.ID] = row
+		return nil
+	})
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *orderItemRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.orderItems[id]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		if err := updateColumns(&row, fields); err != nil {
+			return err
+		}
+		row.UpdatedAt = time.Now()
+		t.orderItems[id] = row
+		return nil
+	})
+}
+
+func (r *orderItemRepository) SoftDelete(ctx context.Context, id int) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.orderItems[id]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		row.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		t.orderItems[id] = row
+		return nil
+	})
+}
+
+func (r *orderItemRepository) GetByID(ctx context.Context, id int) (*entity.OrderItem, error) {
+	var orderItem entity.OrderItem
+	err := r.store.run(ctx, func(t *tables) error {
+		row, ok := t.orderItems[id]
+		if !ok || row.DeletedAt.Valid {
+			return gorm.ErrRecordNotFound
+		}
+		orderItem = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &orderItem, nil
+}
+
+// GetByIDs copies the Order Items of ids, soft-deleted ones included like the Order Items preloaded on an Order History
+func (r *orderItemRepository) GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error) {
+	var orderItems []*entity.OrderItem
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range sortedIDs(t.orderItems, inIDs(ids, func(orderItem entity.OrderItem) int { return orderItem.ID })) {
+			orderItem := t.orderItems[id]
+			orderItems = append(orderItems, &orderItem)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderItems, nil
+}
+
+func (r *orderItemRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderItem, error) {
+	var orderItems []*entity.OrderItem
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range paginate(sortedIDs(t.orderItems, notDeletedOrderItem), limit, offset) {
+			orderItem := t.orderItems[id]
+			orderItems = append(orderItems, &orderItem)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderItems, nil
+}
+
+func (r *orderItemRepository) CountData(ctx context.Context) int64 {
+	var count int64
+	_ = r.store.run(ctx, func(t *tables) error {
+		count = int64(len(sortedIDs(t.orderItems, notDeletedOrderItem)))
+		return nil
+	})
+	return count
+}
+
+func notDeletedOrderItem(orderItem entity.OrderItem) bool {
+	return !orderItem.DeletedAt.Valid
+}
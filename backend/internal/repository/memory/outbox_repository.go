@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type outboxRepository struct {
+	store *Store
+}
+
+func NewOutboxRepository(store *Store) repository.OutboxRepository {
+	return &outboxRepository{store}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, events ...*entity.OutboxEvent) error {
+	return r.store.run(ctx, func(t *tables) error {
+		for _, event := range events {
+			_, exists := t.outboxEvents[event.ID]
+			if err := nextID(&event.ID, &t.lastOutboxEventID, exists); err != nil {
+				return err
+			}
+			t.outboxEvents[event.ID] = *event
+		}
+		return nil
+	})
+}
+
+func (r *outboxRepository) Sequence(ctx context.Context, limit int) (int, error) {
+	var sequenced int
+	err := r.store.run(ctx, func(t *tables) error {
+		last := 0
+		for _, event := range t.outboxEvents {
+			if event.Sequence != nil && *event.Sequence > last {
+				last = *event.Sequence
+			}
+		}
+		for _, cursor := range t.outboxCursors {
+			if cursor.LastSequence > last {
+				last = cursor.LastSequence
+			}
+		}
+		unsequenced := sortedIDs(t.outboxEvents, func(event entity.OutboxEvent) bool { return event.Sequence == nil })
+		for _, id := range paginate(unsequenced, limit, 0) {
+			last++
+			sequence := last
+			event := t.outboxEvents[id]
+			event.Sequence = &sequence
+			t.outboxEvents[id] = event
+			sequenced++
+		}
+		return nil
+	})
+	return sequenced, err
+}
+
+func (r *outboxRepository) After(ctx context.Context, sequence, limit int) ([]*entity.OutboxEvent, error) {
+	var events []*entity.OutboxEvent
+	err := r.store.run(ctx, func(t *tables) error {
+		after := sortedIDs(t.outboxEvents, func(event entity.OutboxEvent) bool {
+			return event.Sequence != nil && *event.Sequence > sequence
+		})
+		sort.Slice(after, func(i, j int) bool {
+			return *t.outboxEvents[after[i]].Sequence < *t.outboxEvents[after[j]].Sequence
+		})
+		for _, id := range paginate(after, limit, 0) {
+			event := t.outboxEvents[id]
+			events = append(events, &event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+func (r *outboxRepository) Cursor(ctx context.Context, sink string) (*entity.OutboxCursor, error) {
+	var cursor entity.OutboxCursor
+	err := r.store.run(ctx, func(t *tables) error {
+		if row, ok := t.outboxCursors[sink]; ok {
+			cursor = row
+			return nil
+		}
+		cursor = entity.OutboxCursor{Sink: sink}
+		for _, event := range t.outboxEvents {
+			if event.PublishedAt != nil && *event.Sequence > cursor.LastSequence {
+				cursor.LastSequence = *event.Sequence
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (r *outboxRepository) SaveCursor(ctx context.Context, cursor *entity.OutboxCursor, deadLetters ...*entity.OutboxDeadLetter) error {
+	return r.store.run(ctx, func(t *tables) error {
+		for _, deadLetter := range deadLetters {
+			_, exists := t.outboxDeadLetters[deadLetter.ID]
+			if err := nextID(&deadLetter.ID, &t.lastDeadLetterID, exists); err != nil {
+				return err
+			}
+		}
+		cursor.LastError = truncate(cursor.LastError)
+		cursor.UpdatedAt = time.Now()
+		t.outboxCursors[cursor.Sink] = *cursor
+		for _, deadLetter := range deadLetters {
+			deadLetter.LastError = truncate(deadLetter.LastError)
+			if deadLetter.CreatedAt.IsZero() {
+				deadLetter.CreatedAt = cursor.UpdatedAt
+			}
+			t.outboxDeadLetters[deadLetter.ID] = *deadLetter
+		}
+		return nil
+	})
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, sequence int, at time.Time) (int64, error) {
+	var marked int64
+	err := r.store.run(ctx, func(t *tables) error {
+		for eventID, event := range t.outboxEvents {
+			if event.Sequence != nil && *event.Sequence <= sequence && event.PublishedAt == nil {
+				event.PublishedAt = &at
+				t.outboxEvents[eventID] = event
+				marked++
+			}
+		}
+		return nil
+	})
+	return marked, err
+}
+
+func (r *outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.store.run(ctx, func(t *tables) error {
+		for id, event := range t.outboxEvents {
+			if event.PublishedAt != nil && event.PublishedAt.Before(before) {
+				delete(t.outboxEvents, id)
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// truncate cuts reason to the size of the last_error columns
+func truncate(reason string) string {
+	if len(reason) > 255 {
+		return reason[:255]
+	}
+	return reason
+}
@@ -0,0 +1,257 @@
+// Package memory implements the repositories of the service without a database.
+// Soft delete, pagination and partial updates behave like the gorm repositories,
+// so use cases and handlers can be tested against it.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type txKey struct{}
+
+// Store holds the tables shared by the repositories and the Transactor built on it
+type Store struct {
+	// tx is held by the running transaction, a call outside of it waits like on a locked row
+	tx sync.Mutex
+	mu sync.Mutex
+	tables
+	fail error
+}
+
+type tables struct {
+	users              map[int]entity.User
+	orderItems         map[int]entity.OrderItem
+	orderHistories     map[int]entity.OrderHistory
+	outboxEvents       map[int]entity.OutboxEvent
+	outboxCursors      map[string]entity.OutboxCursor
+	outboxDeadLetters  map[int]entity.OutboxDeadLetter
+	webhooks           map[int]entity.WebhookSubscription
+	webhookDeliveries  map[int]entity.WebhookDelivery
+	webhookAttempts    map[int]entity.WebhookAttempt
+	lastUserID         int
+	lastOrderItemID    int
+	lastOrderHistoryID int
+	lastOutboxEventID  int
+	lastDeadLetterID   int
+	lastWebhookID      int
+	lastDeliveryID     int
+	lastAttemptID      int
+}
+
+func NewStore() *Store {
+	return &Store{tables: tables{
+		users:             map[int]entity.User{},
+		orderItems:        map[int]entity.OrderItem{},
+		orderHistories:    map[int]entity.OrderHistory{},
+		outboxEvents:      map[int]entity.OutboxEvent{},
+		outboxCursors:     map[string]entity.OutboxCursor{},
+		outboxDeadLetters: map[int]entity.OutboxDeadLetter{},
+		webhooks:          map[int]entity.WebhookSubscription{},
+		webhookDeliveries: map[int]entity.WebhookDelivery{},
+		webhookAttempts:   map[int]entity.WebhookAttempt{},
+	}}
+}
+
+// FailWith makes every following call of the repositories fail with err, nil recovers the Store
+func (s *Store) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = err
+}
+
+// run calls fn with the tables locked
+func (s *Store) run(ctx context.Context, fn func(t *tables) error) error {
+	if ctx.Value(txKey{}) != s {
+		s.tx.Lock()
+		defer s.tx.Unlock()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	return fn(&s.tables)
+}
+
+func (t tables) clone() tables {
+	t.users = cloneRows(t.users)
+	t.orderItems = cloneRows(t.orderItems)
+	t.orderHistories = cloneRows(t.orderHistories)
+	t.outboxEvents = cloneRows(t.outboxEvents)
+	t.outboxCursors = cloneRows(t.outboxCursors)
+	t.outboxDeadLetters = cloneRows(t.outboxDeadLetters)
+	t.webhooks = cloneRows(t.webhooks)
+	t.webhookDeliveries = cloneRows(t.webhookDeliveries)
+	t.webhookAttempts = cloneRows(t.webhookAttempts)
+	return t
+}
+
+func cloneRows[K comparable, T any](rows map[K]T) map[K]T {
+	clone := make(map[K]T, len(rows))
+	for id, row := range rows {
+		clone[id] = row
+	}
+	return clone
+}
+
+// sortedIDs lists the primary keys of rows accepted by keep in insertion order
+func sortedIDs[T any](rows map[int]T, keep func(row T) bool) []int {
+	ids := make([]int, 0, len(rows))
+	for id, row := range rows {
+		if keep(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// inIDs keeps the rows whose key, read by keyOf, is one of ids
+func inIDs[T any](ids []int, keyOf func(row T) int) func(row T) bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(row T) bool {
+		return set[keyOf(row)]
+	}
+}
+
+// paginate applies LIMIT and OFFSET the way gorm writes them, a negative limit means no limit
+func paginate(ids []int, limit, offset int) []int {
+	if offset > 0 {
+		if offset >= len(ids) {
+			return nil
+		}
+		ids = ids[offset:]
+	}
+	if limit >= 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// nextID assigns the next primary key, or keeps and validates the one set by the caller
+func nextID(id *int, last *int, exists bool) error {
+	if *id == 0 {
+		*last++
+		*id = *last
+		return nil
+	}
+	if exists {
+		return fmt.Errorf("duplicate primary key %d", *id)
+	}
+	if *id > *last {
+		*last = *id
+	}
+	return nil
+}
+
+type transactor struct {
+	store *Store
+}
+
+// NewTransactor runs a function inside one transaction of store, a failing function
+// rolls back every change made with its context, a nested call only its own changes
+func NewTransactor(store *Store) repository.Transactor {
+	return &transactor{store}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	s := t.store
+	if ctx.Value(txKey{}) != s {
+		s.tx.Lock()
+		defer s.tx.Unlock()
+		ctx = context.WithValue(ctx, txKey{}, s)
+	}
+
+	s.mu.Lock()
+	if s.fail != nil {
+		s.mu.Unlock()
+		return s.fail
+	}
+	snapshot := s.tables.clone()
+	s.mu.Unlock()
+
+	rollback := func() {
+		s.mu.Lock()
+		s.tables = snapshot
+		s.mu.Unlock()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+var schemas sync.Map
+
+// updateColumns writes fields into row by column name, like gorm Updates with a map
+func updateColumns(row interface{}, fields map[string]interface{}) error {
+	rowSchema, err := schema.Parse(row, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(row)
+	for column, fieldValue := range fields {
+		field := rowSchema.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("no such column: %s", column)
+		}
+		if err := field.Set(context.Background(), value, fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateNonZero copies the columns of src that are not zero into row, like gorm Updates with a struct
+func updateNonZero(row, src interface{}) error {
+	rowSchema, err := schema.Parse(row, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	rowValue, srcValue := reflect.ValueOf(row), reflect.ValueOf(src)
+	for _, field := range rowSchema.Fields {
+		if field.DBName == "" || field.PrimaryKey {
+			continue
+		}
+		fieldValue, zero := field.ValueOf(context.Background(), srcValue)
+		if zero {
+			continue
+		}
+		if err := field.Set(context.Background(), rowValue, fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timestamps fills the autoCreateTime and autoUpdateTime columns of a new row
+func timestamps(createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt.IsZero() {
+		*updatedAt = now
+	}
+}
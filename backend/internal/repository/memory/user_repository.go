@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{store}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
+	err := r.store.run(ctx, func(t *tables) error {
+		_, exists := t.users[user.ID]
+		if err := nextID(&user.ID, &t.lastUserID, exists); err != nil {
+			return err
+		}
+		timestamps(&user.CreatedAt, &user.UpdatedAt)
+
+		row := *user
+		row.OrderHistories = nil
+		t.users[row.ID] = row
+		return nil
+	})
+	return user, err
+}
+
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.users[user.ID]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		user.UpdatedAt = time.Now()
+		if err := updateNonZero(&row, user); err != nil {
+			return err
+		}
+		t.users[row.ID] = row
+		return nil
+	})
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *userRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.users[id]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		if err := updateColumns(&row, fields); err != nil {
+			return err
+		}
+		row.UpdatedAt = time.Now()
+		t.users[id] = row
+		return nil
+	})
+}
+
+func (r *userRepository) SoftDelete(ctx context.Context, id int) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.users[id]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		row.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		t.users[id] = row
+		return nil
+	})
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*entity.User, error) {
+	var user entity.User
+	err := r.store.run(ctx, func(t *tables) error {
+		row, ok := t.users[id]
+		if !ok || row.DeletedAt.Valid {
+			return gorm.ErrRecordNotFound
+		}
+		user = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByIDs copies the Users of ids, soft-deleted ones included like the Users preloaded on an Order History
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error) {
+	var users []*entity.User
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range sortedIDs(t.users, inIDs(ids, func(user entity.User) int { return user.ID })) {
+			user := t.users[id]
+			users = append(users, &user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.User, error) {
+	var users []*entity.User
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range paginate(sortedIDs(t.users, notDeletedUser), limit, offset) {
+			user := t.users[id]
+			users = append(users, &user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) CountData(ctx context.Context) int64 {
+	var count int64
+	_ = r.store.run(ctx, func(t *tables) error {
+		count = int64(len(sortedIDs(t.users, notDeletedUser)))
+		return nil
+	})
+	return count
+}
+
+func notDeletedUser(user entity.User) bool {
+	return !user.DeletedAt.Valid
+}
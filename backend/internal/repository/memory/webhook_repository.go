@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type webhookRepository struct {
+	store *Store
+}
+
+func NewWebhookRepository(store *Store) repository.WebhookRepository {
+	return &webhookRepository{store}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	return r.store.run(ctx, func(t *tables) error {
+		_, exists := t.webhooks[subscription.ID]
+		if err := nextID(&subscription.ID, &t.lastWebhookID, exists); err != nil {
+			return err
+		}
+		timestamps(&subscription.CreatedAt, &subscription.UpdatedAt)
+		t.webhooks[subscription.ID] = *subscription
+		return nil
+	})
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *webhookRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.webhooks[id]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		if err := updateColumns(&row, fields); err != nil {
+			return err
+		}
+		row.UpdatedAt = time.Now()
+		t.webhooks[id] = row
+		return nil
+	})
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id int) (*entity.WebhookSubscription, error) {
+	var subscription entity.WebhookSubscription
+	err := r.store.run(ctx, func(t *tables) error {
+		row, ok := t.webhooks[id]
+		if !ok || row.DeletedAt.Valid {
+			return gorm.ErrRecordNotFound
+		}
+		subscription = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *webhookRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.WebhookSubscription, error) {
+	var subscriptions []*entity.WebhookSubscription
+	err := r.store.run(ctx, func(t *tables) error {
+		for _, id := range paginate(sortedIDs(t.webhooks, notDeletedWebhook), limit, offset) {
+			subscription := t.webhooks[id]
+			subscriptions = append(subscriptions, &subscription)
+		}
+		return nil
+	})
+	return subscriptions, err
+}
+
+func (r *webhookRepository) SoftDelete(ctx context.Context, id int) error {
+	return r.store.run(ctx, func(t *tables) error {
+		row, ok := t.webhooks[id]
+		if !ok || row.DeletedAt.Valid {
+			return nil
+		}
+		row.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		t.webhooks[id] = row
+		return nil
+	})
+}
+
+func (r *webhookRepository) CountData(ctx context.Context) int64 {
+	var count int64
+	_ = r.store.run(ctx, func(t *tables) error {
+		count = int64(len(sortedIDs(t.webhooks, notDeletedWebhook)))
+		return nil
+	})
+	return count
+}
+
+func (r *webhookRepository) Subscribed(ctx context.Context, eventType string) ([]*entity.WebhookSubscription, error) {
+	var subscriptions []*entity.WebhookSubscription
+	err := r.store.run(ctx, func(t *tables) error {
+		subscribed := func(subscription entity.WebhookSubscription) bool {
+			return notDeletedWebhook(subscription) && subscription.Active && subscription.Events.Has(eventType)
+		}
+		for _, id := range sortedIDs(t.webhooks, subscribed) {
+			subscription := t.webhooks[id]
+			subscriptions = append(subscriptions, &subscription)
+		}
+		return nil
+	})
+	return subscriptions, err
+}
+
+func notDeletedWebhook(subscription entity.WebhookSubscription) bool {
+	return !subscription.DeletedAt.Valid
+}
+
+type webhookDeliveryRepository struct {
+	store *Store
+}
+
+func NewWebhookDeliveryRepository(store *Store) repository.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{store}
+}
+
+func (r *webhookDeliveryRepository) Add(ctx context.Context, deliveries ...*entity.WebhookDelivery) error {
+	return r.store.run(ctx, func(t *tables) error {
+		for _, delivery := range deliveries {
+			duplicate := false
+			for _, row := range t.webhookDeliveries {
+				duplicate = duplicate || (row.SubscriptionID == delivery.SubscriptionID && row.EventID == delivery.EventID)
+			}
+			if duplicate {
+				continue
+			}
+			_, exists := t.webhookDeliveries[delivery.ID]
+			if err := nextID(&delivery.ID, &t.lastDeliveryID, exists); err != nil {
+				return err
+			}
+			timestamps(&delivery.CreatedAt, &delivery.UpdatedAt)
+			t.webhookDeliveries[delivery.ID] = *delivery
+		}
+		return nil
+	})
+}
+
+func (r *webhookDeliveryRepository) Due(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	err := r.store.run(ctx, func(t *tables) error {
+		ids := sortedIDs(t.webhookDeliveries, func(delivery entity.WebhookDelivery) bool {
+			return (delivery.Status == entity.DeliveryPending || delivery.Status == entity.DeliveryFailed) &&
+				delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now)
+		})
+		sort.SliceStable(ids, func(i, j int) bool {
+			return t.webhookDeliveries[ids[i]].NextAttemptAt.Before(*t.webhookDeliveries[ids[j]].NextAttemptAt)
+		})
+		for _, id := range paginate(ids, limit, 0) {
+			delivery := t.webhookDeliveries[id]
+			deliveries = append(deliveries, &delivery)
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) Save(ctx context.Context, delivery *entity.WebhookDelivery, attempts ...*entity.WebhookAttempt) error {
+	return r.store.run(ctx, func(t *tables) error {
+		for _, attempt := range attempts {
+			_, exists := t.webhookAttempts[attempt.ID]
+			if err := nextID(&attempt.ID, &t.lastAttemptID, exists); err != nil {
+				return err
+			}
+		}
+		delivery.UpdatedAt = time.Now()
+		t.webhookDeliveries[delivery.ID] = *delivery
+		for _, attempt := range attempts {
+			attempt.DeliveryID = delivery.ID
+			t.webhookAttempts[attempt.ID] = *attempt
+		}
+		return nil
+	})
+}
+
+func (r *webhookDeliveryRepository) GetByID(ctx context.Context, subscriptionID, id int) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := r.store.run(ctx, func(t *tables) error {
+		row, ok := t.webhookDeliveries[id]
+		if !ok || row.SubscriptionID != subscriptionID {
+			return gorm.ErrRecordNotFound
+		}
+		delivery = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) GetBySubscription(ctx context.Context, subscriptionID, limit, offset int) ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	err := r.store.run(ctx, func(t *tables) error {
+		ids := sortedIDs(t.webhookDeliveries, func(delivery entity.WebhookDelivery) bool {
+			return delivery.SubscriptionID == subscriptionID
+		})
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+		for _, id := range paginate(ids, limit, offset) {
+			delivery := t.webhookDeliveries[id]
+			deliveries = append(deliveries, &delivery)
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) CountBySubscription(ctx context.Context, subscriptionID int) int64 {
+	var count int64
+	_ = r.store.run(ctx, func(t *tables) error {
+		for _, delivery := range t.webhookDeliveries {
+			if delivery.SubscriptionID == subscriptionID {
+				count++
+			}
+		}
+		return nil
+	})
+	return count
+}
+
+func (r *webhookDeliveryRepository) Attempts(ctx context.Context, deliveryID, limit, offset int) ([]*entity.WebhookAttempt, error) {
+	var attempts []*entity.WebhookAttempt
+	err := r.store.run(ctx, func(t *tables) error {
+		ids := sortedIDs(t.webhookAttempts, func(attempt entity.WebhookAttempt) bool {
+			return attempt.DeliveryID == deliveryID
+		})
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+		for _, id := range paginate(ids, limit, offset) {
+			attempt := t.webhookAttempts[id]
+			attempts = append(attempts, &attempt)
+		}
+		return nil
+	})
+	return attempts, err
+}
+
+func (r *webhookDeliveryRepository) CountAttempts(ctx context.Context, deliveryID int) int64 {
+	var count int64
+	_ = r.store.run(ctx, func(t *tables) error {
+		for _, attempt := range t.webhookAttempts {
+			if attempt.DeliveryID == deliveryID {
+				count++
+			}
+		}
+		return nil
+	})
+	return count
+}
@@ -13,11 +13,14 @@ import (
 type OrderHistoryRepository interface {
 	Create(ctx context.Context, orderHistory *entity.OrderHistory) (*entity.OrderHistory, error)
 	Update(ctx context.Context, orderHistory *entity.OrderHistory) error
+	UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error
 	GetByID(ctx context.Context, id int) (*entity.OrderHistory, error)
 	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderHistory, error)
 	GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.OrderHistory, error)
+	GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error)
 	SoftDelete(ctx context.Context, id int) error
 	CountData(ctx context.Context, userId int) int64
+	Export(ctx context.Context, filter entity.OrderHistoryFilter, fn func(row *entity.OrderHistoryExport) error) error
 }
 
 type orderHistoryRepository struct {
@@ -29,16 +32,16 @@ func NewOrderHistoryRepository(db *gorm.DB) OrderHistoryRepository {
 }
 
 func (r *orderHistoryRepository) Create(ctx context.Context, orderHistory *entity.OrderHistory) (*entity.OrderHistory, error) {
-	execDB := r.db.WithContext(ctx).Create(&orderHistory)
+	execDB := conn(ctx, r.db).Create(&orderHistory)
 	if execDB.Error != nil {
 		return orderHistory, execDB.Error
 	}
 
 	var count int64
-	r.db.Model(&entity.User{}).Where("id = ? AND first_order IS NULL", orderHistory.UserID).Count(&count)
+	conn(ctx, r.db).Model(&entity.User{}).Where("id = ? AND first_order IS NULL", orderHistory.UserID).Count(&count)
 	if count == 1 {
 		now := time.Now()
-		r.db.Model(&entity.User{}).Where("id = ? AND first_order IS NULL", orderHistory.UserID).Update("first_order", &now)
+		conn(ctx, r.db).Model(&entity.User{}).Where("id = ? AND first_order IS NULL", orderHistory.UserID).Update("first_order", &now)
 	}
 
 	return orderHistory, nil
@@ -47,27 +50,32 @@ func (r *orderHistoryRepository) Create(ctx context.Context, orderHistory *entit
 func (r *orderHistoryRepository) Update(ctx context.Context, orderHistory *entity.OrderHistory) error {
 	// Check if the related User is not soft-deleted
 	var user entity.User
-	if err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", orderHistory.UserID).First(&user).Error; err != nil {
+	if err := conn(ctx, r.db).Where("id = ? AND deleted_at IS NULL", orderHistory.UserID).First(&user).Error; err != nil {
 		return fmt.Errorf("user data not found")
 	}
 
 	// Check if the related OrderItem is not soft-deleted
 	var orderItem entity.OrderItem
-	if err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", orderHistory.OrderItemID).First(&orderItem).Error; err != nil {
+	if err := conn(ctx, r.db).Where("id = ? AND deleted_at IS NULL", orderHistory.OrderItemID).First(&orderItem).Error; err != nil {
 		return fmt.Errorf("order item data not found")
 	}
 
 	// Update the OrderHistory if the related data is not soft-deleted
-	if err := r.db.WithContext(ctx).Model(orderHistory).Updates(&orderHistory).Error; err != nil {
+	if err := conn(ctx, r.db).Model(orderHistory).Updates(&orderHistory).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// UpdateFields only writes the given columns, zero values included
+func (r *orderHistoryRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return conn(ctx, r.db).Model(&entity.OrderHistory{ID: id}).Updates(fields).Error
+}
+
 func (r *orderHistoryRepository) SoftDelete(ctx context.Context, id int) error {
 	orderHistory := &entity.OrderHistory{ID: id}
-	err := r.db.Delete(orderHistory).Error
+	err := conn(ctx, r.db).Delete(orderHistory).Error
 	if err != nil {
 		return fmt.Errorf("error soft-deleting order history with ID %d: %s", id, err.Error())
 	}
@@ -77,14 +85,14 @@ func (r *orderHistoryRepository) SoftDelete(ctx context.Context, id int) error {
 
 func (r *orderHistoryRepository) GetByID(ctx context.Context, id int) (*entity.OrderHistory, error) {
 	orderHistory := &entity.OrderHistory{}
-	err := r.db.
+	err := conn(ctx, r.db).
 		Preload("OrderItem", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).
 		Preload("User", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).
-		WithContext(ctx).First(orderHistory, id).Error
+		First(orderHistory, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -94,11 +102,31 @@ func (r *orderHistoryRepository) GetByID(ctx context.Context, id int) (*entity.O
 
 func (r *orderHistoryRepository) GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.OrderHistory, error) {
 	var orderHistories []*entity.OrderHistory
-	err := r.db.
+	err := conn(ctx, r.db).
 		Preload("OrderItem", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).
-		WithContext(ctx).Where("user_id = ?", userID).Limit(limit).Offset(offset).Find(&orderHistories).Error
+		Where("user_id = ?", userID).Limit(limit).Offset(offset).Find(&orderHistories).Error
+	if err != nil {
+		return nil, err
+	}
+	return orderHistories, nil
+}
+
+// GetByUserIDs reads the first limit Order Histories of every User of userIDs in one query, ordered
+// by User then by ID. Nothing is preloaded, the caller batches the relations it needs itself
+func (r *orderHistoryRepository) GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error) {
+	ranked := conn(ctx, r.db).
+		Model(&entity.OrderHistory{}).
+		Select("order_histories.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY id) AS rank_of_user").
+		Where("user_id IN ?", userIDs)
+
+	var orderHistories []*entity.OrderHistory
+	err := conn(ctx, r.db).
+		Table("(?) AS ranked", ranked).
+		Where("rank_of_user <= ?", limit).
+		Order("user_id, id").
+		Find(&orderHistories).Error
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +136,7 @@ func (r *orderHistoryRepository) GetByUserID(ctx context.Context, userID, limit,
 func (r *orderHistoryRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderHistory, error) {
 	var orderHistory []*entity.OrderHistory
 
-	err := r.db.
+	err := conn(ctx, r.db).
 		Preload("OrderItem", func(db *gorm.DB) *gorm.DB {
 			return db.Unscoped()
 		}).
@@ -128,9 +156,41 @@ func (r *orderHistoryRepository) CountData(ctx context.Context, userID int) int6
 	var orderHistory []*entity.OrderHistory
 
 	if userID < 1 {
-		r.db.Find(&orderHistory).Count(&count)
+		conn(ctx, r.db).Find(&orderHistory).Count(&count)
 	} else {
-		r.db.Where("user_id = ?", userID).Find(&orderHistory).Count(&count)
+		conn(ctx, r.db).Where("user_id = ?", userID).Find(&orderHistory).Count(&count)
 	}
 	return count
 }
+
+// Export walks the filtered Order Histories joined with their User and Order Item
+// row by row from the database cursor, so the table is never loaded into memory
+func (r *orderHistoryRepository) Export(ctx context.Context, filter entity.OrderHistoryFilter, fn func(row *entity.OrderHistoryExport) error) error {
+	query := conn(ctx, r.db).
+		Table("order_histories AS oh").
+		Select("oh.id, oh.created_at, oh.user_id, COALESCE(u.full_name, '') AS user_name, " +
+			"oh.order_item_id, COALESCE(oi.name, '') AS order_item_name, COALESCE(oh.price, oi.price, 0) AS order_item_price, oh.descriptions").
+		Joins("LEFT JOIN users AS u ON u.id = oh.user_id").
+		Joins("LEFT JOIN order_items AS oi ON oi.id = oh.order_item_id").
+		Order("oh.id")
+	if filter.UserID > 0 {
+		query = query.Where("oh.user_id = ?", filter.UserID)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row entity.OrderHistoryExport
+		if err := query.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
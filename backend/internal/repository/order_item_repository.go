@@ -12,7 +12,9 @@ import (
 type OrderItemRepository interface {
 	Create(ctx context.Context, orderItem *entity.OrderItem) error
 	Update(ctx context.Context, orderItem *entity.OrderItem) error
+	UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error
 	GetByID(ctx context.Context, id int) (*entity.OrderItem, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error)
 	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderItem, error)
 	SoftDelete(ctx context.Context, id int) error
 	CountData(ctx context.Context) int64
@@ -27,7 +29,7 @@ func NewOrderItemRepository(db *gorm.DB) OrderItemRepository {
 }
 
 func (r *orderItemRepository) Create(ctx context.Context, orderItem *entity.OrderItem) error {
-	execDB := r.db.WithContext(ctx).Create(&orderItem)
+	execDB := conn(ctx, r.db).Create(&orderItem)
 	if execDB.Error != nil {
 		return execDB.Error
 	}
@@ -35,12 +37,17 @@ func (r *orderItemRepository) Create(ctx context.Context, orderItem *entity.Orde
 }
 
 func (r *orderItemRepository) Update(ctx context.Context, orderItem *entity.OrderItem) error {
-	return r.db.WithContext(ctx).Model(orderItem).Updates(&orderItem).Error
+	return conn(ctx, r.db).Model(orderItem).Updates(&orderItem).Error
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *orderItemRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return conn(ctx, r.db).Model(&entity.OrderItem{ID: id}).Updates(fields).Error
 }
 
 func (r *orderItemRepository) SoftDelete(ctx context.Context, id int) error {
 	orderItem := &entity.OrderItem{ID: id}
-	err := r.db.Delete(orderItem).Error
+	err := conn(ctx, r.db).Delete(orderItem).Error
 	if err != nil {
 		return fmt.Errorf("error soft-deleting order item with ID %d: %s", id, err.Error())
 	}
@@ -49,17 +56,28 @@ func (r *orderItemRepository) SoftDelete(ctx context.Context, id int) error {
 
 func (r *orderItemRepository) GetByID(ctx context.Context, id int) (*entity.OrderItem, error) {
 	orderItem := &entity.OrderItem{}
-	err := r.db.WithContext(ctx).First(orderItem, id).Error
+	err := conn(ctx, r.db).First(orderItem, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return orderItem, nil
 }
 
+// GetByIDs reads the Order Items of ids in one query, soft-deleted ones included like the Order Items
+// preloaded on an Order History, an unknown id is left out
+func (r *orderItemRepository) GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error) {
+	var orderItems []*entity.OrderItem
+	err := conn(ctx, r.db).Unscoped().Where("id IN ?", ids).Order("id").Find(&orderItems).Error
+	if err != nil {
+		return nil, err
+	}
+	return orderItems, nil
+}
+
 func (r *orderItemRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderItem, error) {
 	var orderItems []*entity.OrderItem
 
-	err := r.db.Limit(limit).Offset(offset).Find(&orderItems).Error
+	err := conn(ctx, r.db).Limit(limit).Offset(offset).Find(&orderItems).Error
 	if err != nil {
 		return nil, fmt.Errorf("error getting users: %s", err.Error())
 	}
@@ -71,6 +89,6 @@ func (r *orderItemRepository) CountData(ctx context.Context) int64 {
 	var count int64
 	var orderItems []*entity.OrderItem
 
-	r.db.Find(&orderItems).Count(&count)
+	conn(ctx, r.db).Find(&orderItems).Count(&count)
 	return count
 }
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+// maxLastError is the size of the last_error column of the outbox cursors and dead letters
+const maxLastError = 255
+
+// OutboxRepository stores the domain events until the relay published them to every sink
+type OutboxRepository interface {
+	// Add writes events, in the transaction of ctx when there is one
+	Add(ctx context.Context, events ...*entity.OutboxEvent) error
+	// Sequence gives the next sequences to up to limit visible events without one, smallest ID
+	// first, and returns their number. The sequences keep growing after the events are deleted.
+	Sequence(ctx context.Context, limit int) (int, error)
+	// After returns up to limit events sequenced after sequence, in the order of their sequence
+	After(ctx context.Context, sequence, limit int) ([]*entity.OutboxEvent, error)
+	// Cursor returns the position of sink, a sink seen for the first time starts after the
+	// last event published to every sink
+	Cursor(ctx context.Context, sink string) (*entity.OutboxCursor, error)
+	// SaveCursor writes cursor and adds deadLetters, in one transaction
+	SaveCursor(ctx context.Context, cursor *entity.OutboxCursor, deadLetters ...*entity.OutboxDeadLetter) error
+	// MarkPublished sets the publication time of the events sequenced up to sequence not published yet
+	// and returns their number
+	MarkPublished(ctx context.Context, sequence int, at time.Time) (int64, error)
+	// DeletePublished removes the events published before before and returns their number
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, events ...*entity.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Create(events).Error
+}
+
+// Sequence continues after the greatest sequence of the events and the cursors, a cursor keeps it
+// once every event was published and deleted. The unique index makes an instance sequencing
+// at the same time as another one fail instead of giving a sequence twice.
+func (r *outboxRepository) Sequence(ctx context.Context, limit int) (int, error) {
+	var ids []int
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var lastEvent, lastCursor int
+		if err := tx.Model(&entity.OutboxEvent{}).Select("COALESCE(MAX(sequence), 0)").Scan(&lastEvent).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.OutboxCursor{}).Select("COALESCE(MAX(last_sequence), 0)").Scan(&lastCursor).Error; err != nil {
+			return err
+		}
+		last := lastEvent
+		if lastCursor > last {
+			last = lastCursor
+		}
+		if err := tx.Model(&entity.OutboxEvent{}).Where("sequence IS NULL").Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			last++
+			if err := tx.Model(&entity.OutboxEvent{ID: id}).Update("sequence", last).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func (r *outboxRepository) After(ctx context.Context, sequence, limit int) ([]*entity.OutboxEvent, error) {
+	var events []*entity.OutboxEvent
+	err := conn(ctx, r.db).Where("sequence > ?", sequence).Order("sequence").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// Cursor starts a new sink after the last published event, the events are published in the order of their sequence
+func (r *outboxRepository) Cursor(ctx context.Context, sink string) (*entity.OutboxCursor, error) {
+	cursor := &entity.OutboxCursor{}
+	err := conn(ctx, r.db).Where("sink = ?", sink).First(cursor).Error
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return cursor, err
+	}
+	cursor = &entity.OutboxCursor{Sink: sink}
+	err = conn(ctx, r.db).Model(&entity.OutboxEvent{}).
+		Where("published_at IS NOT NULL").
+		Select("COALESCE(MAX(sequence), 0)").
+		Scan(&cursor.LastSequence).Error
+	return cursor, err
+}
+
+func (r *outboxRepository) SaveCursor(ctx context.Context, cursor *entity.OutboxCursor, deadLetters ...*entity.OutboxDeadLetter) error {
+	if len(cursor.LastError) > maxLastError {
+		cursor.LastError = cursor.LastError[:maxLastError]
+	}
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "sink"}}, UpdateAll: true}).Create(cursor).Error
+		if err != nil || len(deadLetters) == 0 {
+			return err
+		}
+		for _, deadLetter := range deadLetters {
+			if len(deadLetter.LastError) > maxLastError {
+				deadLetter.LastError = deadLetter.LastError[:maxLastError]
+			}
+		}
+		return tx.Create(deadLetters).Error
+	})
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, sequence int, at time.Time) (int64, error) {
+	execDB := conn(ctx, r.db).Model(&entity.OutboxEvent{}).
+		Where("sequence <= ? AND published_at IS NULL", sequence).
+		Update("published_at", at)
+	return execDB.RowsAffected, execDB.Error
+}
+
+func (r *outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	execDB := conn(ctx, r.db).Where("published_at < ?", before).Delete(&entity.OutboxEvent{})
+	return execDB.RowsAffected, execDB.Error
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+// orderAmount is the price paid for an order, the snapshot when it exists
+const orderAmount = "COALESCE(oh.price, oi.price, 0)"
+
+type ReportRepository interface {
+	Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error)
+	TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error)
+	TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error)
+	CohortSizes(ctx context.Context, filter entity.ReportFilter) ([]*entity.CohortActivity, error)
+	CohortActivity(ctx context.Context, filter entity.ReportFilter) ([]*entity.CohortActivity, error)
+	UserStats(ctx context.Context, userID int) (*entity.UserStats, error)
+}
+
+type reportRepository struct {
+	db      *gorm.DB
+	dialect dialect
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &reportRepository{db: db, dialect: dialectOf(db)}
+}
+
+// orders selects the Order Histories of the period joined with their Order Item,
+// soft-deleted items are kept since they were sold
+func (r *reportRepository) orders(ctx context.Context, filter entity.ReportFilter) *gorm.DB {
+	return conn(ctx, r.db).
+		Table("order_histories AS oh").
+		Joins("LEFT JOIN order_items AS oi ON oi.id = oh.order_item_id").
+		Where("oh.created_at >= ? AND oh.created_at < ?", filter.From, filter.To)
+}
+
+func (r *reportRepository) Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error) {
+	period, err := r.period(filter.Granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []*entity.RevenuePoint
+	err = r.orders(ctx, filter).
+		Select(fmt.Sprintf("%s AS period, COUNT(*) AS orders, SUM(%s) AS revenue", period, orderAmount)).
+		Group("period").
+		Order("period").
+		Scan(&points).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting revenue: %s", err.Error())
+	}
+	return points, nil
+}
+
+func (r *reportRepository) TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error) {
+	var items []*entity.TopItem
+	err := r.orders(ctx, filter).
+		Select(fmt.Sprintf("oh.order_item_id, COALESCE(MAX(oi.name), '') AS name, COUNT(*) AS orders, SUM(%s) AS revenue", orderAmount)).
+		Group("oh.order_item_id").
+		Order("revenue DESC, orders DESC, oh.order_item_id").
+		Limit(filter.Limit).
+		Scan(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting top items: %s", err.Error())
+	}
+	return items, nil
+}
+
+func (r *reportRepository) TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error) {
+	var users []*entity.TopUser
+	err := r.orders(ctx, filter).
+		Joins("LEFT JOIN users AS u ON u.id = oh.user_id").
+		Select(fmt.Sprintf("oh.user_id, COALESCE(MAX(u.full_name), '') AS name, COUNT(*) AS orders, SUM(%s) AS revenue", orderAmount)).
+		Group("oh.user_id").
+		Order("revenue DESC, orders DESC, oh.user_id").
+		Limit(filter.Limit).
+		Scan(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting top users: %s", err.Error())
+	}
+	return users, nil
+}
+
+// CohortSizes counts the Users whose first order falls in the period per month (MonthOffset is 0)
+func (r *reportRepository) CohortSizes(ctx context.Context, filter entity.ReportFilter) ([]*entity.CohortActivity, error) {
+	var sizes []*entity.CohortActivity
+	err := conn(ctx, r.db).
+		Table("users AS u").
+		Select(r.dialect.Month("u.first_order")+" AS cohort, 0 AS month_offset, COUNT(*) AS users").
+		Where("u.first_order >= ? AND u.first_order < ?", filter.From, filter.To).
+		Group("cohort").
+		Order("cohort").
+		Scan(&sizes).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting cohort sizes: %s", err.Error())
+	}
+	return sizes, nil
+}
+
+// CohortActivity counts per cohort and month offset the distinct Users who ordered in that month
+func (r *reportRepository) CohortActivity(ctx context.Context, filter entity.ReportFilter) ([]*entity.CohortActivity, error) {
+	var activity []*entity.CohortActivity
+	err := conn(ctx, r.db).
+		Table("users AS u").
+		Joins("JOIN order_histories AS oh ON oh.user_id = u.id").
+		Select(fmt.Sprintf("%s AS cohort, %s AS month_offset, COUNT(DISTINCT oh.user_id) AS users",
+			r.dialect.Month("u.first_order"), r.dialect.MonthsBetween("u.first_order", "oh.created_at"))).
+		Where("u.first_order >= ? AND u.first_order < ?", filter.From, filter.To).
+		Group("cohort, month_offset").
+		Order("cohort, month_offset").
+		Scan(&activity).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting cohort activity: %s", err.Error())
+	}
+	return activity, nil
+}
+
+// UserStats sums up every order of a User, AvgDaysBetweenOrders is left to the caller
+func (r *reportRepository) UserStats(ctx context.Context, userID int) (*entity.UserStats, error) {
+	var row struct {
+		Orders        int64
+		LifetimeValue int64
+		FirstOrder    aggregateTime
+		LastOrder     aggregateTime
+	}
+	err := conn(ctx, r.db).
+		Table("order_histories AS oh").
+		Joins("LEFT JOIN order_items AS oi ON oi.id = oh.order_item_id").
+		Select(fmt.Sprintf("COUNT(*) AS orders, COALESCE(SUM(%s), 0) AS lifetime_value, "+
+			"MIN(oh.created_at) AS first_order, MAX(oh.created_at) AS last_order", orderAmount)).
+		Where("oh.user_id = ?", userID).
+		Scan(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting stats of user %d: %s", userID, err.Error())
+	}
+	return &entity.UserStats{
+		UserID:        userID,
+		Orders:        row.Orders,
+		LifetimeValue: row.LifetimeValue,
+		FirstOrder:    row.FirstOrder.Time,
+		LastOrder:     row.LastOrder.Time,
+	}, nil
+}
+
+// period truncates oh.created_at to the start of its day, week (Monday) or month
+func (r *reportRepository) period(granularity string) (string, error) {
+	switch granularity {
+	case entity.GranularityDay:
+		return r.dialect.Day("oh.created_at"), nil
+	case entity.GranularityWeek:
+		return r.dialect.Week("oh.created_at"), nil
+	case entity.GranularityMonth:
+		return r.dialect.Month("oh.created_at"), nil
+	}
+	return "", fmt.Errorf("unknown granularity %q", granularity)
+}
@@ -0,0 +1,128 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/config"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+// openSQLite migrates a new SQLite Database in the temporary directory of t
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Name = filepath.Join(t.TempDir(), "test.db")
+
+	db, err := config.OpenDatabase(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func date(day string) time.Time {
+	parsed, err := time.Parse("2006-01-02 15:04", day)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestReportRepositoryOnSQLite(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+
+	firstOrder := date("2023-01-31 22:00")
+	users := []*entity.User{{FullName: "Ann", FirstOrder: &firstOrder}, {FullName: "Bob"}}
+	item := &entity.OrderItem{Name: "Tea", Price: 1500, ExpiredAt: date("2030-01-01 00:00")}
+	if err := db.Create(users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(item).Error; err != nil {
+		t.Fatal(err)
+	}
+	price := 1000
+	for _, order := range []*entity.OrderHistory{
+		// a Tuesday, a Sunday and a Monday of the next months
+		{UserID: users[0].ID, OrderItemID: item.ID, CreatedAt: firstOrder},
+		{UserID: users[0].ID, OrderItemID: item.ID, CreatedAt: date("2023-02-05 10:00"), Price: &price},
+		{UserID: users[0].ID, OrderItemID: item.ID, CreatedAt: date("2023-03-06 08:00")},
+	} {
+		if err := db.Create(order).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := repository.NewReportRepository(db)
+	filter := entity.ReportFilter{From: date("2023-01-01 00:00"), To: date("2023-04-01 00:00"), Limit: 10}
+
+	for granularity, want := range map[string][]string{
+		entity.GranularityDay:   {"2023-01-31", "2023-02-05", "2023-03-06"},
+		entity.GranularityWeek:  {"2023-01-30", "2023-01-30", "2023-03-06"},
+		entity.GranularityMonth: {"2023-01", "2023-02", "2023-03"},
+	} {
+		filter.Granularity = granularity
+		points, err := repo.Revenue(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var periods []string
+		var revenue int64
+		for _, point := range points {
+			for i := int64(0); i < point.Orders; i++ {
+				periods = append(periods, point.Period)
+			}
+			revenue += point.Revenue
+		}
+		if len(periods) != len(want) || revenue != 4000 {
+			t.Fatalf("%s revenue = %v (%d), want periods %v and 4000", granularity, periods, revenue, want)
+		}
+		for i := range want {
+			if periods[i] != want[i] {
+				t.Errorf("%s period of order %d = %s, want %s", granularity, i+1, periods[i], want[i])
+			}
+		}
+	}
+
+	activity, err := repo.CohortActivity(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int
+	for _, row := range activity {
+		if row.Cohort != "2023-01" || row.Users != 1 {
+			t.Errorf("activity row = %+v, want cohort 2023-01 with one user", row)
+		}
+		offsets = append(offsets, row.MonthOffset)
+	}
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != 1 || offsets[2] != 2 {
+		t.Errorf("month offsets = %v, want [0 1 2]", offsets)
+	}
+
+	stats, err := repo.UserStats(ctx, users[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Orders != 3 || stats.LifetimeValue != 4000 || stats.FirstOrder == nil || !stats.FirstOrder.Equal(firstOrder) ||
+		stats.LastOrder == nil || !stats.LastOrder.Equal(date("2023-03-06 08:00")) {
+		t.Errorf("stats = %+v, first %v, last %v", stats, stats.FirstOrder, stats.LastOrder)
+	}
+	if empty, err := repo.UserStats(ctx, users[1].ID); err != nil || empty.Orders != 0 || empty.FirstOrder != nil {
+		t.Errorf("stats without orders = %+v, %v", empty, err)
+	}
+}
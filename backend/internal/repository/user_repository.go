@@ -11,7 +11,9 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) (*entity.User, error)
 	Update(ctx context.Context, user *entity.User) error
+	UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error
 	GetByID(ctx context.Context, id int) (*entity.User, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error)
 	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.User, error)
 	SoftDelete(ctx context.Context, id int) error
 	CountData(ctx context.Context) int64
@@ -26,7 +28,7 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *entity.User) (*entity.User, error) {
-	execDB := r.db.WithContext(ctx).Create(&user)
+	execDB := conn(ctx, r.db).Create(&user)
 	if execDB.Error != nil {
 		return user, execDB.Error
 	}
@@ -34,13 +36,18 @@ func (r *userRepository) Create(ctx context.Context, user *entity.User) (*entity
 }
 
 func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
-	return r.db.WithContext(ctx).Model(user).Updates(&user).Error
+	return conn(ctx, r.db).Model(user).Updates(&user).Error
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *userRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return conn(ctx, r.db).Model(&entity.User{ID: id}).Updates(fields).Error
 }
 
 func (r *userRepository) SoftDelete(ctx context.Context, id int) error {
 	user := &entity.User{ID: id}
 
-	err := r.db.Delete(user).Error
+	err := conn(ctx, r.db).Delete(user).Error
 	if err != nil {
 		return fmt.Errorf("error soft-deleting user with ID %d: %s", id, err.Error())
 	}
@@ -50,17 +57,28 @@ func (r *userRepository) SoftDelete(ctx context.Context, id int) error {
 
 func (r *userRepository) GetByID(ctx context.Context, id int) (*entity.User, error) {
 	user := &entity.User{}
-	err := r.db.WithContext(ctx).First(user, id).Error
+	err := conn(ctx, r.db).First(user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
+// GetByIDs reads the Users of ids in one query, soft-deleted ones included like the Users preloaded
+// on an Order History, an unknown id is left out
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error) {
+	var users []*entity.User
+	err := conn(ctx, r.db).Unscoped().Where("id IN ?", ids).Order("id").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *userRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.User, error) {
 	var users []*entity.User
 
-	err := r.db.Limit(limit).Offset(offset).Find(&users).Error
+	err := conn(ctx, r.db).Limit(limit).Offset(offset).Find(&users).Error
 	if err != nil {
 		return nil, fmt.Errorf("error getting users: %s", err.Error())
 	}
@@ -72,6 +90,6 @@ func (r *userRepository) CountData(ctx context.Context) int64 {
 	var count int64
 	var users []*entity.User
 
-	r.db.Find(&users).Count(&count)
+	conn(ctx, r.db).Find(&users).Count(&count)
 	return count
 }
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, subscription *entity.WebhookSubscription) error
+	UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error
+	GetByID(ctx context.Context, id int) (*entity.WebhookSubscription, error)
+	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.WebhookSubscription, error)
+	SoftDelete(ctx context.Context, id int) error
+	CountData(ctx context.Context) int64
+	// Subscribed returns the active subscriptions to eventType
+	Subscribed(ctx context.Context, eventType string) ([]*entity.WebhookSubscription, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	return conn(ctx, r.db).Create(subscription).Error
+}
+
+// UpdateFields only writes the given columns, zero values included
+func (r *webhookRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}) error {
+	return conn(ctx, r.db).Model(&entity.WebhookSubscription{ID: id}).Updates(fields).Error
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id int) (*entity.WebhookSubscription, error) {
+	subscription := &entity.WebhookSubscription{}
+	if err := conn(ctx, r.db).First(subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *webhookRepository) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.WebhookSubscription, error) {
+	var subscriptions []*entity.WebhookSubscription
+	err := conn(ctx, r.db).Order("id").Limit(limit).Offset(offset).Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookRepository) SoftDelete(ctx context.Context, id int) error {
+	return conn(ctx, r.db).Delete(&entity.WebhookSubscription{ID: id}).Error
+}
+
+func (r *webhookRepository) CountData(ctx context.Context) int64 {
+	var count int64
+	conn(ctx, r.db).Model(&entity.WebhookSubscription{}).Count(&count)
+	return count
+}
+
+// Subscribed filters the event types in Go, there are few subscriptions and the list is one column
+func (r *webhookRepository) Subscribed(ctx context.Context, eventType string) ([]*entity.WebhookSubscription, error) {
+	var active []*entity.WebhookSubscription
+	if err := conn(ctx, r.db).Where("active = ?", true).Order("id").Find(&active).Error; err != nil {
+		return nil, err
+	}
+	var subscriptions []*entity.WebhookSubscription
+	for _, subscription := range active {
+		if subscription.Events.Has(eventType) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+type WebhookDeliveryRepository interface {
+	// Add writes deliveries, one already written for the same subscription and event is skipped
+	Add(ctx context.Context, deliveries ...*entity.WebhookDelivery) error
+	// Due returns up to limit pending or failed deliveries to attempt at now, the most overdue first
+	Due(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	// Save writes every column of delivery and adds attempts to its history, in one transaction
+	Save(ctx context.Context, delivery *entity.WebhookDelivery, attempts ...*entity.WebhookAttempt) error
+	// GetByID returns the delivery id of the subscription
+	GetByID(ctx context.Context, subscriptionID, id int) (*entity.WebhookDelivery, error)
+	// GetBySubscription returns the deliveries of a subscription, the newest first
+	GetBySubscription(ctx context.Context, subscriptionID, limit, offset int) ([]*entity.WebhookDelivery, error)
+	CountBySubscription(ctx context.Context, subscriptionID int) int64
+	// Attempts returns the attempts of a delivery, the newest first
+	Attempts(ctx context.Context, deliveryID, limit, offset int) ([]*entity.WebhookAttempt, error)
+	CountAttempts(ctx context.Context, deliveryID int) int64
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db}
+}
+
+func (r *webhookDeliveryRepository) Add(ctx context.Context, deliveries ...*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error
+}
+
+func (r *webhookDeliveryRepository) Due(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	err := conn(ctx, r.db).
+		Where("status IN ? AND next_attempt_at <= ?", []string{entity.DeliveryPending, entity.DeliveryFailed}, now).
+		Order("next_attempt_at").Order("id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) Save(ctx context.Context, delivery *entity.WebhookDelivery, attempts ...*entity.WebhookAttempt) error {
+	if len(attempts) == 0 {
+		return conn(ctx, r.db).Save(delivery).Error
+	}
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(delivery).Error; err != nil {
+			return err
+		}
+		for _, attempt := range attempts {
+			attempt.DeliveryID = delivery.ID
+		}
+		return tx.Create(attempts).Error
+	})
+}
+
+func (r *webhookDeliveryRepository) GetByID(ctx context.Context, subscriptionID, id int) (*entity.WebhookDelivery, error) {
+	delivery := &entity.WebhookDelivery{}
+	if err := conn(ctx, r.db).Where("subscription_id = ?", subscriptionID).First(delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (r *webhookDeliveryRepository) GetBySubscription(ctx context.Context, subscriptionID, limit, offset int) ([]*entity.WebhookDelivery, error) {
+	var deliveries []*entity.WebhookDelivery
+	err := conn(ctx, r.db).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).Offset(offset).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) CountBySubscription(ctx context.Context, subscriptionID int) int64 {
+	var count int64
+	conn(ctx, r.db).Model(&entity.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID).Count(&count)
+	return count
+}
+
+func (r *webhookDeliveryRepository) Attempts(ctx context.Context, deliveryID, limit, offset int) ([]*entity.WebhookAttempt, error) {
+	var attempts []*entity.WebhookAttempt
+	err := conn(ctx, r.db).
+		Where("delivery_id = ?", deliveryID).
+		Order("id DESC").
+		Limit(limit).Offset(offset).
+		Find(&attempts).Error
+	return attempts, err
+}
+
+func (r *webhookDeliveryRepository) CountAttempts(ctx context.Context, deliveryID int) int64 {
+	var count int64
+	conn(ctx, r.db).Model(&entity.WebhookAttempt{}).Where("delivery_id = ?", deliveryID).Count(&count)
+	return count
+}
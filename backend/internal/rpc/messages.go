@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"test-crud-user-orders/internal/entity"
+	ordersv1 "test-crud-user-orders/pkg/pb/orders/v1"
+)
+
+// maxBulkOperations limits the number of operations in one bulk request, as on the REST API
+const maxBulkOperations = 1000
+
+// pageOf reads a PageRequest with the defaults of ?limit=&page= on the REST API
+func pageOf(req *ordersv1.PageRequest) (limit, page, offset int64) {
+	limit, page = req.GetLimit(), req.GetPage()
+	if limit < 1 {
+		limit = 10
+	}
+	if page < 1 {
+		page = 1
+	}
+	return limit, page, (page - 1) * limit
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// checkBulk rejects a bulk request without operations or with too many of them
+func checkBulk(operations int) error {
+	if operations < 1 {
+		return status.Error(codes.InvalidArgument, "Zero Operations")
+	}
+	if operations > maxBulkOperations {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("Maximum %d Operations per Request", maxBulkOperations))
+	}
+	return nil
+}
+
+// bulkResponse executes the valid operations and answers with one result per operation, like the
+// bulk endpoints of the REST API. An atomic request with any invalid operation executes nothing.
+func bulkResponse(atomic bool, invalid []entity.BulkResult, valid []entity.BulkResult, execute func() []entity.BulkResult) *ordersv1.BulkResponse {
+	var results []entity.BulkResult
+	if atomic && len(invalid) > 0 {
+		for _, result := range valid {
+			result.Error = "not executed"
+			results = append(results, result)
+		}
+	} else if len(valid) > 0 {
+		results = execute()
+	}
+	results = append(results, invalid...)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+
+	res := &ordersv1.BulkResponse{Results: make([]*ordersv1.BulkResult, len(results))}
+	for i, result := range results {
+		res.Results[i] = &ordersv1.BulkResult{
+			Index:   int32(result.Index),
+			Op:      result.Op,
+			Id:      int64(result.ID),
+			Success: result.Success,
+			Error:   result.Error,
+		}
+	}
+	return res
+}
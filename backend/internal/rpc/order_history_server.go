@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/usecase"
+	ordersv1 "test-crud-user-orders/pkg/pb/orders/v1"
+)
+
+type orderHistoryServer struct {
+	ordersv1.UnimplementedOrderHistoryServiceServer
+	useCase  usecase.OrderHistoryUseCase
+	validate *validator.Validate
+}
+
+func orderHistoryMessage(orderHistory *entity.OrderHistory) *ordersv1.OrderHistory {
+	message := &ordersv1.OrderHistory{
+		Id:           int64(orderHistory.ID),
+		UserId:       int64(orderHistory.UserID),
+		OrderItemId:  int64(orderHistory.OrderItemID),
+		Descriptions: orderHistory.Descriptions,
+		Status:       orderHistory.Status,
+		CreatedAt:    timestamp(orderHistory.CreatedAt),
+		UpdatedAt:    timestamp(orderHistory.UpdatedAt),
+	}
+	if orderHistory.Price != nil {
+		price := int64(*orderHistory.Price)
+		message.Price = &price
+	}
+	if orderHistory.User != nil {
+		message.User = userMessage(orderHistory.User)
+	}
+	if orderHistory.OrderItem != nil {
+		message.OrderItem = orderItemMessage(orderHistory.OrderItem)
+	}
+	return message
+}
+
+// orderHistoryNotFound holds the messages of a missing Order History, User or Order Item on an
+// update, the same as the REST API
+var orderHistoryNotFound = map[string]string{
+	"record not found":          "Order History Not Found",
+	"order history not found":   "Order History Not Found",
+	"user data not found":       "UserID Not Found",
+	"order item data not found": "OrderItemID Not Found",
+}
+
+func (s *orderHistoryServer) CreateOrderHistory(ctx context.Context, req *ordersv1.CreateOrderHistoryRequest) (*ordersv1.OrderHistory, error) {
+	input := entity.CreateOrderHistory{UserID: int(req.GetUserId()), OrderItemID: int(req.GetOrderItemId()), Descriptions: req.GetDescriptions()}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	orderHistory, err := s.useCase.Create(ctx, input.UserID, input.OrderItemID, input.Descriptions)
+	if err != nil {
+		return nil, errorStatus(ctx, err, map[string]string{
+			"user not found":       fmt.Sprintf("UserID %d Not Found or Deleted", input.UserID),
+			"order item not found": fmt.Sprintf("OrderItemID %d Not Found or Deleted", input.OrderItemID),
+		})
+	}
+	return orderHistoryMessage(orderHistory), nil
+}
+
+func (s *orderHistoryServer) GetOrderHistory(ctx context.Context, req *ordersv1.GetOrderHistoryRequest) (*ordersv1.OrderHistory, error) {
+	orderHistory, err := s.useCase.GetByID(ctx, int(req.GetId()))
+	if err == nil && orderHistory == nil {
+		err = errors.New("order history not found")
+	}
+	if err != nil {
+		return nil, errorStatus(ctx, err, orderHistoryNotFound)
+	}
+	return orderHistoryMessage(orderHistory), nil
+}
+
+func (s *orderHistoryServer) ListOrderHistories(ctx context.Context, req *ordersv1.ListOrderHistoriesRequest) (*ordersv1.ListOrderHistoriesResponse, error) {
+	limit, page, offset := pageOf(req.GetPage())
+	userID := int(req.GetUserId())
+	if userID < 0 {
+		userID = 0
+	}
+
+	total := s.useCase.CountData(ctx, userID)
+	var orderHistories []*entity.OrderHistory
+	if offset < total {
+		var err error
+		if userID > 0 {
+			orderHistories, err = s.useCase.GetByUserID(ctx, userID, int(limit), int(offset))
+		} else {
+			orderHistories, err = s.useCase.GetAllPagination(ctx, int(limit), int(offset))
+		}
+		if err != nil {
+			return nil, errorStatus(ctx, err, nil)
+		}
+	}
+
+	res := &ordersv1.ListOrderHistoriesResponse{
+		OrderHistories: make([]*ordersv1.OrderHistory, len(orderHistories)),
+		Page:           &ordersv1.Page{Limit: limit, Page: page, Show: int32(len(orderHistories)), Total: total},
+	}
+	for i, orderHistory := range orderHistories {
+		res.OrderHistories[i] = orderHistoryMessage(orderHistory)
+	}
+	return res, nil
+}
+
+func (s *orderHistoryServer) UpdateOrderHistory(ctx context.Context, req *ordersv1.UpdateOrderHistoryRequest) (*emptypb.Empty, error) {
+	input := entity.CreateOrderHistory{UserID: int(req.GetUserId()), OrderItemID: int(req.GetOrderItemId()), Descriptions: req.GetDescriptions()}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := s.useCase.Update(ctx, int(req.GetId()), input.UserID, input.OrderItemID, input.Descriptions); err != nil {
+		return nil, errorStatus(ctx, err, orderHistoryNotFound)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *orderHistoryServer) PatchOrderHistory(ctx context.Context, req *ordersv1.PatchOrderHistoryRequest) (*ordersv1.OrderHistory, error) {
+	input := entity.PatchOrderHistory{Descriptions: req.Descriptions, Status: req.Status}
+	if req.UserId != nil {
+		userID := int(*req.UserId)
+		input.UserID = &userID
+	}
+	if req.OrderItemId != nil {
+		orderItemID := int(*req.OrderItemId)
+		input.OrderItemID = &orderItemID
+	}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	fields := map[string]interface{}{}
+	if input.UserID != nil {
+		fields["user_id"] = *input.UserID
+	}
+	if input.OrderItemID != nil {
+		fields["order_item_id"] = *input.OrderItemID
+	}
+	if input.Descriptions != nil {
+		fields["descriptions"] = *input.Descriptions
+	}
+	if input.Status != nil {
+		fields["status"] = *input.Status
+	}
+
+	orderHistory, err := s.useCase.Patch(ctx, int(req.GetId()), fields)
+	if err != nil {
+		return nil, errorStatus(ctx, err, orderHistoryNotFound)
+	}
+	return orderHistoryMessage(orderHistory), nil
+}
+
+func (s *orderHistoryServer) BulkOrderHistories(ctx context.Context, req *ordersv1.BulkOrderHistoriesRequest) (*ordersv1.BulkResponse, error) {
+	if err := checkBulk(len(req.GetOperations())); err != nil {
+		return nil, err
+	}
+
+	var ops []entity.BulkOrderHistory
+	var valid, invalid []entity.BulkResult
+	for i, operation := range req.GetOperations() {
+		result := entity.BulkResult{Index: i, Op: operation.GetOp(), ID: int(operation.GetId())}
+
+		input := entity.CreateOrderHistory{
+			UserID:       int(operation.GetUserId()),
+			OrderItemID:  int(operation.GetOrderItemId()),
+			Descriptions: operation.GetDescriptions(),
+		}
+		err := s.validate.Struct(&entity.BulkOperation{Op: result.Op, ID: result.ID})
+		if err == nil && result.Op != entity.BulkDelete {
+			err = s.validate.Struct(&input)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			invalid = append(invalid, result)
+			continue
+		}
+
+		ops = append(ops, entity.BulkOrderHistory{
+			Index:        i,
+			Op:           result.Op,
+			ID:           result.ID,
+			UserID:       input.UserID,
+			OrderItemID:  input.OrderItemID,
+			Descriptions: input.Descriptions,
+		})
+		valid = append(valid, result)
+	}
+
+	atomic := !req.GetPartial()
+	return bulkResponse(atomic, invalid, valid, func() []entity.BulkResult {
+		return s.useCase.Bulk(ctx, ops, atomic)
+	}), nil
+}
+
+func (s *orderHistoryServer) ExportOrderHistories(req *ordersv1.ExportOrderHistoriesRequest, stream ordersv1.OrderHistoryService_ExportOrderHistoriesServer) error {
+	var filter entity.OrderHistoryFilter
+	if req.GetUserId() > 0 {
+		filter.UserID = int(req.GetUserId())
+	}
+
+	ctx := stream.Context()
+	err := s.useCase.Export(ctx, filter, func(row *entity.OrderHistoryExport) error {
+		return stream.Send(&ordersv1.OrderHistoryExport{
+			Id:             int64(row.ID),
+			CreatedAt:      timestamp(row.CreatedAt),
+			UserId:         int64(row.UserID),
+			UserName:       row.UserName,
+			OrderItemId:    int64(row.OrderItemID),
+			OrderItemName:  row.OrderItemName,
+			OrderItemPrice: int64(row.OrderItemPrice),
+			Descriptions:   row.Descriptions,
+		})
+	})
+	if err != nil {
+		return errorStatus(ctx, err, nil)
+	}
+	return nil
+}
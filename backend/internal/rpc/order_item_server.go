@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/usecase"
+	ordersv1 "test-crud-user-orders/pkg/pb/orders/v1"
+)
+
+type orderItemServer struct {
+	ordersv1.UnimplementedOrderItemServiceServer
+	useCase  usecase.OrderItemUseCase
+	validate *validator.Validate
+}
+
+func orderItemMessage(orderItem *entity.OrderItem) *ordersv1.OrderItem {
+	return &ordersv1.OrderItem{
+		Id:        int64(orderItem.ID),
+		Name:      orderItem.Name,
+		Price:     int64(orderItem.Price),
+		ExpiredAt: timestamp(orderItem.ExpiredAt),
+		CreatedAt: timestamp(orderItem.CreatedAt),
+		UpdatedAt: timestamp(orderItem.UpdatedAt),
+	}
+}
+
+// orderItemNotFound is the message of a missing Order Item, the same as the REST API
+func orderItemNotFound(id int64) map[string]string {
+	message := fmt.Sprintf("OrderItemID #%d Not Found or Deleted", id)
+	return map[string]string{"record not found": message, "order item not found": message}
+}
+
+// expiresIn is the expiry of an Order Item expiring in days, at least 1, like on the REST API
+func expiresIn(days int) time.Time {
+	if days < 1 {
+		days = 1
+	}
+	return time.Now().AddDate(0, 0, days)
+}
+
+// orderItemInput validates the fields of a create or full update
+func (s *orderItemServer) orderItemInput(name string, price, expiredDays int64) (entity.OrderItem, error) {
+	input := entity.CreateOrderItem{Name: name, Price: int(price), ExpiredDay: int(expiredDays)}
+	if err := s.validate.Struct(&input); err != nil {
+		return entity.OrderItem{}, err
+	}
+	return entity.OrderItem{Name: input.Name, Price: input.Price, ExpiredAt: expiresIn(input.ExpiredDay)}, nil
+}
+
+func (s *orderItemServer) CreateOrderItem(ctx context.Context, req *ordersv1.CreateOrderItemRequest) (*ordersv1.OrderItem, error) {
+	orderItem, err := s.orderItemInput(req.GetName(), req.GetPrice(), req.GetExpiredDays())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := s.useCase.Create(ctx, &orderItem); err != nil {
+		return nil, errorStatus(ctx, err, nil)
+	}
+	return orderItemMessage(&orderItem), nil
+}
+
+func (s *orderItemServer) GetOrderItem(ctx context.Context, req *ordersv1.GetOrderItemRequest) (*ordersv1.OrderItem, error) {
+	orderItem, err := s.useCase.GetByID(ctx, int(req.GetId()))
+	if err == nil && orderItem == nil {
+		err = errors.New("order item not found")
+	}
+	if err != nil {
+		return nil, errorStatus(ctx, err, orderItemNotFound(req.GetId()))
+	}
+	return orderItemMessage(orderItem), nil
+}
+
+func (s *orderItemServer) ListOrderItems(ctx context.Context, req *ordersv1.ListOrderItemsRequest) (*ordersv1.ListOrderItemsResponse, error) {
+	limit, page, offset := pageOf(req.GetPage())
+	total := s.useCase.CountData(ctx)
+	var orderItems []*entity.OrderItem
+	if offset < total {
+		var err error
+		if orderItems, err = s.useCase.GetAllPagination(ctx, int(limit), int(offset)); err != nil {
+			return nil, errorStatus(ctx, err, nil)
+		}
+	}
+
+	res := &ordersv1.ListOrderItemsResponse{
+		OrderItems: make([]*ordersv1.OrderItem, len(orderItems)),
+		Page:       &ordersv1.Page{Limit: limit, Page: page, Show: int32(len(orderItems)), Total: total},
+	}
+	for i, orderItem := range orderItems {
+		res.OrderItems[i] = orderItemMessage(orderItem)
+	}
+	return res, nil
+}
+
+func (s *orderItemServer) UpdateOrderItem(ctx context.Context, req *ordersv1.UpdateOrderItemRequest) (*ordersv1.OrderItem, error) {
+	orderItem, err := s.orderItemInput(req.GetName(), req.GetPrice(), req.GetExpiredDays())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	orderItem.ID = int(req.GetId())
+	if err := s.useCase.Update(ctx, &orderItem); err != nil {
+		return nil, errorStatus(ctx, err, orderItemNotFound(req.GetId()))
+	}
+	return orderItemMessage(&orderItem), nil
+}
+
+func (s *orderItemServer) PatchOrderItem(ctx context.Context, req *ordersv1.PatchOrderItemRequest) (*ordersv1.OrderItem, error) {
+	input := entity.PatchOrderItem{Name: req.Name}
+	if req.Price != nil {
+		price := int(*req.Price)
+		input.Price = &price
+	}
+	if req.ExpiredDays != nil {
+		expiredDays := int(*req.ExpiredDays)
+		input.ExpiredDay = &expiredDays
+	}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	fields := map[string]interface{}{}
+	if input.Name != nil {
+		fields["name"] = *input.Name
+	}
+	if input.Price != nil {
+		fields["price"] = *input.Price
+	}
+	if input.ExpiredDay != nil {
+		fields["expired_at"] = expiresIn(*input.ExpiredDay)
+	}
+
+	orderItem, err := s.useCase.Patch(ctx, int(req.GetId()), fields)
+	if err != nil {
+		return nil, errorStatus(ctx, err, orderItemNotFound(req.GetId()))
+	}
+	return orderItemMessage(orderItem), nil
+}
+
+func (s *orderItemServer) DeleteOrderItem(ctx context.Context, req *ordersv1.DeleteOrderItemRequest) (*emptypb.Empty, error) {
+	if err := s.useCase.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, errorStatus(ctx, err, orderItemNotFound(req.GetId()))
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *orderItemServer) BulkOrderItems(ctx context.Context, req *ordersv1.BulkOrderItemsRequest) (*ordersv1.BulkResponse, error) {
+	if err := checkBulk(len(req.GetOperations())); err != nil {
+		return nil, err
+	}
+
+	var ops []entity.BulkOrderItem
+	var valid, invalid []entity.BulkResult
+	for i, operation := range req.GetOperations() {
+		result := entity.BulkResult{Index: i, Op: operation.GetOp(), ID: int(operation.GetId())}
+
+		var orderItem entity.OrderItem
+		err := s.validate.Struct(&entity.BulkOperation{Op: result.Op, ID: result.ID})
+		if err == nil && result.Op != entity.BulkDelete {
+			orderItem, err = s.orderItemInput(operation.GetName(), operation.GetPrice(), operation.GetExpiredDays())
+		}
+		if err != nil {
+			result.Error = err.Error()
+			invalid = append(invalid, result)
+			continue
+		}
+
+		orderItem.ID = result.ID
+		ops = append(ops, entity.BulkOrderItem{Index: i, Op: result.Op, OrderItem: orderItem})
+		valid = append(valid, result)
+	}
+
+	atomic := !req.GetPartial()
+	return bulkResponse(atomic, invalid, valid, func() []entity.BulkResult {
+		return s.useCase.Bulk(ctx, ops, atomic)
+	}), nil
+}
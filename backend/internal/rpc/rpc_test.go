@@ -0,0 +1,190 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository/memory"
+	"test-crud-user-orders/internal/usecase"
+	ordersv1 "test-crud-user-orders/pkg/pb/orders/v1"
+)
+
+// testAPI is a client of the gRPC API served on in-memory repositories
+type testAPI struct {
+	store        *memory.Store
+	users        ordersv1.UserServiceClient
+	orderItems   ordersv1.OrderItemServiceClient
+	orderHistory ordersv1.OrderHistoryServiceClient
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	store := memory.NewStore()
+	userRepo := memory.NewUserRepository(store)
+	orderItemRepo := memory.NewOrderItemRepository(store)
+	outboxRepo := memory.NewOutboxRepository(store)
+	transactor := memory.NewTransactor(store)
+
+	log := zerolog.Nop()
+	server := NewServer(&log, UseCases{
+		User:         usecase.NewUserUseCase(userRepo, outboxRepo, transactor),
+		OrderItem:    usecase.NewOrderItemUseCase(orderItemRepo, outboxRepo, transactor, cache.NewLoader(cache.NewNoopCache(), nil, nil)),
+		OrderHistory: usecase.NewOrderHistoryUseCase(memory.NewOrderHistoryRepository(store), orderItemRepo, userRepo, outboxRepo, transactor),
+	}, true)
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testAPI{
+		store:        store,
+		users:        ordersv1.NewUserServiceClient(conn),
+		orderItems:   ordersv1.NewOrderItemServiceClient(conn),
+		orderHistory: ordersv1.NewOrderHistoryServiceClient(conn),
+	}
+}
+
+// wantStatus fails unless err has code and message
+func wantStatus(t *testing.T, name string, err error, code codes.Code, message string) {
+	t.Helper()
+	if got := status.Convert(err); got.Code() != code || got.Message() != message {
+		t.Errorf("%s = %s %q, want %s %q", name, got.Code(), got.Message(), code, message)
+	}
+}
+
+func TestUserService(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+
+	user, err := api.users.CreateUser(ctx, &ordersv1.CreateUserRequest{Name: "Budi"})
+	if err != nil || user.Id != 1 || user.Name != "Budi" || user.CreatedAt == nil {
+		t.Fatalf("CreateUser = %v, %v", user, err)
+	}
+	_, err = api.users.CreateUser(ctx, &ordersv1.CreateUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateUser without a name = %v, want InvalidArgument", err)
+	}
+
+	name := "Budi Santoso"
+	if patched, err := api.users.PatchUser(ctx, &ordersv1.PatchUserRequest{Id: 1, Name: &name}); err != nil || patched.Name != name {
+		t.Errorf("PatchUser = %v, %v", patched, err)
+	}
+	_, err = api.users.PatchUser(ctx, &ordersv1.PatchUserRequest{Id: 9, Name: &name})
+	wantStatus(t, "PatchUser of an unknown User", err, codes.NotFound, "UserID 9 Not Found")
+	_, err = api.users.UpdateUser(ctx, &ordersv1.UpdateUserRequest{Id: 9, Name: name})
+	wantStatus(t, "UpdateUser of an unknown User", err, codes.NotFound, "UserID 9 Not Found")
+
+	list, err := api.users.ListUsers(ctx, &ordersv1.ListUsersRequest{})
+	want := &ordersv1.Page{Limit: 10, Page: 1, Show: 1, Total: 1}
+	if err != nil || len(list.Users) != 1 || !proto.Equal(list.Page, want) {
+		t.Errorf("ListUsers = %v, %v, want 1 User on %v", list, err, want)
+	}
+
+	if _, err := api.users.DeleteUser(ctx, &ordersv1.DeleteUserRequest{Id: 1}); err != nil {
+		t.Fatalf("DeleteUser = %v", err)
+	}
+	_, err = api.users.GetUser(ctx, &ordersv1.GetUserRequest{Id: 1})
+	wantStatus(t, "GetUser of a deleted User", err, codes.NotFound, "UserID 1 Not Found or Deleted")
+
+	api.store.FailWith(errors.New("connection refused"))
+	_, err = api.users.GetUser(ctx, &ordersv1.GetUserRequest{Id: 1})
+	wantStatus(t, "GetUser while the Database fails", err, codes.Internal, "Internal Server Error")
+}
+
+func TestOrderHistoryService(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+	if _, err := api.users.CreateUser(ctx, &ordersv1.CreateUserRequest{Name: "Budi"}); err != nil {
+		t.Fatal(err)
+	}
+	orderItem, err := api.orderItems.CreateOrderItem(ctx, &ordersv1.CreateOrderItemRequest{Name: "Kopi", Price: 15000, ExpiredDays: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.orderHistory.CreateOrderHistory(ctx, &ordersv1.CreateOrderHistoryRequest{UserId: 2, OrderItemId: orderItem.Id, Descriptions: "pagi"})
+	wantStatus(t, "CreateOrderHistory of an unknown User", err, codes.NotFound, "UserID 2 Not Found or Deleted")
+	orderHistory, err := api.orderHistory.CreateOrderHistory(ctx, &ordersv1.CreateOrderHistoryRequest{UserId: 1, OrderItemId: orderItem.Id, Descriptions: "pagi"})
+	if err != nil || orderHistory.GetPrice() != 15000 || orderHistory.Status != entity.OrderPending {
+		t.Fatalf("CreateOrderHistory = %v, %v, want the price of the Order Item and a pending status", orderHistory, err)
+	}
+
+	descriptions := "siang"
+	_, err = api.orderHistory.PatchOrderHistory(ctx, &ordersv1.PatchOrderHistoryRequest{Id: orderHistory.Id, OrderItemId: proto.Int64(7)})
+	wantStatus(t, "PatchOrderHistory to an unknown Order Item", err, codes.NotFound, "OrderItemID Not Found")
+	_, err = api.orderHistory.GetOrderHistory(ctx, &ordersv1.GetOrderHistoryRequest{Id: 5})
+	wantStatus(t, "GetOrderHistory of an unknown Order History", err, codes.NotFound, "Order History Not Found")
+	if patched, err := api.orderHistory.PatchOrderHistory(ctx, &ordersv1.PatchOrderHistoryRequest{Id: orderHistory.Id, Descriptions: &descriptions}); err != nil || patched.Descriptions != descriptions {
+		t.Errorf("PatchOrderHistory = %v, %v", patched, err)
+	}
+	_, err = api.orderHistory.PatchOrderHistory(ctx, &ordersv1.PatchOrderHistoryRequest{Id: orderHistory.Id, Status: proto.String("lost")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("PatchOrderHistory to an unknown status = %v, want InvalidArgument", err)
+	}
+	if patched, err := api.orderHistory.PatchOrderHistory(ctx, &ordersv1.PatchOrderHistoryRequest{Id: orderHistory.Id, Status: proto.String(entity.OrderPaid)}); err != nil || patched.Status != entity.OrderPaid {
+		t.Errorf("PatchOrderHistory of the status = %v, %v, want it paid", patched, err)
+	}
+
+	bulk, err := api.orderHistory.BulkOrderHistories(ctx, &ordersv1.BulkOrderHistoriesRequest{Operations: []*ordersv1.BulkOrderHistoryOperation{
+		{Op: "create", UserId: 1, OrderItemId: orderItem.Id, Descriptions: "malam"},
+		{Op: "create", UserId: 1},
+	}})
+	if err != nil || len(bulk.Results) != 2 || bulk.Results[0].Error != "not executed" || bulk.Results[1].Success {
+		t.Errorf("atomic BulkOrderHistories with an invalid operation = %v, %v, want nothing executed", bulk, err)
+	}
+	_, err = api.orderHistory.BulkOrderHistories(ctx, &ordersv1.BulkOrderHistoriesRequest{})
+	wantStatus(t, "BulkOrderHistories without operations", err, codes.InvalidArgument, "Zero Operations")
+
+	stream, err := api.orderHistory.ExportOrderHistories(ctx, &ordersv1.ExportOrderHistoriesRequest{UserId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []*ordersv1.OrderHistoryExport
+	for {
+		row, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ExportOrderHistories = %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) != 1 || rows[0].UserName != "Budi" || rows[0].OrderItemName != "Kopi" || rows[0].Descriptions != descriptions {
+		t.Errorf("exported %v, want the patched Order History with its User and Order Item", rows)
+	}
+}
+
+func TestServerRegistersReflection(t *testing.T) {
+	log := zerolog.Nop()
+	for _, reflect := range []bool{true, false} {
+		services := NewServer(&log, UseCases{}, reflect).GetServiceInfo()
+		if _, ok := services["orders.v1.OrderHistoryService"]; !ok {
+			t.Errorf("services %v, want the OrderHistoryService", services)
+		}
+		if _, ok := services["grpc.reflection.v1alpha.ServerReflection"]; ok != reflect {
+			t.Errorf("reflection registered = %t, want %t", ok, reflect)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package rpc serves the gRPC API of proto/orders/v1. Every call goes through the same UseCases as
+// the REST handlers, the services only translate the messages and map the errors to status codes.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/usecase"
+	"test-crud-user-orders/pkg/logger"
+	ordersv1 "test-crud-user-orders/pkg/pb/orders/v1"
+)
+
+// UseCases served by the gRPC API
+type UseCases struct {
+	User         usecase.UserUseCase
+	OrderItem    usecase.OrderItemUseCase
+	OrderHistory usecase.OrderHistoryUseCase
+}
+
+// NewServer registers the services of useCases on a gRPC server logging every call through log,
+// reflection lets tools like grpcurl list the services without the .proto files
+func NewServer(log *zerolog.Logger, useCases UseCases, reflect bool) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor(), unaryLogger(log), unaryRecover),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor(), streamLogger(log), streamRecover),
+	)
+
+	validate := validator.New()
+	ordersv1.RegisterUserServiceServer(server, &userServer{useCase: useCases.User, validate: validate})
+	ordersv1.RegisterOrderItemServiceServer(server, &orderItemServer{useCase: useCases.OrderItem, validate: validate})
+	ordersv1.RegisterOrderHistoryServiceServer(server, &orderHistoryServer{useCase: useCases.OrderHistory, validate: validate})
+	if reflect {
+		reflection.Register(server)
+	}
+	return server
+}
+
+// callLogger is the logger of one call, tagged with its trace like the logger of a request
+func callLogger(ctx context.Context, base *zerolog.Logger) zerolog.Logger {
+	fields := base.With()
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		fields = fields.Str("trace_id", span.TraceID().String())
+	}
+	return fields.Logger()
+}
+
+// logCall writes the access line of a call, the level follows the status code like the one of a request
+func logCall(log zerolog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	event := log.Info()
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		event = log.Error()
+	default:
+		event = log.Warn()
+	}
+	event.
+		Str("method", method).
+		Str("code", code.String()).
+		Dur("latency", time.Since(start)).
+		Msg("call")
+}
+
+func unaryLogger(base *zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		log := callLogger(ctx, base)
+		res, err := handler(log.WithContext(ctx), req)
+		logCall(log, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+func streamLogger(base *zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		log := callLogger(ss.Context(), base)
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: log.WithContext(ss.Context())})
+		logCall(log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// contextStream replaces the context of a ServerStream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// unaryRecover answers Internal to a call that panicked, like the Recover middleware of Echo
+func unaryRecover(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer recoverCall(ctx, &err)
+	return handler(ctx, req)
+}
+
+func streamRecover(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverCall(ss.Context(), &err)
+	return handler(srv, ss)
+}
+
+func recoverCall(ctx context.Context, err *error) {
+	if r := recover(); r != nil {
+		logger.FromContext(ctx).Error().Err(fmt.Errorf("%v", r)).Str("stack", string(debug.Stack())).Msg("panic recovered")
+		*err = status.Error(codes.Internal, "Internal Server Error")
+	}
+}
+
+// errorStatus maps an error of a UseCase to the status of the same REST answer: notFound holds the
+// message answered for every error meaning a missing record, keyed by the text of the error
+func errorStatus(ctx context.Context, err error, notFound map[string]string) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		// failed sending to the client
+		return err
+	}
+	key := err.Error()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		key = gorm.ErrRecordNotFound.Error()
+	}
+	if message, ok := notFound[key]; ok {
+		return status.Error(codes.NotFound, message)
+	}
+	logger.FromContext(ctx).Error().Err(err).Msg("call failed")
+	return status.Error(codes.Internal, "Internal Server Error")
+}
+
+// invalidArgument answers a request rejected by the validator
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
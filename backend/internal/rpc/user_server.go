@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/usecase"
+	ordersv1 "test-crud-user-orders/pkg/pb/orders/v1"
+)
+
+type userServer struct {
+	ordersv1.UnimplementedUserServiceServer
+	useCase  usecase.UserUseCase
+	validate *validator.Validate
+}
+
+func userMessage(user *entity.User) *ordersv1.User {
+	message := &ordersv1.User{
+		Id:        int64(user.ID),
+		Name:      user.FullName,
+		CreatedAt: timestamp(user.CreatedAt),
+		UpdatedAt: timestamp(user.UpdatedAt),
+	}
+	if user.FirstOrder != nil {
+		message.FirstOrder = timestamp(*user.FirstOrder)
+	}
+	return message
+}
+
+// userNotFound is the message of a missing User, the same as the REST API
+func userNotFound(id int64) map[string]string {
+	message := fmt.Sprintf("UserID %d Not Found", id)
+	return map[string]string{"record not found": message, "user not found": message}
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *ordersv1.CreateUserRequest) (*ordersv1.User, error) {
+	input := entity.CreateUser{FullName: req.GetName()}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+	user, err := s.useCase.Create(ctx, input.FullName)
+	if err != nil {
+		return nil, errorStatus(ctx, err, nil)
+	}
+	return userMessage(user), nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *ordersv1.GetUserRequest) (*ordersv1.User, error) {
+	user, err := s.useCase.GetByID(ctx, int(req.GetId()))
+	if err == nil && user == nil {
+		err = errors.New("user not found")
+	}
+	if err != nil {
+		message := fmt.Sprintf("UserID %d Not Found or Deleted", req.GetId())
+		return nil, errorStatus(ctx, err, map[string]string{"record not found": message, "user not found": message})
+	}
+	return userMessage(user), nil
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *ordersv1.ListUsersRequest) (*ordersv1.ListUsersResponse, error) {
+	limit, page, offset := pageOf(req.GetPage())
+	total := s.useCase.CountData(ctx)
+	var users []*entity.User
+	if offset < total {
+		var err error
+		if users, err = s.useCase.GetAllPagination(ctx, int(limit), int(offset)); err != nil {
+			return nil, errorStatus(ctx, err, nil)
+		}
+	}
+
+	res := &ordersv1.ListUsersResponse{
+		Users: make([]*ordersv1.User, len(users)),
+		Page:  &ordersv1.Page{Limit: limit, Page: page, Show: int32(len(users)), Total: total},
+	}
+	for i, user := range users {
+		res.Users[i] = userMessage(user)
+	}
+	return res, nil
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *ordersv1.UpdateUserRequest) (*emptypb.Empty, error) {
+	input := entity.CreateUser{FullName: req.GetName()}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+	if err := s.useCase.Update(ctx, int(req.GetId()), input.FullName); err != nil {
+		return nil, errorStatus(ctx, err, userNotFound(req.GetId()))
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *userServer) PatchUser(ctx context.Context, req *ordersv1.PatchUserRequest) (*ordersv1.User, error) {
+	input := entity.PatchUser{FullName: req.Name}
+	if err := s.validate.Struct(&input); err != nil {
+		return nil, invalidArgument(err)
+	}
+	fields := map[string]interface{}{}
+	if input.FullName != nil {
+		fields["full_name"] = *input.FullName
+	}
+
+	user, err := s.useCase.Patch(ctx, int(req.GetId()), fields)
+	if err != nil {
+		return nil, errorStatus(ctx, err, userNotFound(req.GetId()))
+	}
+	return userMessage(user), nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *ordersv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := s.useCase.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, errorStatus(ctx, err, userNotFound(req.GetId()))
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *userServer) BulkUsers(ctx context.Context, req *ordersv1.BulkUsersRequest) (*ordersv1.BulkResponse, error) {
+	if err := checkBulk(len(req.GetOperations())); err != nil {
+		return nil, err
+	}
+
+	var ops []entity.BulkUser
+	var valid, invalid []entity.BulkResult
+	for i, operation := range req.GetOperations() {
+		result := entity.BulkResult{Index: i, Op: operation.GetOp(), ID: int(operation.GetId())}
+
+		input := entity.CreateUser{FullName: operation.GetName()}
+		err := s.validate.Struct(&entity.BulkOperation{Op: result.Op, ID: result.ID})
+		if err == nil && result.Op != entity.BulkDelete {
+			err = s.validate.Struct(&input)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			invalid = append(invalid, result)
+			continue
+		}
+
+		ops = append(ops, entity.BulkUser{Index: i, Op: result.Op, ID: result.ID, FullName: input.FullName})
+		valid = append(valid, result)
+	}
+
+	atomic := !req.GetPartial()
+	return bulkResponse(atomic, invalid, valid, func() []entity.BulkResult {
+		return s.useCase.Bulk(ctx, ops, atomic)
+	}), nil
+}
@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/pkg/logger"
+)
+
+// Channel is the Redis pub/sub channel the Sink publishes the messages on for every instance
+const Channel = "stream:order-histories"
+
+// resubscribeDelay is the wait between two subscriptions while Redis does not answer
+const resubscribeDelay = time.Second
+
+// Sink streams the events of the Order Histories relayed from the outbox. With a Redis client the
+// messages go through Channel to the Hub of every instance running Run, without one they go to the
+// Hub of this instance only.
+type Sink struct {
+	hub    *Hub
+	client redis.UniversalClient
+}
+
+func NewSink(hub *Hub, client redis.UniversalClient) *Sink {
+	return &Sink{hub: hub, client: client}
+}
+
+func (s *Sink) Name() string {
+	return "stream"
+}
+
+// Publish ignores every event but the ones of the Order Histories. Redis pub/sub does not keep the
+// messages, an instance not subscribed at the time misses them.
+func (s *Sink) Publish(ctx context.Context, event entity.Event) error {
+	message := Message{ID: event.ID, Type: event.Type, Data: event.Payload}
+	switch event.Type {
+	case entity.EventOrderCreated, entity.EventOrderUpdated:
+		var order entity.OrderEvent
+		if err := json.Unmarshal(event.Payload, &order); err != nil {
+			return err
+		}
+		message.OrderID, message.UserID = order.ID, order.UserID
+	case entity.EventOrderStatusChanged:
+		var change entity.OrderStatusChangedEvent
+		if err := json.Unmarshal(event.Payload, &change); err != nil {
+			return err
+		}
+		message.OrderID, message.UserID = change.OrderID, change.UserID
+	default:
+		return nil
+	}
+
+	if s.client == nil {
+		s.hub.Broadcast(message)
+		return nil
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, Channel, data).Err()
+}
+
+// Run broadcasts the messages published on Channel to the Hub until ctx is done, then closes the
+// Hub to end the streams of the clients
+func (s *Sink) Run(ctx context.Context) error {
+	defer s.hub.Close()
+	if s.client == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	pubsub := s.client.Subscribe(ctx, Channel)
+	defer pubsub.Close()
+	for {
+		received, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			logger.FromContext(ctx).Warn().Err(err).Msg("stream messages not received")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+
+		if received, ok := received.(*redis.Message); ok {
+			var message Message
+			if err := json.Unmarshal([]byte(received.Payload), &message); err != nil {
+				logger.FromContext(ctx).Warn().Err(err).Msg("invalid stream message")
+				continue
+			}
+			s.hub.Broadcast(message)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package stream pushes the events of the Order Histories to the clients of the Server-Sent Events
+// endpoint. The Sink receives the events relayed from the outbox and fans them out to the Hub of every
+// instance through Redis pub/sub, each Hub keeps the last events for the clients resuming a stream.
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// subscriberBuffer is the number of messages a client may lag behind before it is dropped, it then
+// resumes from the Hub buffer with Last-Event-ID
+const subscriberBuffer = 64
+
+// Message is an event of an Order History as streamed to the clients
+type Message struct {
+	// ID is the ID of the event, sent as the SSE id
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// OrderID and UserID of the Order History, the clients filter on them
+	OrderID int `json:"order_id"`
+	UserID  int `json:"user_id"`
+	// Data is the payload of the event, an OrderEvent or an OrderStatusChangedEvent, as JSON
+	Data json.RawMessage `json:"data"`
+}
+
+// Subscriber receives the messages broadcast after it subscribed, Messages is closed when the
+// Subscriber fell too far behind or the Hub was closed
+type Subscriber struct {
+	Messages <-chan Message
+	messages chan Message
+	userID   int
+}
+
+func (s *Subscriber) wants(message Message) bool {
+	return s.userID == 0 || s.userID == message.UserID
+}
+
+// Hub broadcasts the messages of one instance to its subscribers and keeps the last size of them
+type Hub struct {
+	mu          sync.Mutex
+	size        int
+	buffer      []Message
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+}
+
+func NewHub(size int) *Hub {
+	return &Hub{size: size, subscribers: map[*Subscriber]struct{}{}}
+}
+
+// Broadcast keeps message in the buffer and hands it to every subscriber wanting it, a subscriber
+// that is not keeping up is dropped instead of slowing down the others. A message still buffered,
+// relayed again after another sink failed, is not broadcast twice.
+func (h *Hub) Broadcast(message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	for _, buffered := range h.buffer {
+		if buffered.ID == message.ID {
+			return
+		}
+	}
+
+	h.buffer = append(h.buffer, message)
+	if len(h.buffer) > h.size {
+		copy(h.buffer, h.buffer[1:])
+		h.buffer = h.buffer[:h.size]
+	}
+
+	for subscriber := range h.subscribers {
+		if !subscriber.wants(message) {
+			continue
+		}
+		select {
+		case subscriber.messages <- message:
+		default:
+			h.drop(subscriber)
+		}
+	}
+}
+
+// Subscribe returns a Subscriber to the messages of userID, 0 for every user, and the buffered
+// messages it missed after lastEventID. An empty lastEventID misses nothing, one no longer in
+// the buffer missed the whole buffer.
+func (h *Hub) Subscribe(lastEventID string, userID int) (*Subscriber, []Message) {
+	messages := make(chan Message, subscriberBuffer)
+	subscriber := &Subscriber{Messages: messages, messages: messages, userID: userID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(messages)
+		return subscriber, nil
+	}
+	h.subscribers[subscriber] = struct{}{}
+
+	if lastEventID == "" {
+		return subscriber, nil
+	}
+	missed := h.buffer
+	for i, message := range h.buffer {
+		if message.ID == lastEventID {
+			missed = h.buffer[i+1:]
+			break
+		}
+	}
+	var replay []Message
+	for _, message := range missed {
+		if subscriber.wants(message) {
+			replay = append(replay, message)
+		}
+	}
+	return subscriber, replay
+}
+
+// Closed reports whether the Hub was closed, the streams end because the instance shuts down
+func (h *Hub) Closed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closed
+}
+
+// Unsubscribe stops the messages of subscriber
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[subscriber]; ok {
+		h.drop(subscriber)
+	}
+}
+
+// Close ends every stream, the later subscribers are closed right away
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for subscriber := range h.subscribers {
+		h.drop(subscriber)
+	}
+}
+
+// drop closes subscriber, h.mu must be held
+func (h *Hub) drop(subscriber *Subscriber) {
+	delete(h.subscribers, subscriber)
+	close(subscriber.messages)
+}
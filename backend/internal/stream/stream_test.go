@@ -0,0 +1,187 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+func message(id string, userID int) Message {
+	return Message{ID: id, Type: entity.EventOrderCreated, UserID: userID, Data: json.RawMessage(`{}`)}
+}
+
+func ids(messages []Message) []string {
+	got := []string{}
+	for _, message := range messages {
+		got = append(got, message.ID)
+	}
+	return got
+}
+
+// receive returns the next message of subscriber, or fails after a second
+func receive(t *testing.T, subscriber *Subscriber) (Message, bool) {
+	t.Helper()
+	select {
+	case message, ok := <-subscriber.Messages:
+		return message, ok
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return Message{}, false
+	}
+}
+
+func TestHubReplaysTheMissedMessages(t *testing.T) {
+	hub := NewHub(3)
+	for i := 1; i <= 4; i++ {
+		hub.Broadcast(message(fmt.Sprint(i), i%2))
+	}
+
+	for _, test := range []struct {
+		name        string
+		lastEventID string
+		userID      int
+		want        string
+	}{
+		{"new stream", "", 0, "[]"},
+		{"resumed", "2", 0, "[3 4]"},
+		{"resumed with a filter", "2", 1, "[3]"},
+		{"up to date", "4", 0, "[]"},
+		{"resumed past the buffer", "1", 0, "[2 3 4]"},
+	} {
+		subscriber, missed := hub.Subscribe(test.lastEventID, test.userID)
+		if got := fmt.Sprint(ids(missed)); got != test.want {
+			t.Errorf("%s: missed = %s, want %s", test.name, got, test.want)
+		}
+		hub.Unsubscribe(subscriber)
+	}
+}
+
+func TestHubBroadcastsToTheSubscribersOfTheUser(t *testing.T) {
+	hub := NewHub(10)
+	all, _ := hub.Subscribe("", 0)
+	ann, _ := hub.Subscribe("", 1)
+
+	hub.Broadcast(message("a", 2))
+	hub.Broadcast(message("b", 1))
+	// relayed again after another sink failed
+	hub.Broadcast(message("a", 2))
+
+	for _, want := range []string{"a", "b"} {
+		if got, _ := receive(t, all); got.ID != want {
+			t.Errorf("subscriber to every user received %s, want %s", got.ID, want)
+		}
+	}
+	if got, _ := receive(t, ann); got.ID != "b" {
+		t.Errorf("subscriber to user 1 received %s, want b", got.ID)
+	}
+	select {
+	case got := <-all.Messages:
+		t.Errorf("received %s twice", got.ID)
+	default:
+	}
+}
+
+func TestHubDropsSlowSubscribersAndClosesOnShutdown(t *testing.T) {
+	hub := NewHub(100)
+	slow, _ := hub.Subscribe("", 0)
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Broadcast(message(fmt.Sprint(i), 1))
+	}
+	for i := 0; i < subscriberBuffer; i++ {
+		receive(t, slow)
+	}
+	if _, ok := receive(t, slow); ok {
+		t.Error("a subscriber that fell behind was not dropped")
+	}
+
+	subscriber, _ := hub.Subscribe("", 0)
+	hub.Close()
+	if _, ok := receive(t, subscriber); ok {
+		t.Error("subscriber not closed by Close")
+	}
+	late, _ := hub.Subscribe("", 0)
+	if _, ok := receive(t, late); ok {
+		t.Error("subscriber of a closed hub not closed")
+	}
+}
+
+func TestSinkBroadcastsTheOrderEventsLocally(t *testing.T) {
+	hub := NewHub(10)
+	sink := NewSink(hub, nil)
+	subscriber, _ := hub.Subscribe("", 7)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = sink.Run(ctx)
+	}()
+
+	events := []entity.Event{
+		{ID: "a", Type: entity.EventUserCreated, Payload: json.RawMessage(`{"id":7}`)},
+		{ID: "b", Type: entity.EventOrderCreated, Payload: json.RawMessage(`{"id":1,"user_id":7}`)},
+		{ID: "c", Type: entity.EventOrderUpdated, Payload: json.RawMessage(`{"id":1,"user_id":7}`)},
+		{ID: "d", Type: entity.EventOrderStatusChanged, Payload: json.RawMessage(`{"order_id":1,"user_id":7,"old_status":"pending","new_status":"paid"}`)},
+	}
+	for _, event := range events {
+		if err := sink.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range events[1:] {
+		if got, _ := receive(t, subscriber); got.ID != want.ID || got.Type != want.Type || got.OrderID != 1 || got.UserID != 7 || string(got.Data) != string(want.Payload) {
+			t.Errorf("received %+v, want order event %s", got, want.ID)
+		}
+	}
+
+	cancel()
+	<-done
+	if _, ok := receive(t, subscriber); ok {
+		t.Error("streams not ended once the sink stopped")
+	}
+}
+
+func TestSinkFansOutThroughRedisToEveryInstance(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// two instances of the service, only the first one relays the outbox
+	first, second := NewHub(10), NewHub(10)
+	relaying := NewSink(first, client)
+	subscribers := []*Subscriber{}
+	for _, hub := range []*Hub{first, second} {
+		sink := NewSink(hub, client)
+		go func() { _ = sink.Run(ctx) }()
+		subscriber, _ := hub.Subscribe("", 0)
+		subscribers = append(subscribers, subscriber)
+	}
+	deadline := time.Now().Add(time.Second)
+	for server.PubSubNumSub(Channel)[Channel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("instances not subscribed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	event := entity.Event{ID: "a", Type: entity.EventOrderCreated, Payload: json.RawMessage(`{"id":1,"user_id":7}`)}
+	if err := relaying.Publish(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	for i, subscriber := range subscribers {
+		if got, _ := receive(t, subscriber); got.ID != "a" || got.UserID != 7 || string(got.Data) != `{"id":1,"user_id":7}` {
+			t.Errorf("instance %d received %+v, want event a", i+1, got)
+		}
+	}
+	if _, missed := second.Subscribe("0", 0); len(missed) != 1 {
+		t.Errorf("buffer of the second instance = %v, want event a", ids(missed))
+	}
+}
@@ -1,11 +1,29 @@
 package template
 
 type ResponseHTTP struct {
-	Status  int         `json:"status"`
-	Message string      `json:"message"`
-	Error   error       `json:"error,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Page    interface{} `json:"page,omitempty"`
+	Status    int         `json:"status"`
+	Message   string      `json:"message"`
+	Error     error       `json:"error,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Page      interface{} `json:"page,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ResponseV2 is the envelope of the v2 API, Error is set when the HTTP status is an error
+type ResponseV2 struct {
+	Data      interface{} `json:"data"`
+	Page      interface{} `json:"page,omitempty"`
+	Error     *ErrorV2    `json:"error,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ErrorV2 is the error of a ResponseV2, Code is the HTTP status in snake case (not_found) and Details
+// the cause of a client error
+type ErrorV2 struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
 }
 
 type PagePagination struct {
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+// BulkBatchSize is the number of operations executed per database transaction
+const BulkBatchSize = 100
+
+var errBulkAborted = errors.New("bulk aborted")
+
+// runBulk executes every operation through exec and reports one result per operation.
+//
+// In atomic mode all batches share one transaction and the first failure rolls
+// everything back. Otherwise each batch gets its own transaction and every
+// operation runs in a savepoint, so a failing operation only undoes itself.
+// afterCommit is called once for each committed transaction that changed data.
+func runBulk(
+	ctx context.Context,
+	transactor repository.Transactor,
+	results []entity.BulkResult,
+	atomic bool,
+	exec func(ctx context.Context, i int) (int, error),
+	afterCommit func(ctx context.Context),
+) {
+	runBatch := func(ctx context.Context, from, to int) bool {
+		for i := from; i < to; i++ {
+			var id int
+			var err error
+			if atomic {
+				id, err = exec(ctx, i)
+			} else {
+				err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					id, err = exec(ctx, i)
+					return err
+				})
+			}
+			if id > 0 {
+				results[i].ID = id
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+				if atomic {
+					return false
+				}
+				continue
+			}
+			results[i].Success = true
+		}
+		return true
+	}
+
+	if atomic {
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			for from := 0; from < len(results); from += BulkBatchSize {
+				if !runBatch(ctx, from, minInt(from+BulkBatchSize, len(results))) {
+					return errBulkAborted
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			markRolledBack(results, 0, len(results), err)
+			return
+		}
+		if len(results) > 0 {
+			afterCommit(ctx)
+		}
+		return
+	}
+
+	for from := 0; from < len(results); from += BulkBatchSize {
+		to := minInt(from+BulkBatchSize, len(results))
+		changed := false
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			runBatch(ctx, from, to)
+			for i := from; i < to; i++ {
+				changed = changed || results[i].Success
+			}
+			return nil
+		})
+		if err != nil {
+			markRolledBack(results, from, to, err)
+			continue
+		}
+		if changed {
+			afterCommit(ctx)
+		}
+	}
+}
+
+// countCreated counts the committed create operations
+func countCreated(results []entity.BulkResult) int {
+	count := 0
+	for _, result := range results {
+		if result.Op == entity.BulkCreate && result.Success {
+			count++
+		}
+	}
+	return count
+}
+
+// markRolledBack flags results of a transaction that did not commit
+func markRolledBack(results []entity.BulkResult, from, to int, err error) {
+	for i := from; i < to; i++ {
+		results[i].Success = false
+		if results[i].Op == entity.BulkCreate {
+			results[i].ID = 0
+		}
+		if results[i].Error != "" {
+			continue
+		}
+		if errors.Is(err, errBulkAborted) {
+			results[i].Error = "rolled back"
+		} else {
+			results[i].Error = "rolled back: " + err.Error()
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
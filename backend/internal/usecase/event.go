@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+// record writes a domain event to the outbox with the context of the change, so both are
+// committed or rolled back together. The relay publishes it once committed.
+func record(ctx context.Context, outboxRepo repository.OutboxRepository, eventType string, aggregateID int, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return outboxRepo.Add(ctx, &entity.OutboxEvent{
+		EventID:     uuid.NewString(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Payload:     string(data),
+		OccurredAt:  time.Now(),
+	})
+}
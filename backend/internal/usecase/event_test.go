@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+// failingOutbox refuses every event, like an outbox table that cannot be written
+type failingOutbox struct {
+	repository.OutboxRepository
+}
+
+func (failingOutbox) Add(context.Context, ...*entity.OutboxEvent) error {
+	return errors.New("outbox down")
+}
+
+// events returns the events waiting in the outbox of f, oldest first
+func (f *fixture) events(t *testing.T) []*entity.OutboxEvent {
+	t.Helper()
+	if _, err := f.outbox.Sequence(context.Background(), 100); err != nil {
+		t.Fatal(err)
+	}
+	events, err := f.outbox.After(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func eventTypes(events []*entity.OutboxEvent) []string {
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func equalTypes(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUseCasesRecordEvents(t *testing.T) {
+	f := newFixture(t)
+	users := NewUserUseCase(f.users, f.outbox, f.transactor)
+	orderItems := NewOrderItemUseCase(f.orderItems, f.outbox, f.transactor, f.loader(f.cache))
+	orderHistories := NewOrderHistoryUseCase(f.orderHistories, f.orderItems, f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+
+	user, err := users.Create(ctx, "Ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderItem := f.orderItem(t, 100)
+	if err := orderItems.Update(ctx, &entity.OrderItem{ID: orderItem.ID, Name: "Tea", Price: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orderItems.Patch(ctx, orderItem.ID, map[string]interface{}{"price": 120}); err != nil {
+		t.Fatal(err)
+	}
+	orderHistory, err := orderHistories.Create(ctx, user.ID, orderItem.ID, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orderHistories.Update(ctx, orderHistory.ID, user.ID, orderItem.ID, "updated"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orderHistories.Patch(ctx, orderHistory.ID, map[string]interface{}{"descriptions": "patched"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orderHistories.Patch(ctx, orderHistory.ID, map[string]interface{}{"status": entity.OrderPaid}); err != nil {
+		t.Fatal(err)
+	}
+	// the same status again is no change
+	if _, err := orderHistories.Patch(ctx, orderHistory.ID, map[string]interface{}{"status": entity.OrderPaid}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	events := f.events(t)
+	if got := eventTypes(events); !equalTypes(got, entity.EventUserCreated, entity.EventOrderItemPriceChanged, entity.EventOrderCreated,
+		entity.EventOrderUpdated, entity.EventOrderUpdated, entity.EventOrderUpdated, entity.EventOrderStatusChanged, entity.EventOrderUpdated,
+		entity.EventUserDeleted) {
+		t.Fatalf("events = %v, want no event for the updates keeping the price or the status", got)
+	}
+	if events[0].EventID == "" || events[0].EventID == events[1].EventID {
+		t.Errorf("event IDs %q and %q, want unique IDs", events[0].EventID, events[1].EventID)
+	}
+	var priceChanged entity.OrderItemPriceChangedEvent
+	if err := json.Unmarshal([]byte(events[1].Payload), &priceChanged); err != nil {
+		t.Fatal(err)
+	}
+	if priceChanged != (entity.OrderItemPriceChangedEvent{OrderItemID: orderItem.ID, OldPrice: 100, NewPrice: 120}) {
+		t.Errorf("OrderItemPriceChanged = %+v", priceChanged)
+	}
+	var order entity.OrderEvent
+	if err := json.Unmarshal([]byte(events[2].Payload), &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.UserID != user.ID || order.Price == nil || *order.Price != 120 || order.Status != entity.OrderPending || events[2].AggregateID != order.ID {
+		t.Errorf("OrderCreated = %+v on aggregate %d", order, events[2].AggregateID)
+	}
+	var patched entity.OrderEvent
+	if err := json.Unmarshal([]byte(events[4].Payload), &patched); err != nil {
+		t.Fatal(err)
+	}
+	if patched.ID != order.ID || patched.Descriptions != "patched" || patched.UserID != user.ID {
+		t.Errorf("OrderUpdated = %+v, want the patched row", patched)
+	}
+	var statusChanged entity.OrderStatusChangedEvent
+	if err := json.Unmarshal([]byte(events[6].Payload), &statusChanged); err != nil {
+		t.Fatal(err)
+	}
+	want := entity.OrderStatusChangedEvent{OrderID: order.ID, UserID: order.UserID, OldStatus: entity.OrderPending, NewStatus: entity.OrderPaid}
+	if statusChanged != want || events[6].AggregateID != order.ID {
+		t.Errorf("OrderStatusChanged = %+v on aggregate %d, want %+v", statusChanged, events[6].AggregateID, want)
+	}
+}
+
+func TestUseCasesRollBackWithTheirEvents(t *testing.T) {
+	f := newFixture(t)
+	outbox := failingOutbox{f.outbox}
+	users := NewUserUseCase(f.users, outbox, f.transactor)
+	orderItems := NewOrderItemUseCase(f.orderItems, outbox, f.transactor, f.loader(f.cache))
+	ctx := context.Background()
+
+	if _, err := users.Create(ctx, "Ann"); err == nil {
+		t.Error("Create succeeded without its event")
+	}
+	if count := f.users.CountData(ctx); count != 0 {
+		t.Errorf("users = %d, want the user rolled back with its event", count)
+	}
+
+	orderItem := f.orderItem(t, 100)
+	if _, err := orderItems.Patch(ctx, orderItem.ID, map[string]interface{}{"price": 120}); err == nil {
+		t.Error("Patch succeeded without its event")
+	}
+	if got, _ := f.orderItems.GetByID(ctx, orderItem.ID); got.Price != 100 {
+		t.Errorf("price = %d, want the change rolled back with its event", got.Price)
+	}
+
+	user := f.user(t, "Bob")
+	orderHistory, err := f.orderHistories.Create(ctx, &entity.OrderHistory{UserID: user.ID, OrderItemID: orderItem.ID, Descriptions: "order"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderHistories := NewOrderHistoryUseCase(f.orderHistories, f.orderItems, f.users, outbox, f.transactor)
+	if err := orderHistories.Update(ctx, orderHistory.ID, user.ID, orderItem.ID, "updated"); err == nil {
+		t.Error("Update succeeded without its event")
+	}
+	if _, err := orderHistories.Patch(ctx, orderHistory.ID, map[string]interface{}{"descriptions": "patched"}); err == nil {
+		t.Error("Patch of an order succeeded without its event")
+	}
+	if got, _ := f.orderHistories.GetByID(ctx, orderHistory.ID); got.Descriptions != "order" {
+		t.Errorf("descriptions = %q, want the changes rolled back with their events", got.Descriptions)
+	}
+}
@@ -6,33 +6,56 @@ import (
 	"time"
 
 	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/metrics"
 	"test-crud-user-orders/internal/repository"
 )
 
 type OrderHistoryUseCase interface {
 	Create(ctx context.Context, userID int, orderItemID int, descriptions string) (*entity.OrderHistory, error)
 	Update(ctx context.Context, id int, userID int, orderItemID int, descriptions string) error
+	Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.OrderHistory, error)
 	GetByID(ctx context.Context, id int) (*entity.OrderHistory, error)
+	GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error)
 	GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.OrderHistory, error)
 	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderHistory, error)
 	CountData(ctx context.Context, userId int) int64
+	Bulk(ctx context.Context, ops []entity.BulkOrderHistory, atomic bool) []entity.BulkResult
+	Export(ctx context.Context, filter entity.OrderHistoryFilter, fn func(row *entity.OrderHistoryExport) error) error
 }
 
 type orderHistoryUseCase struct {
 	orderHistoryRepo repository.OrderHistoryRepository
 	orderItemRepo    repository.OrderItemRepository
 	userRepo         repository.UserRepository
+	outboxRepo       repository.OutboxRepository
+	transactor       repository.Transactor
 }
 
 func NewOrderHistoryUseCase(
 	orderHistory repository.OrderHistoryRepository,
 	orderItem repository.OrderItemRepository,
 	user repository.UserRepository,
+	outbox repository.OutboxRepository,
+	transactor repository.Transactor,
 ) OrderHistoryUseCase {
-	return &orderHistoryUseCase{orderHistory, orderItem, user}
+	return &orderHistoryUseCase{orderHistory, orderItem, user, outbox, transactor}
 }
 
 func (uc *orderHistoryUseCase) Create(ctx context.Context, userID int, orderItemID int, descriptions string) (*entity.OrderHistory, error) {
+	var orderHistory *entity.OrderHistory
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		orderHistory, err = uc.create(ctx, userID, orderItemID, descriptions)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.OrdersCreated.Inc()
+	return orderHistory, nil
+}
+
+// create writes the Order History and its OrderCreated event with ctx, which must carry a transaction
+func (uc *orderHistoryUseCase) create(ctx context.Context, userID int, orderItemID int, descriptions string) (*entity.OrderHistory, error) {
 	userData, errUser := uc.userRepo.GetByID(ctx, userID)
 	if errUser != nil {
 		return nil, errors.New("user not found")
@@ -46,14 +69,30 @@ func (uc *orderHistoryUseCase) Create(ctx context.Context, userID int, orderItem
 		UserID:       userID,
 		OrderItemID:  orderItemID,
 		Descriptions: descriptions,
+		Price:        &orderItemData.Price,
+		Status:       entity.OrderPending,
 		CreatedAt:    time.Now(),
 		User:         userData,
 		OrderItem:    orderItemData,
 	}
-	return uc.orderHistoryRepo.Create(ctx, orderHistory)
+	orderHistory, err := uc.orderHistoryRepo.Create(ctx, orderHistory)
+	if err != nil {
+		return nil, err
+	}
+	if err := record(ctx, uc.outboxRepo, entity.EventOrderCreated, orderHistory.ID, entity.NewOrderEvent(orderHistory)); err != nil {
+		return nil, err
+	}
+	return orderHistory, nil
 }
 
 func (uc *orderHistoryUseCase) Update(ctx context.Context, id int, userID int, orderItemID int, descriptions string) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return uc.update(ctx, id, userID, orderItemID, descriptions)
+	})
+}
+
+// update writes the Order History and its OrderUpdated event with ctx, which must carry a transaction
+func (uc *orderHistoryUseCase) update(ctx context.Context, id int, userID int, orderItemID int, descriptions string) error {
 	orderHistory, err := uc.orderHistoryRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -61,10 +100,83 @@ func (uc *orderHistoryUseCase) Update(ctx context.Context, id int, userID int, o
 	if orderHistory == nil {
 		return errors.New("order history not found")
 	}
+	// The preloaded relations would otherwise write their old IDs back on save
+	if orderHistory.UserID != userID {
+		orderHistory.User = nil
+	}
+	if orderHistory.OrderItemID != orderItemID {
+		orderItemData, err := uc.orderItemRepo.GetByID(ctx, orderItemID)
+		if err != nil {
+			return errors.New("order item data not found")
+		}
+		// Snapshot the price of the newly ordered item
+		orderHistory.OrderItem = orderItemData
+		orderHistory.Price = &orderItemData.Price
+	}
 	orderHistory.UserID = userID
 	orderHistory.OrderItemID = orderItemID
 	orderHistory.Descriptions = descriptions
-	return uc.orderHistoryRepo.Update(ctx, orderHistory)
+	if err := uc.orderHistoryRepo.Update(ctx, orderHistory); err != nil {
+		return err
+	}
+	return record(ctx, uc.outboxRepo, entity.EventOrderUpdated, id, entity.NewOrderEvent(orderHistory))
+}
+
+// Patch updates only the supplied columns of an Order History and returns the fresh row,
+// a new status is also recorded as OrderStatusChanged
+func (uc *orderHistoryUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.OrderHistory, error) {
+	orderHistory, err := uc.orderHistoryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderHistory == nil {
+		return nil, errors.New("order history not found")
+	}
+	if len(fields) == 0 {
+		return orderHistory, nil
+	}
+
+	// Related data must still exist (not soft-deleted) when it is being changed
+	if userID, ok := fields["user_id"].(int); ok {
+		if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+			return nil, errors.New("user data not found")
+		}
+	}
+	if orderItemID, ok := fields["order_item_id"].(int); ok && orderItemID != orderHistory.OrderItemID {
+		orderItemData, err := uc.orderItemRepo.GetByID(ctx, orderItemID)
+		if err != nil {
+			return nil, errors.New("order item data not found")
+		}
+		// Snapshot the price of the newly ordered item
+		fields["price"] = orderItemData.Price
+	}
+
+	oldStatus := orderHistory.Status
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.orderHistoryRepo.UpdateFields(ctx, id, fields); err != nil {
+			return err
+		}
+		orderHistory, err = uc.orderHistoryRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := record(ctx, uc.outboxRepo, entity.EventOrderUpdated, id, entity.NewOrderEvent(orderHistory)); err != nil {
+			return err
+		}
+		if orderHistory.Status == oldStatus {
+			return nil
+		}
+		return record(ctx, uc.outboxRepo, entity.EventOrderStatusChanged, id, entity.OrderStatusChangedEvent{
+			OrderID:   id,
+			UserID:    orderHistory.UserID,
+			OldStatus: oldStatus,
+			NewStatus: orderHistory.Status,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderHistory, nil
 }
 
 func (uc *orderHistoryUseCase) GetByID(ctx context.Context, id int) (*entity.OrderHistory, error) {
@@ -75,10 +187,47 @@ func (uc *orderHistoryUseCase) GetByUserID(ctx context.Context, userID, limit, o
 	return uc.orderHistoryRepo.GetByUserID(ctx, userID, limit, offset)
 }
 
+// GetByUserIDs reads the first limit Order Histories of many Users at once, without their relations
+func (uc *orderHistoryUseCase) GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error) {
+	return uc.orderHistoryRepo.GetByUserIDs(ctx, userIDs, limit)
+}
+
 func (uc *orderHistoryUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderHistory, error) {
 	return uc.orderHistoryRepo.GetAllPagination(ctx, limit, offset)
 }
 
+func (uc *orderHistoryUseCase) Export(ctx context.Context, filter entity.OrderHistoryFilter, fn func(row *entity.OrderHistoryExport) error) error {
+	return uc.orderHistoryRepo.Export(ctx, filter, fn)
+}
+
 func (uc *orderHistoryUseCase) CountData(ctx context.Context, userId int) int64 {
 	return uc.orderHistoryRepo.CountData(ctx, userId)
 }
+
+// Bulk executes create and update operations of Order Histories in batches, deleting stays forbidden
+func (uc *orderHistoryUseCase) Bulk(ctx context.Context, ops []entity.BulkOrderHistory, atomic bool) []entity.BulkResult {
+	results := make([]entity.BulkResult, len(ops))
+	for i, op := range ops {
+		results[i] = entity.BulkResult{Index: op.Index, Op: op.Op, ID: op.ID}
+	}
+
+	runBulk(ctx, uc.transactor, results, atomic, func(ctx context.Context, i int) (int, error) {
+		op := ops[i]
+		switch op.Op {
+		case entity.BulkCreate:
+			orderHistory, err := uc.create(ctx, op.UserID, op.OrderItemID, op.Descriptions)
+			if err != nil {
+				return 0, err
+			}
+			return orderHistory.ID, nil
+		case entity.BulkUpdate:
+			return op.ID, uc.update(ctx, op.ID, op.UserID, op.OrderItemID, op.Descriptions)
+		case entity.BulkDelete:
+			return op.ID, errors.New("delete transaction not allowed")
+		}
+		return 0, errors.New("unknown operation")
+	}, func(ctx context.Context) {})
+
+	metrics.OrdersCreated.Add(float64(countCreated(results)))
+	return results
+}
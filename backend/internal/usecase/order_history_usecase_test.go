@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+func TestOrderHistoryUseCaseSnapshotsThePrice(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderHistoryUseCase(f.orderHistories, f.orderItems, f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+	user := f.user(t, "Ann")
+	tea, coffee := f.orderItem(t, 100), f.orderItem(t, 200)
+
+	orderHistory, err := uc.Create(ctx, user.ID, tea.ID, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orderHistory.Price == nil || *orderHistory.Price != 100 || orderHistory.User == nil || orderHistory.OrderItem == nil {
+		t.Fatalf("created order history = %+v, want the price and relations of the order", orderHistory)
+	}
+
+	// a later price change of the item does not change the order
+	if err := f.orderItems.UpdateFields(ctx, tea.ID, map[string]interface{}{"price": 300}); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.Update(ctx, orderHistory.ID, user.ID, tea.ID, "same item"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := uc.GetByID(ctx, orderHistory.ID); *got.Price != 100 || got.Descriptions != "same item" {
+		t.Errorf("order history = %+v, want the price kept while the item is unchanged", got)
+	}
+
+	if err := uc.Update(ctx, orderHistory.ID, user.ID, coffee.ID, "other item"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := uc.GetByID(ctx, orderHistory.ID); *got.Price != 200 {
+		t.Errorf("price = %d, want the one of the new item", *got.Price)
+	}
+
+	patched, err := uc.Patch(ctx, orderHistory.ID, map[string]interface{}{"order_item_id": tea.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *patched.Price != 300 || patched.OrderItemID != tea.ID {
+		t.Errorf("patched order history = %+v, want the current price of the item", patched)
+	}
+}
+
+func TestOrderHistoryUseCaseErrors(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderHistoryUseCase(f.orderHistories, f.orderItems, f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+	user, deletedUser := f.user(t, "Ann"), f.user(t, "Bob")
+	orderItem, deletedItem := f.orderItem(t, 100), f.orderItem(t, 200)
+	orderHistory, err := uc.Create(ctx, user.ID, orderItem.ID, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.users.SoftDelete(ctx, deletedUser.ID)
+	_ = f.orderItems.SoftDelete(ctx, deletedItem.ID)
+
+	patch := func(id int, fields map[string]interface{}) error {
+		_, err := uc.Patch(ctx, id, fields)
+		return err
+	}
+	create := func(userID, orderItemID int) error {
+		_, err := uc.Create(ctx, userID, orderItemID, "x")
+		return err
+	}
+	for _, test := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Create of a deleted user", create(deletedUser.ID, orderItem.ID), "user not found"},
+		{"Create of a deleted item", create(user.ID, deletedItem.ID), "order item not found"},
+		{"Update of a missing order", uc.Update(ctx, 999, user.ID, orderItem.ID, "x"), "record not found"},
+		{"Update to a deleted user", uc.Update(ctx, orderHistory.ID, deletedUser.ID, orderItem.ID, "x"), "user data not found"},
+		{"Update to a deleted item", uc.Update(ctx, orderHistory.ID, user.ID, deletedItem.ID, "x"), "order item data not found"},
+		{"Patch of a missing order", patch(999, map[string]interface{}{"descriptions": "x"}), "record not found"},
+		{"Patch to a deleted user", patch(orderHistory.ID, map[string]interface{}{"user_id": deletedUser.ID}), "user data not found"},
+		{"Patch to a deleted item", patch(orderHistory.ID, map[string]interface{}{"order_item_id": deletedItem.ID}), "order item data not found"},
+	} {
+		if test.err == nil || test.err.Error() != test.want {
+			t.Errorf("%s = %v, want %s", test.name, test.err, test.want)
+		}
+	}
+}
+
+func TestOrderHistoryUseCaseBulkRefusesDeletes(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderHistoryUseCase(f.orderHistories, f.orderItems, f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+	user := f.user(t, "Ann")
+	orderItem := f.orderItem(t, 100)
+
+	results := uc.Bulk(ctx, []entity.BulkOrderHistory{
+		{Index: 0, Op: entity.BulkCreate, UserID: user.ID, OrderItemID: orderItem.ID, Descriptions: "a"},
+		{Index: 1, Op: entity.BulkDelete, ID: 1},
+		{Index: 2, Op: entity.BulkCreate, UserID: 999, OrderItemID: orderItem.ID, Descriptions: "b"},
+	}, false)
+
+	want := []string{"ok", "delete transaction not allowed", "user not found"}
+	for i, result := range results {
+		if errorOf(result) != want[i] {
+			t.Errorf("operation %d = %q, want %q", i, errorOf(result), want[i])
+		}
+	}
+	if count := uc.CountData(ctx, user.ID); count != 1 {
+		t.Errorf("CountData = %d, want the one created order", count)
+	}
+}
@@ -4,41 +4,82 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/redis/go-redis/v9"
+	"test-crud-user-orders/internal/cache"
 	"test-crud-user-orders/internal/entity"
 	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/pkg/logger"
 )
 
 type OrderItemUseCase interface {
 	GetByID(ctx context.Context, id int) (*entity.OrderItem, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error)
 	Create(ctx context.Context, orderItem *entity.OrderItem) error
 	Update(ctx context.Context, orderItem *entity.OrderItem) error
+	Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.OrderItem, error)
 	Delete(ctx context.Context, id int) error
 	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderItem, error)
 	CountData(ctx context.Context) int64
+	Bulk(ctx context.Context, ops []entity.BulkOrderItem, atomic bool) []entity.BulkResult
 }
 
 type orderItemUseCase struct {
 	orderItemRepo repository.OrderItemRepository
-	redisClient   *redis.Client
+	outboxRepo    repository.OutboxRepository
+	transactor    repository.Transactor
+	cache         *cache.Loader
 }
 
-func NewOrderItemUseCase(orderItemRepo repository.OrderItemRepository, redisClient *redis.Client) OrderItemUseCase {
+// orderItemsCachePrefix starts the key of every cached page of Order Items, they are removed after every change
+const orderItemsCachePrefix = "order_items:"
+
+func NewOrderItemUseCase(orderItemRepo repository.OrderItemRepository, outboxRepo repository.OutboxRepository, transactor repository.Transactor, cache *cache.Loader) OrderItemUseCase {
 	return &orderItemUseCase{
 		orderItemRepo: orderItemRepo,
-		redisClient:   redisClient,
+		outboxRepo:    outboxRepo,
+		transactor:    transactor,
+		cache:         cache,
 	}
 }
 
+// GetAllPagination reads the page through the cache, a hot page expiring is recomputed once
 func (uc *orderItemUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderItem, error) {
-	return uc.orderItemRepo.GetAllPagination(ctx, limit, offset)
+	key := fmt.Sprintf("%spage:%d:%d", orderItemsCachePrefix, limit, offset)
+	data, err := uc.cache.Load(ctx, key, func(ctx context.Context) ([]byte, error) {
+		orderItems, err := uc.orderItemRepo.GetAllPagination(ctx, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		return entity.MarshalOrderItems(orderItems)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var orderItems []*entity.OrderItem
+	if err := entity.UnmarshalOrderItems(data, &orderItems); err != nil {
+		return nil, err
+	}
+	return orderItems, nil
 }
 
 func (uc *orderItemUseCase) GetByID(ctx context.Context, id int) (*entity.OrderItem, error) {
 	return uc.orderItemRepo.GetByID(ctx, id)
 }
 
+// GetByIDs reads many Order Items at once, soft-deleted ones included, for the relations of Order Histories
+func (uc *orderItemUseCase) GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error) {
+	return uc.orderItemRepo.GetByIDs(ctx, ids)
+}
+
 func (uc *orderItemUseCase) Create(ctx context.Context, orderItem *entity.OrderItem) error {
+	if err := uc.create(ctx, orderItem); err != nil {
+		return err
+	}
+	uc.invalidateCache(ctx)
+	return nil
+}
+
+func (uc *orderItemUseCase) create(ctx context.Context, orderItem *entity.OrderItem) error {
 	if err := uc.orderItemRepo.Create(ctx, orderItem); err != nil {
 		return fmt.Errorf("error creating order item: %s", err.Error())
 	}
@@ -46,6 +87,18 @@ func (uc *orderItemUseCase) Create(ctx context.Context, orderItem *entity.OrderI
 }
 
 func (uc *orderItemUseCase) Update(ctx context.Context, orderItem *entity.OrderItem) error {
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return uc.update(ctx, orderItem)
+	})
+	if err != nil {
+		return err
+	}
+	uc.invalidateCache(ctx)
+	return nil
+}
+
+// update writes the Order Item and its OrderItemPriceChanged event with ctx, which must carry a transaction
+func (uc *orderItemUseCase) update(ctx context.Context, orderItem *entity.OrderItem) error {
 	orderItemDB, err := uc.orderItemRepo.GetByID(ctx, orderItem.ID)
 	if err != nil {
 		return err
@@ -54,6 +107,7 @@ func (uc *orderItemUseCase) Update(ctx context.Context, orderItem *entity.OrderI
 		return errors.New("order item not found")
 	}
 
+	oldPrice := orderItemDB.Price
 	orderItemDB.Name = orderItem.Name
 	orderItemDB.Price = orderItem.Price
 	orderItemDB.ExpiredAt = orderItem.ExpiredAt
@@ -61,21 +115,60 @@ func (uc *orderItemUseCase) Update(ctx context.Context, orderItem *entity.OrderI
 	if err := uc.orderItemRepo.Update(ctx, orderItemDB); err != nil {
 		return fmt.Errorf("error updating order item with ID %d: %s", orderItem.ID, err.Error())
 	}
+	return uc.recordPriceChange(ctx, orderItem.ID, oldPrice, orderItem.Price)
+}
+
+// recordPriceChange records OrderItemPriceChanged when the price of an Order Item changed
+func (uc *orderItemUseCase) recordPriceChange(ctx context.Context, id, oldPrice, newPrice int) error {
+	if newPrice == oldPrice {
+		return nil
+	}
+	return record(ctx, uc.outboxRepo, entity.EventOrderItemPriceChanged, id, entity.OrderItemPriceChangedEvent{
+		OrderItemID: id,
+		OldPrice:    oldPrice,
+		NewPrice:    newPrice,
+	})
+}
+
+// Patch updates only the supplied columns of an Order Item and returns the fresh row
+func (uc *orderItemUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.OrderItem, error) {
+	orderItemDB, err := uc.orderItemRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderItemDB == nil {
+		return nil, errors.New("order item not found")
+	}
+	if len(fields) == 0 {
+		return orderItemDB, nil
+	}
 
-	// Check Redis Connection with method Ping()
-	_, err = uc.redisClient.Ping(ctx).Result()
-	if err == nil {
-		// Delete Redis Data of this ID
-		key := "order_items:all"
-		if err := uc.redisClient.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("error deleting data from Redis cache: %s", err.Error())
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.orderItemRepo.UpdateFields(ctx, id, fields); err != nil {
+			return fmt.Errorf("error updating order item with ID %d: %s", id, err.Error())
 		}
+		if price, ok := fields["price"].(int); ok {
+			return uc.recordPriceChange(ctx, id, orderItemDB.Price, price)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	uc.invalidateCache(ctx)
 
-	return nil
+	return uc.orderItemRepo.GetByID(ctx, id)
 }
 
 func (uc *orderItemUseCase) Delete(ctx context.Context, id int) error {
+	if err := uc.delete(ctx, id); err != nil {
+		return err
+	}
+	uc.invalidateCache(ctx)
+	return nil
+}
+
+func (uc *orderItemUseCase) delete(ctx context.Context, id int) error {
 	orderItemDB, err := uc.orderItemRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -87,18 +180,42 @@ func (uc *orderItemUseCase) Delete(ctx context.Context, id int) error {
 	if err := uc.orderItemRepo.SoftDelete(ctx, id); err != nil {
 		return fmt.Errorf("error deleting order item with ID %d: %s", id, err.Error())
 	}
+	return nil
+}
 
-	// Check Redis Connection with method Ping()
-	_, err = uc.redisClient.Ping(ctx).Result()
-	if err == nil {
-		// Delete the cached data since it has been deleted
-		key := "order_items:all"
-		if err := uc.redisClient.Del(ctx, key).Err(); err != nil {
-			return fmt.Errorf("error deleting data from Redis cache: %s", err.Error())
-		}
+// Bulk executes create, update and delete operations of Order Items in batches,
+// the cache is invalidated once per committed batch instead of once per item
+func (uc *orderItemUseCase) Bulk(ctx context.Context, ops []entity.BulkOrderItem, atomic bool) []entity.BulkResult {
+	results := make([]entity.BulkResult, len(ops))
+	for i, op := range ops {
+		results[i] = entity.BulkResult{Index: op.Index, Op: op.Op, ID: op.OrderItem.ID}
 	}
 
-	return nil
+	runBulk(ctx, uc.transactor, results, atomic, func(ctx context.Context, i int) (int, error) {
+		orderItem := ops[i].OrderItem
+		switch ops[i].Op {
+		case entity.BulkCreate:
+			if err := uc.create(ctx, &orderItem); err != nil {
+				return 0, err
+			}
+			return orderItem.ID, nil
+		case entity.BulkUpdate:
+			return orderItem.ID, uc.update(ctx, &orderItem)
+		case entity.BulkDelete:
+			return orderItem.ID, uc.delete(ctx, orderItem.ID)
+		}
+		return 0, errors.New("unknown operation")
+	}, uc.invalidateCache)
+
+	return results
+}
+
+// invalidateCache removes the cached pages of Order Items after a change. The change is already
+// committed, so a Cache that fails is only logged: a stale page lives until its TTL.
+func (uc *orderItemUseCase) invalidateCache(ctx context.Context) {
+	if err := uc.cache.DeleteByPrefix(ctx, orderItemsCachePrefix); err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("order item cache not invalidated")
+	}
 }
 
 func (uc *orderItemUseCase) CountData(ctx context.Context) int64 {
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+// pageKey is the cache key of the first page of 10 Order Items
+const pageKey = orderItemsCachePrefix + "page:10:0"
+
+func TestOrderItemUseCaseCachesPages(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderItemUseCase(f.orderItems, f.outbox, f.transactor, f.loader(f.cache))
+	ctx := context.Background()
+	f.orderItem(t, 100)
+
+	if orderItems, err := uc.GetAllPagination(ctx, 10, 0); err != nil || len(orderItems) != 1 {
+		t.Fatalf("GetAllPagination = %+v, %v", orderItems, err)
+	}
+	// written behind the back of the UseCase, the cached page is served
+	f.orderItem(t, 200)
+	if orderItems, _ := uc.GetAllPagination(ctx, 10, 0); len(orderItems) != 1 || orderItems[0].Price != 100 {
+		t.Errorf("cached page = %+v, want the first Order Item only", orderItems)
+	}
+
+	if err := uc.Create(ctx, &entity.OrderItem{Name: "Cake", Price: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if orderItems, _ := uc.GetAllPagination(ctx, 10, 0); len(orderItems) != 3 {
+		t.Errorf("page after Create = %+v, want the 3 Order Items", orderItems)
+	}
+
+	f.store.FailWith(errors.New("database down"))
+	if _, err := uc.GetAllPagination(ctx, 10, 10); err == nil {
+		t.Error("GetAllPagination of an uncached page with the database down succeeded")
+	}
+}
+
+func TestOrderItemUseCaseInvalidatesTheCache(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderItemUseCase(f.orderItems, f.outbox, f.transactor, f.loader(f.cache))
+	ctx := context.Background()
+
+	orderItem := &entity.OrderItem{Name: "Tea", Price: 100}
+	if err := uc.Create(ctx, orderItem); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		name   string
+		change func() error
+	}{
+		{"Update", func() error {
+			return uc.Update(ctx, &entity.OrderItem{ID: orderItem.ID, Name: "Green Tea", Price: 120})
+		}},
+		{"Patch", func() error {
+			_, err := uc.Patch(ctx, orderItem.ID, map[string]interface{}{"price": 150})
+			return err
+		}},
+		{"Delete", func() error { return uc.Delete(ctx, orderItem.ID) }},
+	} {
+		_ = f.cache.Set(ctx, pageKey, []byte("[]"), 0)
+		if err := step.change(); err != nil {
+			t.Fatalf("%s = %v", step.name, err)
+		}
+		if f.cached(pageKey) {
+			t.Errorf("%s left the cached list behind", step.name)
+		}
+	}
+
+	// a cache that fails does not fail the change
+	uc = NewOrderItemUseCase(f.orderItems, f.outbox, f.transactor, f.loader(failingCache{}))
+	other := &entity.OrderItem{Name: "Coffee", Price: 200}
+	if err := uc.Create(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.Update(ctx, &entity.OrderItem{ID: other.ID, Name: "Coffee", Price: 210}); err != nil {
+		t.Errorf("Update with the cache down = %v", err)
+	}
+}
+
+func TestOrderItemUseCaseErrors(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderItemUseCase(f.orderItems, f.outbox, f.transactor, f.loader(f.cache))
+	ctx := context.Background()
+
+	for name, err := range map[string]error{
+		"Update": uc.Update(ctx, &entity.OrderItem{ID: 999, Name: "Ghost", Price: 1}),
+		"Patch":  func() error { _, err := uc.Patch(ctx, 999, map[string]interface{}{"price": 1}); return err }(),
+		"Delete": uc.Delete(ctx, 999),
+	} {
+		if err == nil || err.Error() != "record not found" {
+			t.Errorf("%s of a missing order item = %v, want record not found", name, err)
+		}
+	}
+
+	f.store.FailWith(errors.New("database down"))
+	if err := uc.Create(ctx, &entity.OrderItem{Name: "Tea", Price: 1}); err == nil || !strings.HasPrefix(err.Error(), "error creating order item") {
+		t.Errorf("Create = %v, want the failure wrapped", err)
+	}
+}
+
+func TestOrderItemUseCaseBulkInvalidatesOncePerCommit(t *testing.T) {
+	f := newFixture(t)
+	uc := NewOrderItemUseCase(f.orderItems, f.outbox, f.transactor, f.loader(f.cache))
+	ctx := context.Background()
+	existing := f.orderItem(t, 100)
+
+	_ = f.cache.Set(ctx, pageKey, []byte("[]"), 0)
+	results := uc.Bulk(ctx, []entity.BulkOrderItem{
+		{Index: 0, Op: entity.BulkCreate, OrderItem: entity.OrderItem{Name: "Tea", Price: 10}},
+		{Index: 1, Op: entity.BulkUpdate, OrderItem: entity.OrderItem{ID: 999, Name: "Ghost", Price: 1}},
+	}, true)
+	if errorOf(results[0]) != "rolled back" || errorOf(results[1]) != "record not found" {
+		t.Fatalf("atomic results = %+v", results)
+	}
+	if !f.cached(pageKey) {
+		t.Error("cache invalidated although nothing was committed")
+	}
+
+	results = uc.Bulk(ctx, []entity.BulkOrderItem{
+		{Index: 0, Op: entity.BulkUpdate, OrderItem: entity.OrderItem{ID: existing.ID, Name: "Tea", Price: 90}},
+		{Index: 1, Op: entity.BulkDelete, OrderItem: entity.OrderItem{ID: 999}},
+	}, false)
+	if errorOf(results[0]) != "ok" || errorOf(results[1]) != "record not found" {
+		t.Fatalf("partial results = %+v", results)
+	}
+	if f.cached(pageKey) {
+		t.Error("cache kept after a committed change")
+	}
+	if orderItem, _ := f.orderItems.GetByID(ctx, existing.ID); orderItem.Price != 90 {
+		t.Errorf("price = %d, want 90", orderItem.Price)
+	}
+}
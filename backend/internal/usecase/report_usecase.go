@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type ReportUseCase interface {
+	Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error)
+	TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error)
+	TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error)
+	Cohorts(ctx context.Context, filter entity.ReportFilter) ([]*entity.Cohort, error)
+	UserStats(ctx context.Context, userID int) (*entity.UserStats, error)
+}
+
+type reportUseCase struct {
+	reportRepo repository.ReportRepository
+	userRepo   repository.UserRepository
+	cache      *cache.Loader
+}
+
+// NewReportUseCase caches every report through cache, with the policy of the "reports" namespace
+func NewReportUseCase(reportRepo repository.ReportRepository, userRepo repository.UserRepository, cache *cache.Loader) ReportUseCase {
+	return &reportUseCase{
+		reportRepo: reportRepo,
+		userRepo:   userRepo,
+		cache:      cache,
+	}
+}
+
+func (uc *reportUseCase) Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error) {
+	return cached(ctx, uc.cache, reportKey("revenue", filter), func(ctx context.Context) ([]*entity.RevenuePoint, error) {
+		return uc.reportRepo.Revenue(ctx, filter)
+	})
+}
+
+func (uc *reportUseCase) TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error) {
+	return cached(ctx, uc.cache, reportKey("top_items", filter), func(ctx context.Context) ([]*entity.TopItem, error) {
+		return uc.reportRepo.TopItems(ctx, filter)
+	})
+}
+
+func (uc *reportUseCase) TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error) {
+	return cached(ctx, uc.cache, reportKey("top_users", filter), func(ctx context.Context) ([]*entity.TopUser, error) {
+		return uc.reportRepo.TopUsers(ctx, filter)
+	})
+}
+
+// Cohorts groups Users by the month of their first order and reports, for every following
+// month up to now, the percentage of each cohort that ordered again in that month
+func (uc *reportUseCase) Cohorts(ctx context.Context, filter entity.ReportFilter) ([]*entity.Cohort, error) {
+	return cached(ctx, uc.cache, reportKey("cohorts", filter), func(ctx context.Context) ([]*entity.Cohort, error) {
+		sizes, err := uc.reportRepo.CohortSizes(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		activity, err := uc.reportRepo.CohortActivity(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return buildCohorts(sizes, activity, filter.To, time.Now()), nil
+	})
+}
+
+// buildCohorts follows the retention of every cohort up to the last month of the filter ending
+// at to, or up to now when to is later
+func buildCohorts(sizes, activity []*entity.CohortActivity, to, now time.Time) []*entity.Cohort {
+	// to is exclusive, the filter ends the day before
+	until := to.AddDate(0, 0, -1)
+	if now.Before(until) {
+		until = now
+	}
+
+	active := map[string]map[int]int64{}
+	for _, row := range activity {
+		if active[row.Cohort] == nil {
+			active[row.Cohort] = map[int]int64{}
+		}
+		active[row.Cohort][row.MonthOffset] = row.Users
+	}
+
+	cohorts := make([]*entity.Cohort, 0, len(sizes))
+	for _, size := range sizes {
+		cohort := &entity.Cohort{Cohort: size.Cohort, Users: size.Users, Retention: []entity.CohortRetention{}}
+		start, err := time.Parse("2006-01", size.Cohort)
+		if err != nil || size.Users < 1 {
+			cohorts = append(cohorts, cohort)
+			continue
+		}
+
+		months := (until.Year()-start.Year())*12 + int(until.Month()-start.Month())
+		for month := 1; month <= months; month++ {
+			users := active[size.Cohort][month]
+			cohort.Retention = append(cohort.Retention, entity.CohortRetention{
+				Month:      month,
+				Users:      users,
+				Percentage: math.Round(float64(users)*10000/float64(size.Users)) / 100,
+			})
+		}
+		cohorts = append(cohorts, cohort)
+	}
+	return cohorts
+}
+
+// UserStats returns the lifetime figures of one User, they are not cached
+func (uc *reportUseCase) UserStats(ctx context.Context, userID int) (*entity.UserStats, error) {
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	stats, err := uc.reportRepo.UserStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Orders > 1 && stats.FirstOrder != nil && stats.LastOrder != nil {
+		days := stats.LastOrder.Sub(*stats.FirstOrder).Hours() / 24 / float64(stats.Orders-1)
+		days = math.Round(days*100) / 100
+		stats.AvgDaysBetweenOrders = &days
+	}
+	return stats, nil
+}
+
+// cached reads key from c, or computes the value and caches it as JSON. compute may run in the
+// background, after the request, to refresh a stale value. A Cache that fails only costs the copy.
+func cached[T any](ctx context.Context, c *cache.Loader, key string, compute func(context.Context) (T, error)) (T, error) {
+	var value T
+	data, err := c.Load(ctx, key, func(ctx context.Context) ([]byte, error) {
+		computed, err := compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(computed)
+	})
+	if err != nil {
+		return value, err
+	}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+func reportKey(name string, filter entity.ReportFilter) string {
+	return fmt.Sprintf("reports:%s:%d:%d:%s:%d", name, filter.From.Unix(), filter.To.Unix(), filter.Granularity, filter.Limit)
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+// countingReportRepository answers fixed reports and counts the queries
+type countingReportRepository struct {
+	queries int
+	stats   entity.UserStats
+}
+
+func (r *countingReportRepository) Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error) {
+	r.queries++
+	return []*entity.RevenuePoint{{Period: "2023-01-01", Orders: 2, Revenue: 300}}, nil
+}
+
+func (r *countingReportRepository) TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error) {
+	r.queries++
+	return nil, nil
+}
+
+func (r *countingReportRepository) TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error) {
+	r.queries++
+	return nil, nil
+}
+
+func (r *countingReportRepository) CohortSizes(ctx context.Context, filter entity.ReportFilter) ([]*entity.CohortActivity, error) {
+	r.queries++
+	return nil, nil
+}
+
+func (r *countingReportRepository) CohortActivity(ctx context.Context, filter entity.ReportFilter) ([]*entity.CohortActivity, error) {
+	r.queries++
+	return nil, nil
+}
+
+func (r *countingReportRepository) UserStats(ctx context.Context, userID int) (*entity.UserStats, error) {
+	r.queries++
+	stats := r.stats
+	stats.UserID = userID
+	return &stats, nil
+}
+
+func TestReportUseCaseCachesReports(t *testing.T) {
+	f := newFixture(t)
+	repo := &countingReportRepository{}
+	uc := NewReportUseCase(repo, f.users, f.loader(f.cache))
+	ctx := context.Background()
+	filter := entity.ReportFilter{From: time.Unix(0, 0), To: time.Unix(86400, 0), Granularity: entity.GranularityDay, Limit: 10}
+
+	for i := 0; i < 2; i++ {
+		points, err := uc.Revenue(ctx, filter)
+		if err != nil || len(points) != 1 || points[0].Revenue != 300 {
+			t.Fatalf("Revenue = %+v, %v", points, err)
+		}
+	}
+	if repo.queries != 1 {
+		t.Errorf("queries = %d, want the second report read from the cache", repo.queries)
+	}
+
+	// a cache that fails only costs the copy
+	uc = NewReportUseCase(repo, f.users, f.loader(failingCache{}))
+	for i := 0; i < 2; i++ {
+		if points, err := uc.Revenue(ctx, filter); err != nil || len(points) != 1 {
+			t.Fatalf("Revenue with the cache down = %+v, %v", points, err)
+		}
+	}
+	if repo.queries != 3 {
+		t.Errorf("queries = %d, want every report computed with the cache down", repo.queries)
+	}
+}
+
+func TestReportUseCaseUserStats(t *testing.T) {
+	f := newFixture(t)
+	first, last := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 11, 0, 0, 0, 0, time.UTC)
+	repo := &countingReportRepository{stats: entity.UserStats{Orders: 3, FirstOrder: &first, LastOrder: &last}}
+	uc := NewReportUseCase(repo, f.users, f.loader(f.cache))
+	ctx := context.Background()
+
+	if _, err := uc.UserStats(ctx, 999); err == nil || err.Error() != "record not found" {
+		t.Errorf("UserStats of a missing user = %v, want record not found", err)
+	}
+
+	user := f.user(t, "Ann")
+	stats, err := uc.UserStats(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.AvgDaysBetweenOrders == nil || *stats.AvgDaysBetweenOrders != 5 {
+		t.Errorf("average days between orders = %v, want 5", stats.AvgDaysBetweenOrders)
+	}
+}
+
+func TestBuildCohortsComputesRetention(t *testing.T) {
+	sizes := []*entity.CohortActivity{{Cohort: "2023-01", Users: 4}, {Cohort: "2023-03", Users: 0}}
+	activity := []*entity.CohortActivity{{Cohort: "2023-01", MonthOffset: 2, Users: 1}}
+
+	now := time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC)
+	cohorts := buildCohorts(sizes, activity, now.AddDate(0, 0, 1), now)
+	if len(cohorts) != 2 || len(cohorts[0].Retention) != 2 || len(cohorts[1].Retention) != 0 {
+		t.Fatalf("cohorts = %+v, want two months of retention for January only", cohorts)
+	}
+	if got := cohorts[0].Retention[1]; got.Month != 2 || got.Users != 1 || got.Percentage != 25 {
+		t.Errorf("second month = %+v, want 1 user and 25%%", got)
+	}
+
+	// a filter ending on 2023-02-28 stops the retention at February
+	cohorts = buildCohorts(sizes, activity, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), now)
+	if len(cohorts[0].Retention) != 1 {
+		t.Errorf("retention up to February = %+v, want one month", cohorts[0].Retention)
+	}
+	// months to come are left out
+	cohorts = buildCohorts(sizes, activity, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), now)
+	if len(cohorts[0].Retention) != 2 {
+		t.Errorf("retention up to a later to = %+v, want it to stop at now", cohorts[0].Retention)
+	}
+}
@@ -0,0 +1,371 @@
+package usecase
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/tracing"
+)
+
+var tracer = tracing.Tracer("test-crud-user-orders/internal/usecase")
+
+// startSpan opens the span of a UseCase method, the Repository and Redis spans become its children
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name)
+}
+
+// endSpan closes span, recording err as the failure of the method
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type tracedUserUseCase struct {
+	next UserUseCase
+}
+
+// WithTracingUserUseCase wraps every method of next in a span
+func WithTracingUserUseCase(next UserUseCase) UserUseCase {
+	return &tracedUserUseCase{next}
+}
+
+func (t *tracedUserUseCase) Create(ctx context.Context, fullName string) (*entity.User, error) {
+	ctx, span := startSpan(ctx, "UserUseCase.Create")
+	result, err := t.next.Create(ctx, fullName)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedUserUseCase) Update(ctx context.Context, id int, fullName string) error {
+	ctx, span := startSpan(ctx, "UserUseCase.Update")
+	err := t.next.Update(ctx, id, fullName)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedUserUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.User, error) {
+	ctx, span := startSpan(ctx, "UserUseCase.Patch")
+	result, err := t.next.Patch(ctx, id, fields)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedUserUseCase) Delete(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "UserUseCase.Delete")
+	err := t.next.Delete(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedUserUseCase) GetByID(ctx context.Context, id int) (*entity.User, error) {
+	ctx, span := startSpan(ctx, "UserUseCase.GetByID")
+	result, err := t.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedUserUseCase) GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error) {
+	ctx, span := startSpan(ctx, "UserUseCase.GetByIDs")
+	result, err := t.next.GetByIDs(ctx, ids)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedUserUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.User, error) {
+	ctx, span := startSpan(ctx, "UserUseCase.GetAllPagination")
+	result, err := t.next.GetAllPagination(ctx, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedUserUseCase) CountData(ctx context.Context) int64 {
+	ctx, span := startSpan(ctx, "UserUseCase.CountData")
+	defer span.End()
+	return t.next.CountData(ctx)
+}
+
+func (t *tracedUserUseCase) Bulk(ctx context.Context, ops []entity.BulkUser, atomic bool) []entity.BulkResult {
+	ctx, span := startSpan(ctx, "UserUseCase.Bulk")
+	defer span.End()
+	return t.next.Bulk(ctx, ops, atomic)
+}
+
+type tracedOrderItemUseCase struct {
+	next OrderItemUseCase
+}
+
+// WithTracingOrderItemUseCase wraps every method of next in a span
+func WithTracingOrderItemUseCase(next OrderItemUseCase) OrderItemUseCase {
+	return &tracedOrderItemUseCase{next}
+}
+
+func (t *tracedOrderItemUseCase) GetByID(ctx context.Context, id int) (*entity.OrderItem, error) {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.GetByID")
+	result, err := t.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderItemUseCase) GetByIDs(ctx context.Context, ids []int) ([]*entity.OrderItem, error) {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.GetByIDs")
+	result, err := t.next.GetByIDs(ctx, ids)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderItemUseCase) Create(ctx context.Context, orderItem *entity.OrderItem) error {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.Create")
+	err := t.next.Create(ctx, orderItem)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedOrderItemUseCase) Update(ctx context.Context, orderItem *entity.OrderItem) error {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.Update")
+	err := t.next.Update(ctx, orderItem)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedOrderItemUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.OrderItem, error) {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.Patch")
+	result, err := t.next.Patch(ctx, id, fields)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderItemUseCase) Delete(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.Delete")
+	err := t.next.Delete(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedOrderItemUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderItem, error) {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.GetAllPagination")
+	result, err := t.next.GetAllPagination(ctx, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderItemUseCase) CountData(ctx context.Context) int64 {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.CountData")
+	defer span.End()
+	return t.next.CountData(ctx)
+}
+
+func (t *tracedOrderItemUseCase) Bulk(ctx context.Context, ops []entity.BulkOrderItem, atomic bool) []entity.BulkResult {
+	ctx, span := startSpan(ctx, "OrderItemUseCase.Bulk")
+	defer span.End()
+	return t.next.Bulk(ctx, ops, atomic)
+}
+
+type tracedOrderHistoryUseCase struct {
+	next OrderHistoryUseCase
+}
+
+// WithTracingOrderHistoryUseCase wraps every method of next in a span
+func WithTracingOrderHistoryUseCase(next OrderHistoryUseCase) OrderHistoryUseCase {
+	return &tracedOrderHistoryUseCase{next}
+}
+
+func (t *tracedOrderHistoryUseCase) Create(ctx context.Context, userID int, orderItemID int, descriptions string) (*entity.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.Create")
+	result, err := t.next.Create(ctx, userID, orderItemID, descriptions)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderHistoryUseCase) Update(ctx context.Context, id int, userID int, orderItemID int, descriptions string) error {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.Update")
+	err := t.next.Update(ctx, id, userID, orderItemID, descriptions)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedOrderHistoryUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.Patch")
+	result, err := t.next.Patch(ctx, id, fields)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderHistoryUseCase) GetByID(ctx context.Context, id int) (*entity.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.GetByID")
+	result, err := t.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderHistoryUseCase) GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.GetByUserID")
+	result, err := t.next.GetByUserID(ctx, userID, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderHistoryUseCase) GetByUserIDs(ctx context.Context, userIDs []int, limit int) ([]*entity.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.GetByUserIDs")
+	result, err := t.next.GetByUserIDs(ctx, userIDs, limit)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderHistoryUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.GetAllPagination")
+	result, err := t.next.GetAllPagination(ctx, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedOrderHistoryUseCase) CountData(ctx context.Context, userId int) int64 {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.CountData")
+	defer span.End()
+	return t.next.CountData(ctx, userId)
+}
+
+func (t *tracedOrderHistoryUseCase) Bulk(ctx context.Context, ops []entity.BulkOrderHistory, atomic bool) []entity.BulkResult {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.Bulk")
+	defer span.End()
+	return t.next.Bulk(ctx, ops, atomic)
+}
+
+func (t *tracedOrderHistoryUseCase) Export(ctx context.Context, filter entity.OrderHistoryFilter, fn func(row *entity.OrderHistoryExport) error) error {
+	ctx, span := startSpan(ctx, "OrderHistoryUseCase.Export")
+	err := t.next.Export(ctx, filter, fn)
+	endSpan(span, err)
+	return err
+}
+
+type tracedReportUseCase struct {
+	next ReportUseCase
+}
+
+// WithTracingReportUseCase wraps every method of next in a span
+func WithTracingReportUseCase(next ReportUseCase) ReportUseCase {
+	return &tracedReportUseCase{next}
+}
+
+func (t *tracedReportUseCase) Revenue(ctx context.Context, filter entity.ReportFilter) ([]*entity.RevenuePoint, error) {
+	ctx, span := startSpan(ctx, "ReportUseCase.Revenue")
+	result, err := t.next.Revenue(ctx, filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedReportUseCase) TopItems(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopItem, error) {
+	ctx, span := startSpan(ctx, "ReportUseCase.TopItems")
+	result, err := t.next.TopItems(ctx, filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedReportUseCase) TopUsers(ctx context.Context, filter entity.ReportFilter) ([]*entity.TopUser, error) {
+	ctx, span := startSpan(ctx, "ReportUseCase.TopUsers")
+	result, err := t.next.TopUsers(ctx, filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedReportUseCase) Cohorts(ctx context.Context, filter entity.ReportFilter) ([]*entity.Cohort, error) {
+	ctx, span := startSpan(ctx, "ReportUseCase.Cohorts")
+	result, err := t.next.Cohorts(ctx, filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedReportUseCase) UserStats(ctx context.Context, userID int) (*entity.UserStats, error) {
+	ctx, span := startSpan(ctx, "ReportUseCase.UserStats")
+	result, err := t.next.UserStats(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+type tracedWebhookUseCase struct {
+	next WebhookUseCase
+}
+
+// WithTracingWebhookUseCase wraps every method of next in a span
+func WithTracingWebhookUseCase(next WebhookUseCase) WebhookUseCase {
+	return &tracedWebhookUseCase{next}
+}
+
+func (t *tracedWebhookUseCase) Create(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	ctx, span := startSpan(ctx, "WebhookUseCase.Create")
+	err := t.next.Create(ctx, subscription)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedWebhookUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.WebhookSubscription, error) {
+	ctx, span := startSpan(ctx, "WebhookUseCase.Patch")
+	result, err := t.next.Patch(ctx, id, fields)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedWebhookUseCase) Delete(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "WebhookUseCase.Delete")
+	err := t.next.Delete(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedWebhookUseCase) GetByID(ctx context.Context, id int) (*entity.WebhookSubscription, error) {
+	ctx, span := startSpan(ctx, "WebhookUseCase.GetByID")
+	result, err := t.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedWebhookUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.WebhookSubscription, error) {
+	ctx, span := startSpan(ctx, "WebhookUseCase.GetAllPagination")
+	result, err := t.next.GetAllPagination(ctx, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedWebhookUseCase) CountData(ctx context.Context) int64 {
+	ctx, span := startSpan(ctx, "WebhookUseCase.CountData")
+	defer span.End()
+	return t.next.CountData(ctx)
+}
+
+func (t *tracedWebhookUseCase) Deliveries(ctx context.Context, subscriptionID, limit, offset int) ([]*entity.WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookUseCase.Deliveries")
+	result, err := t.next.Deliveries(ctx, subscriptionID, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedWebhookUseCase) CountDeliveries(ctx context.Context, subscriptionID int) int64 {
+	ctx, span := startSpan(ctx, "WebhookUseCase.CountDeliveries")
+	defer span.End()
+	return t.next.CountDeliveries(ctx, subscriptionID)
+}
+
+func (t *tracedWebhookUseCase) Attempts(ctx context.Context, subscriptionID, deliveryID, limit, offset int) ([]*entity.WebhookAttempt, error) {
+	ctx, span := startSpan(ctx, "WebhookUseCase.Attempts")
+	result, err := t.next.Attempts(ctx, subscriptionID, deliveryID, limit, offset)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedWebhookUseCase) CountAttempts(ctx context.Context, deliveryID int) int64 {
+	ctx, span := startSpan(ctx, "WebhookUseCase.CountAttempts")
+	defer span.End()
+	return t.next.CountAttempts(ctx, deliveryID)
+}
+
+func (t *tracedWebhookUseCase) Redeliver(ctx context.Context, subscriptionID, deliveryID int) (*entity.WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookUseCase.Redeliver")
+	result, err := t.next.Redeliver(ctx, subscriptionID, deliveryID)
+	endSpan(span, err)
+	return result, err
+}
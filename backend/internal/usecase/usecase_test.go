@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/internal/repository/memory"
+)
+
+// fixture holds in-memory repositories and cache for one test
+type fixture struct {
+	store          *memory.Store
+	users          repository.UserRepository
+	orderItems     repository.OrderItemRepository
+	orderHistories repository.OrderHistoryRepository
+	outbox         repository.OutboxRepository
+	transactor     repository.Transactor
+	cache          *cache.LRUCache
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	store := memory.NewStore()
+
+	return &fixture{
+		store:          store,
+		users:          memory.NewUserRepository(store),
+		orderItems:     memory.NewOrderItemRepository(store),
+		orderHistories: memory.NewOrderHistoryRepository(store),
+		outbox:         memory.NewOutboxRepository(store),
+		transactor:     memory.NewTransactor(store),
+		cache:          cache.NewLRUCache(100),
+	}
+}
+
+// loader reads through c with a minute of TTL for the Order Items and the reports
+func (f *fixture) loader(c cache.Cache) *cache.Loader {
+	return cache.NewLoader(c, nil, map[string]cache.Policy{
+		"order_items": {TTL: time.Minute},
+		"reports":     {TTL: time.Minute},
+	})
+}
+
+// cached reports whether key holds a value in the cache of f
+func (f *fixture) cached(key string) bool {
+	_, err := f.cache.Get(context.Background(), key)
+	return err == nil
+}
+
+// failingCache fails every call, like Redis while it is down
+type failingCache struct{}
+
+var errCacheDown = errors.New("cache down")
+
+func (failingCache) Get(context.Context, string) ([]byte, error) {
+	return nil, errCacheDown
+}
+
+func (failingCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errCacheDown
+}
+
+func (failingCache) Delete(context.Context, ...string) error {
+	return errCacheDown
+}
+
+func (failingCache) DeleteByPrefix(context.Context, string) error {
+	return errCacheDown
+}
+
+func (f *fixture) user(t *testing.T, name string) *entity.User {
+	t.Helper()
+	user, err := f.users.Create(context.Background(), &entity.User{FullName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (f *fixture) orderItem(t *testing.T, price int) *entity.OrderItem {
+	t.Helper()
+	orderItem := &entity.OrderItem{Name: "Item", Price: price}
+	if err := f.orderItems.Create(context.Background(), orderItem); err != nil {
+		t.Fatal(err)
+	}
+	return orderItem
+}
+
+// errorOf returns the error of a result, or "ok" when it succeeded
+func errorOf(result entity.BulkResult) string {
+	if result.Success {
+		return "ok"
+	}
+	return result.Error
+}
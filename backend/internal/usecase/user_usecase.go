@@ -4,31 +4,58 @@ import (
 	"context"
 	"errors"
 	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/metrics"
 	"test-crud-user-orders/internal/repository"
 )
 
 type UserUseCase interface {
 	Create(ctx context.Context, fullName string) (*entity.User, error)
 	Update(ctx context.Context, id int, fullName string) error
+	Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.User, error)
 	Delete(ctx context.Context, id int) error
 	GetByID(ctx context.Context, id int) (*entity.User, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error)
 	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.User, error)
 	CountData(ctx context.Context) int64
+	Bulk(ctx context.Context, ops []entity.BulkUser, atomic bool) []entity.BulkResult
 }
 
 type userUseCase struct {
-	userRepo repository.UserRepository
+	userRepo   repository.UserRepository
+	outboxRepo repository.OutboxRepository
+	transactor repository.Transactor
 }
 
-func NewUserUseCase(userRepo repository.UserRepository) UserUseCase {
-	return &userUseCase{userRepo}
+func NewUserUseCase(userRepo repository.UserRepository, outboxRepo repository.OutboxRepository, transactor repository.Transactor) UserUseCase {
+	return &userUseCase{userRepo, outboxRepo, transactor}
 }
 
 func (uc *userUseCase) Create(ctx context.Context, fullName string) (*entity.User, error) {
+	var user *entity.User
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		user, err = uc.create(ctx, fullName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.UsersCreated.Inc()
+	return user, nil
+}
+
+// create writes the User and its UserCreated event with ctx, which must carry a transaction
+func (uc *userUseCase) create(ctx context.Context, fullName string) (*entity.User, error) {
 	user := &entity.User{
 		FullName: fullName,
 	}
-	return uc.userRepo.Create(ctx, user)
+	user, err := uc.userRepo.Create(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := record(ctx, uc.outboxRepo, entity.EventUserCreated, user.ID, user); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
 func (uc *userUseCase) Update(ctx context.Context, id int, fullName string) error {
@@ -43,21 +70,49 @@ func (uc *userUseCase) Update(ctx context.Context, id int, fullName string) erro
 	return uc.userRepo.Update(ctx, user)
 }
 
-func (uc *userUseCase) Delete(ctx context.Context, id int) error {
+// Patch updates only the supplied columns of a User and returns the fresh row
+func (uc *userUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.User, error) {
 	user, err := uc.userRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return nil, errors.New("user not found")
+	}
+	if len(fields) == 0 {
+		return user, nil
 	}
-	return uc.userRepo.SoftDelete(ctx, id)
+	if err := uc.userRepo.UpdateFields(ctx, id, fields); err != nil {
+		return nil, err
+	}
+	return uc.userRepo.GetByID(ctx, id)
+}
+
+func (uc *userUseCase) Delete(ctx context.Context, id int) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return errors.New("user not found")
+		}
+		if err := uc.userRepo.SoftDelete(ctx, id); err != nil {
+			return err
+		}
+		return record(ctx, uc.outboxRepo, entity.EventUserDeleted, id, entity.UserDeletedEvent{ID: id})
+	})
 }
 
 func (uc *userUseCase) GetByID(ctx context.Context, id int) (*entity.User, error) {
 	return uc.userRepo.GetByID(ctx, id)
 }
 
+// GetByIDs reads many Users at once, soft-deleted ones included, for the relations of Order Histories
+func (uc *userUseCase) GetByIDs(ctx context.Context, ids []int) ([]*entity.User, error) {
+	return uc.userRepo.GetByIDs(ctx, ids)
+}
+
 func (uc *userUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.User, error) {
 	return uc.userRepo.GetAllPagination(ctx, limit, offset)
 }
@@ -65,3 +120,31 @@ func (uc *userUseCase) GetAllPagination(ctx context.Context, limit, offset int)
 func (uc *userUseCase) CountData(ctx context.Context) int64 {
 	return uc.userRepo.CountData(ctx)
 }
+
+// Bulk executes create, update and delete operations of Users in batches
+func (uc *userUseCase) Bulk(ctx context.Context, ops []entity.BulkUser, atomic bool) []entity.BulkResult {
+	results := make([]entity.BulkResult, len(ops))
+	for i, op := range ops {
+		results[i] = entity.BulkResult{Index: op.Index, Op: op.Op, ID: op.ID}
+	}
+
+	runBulk(ctx, uc.transactor, results, atomic, func(ctx context.Context, i int) (int, error) {
+		op := ops[i]
+		switch op.Op {
+		case entity.BulkCreate:
+			user, err := uc.create(ctx, op.FullName)
+			if err != nil {
+				return 0, err
+			}
+			return user.ID, nil
+		case entity.BulkUpdate:
+			return op.ID, uc.Update(ctx, op.ID, op.FullName)
+		case entity.BulkDelete:
+			return op.ID, uc.Delete(ctx, op.ID)
+		}
+		return 0, errors.New("unknown operation")
+	}, func(ctx context.Context) {})
+
+	metrics.UsersCreated.Add(float64(countCreated(results)))
+	return results
+}
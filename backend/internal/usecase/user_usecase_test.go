@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"test-crud-user-orders/internal/entity"
+)
+
+func TestUserUseCaseLifecycle(t *testing.T) {
+	f := newFixture(t)
+	uc := NewUserUseCase(f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+
+	user, err := uc.Create(ctx, "Ann")
+	if err != nil || user.ID < 1 {
+		t.Fatalf("Create = %+v, %v", user, err)
+	}
+	if err := uc.Update(ctx, user.ID, "Annie"); err != nil {
+		t.Fatal(err)
+	}
+	patched, err := uc.Patch(ctx, user.ID, map[string]interface{}{"full_name": "Anne"})
+	if err != nil || patched.FullName != "Anne" {
+		t.Fatalf("Patch = %+v, %v, want the fresh row", patched, err)
+	}
+	if unchanged, err := uc.Patch(ctx, user.ID, map[string]interface{}{}); err != nil || unchanged.FullName != "Anne" {
+		t.Errorf("empty Patch = %+v, %v, want the row as it is", unchanged, err)
+	}
+	if count := uc.CountData(ctx); count != 1 {
+		t.Errorf("CountData = %d, want 1", count)
+	}
+
+	if err := uc.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"GetByID": func() error { _, err := uc.GetByID(ctx, user.ID); return err }(),
+		"Update":  uc.Update(ctx, user.ID, "Ghost"),
+		"Patch": func() error {
+			_, err := uc.Patch(ctx, user.ID, map[string]interface{}{"full_name": "Ghost"})
+			return err
+		}(),
+		"Delete": uc.Delete(ctx, user.ID),
+	} {
+		if err == nil || err.Error() != "record not found" {
+			t.Errorf("%s of a deleted user = %v, want record not found", name, err)
+		}
+	}
+
+	f.store.FailWith(errors.New("database down"))
+	if _, err := uc.Create(ctx, "Bob"); err == nil {
+		t.Error("Create succeeded with the database down")
+	}
+	if _, err := uc.GetAllPagination(ctx, 10, 0); err == nil {
+		t.Error("GetAllPagination succeeded with the database down")
+	}
+}
+
+func TestUserUseCaseBulkAtomicRollsBackEverything(t *testing.T) {
+	f := newFixture(t)
+	uc := NewUserUseCase(f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+	existing := f.user(t, "Ann")
+
+	results := uc.Bulk(ctx, []entity.BulkUser{
+		{Index: 0, Op: entity.BulkCreate, FullName: "Bob"},
+		{Index: 1, Op: entity.BulkUpdate, ID: existing.ID, FullName: "Annie"},
+		{Index: 2, Op: entity.BulkDelete, ID: 999},
+		{Index: 3, Op: entity.BulkCreate, FullName: "Cid"},
+	}, true)
+
+	want := []string{"rolled back", "rolled back", "record not found", "rolled back"}
+	for i, result := range results {
+		if errorOf(result) != want[i] {
+			t.Errorf("operation %d = %q, want %q", i, errorOf(result), want[i])
+		}
+	}
+	if results[0].ID != 0 {
+		t.Errorf("rolled back create reports ID %d", results[0].ID)
+	}
+	if user, _ := f.users.GetByID(ctx, existing.ID); user.FullName != "Ann" || f.users.CountData(ctx) != 1 {
+		t.Errorf("user = %+v with %d users, want nothing written", user, f.users.CountData(ctx))
+	}
+}
+
+func TestUserUseCaseBulkPartialKeepsTheSuccesses(t *testing.T) {
+	f := newFixture(t)
+	uc := NewUserUseCase(f.users, f.outbox, f.transactor)
+	ctx := context.Background()
+	existing := f.user(t, "Ann")
+
+	results := uc.Bulk(ctx, []entity.BulkUser{
+		{Index: 0, Op: entity.BulkCreate, FullName: "Bob"},
+		{Index: 1, Op: entity.BulkUpdate, ID: 999, FullName: "Ghost"},
+		{Index: 2, Op: entity.BulkDelete, ID: existing.ID},
+		{Index: 3, Op: "upsert"},
+	}, false)
+
+	want := []string{"ok", "record not found", "ok", "unknown operation"}
+	for i, result := range results {
+		if errorOf(result) != want[i] {
+			t.Errorf("operation %d = %q, want %q", i, errorOf(result), want[i])
+		}
+	}
+	if results[0].ID < 1 {
+		t.Error("created user has no ID")
+	}
+	if count := f.users.CountData(ctx); count != 1 {
+		t.Errorf("CountData = %d, want Bob only", count)
+	}
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+type WebhookUseCase interface {
+	Create(ctx context.Context, subscription *entity.WebhookSubscription) error
+	Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.WebhookSubscription, error)
+	Delete(ctx context.Context, id int) error
+	GetByID(ctx context.Context, id int) (*entity.WebhookSubscription, error)
+	GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.WebhookSubscription, error)
+	CountData(ctx context.Context) int64
+	// Deliveries returns the delivery log of a subscription, the newest first
+	Deliveries(ctx context.Context, subscriptionID, limit, offset int) ([]*entity.WebhookDelivery, error)
+	CountDeliveries(ctx context.Context, subscriptionID int) int64
+	// Attempts returns the posts of a delivery of a subscription, the newest first
+	Attempts(ctx context.Context, subscriptionID, deliveryID, limit, offset int) ([]*entity.WebhookAttempt, error)
+	CountAttempts(ctx context.Context, deliveryID int) int64
+	// Redeliver schedules a delivery of a subscription to be attempted again right away,
+	// a dead delivery gets all its attempts back
+	Redeliver(ctx context.Context, subscriptionID, deliveryID int) (*entity.WebhookDelivery, error)
+}
+
+type webhookUseCase struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+}
+
+func NewWebhookUseCase(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository) WebhookUseCase {
+	return &webhookUseCase{webhookRepo, deliveryRepo}
+}
+
+func (uc *webhookUseCase) Create(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	return uc.webhookRepo.Create(ctx, subscription)
+}
+
+// Patch updates only the supplied columns of a subscription and returns the fresh row
+func (uc *webhookUseCase) Patch(ctx context.Context, id int, fields map[string]interface{}) (*entity.WebhookSubscription, error) {
+	subscription, err := uc.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return subscription, nil
+	}
+	if err := uc.webhookRepo.UpdateFields(ctx, id, fields); err != nil {
+		return nil, err
+	}
+	return uc.webhookRepo.GetByID(ctx, id)
+}
+
+// Delete stops the deliveries of the subscription, the pending ones die on their next attempt
+func (uc *webhookUseCase) Delete(ctx context.Context, id int) error {
+	if _, err := uc.webhookRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return uc.webhookRepo.SoftDelete(ctx, id)
+}
+
+func (uc *webhookUseCase) GetByID(ctx context.Context, id int) (*entity.WebhookSubscription, error) {
+	return uc.webhookRepo.GetByID(ctx, id)
+}
+
+func (uc *webhookUseCase) GetAllPagination(ctx context.Context, limit, offset int) ([]*entity.WebhookSubscription, error) {
+	return uc.webhookRepo.GetAllPagination(ctx, limit, offset)
+}
+
+func (uc *webhookUseCase) CountData(ctx context.Context) int64 {
+	return uc.webhookRepo.CountData(ctx)
+}
+
+func (uc *webhookUseCase) Deliveries(ctx context.Context, subscriptionID, limit, offset int) ([]*entity.WebhookDelivery, error) {
+	if _, err := uc.webhookRepo.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return uc.deliveryRepo.GetBySubscription(ctx, subscriptionID, limit, offset)
+}
+
+func (uc *webhookUseCase) CountDeliveries(ctx context.Context, subscriptionID int) int64 {
+	return uc.deliveryRepo.CountBySubscription(ctx, subscriptionID)
+}
+
+func (uc *webhookUseCase) Attempts(ctx context.Context, subscriptionID, deliveryID, limit, offset int) ([]*entity.WebhookAttempt, error) {
+	if _, err := uc.webhookRepo.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	if _, err := uc.deliveryRepo.GetByID(ctx, subscriptionID, deliveryID); err != nil {
+		if err.Error() == "record not found" {
+			return nil, errors.New("delivery data not found")
+		}
+		return nil, err
+	}
+	return uc.deliveryRepo.Attempts(ctx, deliveryID, limit, offset)
+}
+
+func (uc *webhookUseCase) CountAttempts(ctx context.Context, deliveryID int) int64 {
+	return uc.deliveryRepo.CountAttempts(ctx, deliveryID)
+}
+
+func (uc *webhookUseCase) Redeliver(ctx context.Context, subscriptionID, deliveryID int) (*entity.WebhookDelivery, error) {
+	if _, err := uc.webhookRepo.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	delivery, err := uc.deliveryRepo.GetByID(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, errors.New("delivery data not found")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery.Status = entity.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.LastError = ""
+	if err := uc.deliveryRepo.Save(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository/memory"
+)
+
+func TestWebhookUseCaseLifecycle(t *testing.T) {
+	f := newFixture(t)
+	deliveries := memory.NewWebhookDeliveryRepository(f.store)
+	uc := NewWebhookUseCase(memory.NewWebhookRepository(f.store), deliveries)
+	ctx := context.Background()
+
+	subscription := &entity.WebhookSubscription{URL: "http://a.test/", Events: entity.EventTypes{entity.EventOrderCreated}, Secret: "secret", Active: true}
+	if err := uc.Create(ctx, subscription); err != nil || subscription.ID < 1 {
+		t.Fatalf("Create = %+v, %v", subscription, err)
+	}
+	patched, err := uc.Patch(ctx, subscription.ID, map[string]interface{}{"active": false})
+	if err != nil || patched.Active || patched.URL != subscription.URL {
+		t.Fatalf("Patch = %+v, %v, want the fresh row", patched, err)
+	}
+
+	dead := &entity.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        "a",
+		EventType:      entity.EventOrderCreated,
+		Payload:        `{}`,
+		Status:         entity.DeliveryDead,
+		Attempts:       8,
+		LastError:      "webhook answered 500",
+	}
+	if err := deliveries.Add(ctx, dead); err != nil {
+		t.Fatal(err)
+	}
+	log, err := uc.Deliveries(ctx, subscription.ID, 10, 0)
+	if err != nil || len(log) != 1 || uc.CountDeliveries(ctx, subscription.ID) != 1 {
+		t.Fatalf("Deliveries = %+v, %v, want the dead delivery", log, err)
+	}
+
+	if err := deliveries.Save(ctx, dead, &entity.WebhookAttempt{Attempt: 8, ResponseCode: 500, Error: "webhook answered 500", AttemptedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err := uc.Attempts(ctx, subscription.ID, dead.ID, 10, 0)
+	if err != nil || len(attempts) != 1 || attempts[0].Attempt != 8 || uc.CountAttempts(ctx, dead.ID) != 1 {
+		t.Fatalf("Attempts = %+v, %v, want the last attempt", attempts, err)
+	}
+	if _, err := uc.Attempts(ctx, subscription.ID, 99, 10, 0); err == nil || err.Error() != "delivery data not found" {
+		t.Errorf("Attempts of a missing delivery = %v, want delivery data not found", err)
+	}
+
+	before := time.Now()
+	redelivered, err := uc.Redeliver(ctx, subscription.ID, dead.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redelivered.Status != entity.DeliveryPending || redelivered.Attempts != 0 || redelivered.LastError != "" ||
+		redelivered.NextAttemptAt == nil || redelivered.NextAttemptAt.Before(before) {
+		t.Errorf("Redeliver = %+v, want pending now with every attempt back", redelivered)
+	}
+	if _, err := uc.Redeliver(ctx, subscription.ID, 99); err == nil || err.Error() != "delivery data not found" {
+		t.Errorf("Redeliver of a missing delivery = %v, want delivery data not found", err)
+	}
+
+	if err := uc.Delete(ctx, subscription.ID); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"GetByID": func() error { _, err := uc.GetByID(ctx, subscription.ID); return err }(),
+		"Patch": func() error {
+			_, err := uc.Patch(ctx, subscription.ID, map[string]interface{}{"active": true})
+			return err
+		}(),
+		"Delete":     uc.Delete(ctx, subscription.ID),
+		"Deliveries": func() error { _, err := uc.Deliveries(ctx, subscription.ID, 10, 0); return err }(),
+		"Attempts":   func() error { _, err := uc.Attempts(ctx, subscription.ID, dead.ID, 10, 0); return err }(),
+		"Redeliver":  func() error { _, err := uc.Redeliver(ctx, subscription.ID, dead.ID); return err }(),
+	} {
+		if err == nil || err.Error() != "record not found" {
+			t.Errorf("%s of a deleted subscription = %v, want record not found", name, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is the error of a post to an address the subscriptions may not reach
+var ErrAddressNotAllowed = errors.New("webhook address not allowed")
+
+// sharedAddressSpace (RFC 6598) is private to the networks of the carriers, like the ranges of IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// newClient posts to the public addresses and to allowed only. The address is checked as it is dialed,
+// so a name resolving to a private address is refused too, and redirects are not followed since the
+// subscription registered its URL only.
+func newClient(timeout time.Duration, allowed []*net.IPNet) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: guard(allowed)}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// guard refuses the connections to the loopback, private, link-local, shared and unspecified addresses
+// outside of allowed, so a subscription cannot probe the services next to the API
+func guard(allowed []*net.IPNet) func(network, address string, conn syscall.RawConn) error {
+	return func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+		}
+		for _, network := range allowed {
+			if network.Contains(ip) {
+				return nil
+			}
+		}
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+			ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || sharedAddressSpace.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+		}
+		return nil
+	}
+}
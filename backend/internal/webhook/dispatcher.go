@@ -0,0 +1,260 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"test-crud-user-orders/internal/cache"
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/pkg/logger"
+)
+
+// lockTTL is the longest one instance dispatches while the others wait
+const lockTTL = time.Minute
+
+// maxLastError is the size of the last_error column of the deliveries and the error one of the attempts
+const maxLastError = 255
+
+// Options of a Dispatcher
+type Options struct {
+	// Interval between two looks at the due deliveries
+	Interval time.Duration
+	// BatchSize is the number of deliveries read at once, Concurrency the number posted at once
+	BatchSize   int
+	Concurrency int
+	// MaxAttempts of a delivery before it is dead
+	MaxAttempts int
+	// RetryDelay after the first failed attempt, doubled after every other one up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// Timeout of one post
+	Timeout time.Duration
+	// AllowedNetworks may be posted to although they are private, every other private address is refused
+	AllowedNetworks []*net.IPNet
+	// Locker lets one instance dispatch at a time, nil dispatches on every instance
+	Locker cache.Locker
+}
+
+// Dispatcher posts the due deliveries to their subscriptions
+type Dispatcher struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	client       *http.Client
+	options      Options
+	now          func() time.Time
+	lockTTL      time.Duration
+}
+
+func NewDispatcher(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository, options Options) *Dispatcher {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	return &Dispatcher{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		client:       newClient(options.Timeout, options.AllowedNetworks),
+		options:      options,
+		now:          time.Now,
+		lockTTL:      lockTTL,
+	}
+}
+
+// Run dispatches the due deliveries every Interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			logger.FromContext(ctx).Warn().Err(err).Msg("webhooks not dispatched")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue attempts every delivery due now and returns the number of attempts. Under a lock
+// the pass ends with it, once the lock expired another instance may attempt the same deliveries.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	if d.options.Locker != nil {
+		unlock, err := d.options.Locker.TryLock(ctx, "lock:webhooks", d.lockTTL)
+		if errors.Is(err, cache.ErrLocked) {
+			return 0, nil
+		}
+		if err == nil {
+			defer unlock()
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d.lockTTL)
+			defer cancel()
+		} else {
+			logger.FromContext(ctx).Warn().Err(err).Msg("webhooks lock not taken")
+		}
+	}
+
+	attempted := 0
+	for {
+		deliveries, err := d.deliveryRepo.Due(ctx, d.now(), d.options.BatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		if err := d.dispatch(ctx, deliveries); err != nil {
+			return attempted, err
+		}
+		attempted += len(deliveries)
+		if len(deliveries) < d.options.BatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// dispatch attempts deliveries, Concurrency at a time, and returns the first error saving them
+func (d *Dispatcher) dispatch(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	subscriptions := map[int]*entity.WebhookSubscription{}
+	for _, delivery := range deliveries {
+		if _, ok := subscriptions[delivery.SubscriptionID]; ok {
+			continue
+		}
+		subscription, err := d.webhookRepo.GetByID(ctx, delivery.SubscriptionID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		subscriptions[delivery.SubscriptionID] = subscription
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, d.options.Concurrency)
+	for _, delivery := range deliveries {
+		delivery := delivery
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			var attempts []*entity.WebhookAttempt
+			if attempt := d.attempt(ctx, subscriptions[delivery.SubscriptionID], delivery); attempt != nil {
+				attempts = append(attempts, attempt)
+			}
+			if err := d.deliveryRepo.Save(ctx, delivery, attempts...); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// attempt posts delivery to subscription, records the outcome in delivery and returns the post,
+// nil when the delivery was buried without one
+func (d *Dispatcher) attempt(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) *entity.WebhookAttempt {
+	switch {
+	case subscription == nil:
+		d.bury(delivery, "subscription deleted")
+		return nil
+	case !subscription.Active:
+		d.bury(delivery, "subscription disabled")
+		return nil
+	}
+
+	delivery.Attempts++
+	attempt := &entity.WebhookAttempt{Attempt: delivery.Attempts, AttemptedAt: d.now()}
+	code, err := d.post(ctx, subscription, delivery)
+	delivery.ResponseCode = code
+	attempt.ResponseCode = code
+	if err == nil {
+		now := d.now()
+		delivery.Status = entity.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+		return attempt
+	}
+	attempt.Error = truncate(err.Error())
+
+	logger.FromContext(ctx).Warn().Err(err).
+		Int("subscription_id", subscription.ID).
+		Int("delivery_id", delivery.ID).
+		Int("attempts", delivery.Attempts).
+		Msg("webhook delivery failed")
+	if delivery.Attempts >= d.options.MaxAttempts {
+		d.bury(delivery, err.Error())
+		return attempt
+	}
+	next := d.now().Add(d.backoff(delivery.Attempts))
+	delivery.Status = entity.DeliveryFailed
+	delivery.NextAttemptAt = &next
+	delivery.LastError = attempt.Error
+	return attempt
+}
+
+// bury stops retrying delivery, it is only attempted again when redelivered
+func (d *Dispatcher) bury(delivery *entity.WebhookDelivery, reason string) {
+	delivery.Status = entity.DeliveryDead
+	delivery.NextAttemptAt = nil
+	delivery.LastError = truncate(reason)
+}
+
+// backoff is the delay after the attempts-th failed attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.options.RetryDelay
+	for i := 1; i < attempts && delay < d.options.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.options.MaxRetryDelay {
+		delay = d.options.MaxRetryDelay
+	}
+	return delay
+}
+
+// post sends the payload of delivery and returns the status code, any code but 2xx is an error
+func (d *Dispatcher) post(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-crud-user-orders-webhooks")
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, d.now(), body))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderIdempotencyKey, delivery.EventID)
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+func truncate(reason string) string {
+	if len(reason) > maxLastError {
+		return reason[:maxLastError]
+	}
+	return reason
+}
@@ -0,0 +1,81 @@
+// Package webhook posts the domain events to the URLs of the Webhook Subscriptions. The Sink turns
+// every event relayed from the outbox into one delivery per subscription, the Dispatcher posts the
+// due deliveries, signed with the secret of their subscription, and retries the failed ones with
+// an exponential backoff until they are dead.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+)
+
+// Headers of every post
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	// HeaderIdempotencyKey carries the ID of the event, the same on every delivery of it
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// Sign returns the X-Webhook-Signature of body posted at timestamp: "t=<unix seconds>,v1=<hex>"
+// where hex is the HMAC-SHA256 of "<unix seconds>.<body>" keyed with secret. A receiver recomputes
+// it and also rejects old timestamps to stop replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", unix, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Sink creates the deliveries of every event relayed from the outbox
+type Sink struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	now          func() time.Time
+}
+
+func NewSink(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository) *Sink {
+	return &Sink{webhookRepo: webhookRepo, deliveryRepo: deliveryRepo, now: time.Now}
+}
+
+func (s *Sink) Name() string {
+	return "webhooks"
+}
+
+// Publish creates one pending delivery of event per active subscription to its type. An event
+// published again does not create a second delivery for the same subscription.
+func (s *Sink) Publish(ctx context.Context, event entity.Event) error {
+	subscriptions, err := s.webhookRepo.Subscribed(ctx, event.Type)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	deliveries := make([]*entity.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, &entity.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         entity.DeliveryPending,
+			NextAttemptAt:  &now,
+		})
+	}
+	return s.deliveryRepo.Add(ctx, deliveries...)
+}
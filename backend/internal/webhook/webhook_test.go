@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"test-crud-user-orders/internal/entity"
+	"test-crud-user-orders/internal/repository"
+	"test-crud-user-orders/internal/repository/memory"
+	"test-crud-user-orders/internal/usecase"
+)
+
+const secret = "0123456789abcdef"
+
+// receiver is a partner endpoint answering status and keeping the requests it received
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) answer(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *receiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// fixture wires the Sink and the Dispatcher on an in-memory store with a clock of its own
+type fixture struct {
+	webhooks   repository.WebhookRepository
+	deliveries repository.WebhookDeliveryRepository
+	sink       *Sink
+	dispatcher *Dispatcher
+	now        time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	store := memory.NewStore()
+	f := &fixture{
+		webhooks:   memory.NewWebhookRepository(store),
+		deliveries: memory.NewWebhookDeliveryRepository(store),
+		now:        time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+	f.sink = NewSink(f.webhooks, f.deliveries)
+	f.sink.now = func() time.Time { return f.now }
+	f.dispatcher = NewDispatcher(f.webhooks, f.deliveries, Options{
+		BatchSize:     10,
+		Concurrency:   2,
+		MaxAttempts:   3,
+		RetryDelay:    time.Minute,
+		MaxRetryDelay: 90 * time.Second,
+		Timeout:       time.Second,
+		// the receivers of the tests listen on the loopback
+		AllowedNetworks: []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}},
+	})
+	f.dispatcher.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *fixture) subscribe(t *testing.T, url string, events ...string) *entity.WebhookSubscription {
+	t.Helper()
+	subscription := &entity.WebhookSubscription{URL: url, Events: events, Secret: secret, Active: true}
+	if err := f.webhooks.Create(context.Background(), subscription); err != nil {
+		t.Fatal(err)
+	}
+	return subscription
+}
+
+func (f *fixture) publish(t *testing.T, id, eventType string) {
+	t.Helper()
+	event := entity.Event{ID: id, Type: eventType, AggregateID: 1, OccurredAt: f.now, Payload: json.RawMessage(`{"id":1}`)}
+	if err := f.sink.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) dispatch(t *testing.T) int {
+	t.Helper()
+	attempted, err := f.dispatcher.DispatchDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attempted
+}
+
+func (f *fixture) log(t *testing.T, subscriptionID int) []*entity.WebhookDelivery {
+	t.Helper()
+	deliveries, err := f.deliveries.GetBySubscription(context.Background(), subscriptionID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestSinkFansOutToMatchingSubscriptions(t *testing.T) {
+	f := newFixture(t)
+	orders := f.subscribe(t, "http://orders.test/", entity.EventOrderCreated)
+	all := f.subscribe(t, "http://all.test/", entity.AllEvents)
+	inactive := f.subscribe(t, "http://inactive.test/", entity.AllEvents)
+	_ = f.webhooks.UpdateFields(context.Background(), inactive.ID, map[string]interface{}{"active": false})
+
+	f.publish(t, "a", entity.EventOrderCreated)
+	f.publish(t, "b", entity.EventUserCreated)
+	// an event relayed again after a failure of another sink
+	f.publish(t, "a", entity.EventOrderCreated)
+
+	if got := f.log(t, orders.ID); len(got) != 1 || got[0].EventID != "a" {
+		t.Errorf("deliveries of the order subscription = %+v, want event a once", got)
+	}
+	if got := f.log(t, all.ID); len(got) != 2 {
+		t.Errorf("deliveries of the subscription to every event = %+v, want both events", got)
+	}
+	if got := f.log(t, inactive.ID); len(got) != 0 {
+		t.Errorf("deliveries of the inactive subscription = %+v, want none", got)
+	}
+}
+
+func TestDispatcherPostsSignedPayloads(t *testing.T) {
+	f := newFixture(t)
+	partner := newReceiver(t)
+	subscription := f.subscribe(t, partner.URL, entity.EventOrderCreated)
+	f.publish(t, "a", entity.EventOrderCreated)
+
+	if attempted := f.dispatch(t); attempted != 1 {
+		t.Fatalf("attempted = %d, want 1", attempted)
+	}
+	if partner.received() != 1 {
+		t.Fatalf("partner received %d requests, want 1", partner.received())
+	}
+
+	req, body := partner.requests[0], partner.bodies[0]
+	if got := req.Header.Get(HeaderSignature); got != Sign(secret, f.now, body) || !strings.HasPrefix(got, "t="+strconv.FormatInt(f.now.Unix(), 10)+",v1=") {
+		t.Errorf("signature = %q, want the HMAC of the timestamp and body", got)
+	}
+	if Sign("another secret", f.now, body) == req.Header.Get(HeaderSignature) {
+		t.Error("signature does not depend on the secret")
+	}
+	if req.Header.Get(HeaderEvent) != entity.EventOrderCreated || req.Header.Get(HeaderIdempotencyKey) != "a" {
+		t.Errorf("headers = %v", req.Header)
+	}
+	var event entity.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.ID != "a" || string(event.Payload) != `{"id":1}` {
+		t.Errorf("body = %s, want the event", body)
+	}
+
+	delivery := f.log(t, subscription.ID)[0]
+	if delivery.Status != entity.DeliverySucceeded || delivery.ResponseCode != 200 || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, want succeeded on the first attempt", delivery)
+	}
+	if f.dispatch(t) != 0 || partner.received() != 1 {
+		t.Error("a succeeded delivery was posted again")
+	}
+}
+
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	f := newFixture(t)
+	f.dispatcher.client = newClient(time.Second, nil)
+	partner := newReceiver(t)
+	subscription := f.subscribe(t, partner.URL, entity.EventOrderCreated)
+	f.publish(t, "a", entity.EventOrderCreated)
+
+	f.dispatch(t)
+	if partner.received() != 0 {
+		t.Fatalf("loopback receiver got %d requests, want none", partner.received())
+	}
+	if delivery := f.log(t, subscription.ID)[0]; delivery.Status != entity.DeliveryFailed || !strings.Contains(delivery.LastError, ErrAddressNotAllowed.Error()) {
+		t.Errorf("delivery = %+v, want failed as not allowed", delivery)
+	}
+
+	check := guard(nil)
+	for address, allowed := range map[string]bool{
+		"127.0.0.1:80":         false,
+		"[::1]:80":             false,
+		"10.1.2.3:6379":        false,
+		"172.16.0.1:80":        false,
+		"192.168.1.1:80":       false,
+		"169.254.169.254:80":   false,
+		"100.64.0.1:80":        false,
+		"0.0.0.0:8000":         false,
+		"[fe80::1]:80":         false,
+		"[fd00::1]:80":         false,
+		"[::ffff:10.0.0.1]:80": false,
+		"93.184.216.34:443":    true,
+		"[2606:4700::1]:443":   true,
+	} {
+		err := check("tcp", address, nil)
+		if (err == nil) != allowed || (err != nil && !errors.Is(err, ErrAddressNotAllowed)) {
+			t.Errorf("dial %s = %v, want allowed %v", address, err, allowed)
+		}
+	}
+	if err := guard(f.dispatcher.options.AllowedNetworks)("tcp", "127.0.0.1:80", nil); err != nil {
+		t.Errorf("dial of an allowed network = %v", err)
+	}
+}
+
+func TestDispatcherRetriesWithBackoffUntilDead(t *testing.T) {
+	f := newFixture(t)
+	partner := newReceiver(t)
+	partner.answer(http.StatusServiceUnavailable)
+	subscription := f.subscribe(t, partner.URL, entity.AllEvents)
+	f.publish(t, "a", entity.EventUserCreated)
+	start := f.now
+
+	for attempt, wantDelay := range []time.Duration{time.Minute, 90 * time.Second} {
+		f.dispatch(t)
+		delivery := f.log(t, subscription.ID)[0]
+		if delivery.Status != entity.DeliveryFailed || delivery.ResponseCode != 503 || delivery.LastError != "webhook answered 503" {
+			t.Fatalf("delivery after attempt %d = %+v, want failed", attempt+1, delivery)
+		}
+		if !delivery.NextAttemptAt.Equal(f.now.Add(wantDelay)) {
+			t.Errorf("next attempt after attempt %d in %s, want %s", attempt+1, delivery.NextAttemptAt.Sub(f.now), wantDelay)
+		}
+		// not due before its next attempt
+		if f.dispatch(t) != 0 {
+			t.Errorf("delivery attempted again before its backoff after attempt %d", attempt+1)
+		}
+		f.now = *delivery.NextAttemptAt
+	}
+
+	f.dispatch(t)
+	delivery := f.log(t, subscription.ID)[0]
+	if delivery.Status != entity.DeliveryDead || delivery.Attempts != 3 || delivery.NextAttemptAt != nil {
+		t.Fatalf("delivery after the last attempt = %+v, want dead", delivery)
+	}
+	f.now = start.Add(24 * time.Hour)
+	if f.dispatch(t) != 0 || partner.received() != 3 {
+		t.Errorf("partner received %d requests, want a dead delivery left alone", partner.received())
+	}
+
+	// redelivered by hand once the partner is fixed
+	partner.answer(http.StatusNoContent)
+	uc := usecase.NewWebhookUseCase(f.webhooks, f.deliveries)
+	redelivered, err := uc.Redeliver(context.Background(), subscription.ID, delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = *redelivered.NextAttemptAt
+	f.dispatch(t)
+	delivery = f.log(t, subscription.ID)[0]
+	if delivery.Status != entity.DeliverySucceeded || delivery.ResponseCode != 204 || delivery.Attempts != 1 || partner.received() != 4 {
+		t.Errorf("redelivered delivery = %+v, want succeeded", delivery)
+	}
+
+	// every post is kept, the newest first
+	attempts, err := f.deliveries.Attempts(context.Background(), delivery.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entity.WebhookAttempt{
+		{Attempt: 1, ResponseCode: 204, AttemptedAt: f.now},
+		{Attempt: 3, ResponseCode: 503, Error: "webhook answered 503", AttemptedAt: start.Add(150 * time.Second)},
+		{Attempt: 2, ResponseCode: 503, Error: "webhook answered 503", AttemptedAt: start.Add(time.Minute)},
+		{Attempt: 1, ResponseCode: 503, Error: "webhook answered 503", AttemptedAt: start},
+	}
+	if len(attempts) != len(want) {
+		t.Fatalf("attempts = %+v, want %d", attempts, len(want))
+	}
+	for i, attempt := range attempts {
+		if attempt.DeliveryID != delivery.ID || attempt.Attempt != want[i].Attempt || attempt.ResponseCode != want[i].ResponseCode ||
+			attempt.Error != want[i].Error || !attempt.AttemptedAt.Equal(want[i].AttemptedAt) {
+			t.Errorf("attempt %d = %+v, want %+v", i, attempt, want[i])
+		}
+	}
+}
+
+// grantingLocker hands out every lock
+type grantingLocker struct{}
+
+func (grantingLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	return func() {}, nil
+}
+
+func TestDispatcherStopsWhenItsLockExpires(t *testing.T) {
+	f := newFixture(t)
+	f.dispatcher.options.Locker = grantingLocker{}
+	f.dispatcher.lockTTL = 50 * time.Millisecond
+	// the partner answers after the post timeout of the fixture
+	release := make(chan struct{})
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(partner.Close)
+	t.Cleanup(func() { close(release) })
+	subscription := f.subscribe(t, partner.URL, entity.AllEvents)
+	f.publish(t, "a", entity.EventUserCreated)
+
+	start := time.Now()
+	_, _ = f.dispatcher.DispatchDue(context.Background())
+	if elapsed := time.Since(start); elapsed >= f.dispatcher.options.Timeout {
+		t.Errorf("DispatchDue took %s, want it to end with its lock after %s", elapsed, f.dispatcher.lockTTL)
+	}
+	if delivery := f.log(t, subscription.ID)[0]; delivery.Status == entity.DeliverySucceeded {
+		t.Errorf("delivery = %+v, want it not delivered", delivery)
+	}
+}
+
+func TestDispatcherBuriesDeliveriesOfRemovedSubscriptions(t *testing.T) {
+	f := newFixture(t)
+	partner := newReceiver(t)
+	deleted := f.subscribe(t, partner.URL, entity.AllEvents)
+	disabled := f.subscribe(t, partner.URL, entity.AllEvents)
+	f.publish(t, "a", entity.EventUserCreated)
+	ctx := context.Background()
+	_ = f.webhooks.SoftDelete(ctx, deleted.ID)
+	_ = f.webhooks.UpdateFields(ctx, disabled.ID, map[string]interface{}{"active": false})
+
+	f.dispatch(t)
+	if partner.received() != 0 {
+		t.Errorf("partner received %d requests, want none", partner.received())
+	}
+	for id, reason := range map[int]string{deleted.ID: "subscription deleted", disabled.ID: "subscription disabled"} {
+		delivery := f.log(t, id)[0]
+		if delivery.Status != entity.DeliveryDead || delivery.LastError != reason || delivery.Attempts != 0 {
+			t.Errorf("delivery = %+v, want dead because %s", delivery, reason)
+		}
+		if count := f.deliveries.CountAttempts(ctx, delivery.ID); count != 0 {
+			t.Errorf("%d attempts of a delivery buried without a post, want none", count)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// gormSlowQuery is the duration from which a statement is logged as a warning
+const gormSlowQuery = 200 * time.Millisecond
+
+// GormLogger writes the gorm messages through the logger of the query context,
+// failed statements are errors, slow ones warnings and every other one a debug line
+type GormLogger struct{}
+
+func (l GormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	// the level is set on the zerolog logger
+	return l
+}
+
+func (GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).Info().Msgf(msg, args...)
+}
+
+func (GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).Warn().Msgf(msg, args...)
+}
+
+func (GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).Error().Msgf(msg, args...)
+}
+
+func (GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	log := FromContext(ctx)
+	elapsed := time.Since(begin)
+
+	var event *zerolog.Event
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		event = log.Error().Err(err)
+	case elapsed >= gormSlowQuery:
+		event = log.Warn().Str("slow_query", fmt.Sprintf(">= %s", gormSlowQuery))
+	default:
+		event = log.Debug()
+	}
+	if !event.Enabled() {
+		return
+	}
+
+	sql, rows := fc()
+	event.Str("sql", sql).Int64("rows", rows).Dur("elapsed", elapsed).Msg("query")
+}
@@ -1,32 +1,46 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
-	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
-var logger *zerolog.Logger
-var once sync.Once
+// NewLogger builds the logger of the service writing to out, format is json (default) or console
+// and level one of trace, debug, info (default), warn, error, fatal or panic
+func NewLogger(level, format string, out io.Writer) (*zerolog.Logger, error) {
+	if out == nil {
+		out = os.Stdout
+	}
 
-func NewLogger(logFile string) *zerolog.Logger {
-	once.Do(func() {
-		output := zerolog.ConsoleWriter{Out: os.Stdout}
-		if logFile != "" {
-			file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				log.Error().Msgf("Failed to open log file: %s", err)
-			}
-			output = zerolog.ConsoleWriter{Out: file}
+	lvl := zerolog.InfoLevel
+	if level != "" {
+		parsed, err := zerolog.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("unknown log level %q", level)
 		}
+		lvl = parsed
+	}
 
-		//if first time, create logger.
-		newLogger := zerolog.New(output).With().Timestamp().Logger()
-		//save it to global var.
-		logger = &newLogger
-	})
+	switch format {
+	case "", "json":
+	case "console":
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
 
-	return logger
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	newLogger := zerolog.New(out).Level(lvl).With().Timestamp().Logger()
+	return &newLogger, nil
+}
+
+// FromContext returns the logger carried by ctx, the request logger inside a request
+// and zerolog.DefaultContextLogger otherwise
+func FromContext(ctx context.Context) *zerolog.Logger {
+	return zerolog.Ctx(ctx)
 }
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errorKey holds the cause of a failed request on the echo.Context
+const errorKey = "logger.error"
+
+// SetError records the cause of a failed request, it is written with the access line
+func SetError(c echo.Context, err error) {
+	c.Set(errorKey, err)
+}
+
+// LogPanic writes a panic caught by middleware.Recover through the request logger
+func LogPanic(c echo.Context, err error, stack []byte) error {
+	FromContext(c.Request().Context()).Error().Err(err).Str("stack", string(stack)).Msg("panic recovered")
+	return err
+}
+
+// Middleware puts a child of base tagged with the request ID in the request context and writes one
+// access line per request, it must run after the middleware setting the X-Request-ID response header.
+// An error is handed to the HTTPErrorHandler here so the line has the status and size actually sent.
+func Middleware(base *zerolog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			fields := base.With().Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID))
+			if span := trace.SpanContextFromContext(req.Context()); span.HasTraceID() {
+				fields = fields.Str("trace_id", span.TraceID().String())
+			}
+			requestLogger := fields.Logger()
+			c.SetRequest(req.WithContext(requestLogger.WithContext(req.Context())))
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			event := requestLogger.Info()
+			switch {
+			case status >= http.StatusInternalServerError:
+				event = requestLogger.Error()
+			case status >= http.StatusBadRequest:
+				event = requestLogger.Warn()
+			}
+			if cause, ok := c.Get(errorKey).(error); ok {
+				event = event.Err(cause)
+			}
+			event.
+				Str("method", req.Method).
+				Str("uri", req.RequestURI).
+				Str("route", c.Path()).
+				Int("status", status).
+				Dur("latency", time.Since(start)).
+				Int64("bytes_in", req.ContentLength).
+				Int64("bytes_out", c.Response().Size).
+				Str("remote_ip", c.RealIP()).
+				Str("user_agent", req.UserAgent()).
+				Msg("request")
+			return nil
+		}
+	}
+}
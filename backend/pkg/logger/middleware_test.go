@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestMiddlewareTagsEveryLineWithTheRequestID(t *testing.T) {
+	var out bytes.Buffer
+	log, err := NewLogger("debug", "json", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Use(middleware.RequestID(), Middleware(log))
+	e.GET("/fail", func(c echo.Context) error {
+		FromContext(c.Request().Context()).Debug().Msg("inside the handler")
+		SetError(c, errors.New("database is down"))
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(echo.HeaderXRequestID); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want the incoming req-1", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log lines = %d, want 2:\n%s", len(lines), out.String())
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line is not JSON: %s", line)
+		}
+		if entry["request_id"] != "req-1" {
+			t.Errorf("request_id = %v, want req-1 in %s", entry["request_id"], line)
+		}
+	}
+
+	var access map[string]interface{}
+	_ = json.Unmarshal([]byte(lines[1]), &access)
+	if access["level"] != "error" || access["status"] != float64(500) || access["error"] != "database is down" {
+		t.Errorf("access line = %s, want an error line with status 500 and its cause", lines[1])
+	}
+}
+
+func TestNewLoggerRejectsUnknownSettings(t *testing.T) {
+	if _, err := NewLogger("loud", "json", nil); err == nil {
+		t.Error("unknown level accepted")
+	}
+	if _, err := NewLogger("info", "xml", nil); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
@@ -0,0 +1,308 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DatePlaceholder is replaced by the day (YYYY-MM-DD) in the path of a RotatingWriter
+const DatePlaceholder = "dateformat"
+
+const dateLayout = "2006-01-02"
+
+// RotateOptions configures a RotatingWriter, zero values disable the matching feature
+type RotateOptions struct {
+	// MaxSize in bytes of a file before it is rolled over within the same day
+	MaxSize int64
+	// MaxAge in days a rolled file is kept
+	MaxAge int
+	// Compress gzips the files that are not written anymore
+	Compress bool
+}
+
+// RotatingWriter appends to one log file per day, named after a path containing DatePlaceholder.
+// A file reaching MaxSize is moved aside as "<name>.<n><ext>", files no longer written are gzipped
+// and the ones older than MaxAge days removed. Existing files are never truncated.
+type RotatingWriter struct {
+	opts   RotateOptions
+	dir    string
+	prefix string
+	ext    string
+	now    func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	day  string
+	size int64
+
+	housekeeping chan struct{}
+	done         chan struct{}
+	closed       bool
+}
+
+// NewRotatingWriter opens today's file of path for appending, a path without DatePlaceholder
+// gets the date appended before its extension
+func NewRotatingWriter(path string, opts RotateOptions) (*RotatingWriter, error) {
+	return newRotatingWriter(path, opts, time.Now)
+}
+
+func newRotatingWriter(path string, opts RotateOptions, now func() time.Time) (*RotatingWriter, error) {
+	dir, name := filepath.Split(path)
+	if name == "" {
+		return nil, fmt.Errorf("log path %q has no file name", path)
+	}
+	if !strings.Contains(name, DatePlaceholder) {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" + DatePlaceholder + ext
+	}
+	// everything after the placeholder is kept as the extension
+	i := strings.Index(name, DatePlaceholder)
+	w := &RotatingWriter{
+		opts:         opts,
+		dir:          filepath.Clean(dir),
+		prefix:       name[:i],
+		ext:          name[i+len(DatePlaceholder):],
+		now:          now,
+		housekeeping: make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.housekeep()
+	w.scheduleHousekeeping()
+	return w, nil
+}
+
+// Write appends p to today's file, rolling over first when the day changed or MaxSize would be exceeded
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.now().Format(dateLayout) != w.day {
+		if err := w.reopen(); err != nil {
+			return 0, err
+		}
+	} else if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rollOver(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Reopen closes and opens the current file again, for instance after it was moved by an external tool
+func (w *RotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.reopen()
+}
+
+// Close syncs and closes the current file and waits for a running compression or cleanup
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.housekeeping)
+	err := w.file.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return err
+}
+
+// Filename returns the path of the file being written
+func (w *RotatingWriter) Filename() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.filename(w.day)
+}
+
+func (w *RotatingWriter) filename(day string) string {
+	return filepath.Join(w.dir, w.prefix+day+w.ext)
+}
+
+func (w *RotatingWriter) open() error {
+	day := w.now().Format(dateLayout)
+	file, err := os.OpenFile(w.filename(day), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	w.file, w.day, w.size = file, day, info.Size()
+	return nil
+}
+
+func (w *RotatingWriter) reopen() error {
+	previous := w.day
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	if previous != w.day {
+		w.scheduleHousekeeping()
+	}
+	return nil
+}
+
+// rollOver moves the full file of today aside under the next free index and starts a new one
+func (w *RotatingWriter) rollOver() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	current := w.filename(w.day)
+	base := strings.TrimSuffix(current, w.ext)
+	for index := 1; ; index++ {
+		rolled := base + "." + strconv.Itoa(index) + w.ext
+		if _, err := os.Stat(rolled); err == nil {
+			continue
+		}
+		if _, err := os.Stat(rolled + ".gz"); err == nil {
+			continue
+		}
+		if err := os.Rename(current, rolled); err != nil {
+			return err
+		}
+		break
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.scheduleHousekeeping()
+	return nil
+}
+
+func (w *RotatingWriter) scheduleHousekeeping() {
+	select {
+	case w.housekeeping <- struct{}{}:
+	default:
+		// a run is already pending and will see the new files
+	}
+}
+
+// housekeep compresses and removes the files no longer written, one run at a time
+func (w *RotatingWriter) housekeep() {
+	defer close(w.done)
+	for range w.housekeeping {
+		if err := w.cleanUp(); err != nil {
+			fmt.Fprintf(os.Stderr, "log rotation: %s\n", err.Error())
+		}
+	}
+}
+
+func (w *RotatingWriter) cleanUp() error {
+	matches, err := filepath.Glob(filepath.Join(w.dir, globEscape(w.prefix)+"*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+
+	w.mu.Lock()
+	active := w.filename(w.day)
+	w.mu.Unlock()
+	oldest := w.now().AddDate(0, 0, -w.opts.MaxAge).Format(dateLayout)
+
+	for _, path := range matches {
+		day, ok := w.dayOf(path)
+		if !ok || path == active {
+			continue
+		}
+		switch {
+		case w.opts.MaxAge > 0 && day < oldest:
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		case w.opts.Compress && !strings.HasSuffix(path, ".gz"):
+			if err := compress(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dayOf returns the day of a file written by w: "<prefix><day>[.<n>]<ext>[.gz]"
+func (w *RotatingWriter) dayOf(path string) (string, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), w.prefix), ".gz")
+	if !strings.HasSuffix(name, w.ext) || len(name) < len(dateLayout) {
+		return "", false
+	}
+	day := name[:len(dateLayout)]
+	if _, err := time.Parse(dateLayout, day); err != nil {
+		return "", false
+	}
+	rest := strings.TrimSuffix(name[len(dateLayout):], w.ext)
+	if rest != "" {
+		if _, err := strconv.Atoi(strings.TrimPrefix(rest, ".")); err != nil || rest[0] != '.' {
+			return "", false
+		}
+	}
+	return day, true
+}
+
+// compress writes path.gz and removes path once the archive is complete
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	gz.Name = filepath.Base(path)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(path)
+}
+
+func globEscape(s string) string {
+	replacer := strings.NewReplacer("*", `\*`, "?", `\?`, "[", `\[`, `\`, `\\`)
+	return replacer.Replace(s)
+}
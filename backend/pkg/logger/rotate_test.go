@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingWriterAppendsToExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2023, 3, 1, 10, 0, 0, 0, time.Local)}
+	path := filepath.Join(dir, "ServiceLog dateformat.log")
+	if err := os.WriteFile(filepath.Join(dir, "ServiceLog 2023-03-01.log"), []byte("before restart\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newRotatingWriter(path, RotateOptions{}, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("after restart\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read(t, filepath.Join(dir, "ServiceLog 2023-03-01.log")); got != "before restart\nafter restart\n" {
+		t.Errorf("content = %q, the existing lines must be kept", got)
+	}
+}
+
+func TestRotatingWriterRollsOverByDateAndSize(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2023, 3, 1, 23, 59, 0, 0, time.Local)}
+	w, err := newRotatingWriter(filepath.Join(dir, "app-dateformat.log"), RotateOptions{MaxSize: 10, Compress: true}, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"day1-a\n", "day1-b\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Add(2 * time.Minute)
+	if _, err := w.Write([]byte("day2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app-2023-03-01.1.log.gz", "app-2023-03-01.log.gz", "app-2023-03-02.log"}
+	if got := files(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	if got := read(t, filepath.Join(dir, "app-2023-03-01.1.log.gz")); got != "day1-a\n" {
+		t.Errorf("rolled file = %q, want the first line", got)
+	}
+	if got := read(t, filepath.Join(dir, "app-2023-03-01.log.gz")); got != "day1-b\n" {
+		t.Errorf("file of the first day = %q, want the second line", got)
+	}
+	if got := read(t, filepath.Join(dir, "app-2023-03-02.log")); got != "day2\n" {
+		t.Errorf("file of the second day = %q", got)
+	}
+}
+
+func TestRotatingWriterRemovesFilesOlderThanMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app-2023-02-20.log.gz", "app-2023-02-26.1.log.gz", "app-2023-02-27.log", "other.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := &fakeClock{now: time.Date(2023, 3, 1, 8, 0, 0, 0, time.Local)}
+	w, err := newRotatingWriter(filepath.Join(dir, "app.log"), RotateOptions{MaxAge: 2}, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app-2023-02-27.log", "app-2023-03-01.log", "other.log"}
+	if got := files(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestRotatingWriterReopensMovedFile(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2023, 3, 1, 8, 0, 0, 0, time.Local)}
+	w, err := newRotatingWriter(filepath.Join(dir, "app-dateformat.log"), RotateOptions{}, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(w.Filename(), filepath.Join(dir, "moved.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read(t, filepath.Join(dir, "app-2023-03-01.log")); got != "second\n" {
+		t.Errorf("reopened file = %q, want only the line written after Reopen", got)
+	}
+}
@@ -0,0 +1,6 @@
+// Package ordersv1 holds the messages, clients and servers generated from proto/orders/v1 for the
+// gRPC API. Run go generate ./pkg/pb/... after changing a .proto file, it needs protoc,
+// protoc-gen-go v1.28.1 and protoc-gen-go-grpc v1.3.0 on the PATH.
+package ordersv1
+
+//go:generate protoc -I ../../../../proto --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative orders/v1/common.proto orders/v1/user.proto orders/v1/order_item.proto orders/v1/order_history.proto
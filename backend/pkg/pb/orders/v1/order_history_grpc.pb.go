@@ -0,0 +1,364 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: orders/v1/order_history.proto
+
+package ordersv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	OrderHistoryService_CreateOrderHistory_FullMethodName   = "/orders.v1.OrderHistoryService/CreateOrderHistory"
+	OrderHistoryService_GetOrderHistory_FullMethodName      = "/orders.v1.OrderHistoryService/GetOrderHistory"
+	OrderHistoryService_ListOrderHistories_FullMethodName   = "/orders.v1.OrderHistoryService/ListOrderHistories"
+	OrderHistoryService_UpdateOrderHistory_FullMethodName   = "/orders.v1.OrderHistoryService/UpdateOrderHistory"
+	OrderHistoryService_PatchOrderHistory_FullMethodName    = "/orders.v1.OrderHistoryService/PatchOrderHistory"
+	OrderHistoryService_BulkOrderHistories_FullMethodName   = "/orders.v1.OrderHistoryService/BulkOrderHistories"
+	OrderHistoryService_ExportOrderHistories_FullMethodName = "/orders.v1.OrderHistoryService/ExportOrderHistories"
+)
+
+// OrderHistoryServiceClient is the client API for OrderHistoryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OrderHistoryServiceClient interface {
+	CreateOrderHistory(ctx context.Context, in *CreateOrderHistoryRequest, opts ...grpc.CallOption) (*OrderHistory, error)
+	GetOrderHistory(ctx context.Context, in *GetOrderHistoryRequest, opts ...grpc.CallOption) (*OrderHistory, error)
+	ListOrderHistories(ctx context.Context, in *ListOrderHistoriesRequest, opts ...grpc.CallOption) (*ListOrderHistoriesResponse, error)
+	UpdateOrderHistory(ctx context.Context, in *UpdateOrderHistoryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// PatchOrderHistory changes only the fields that are set
+	PatchOrderHistory(ctx context.Context, in *PatchOrderHistoryRequest, opts ...grpc.CallOption) (*OrderHistory, error)
+	BulkOrderHistories(ctx context.Context, in *BulkOrderHistoriesRequest, opts ...grpc.CallOption) (*BulkResponse, error)
+	// ExportOrderHistories streams every Order History, the oldest first, flattened with its User and Order Item
+	ExportOrderHistories(ctx context.Context, in *ExportOrderHistoriesRequest, opts ...grpc.CallOption) (OrderHistoryService_ExportOrderHistoriesClient, error)
+}
+
+type orderHistoryServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrderHistoryServiceClient(cc grpc.ClientConnInterface) OrderHistoryServiceClient {
+	return &orderHistoryServiceClient{cc}
+}
+
+func (c *orderHistoryServiceClient) CreateOrderHistory(ctx context.Context, in *CreateOrderHistoryRequest, opts ...grpc.CallOption) (*OrderHistory, error) {
+	out := new(OrderHistory)
+	err := c.cc.Invoke(ctx, OrderHistoryService_CreateOrderHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderHistoryServiceClient) GetOrderHistory(ctx context.Context, in *GetOrderHistoryRequest, opts ...grpc.CallOption) (*OrderHistory, error) {
+	out := new(OrderHistory)
+	err := c.cc.Invoke(ctx, OrderHistoryService_GetOrderHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderHistoryServiceClient) ListOrderHistories(ctx context.Context, in *ListOrderHistoriesRequest, opts ...grpc.CallOption) (*ListOrderHistoriesResponse, error) {
+	out := new(ListOrderHistoriesResponse)
+	err := c.cc.Invoke(ctx, OrderHistoryService_ListOrderHistories_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderHistoryServiceClient) UpdateOrderHistory(ctx context.Context, in *UpdateOrderHistoryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, OrderHistoryService_UpdateOrderHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderHistoryServiceClient) PatchOrderHistory(ctx context.Context, in *PatchOrderHistoryRequest, opts ...grpc.CallOption) (*OrderHistory, error) {
+	out := new(OrderHistory)
+	err := c.cc.Invoke(ctx, OrderHistoryService_PatchOrderHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderHistoryServiceClient) BulkOrderHistories(ctx context.Context, in *BulkOrderHistoriesRequest, opts ...grpc.CallOption) (*BulkResponse, error) {
+	out := new(BulkResponse)
+	err := c.cc.Invoke(ctx, OrderHistoryService_BulkOrderHistories_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderHistoryServiceClient) ExportOrderHistories(ctx context.Context, in *ExportOrderHistoriesRequest, opts ...grpc.CallOption) (OrderHistoryService_ExportOrderHistoriesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderHistoryService_ServiceDesc.Streams[0], OrderHistoryService_ExportOrderHistories_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderHistoryServiceExportOrderHistoriesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OrderHistoryService_ExportOrderHistoriesClient interface {
+	Recv() (*OrderHistoryExport, error)
+	grpc.ClientStream
+}
+
+type orderHistoryServiceExportOrderHistoriesClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderHistoryServiceExportOrderHistoriesClient) Recv() (*OrderHistoryExport, error) {
+	m := new(OrderHistoryExport)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OrderHistoryServiceServer is the server API for OrderHistoryService service.
+// All implementations must embed UnimplementedOrderHistoryServiceServer
+// for forward compatibility
+type OrderHistoryServiceServer interface {
+	CreateOrderHistory(context.Context, *CreateOrderHistoryRequest) (*OrderHistory, error)
+	GetOrderHistory(context.Context, *GetOrderHistoryRequest) (*OrderHistory, error)
+	ListOrderHistories(context.Context, *ListOrderHistoriesRequest) (*ListOrderHistoriesResponse, error)
+	UpdateOrderHistory(context.Context, *UpdateOrderHistoryRequest) (*emptypb.Empty, error)
+	// PatchOrderHistory changes only the fields that are set
+	PatchOrderHistory(context.Context, *PatchOrderHistoryRequest) (*OrderHistory, error)
+	BulkOrderHistories(context.Context, *BulkOrderHistoriesRequest) (*BulkResponse, error)
+	// ExportOrderHistories streams every Order History, the oldest first, flattened with its User and Order Item
+	ExportOrderHistories(*ExportOrderHistoriesRequest, OrderHistoryService_ExportOrderHistoriesServer) error
+	mustEmbedUnimplementedOrderHistoryServiceServer()
+}
+
+// UnimplementedOrderHistoryServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedOrderHistoryServiceServer struct {
+}
+
+func (UnimplementedOrderHistoryServiceServer) CreateOrderHistory(context.Context, *CreateOrderHistoryRequest) (*OrderHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrderHistory not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) GetOrderHistory(context.Context, *GetOrderHistoryRequest) (*OrderHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderHistory not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) ListOrderHistories(context.Context, *ListOrderHistoriesRequest) (*ListOrderHistoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrderHistories not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) UpdateOrderHistory(context.Context, *UpdateOrderHistoryRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrderHistory not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) PatchOrderHistory(context.Context, *PatchOrderHistoryRequest) (*OrderHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchOrderHistory not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) BulkOrderHistories(context.Context, *BulkOrderHistoriesRequest) (*BulkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BulkOrderHistories not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) ExportOrderHistories(*ExportOrderHistoriesRequest, OrderHistoryService_ExportOrderHistoriesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportOrderHistories not implemented")
+}
+func (UnimplementedOrderHistoryServiceServer) mustEmbedUnimplementedOrderHistoryServiceServer() {}
+
+// UnsafeOrderHistoryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OrderHistoryServiceServer will
+// result in compilation errors.
+type UnsafeOrderHistoryServiceServer interface {
+	mustEmbedUnimplementedOrderHistoryServiceServer()
+}
+
+func RegisterOrderHistoryServiceServer(s grpc.ServiceRegistrar, srv OrderHistoryServiceServer) {
+	s.RegisterService(&OrderHistoryService_ServiceDesc, srv)
+}
+
+func _OrderHistoryService_CreateOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateOrderHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderHistoryServiceServer).CreateOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderHistoryService_CreateOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderHistoryServiceServer).CreateOrderHistory(ctx, req.(*CreateOrderHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderHistoryService_GetOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderHistoryServiceServer).GetOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderHistoryService_GetOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderHistoryServiceServer).GetOrderHistory(ctx, req.(*GetOrderHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderHistoryService_ListOrderHistories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrderHistoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderHistoryServiceServer).ListOrderHistories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderHistoryService_ListOrderHistories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderHistoryServiceServer).ListOrderHistories(ctx, req.(*ListOrderHistoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderHistoryService_UpdateOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOrderHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderHistoryServiceServer).UpdateOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderHistoryService_UpdateOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderHistoryServiceServer).UpdateOrderHistory(ctx, req.(*UpdateOrderHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderHistoryService_PatchOrderHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchOrderHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderHistoryServiceServer).PatchOrderHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderHistoryService_PatchOrderHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderHistoryServiceServer).PatchOrderHistory(ctx, req.(*PatchOrderHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderHistoryService_BulkOrderHistories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkOrderHistoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderHistoryServiceServer).BulkOrderHistories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderHistoryService_BulkOrderHistories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderHistoryServiceServer).BulkOrderHistories(ctx, req.(*BulkOrderHistoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderHistoryService_ExportOrderHistories_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportOrderHistoriesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderHistoryServiceServer).ExportOrderHistories(m, &orderHistoryServiceExportOrderHistoriesServer{stream})
+}
+
+type OrderHistoryService_ExportOrderHistoriesServer interface {
+	Send(*OrderHistoryExport) error
+	grpc.ServerStream
+}
+
+type orderHistoryServiceExportOrderHistoriesServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderHistoryServiceExportOrderHistoriesServer) Send(m *OrderHistoryExport) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// OrderHistoryService_ServiceDesc is the grpc.ServiceDesc for OrderHistoryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OrderHistoryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "orders.v1.OrderHistoryService",
+	HandlerType: (*OrderHistoryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateOrderHistory",
+			Handler:    _OrderHistoryService_CreateOrderHistory_Handler,
+		},
+		{
+			MethodName: "GetOrderHistory",
+			Handler:    _OrderHistoryService_GetOrderHistory_Handler,
+		},
+		{
+			MethodName: "ListOrderHistories",
+			Handler:    _OrderHistoryService_ListOrderHistories_Handler,
+		},
+		{
+			MethodName: "UpdateOrderHistory",
+			Handler:    _OrderHistoryService_UpdateOrderHistory_Handler,
+		},
+		{
+			MethodName: "PatchOrderHistory",
+			Handler:    _OrderHistoryService_PatchOrderHistory_Handler,
+		},
+		{
+			MethodName: "BulkOrderHistories",
+			Handler:    _OrderHistoryService_BulkOrderHistories_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportOrderHistories",
+			Handler:       _OrderHistoryService_ExportOrderHistories_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "orders/v1/order_history.proto",
+}
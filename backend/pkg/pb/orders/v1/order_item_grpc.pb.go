@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: orders/v1/order_item.proto
+
+package ordersv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	OrderItemService_CreateOrderItem_FullMethodName = "/orders.v1.OrderItemService/CreateOrderItem"
+	OrderItemService_GetOrderItem_FullMethodName    = "/orders.v1.OrderItemService/GetOrderItem"
+	OrderItemService_ListOrderItems_FullMethodName  = "/orders.v1.OrderItemService/ListOrderItems"
+	OrderItemService_UpdateOrderItem_FullMethodName = "/orders.v1.OrderItemService/UpdateOrderItem"
+	OrderItemService_PatchOrderItem_FullMethodName  = "/orders.v1.OrderItemService/PatchOrderItem"
+	OrderItemService_DeleteOrderItem_FullMethodName = "/orders.v1.OrderItemService/DeleteOrderItem"
+	OrderItemService_BulkOrderItems_FullMethodName  = "/orders.v1.OrderItemService/BulkOrderItems"
+)
+
+// OrderItemServiceClient is the client API for OrderItemService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OrderItemServiceClient interface {
+	CreateOrderItem(ctx context.Context, in *CreateOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error)
+	GetOrderItem(ctx context.Context, in *GetOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error)
+	ListOrderItems(ctx context.Context, in *ListOrderItemsRequest, opts ...grpc.CallOption) (*ListOrderItemsResponse, error)
+	UpdateOrderItem(ctx context.Context, in *UpdateOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error)
+	// PatchOrderItem changes only the fields that are set, expired_at is kept unless expired_days is set
+	PatchOrderItem(ctx context.Context, in *PatchOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error)
+	DeleteOrderItem(ctx context.Context, in *DeleteOrderItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	BulkOrderItems(ctx context.Context, in *BulkOrderItemsRequest, opts ...grpc.CallOption) (*BulkResponse, error)
+}
+
+type orderItemServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrderItemServiceClient(cc grpc.ClientConnInterface) OrderItemServiceClient {
+	return &orderItemServiceClient{cc}
+}
+
+func (c *orderItemServiceClient) CreateOrderItem(ctx context.Context, in *CreateOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error) {
+	out := new(OrderItem)
+	err := c.cc.Invoke(ctx, OrderItemService_CreateOrderItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderItemServiceClient) GetOrderItem(ctx context.Context, in *GetOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error) {
+	out := new(OrderItem)
+	err := c.cc.Invoke(ctx, OrderItemService_GetOrderItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderItemServiceClient) ListOrderItems(ctx context.Context, in *ListOrderItemsRequest, opts ...grpc.CallOption) (*ListOrderItemsResponse, error) {
+	out := new(ListOrderItemsResponse)
+	err := c.cc.Invoke(ctx, OrderItemService_ListOrderItems_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderItemServiceClient) UpdateOrderItem(ctx context.Context, in *UpdateOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error) {
+	out := new(OrderItem)
+	err := c.cc.Invoke(ctx, OrderItemService_UpdateOrderItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderItemServiceClient) PatchOrderItem(ctx context.Context, in *PatchOrderItemRequest, opts ...grpc.CallOption) (*OrderItem, error) {
+	out := new(OrderItem)
+	err := c.cc.Invoke(ctx, OrderItemService_PatchOrderItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderItemServiceClient) DeleteOrderItem(ctx context.Context, in *DeleteOrderItemRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, OrderItemService_DeleteOrderItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderItemServiceClient) BulkOrderItems(ctx context.Context, in *BulkOrderItemsRequest, opts ...grpc.CallOption) (*BulkResponse, error) {
+	out := new(BulkResponse)
+	err := c.cc.Invoke(ctx, OrderItemService_BulkOrderItems_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OrderItemServiceServer is the server API for OrderItemService service.
+// All implementations must embed UnimplementedOrderItemServiceServer
+// for forward compatibility
+type OrderItemServiceServer interface {
+	CreateOrderItem(context.Context, *CreateOrderItemRequest) (*OrderItem, error)
+	GetOrderItem(context.Context, *GetOrderItemRequest) (*OrderItem, error)
+	ListOrderItems(context.Context, *ListOrderItemsRequest) (*ListOrderItemsResponse, error)
+	UpdateOrderItem(context.Context, *UpdateOrderItemRequest) (*OrderItem, error)
+	// PatchOrderItem changes only the fields that are set, expired_at is kept unless expired_days is set
+	PatchOrderItem(context.Context, *PatchOrderItemRequest) (*OrderItem, error)
+	DeleteOrderItem(context.Context, *DeleteOrderItemRequest) (*emptypb.Empty, error)
+	BulkOrderItems(context.Context, *BulkOrderItemsRequest) (*BulkResponse, error)
+	mustEmbedUnimplementedOrderItemServiceServer()
+}
+
+// UnimplementedOrderItemServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedOrderItemServiceServer struct {
+}
+
+func (UnimplementedOrderItemServiceServer) CreateOrderItem(context.Context, *CreateOrderItemRequest) (*OrderItem, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrderItem not implemented")
+}
+func (UnimplementedOrderItemServiceServer) GetOrderItem(context.Context, *GetOrderItemRequest) (*OrderItem, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderItem not implemented")
+}
+func (UnimplementedOrderItemServiceServer) ListOrderItems(context.Context, *ListOrderItemsRequest) (*ListOrderItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrderItems not implemented")
+}
+func (UnimplementedOrderItemServiceServer) UpdateOrderItem(context.Context, *UpdateOrderItemRequest) (*OrderItem, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrderItem not implemented")
+}
+func (UnimplementedOrderItemServiceServer) PatchOrderItem(context.Context, *PatchOrderItemRequest) (*OrderItem, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchOrderItem not implemented")
+}
+func (UnimplementedOrderItemServiceServer) DeleteOrderItem(context.Context, *DeleteOrderItemRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteOrderItem not implemented")
+}
+func (UnimplementedOrderItemServiceServer) BulkOrderItems(context.Context, *BulkOrderItemsRequest) (*BulkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BulkOrderItems not implemented")
+}
+func (UnimplementedOrderItemServiceServer) mustEmbedUnimplementedOrderItemServiceServer() {}
+
+// UnsafeOrderItemServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OrderItemServiceServer will
+// result in compilation errors.
+type UnsafeOrderItemServiceServer interface {
+	mustEmbedUnimplementedOrderItemServiceServer()
+}
+
+func RegisterOrderItemServiceServer(s grpc.ServiceRegistrar, srv OrderItemServiceServer) {
+	s.RegisterService(&OrderItemService_ServiceDesc, srv)
+}
+
+func _OrderItemService_CreateOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).CreateOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_CreateOrderItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).CreateOrderItem(ctx, req.(*CreateOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderItemService_GetOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).GetOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_GetOrderItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).GetOrderItem(ctx, req.(*GetOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderItemService_ListOrderItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrderItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).ListOrderItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_ListOrderItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).ListOrderItems(ctx, req.(*ListOrderItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderItemService_UpdateOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).UpdateOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_UpdateOrderItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).UpdateOrderItem(ctx, req.(*UpdateOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderItemService_PatchOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).PatchOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_PatchOrderItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).PatchOrderItem(ctx, req.(*PatchOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderItemService_DeleteOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).DeleteOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_DeleteOrderItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).DeleteOrderItem(ctx, req.(*DeleteOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderItemService_BulkOrderItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkOrderItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).BulkOrderItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_BulkOrderItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).BulkOrderItems(ctx, req.(*BulkOrderItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OrderItemService_ServiceDesc is the grpc.ServiceDesc for OrderItemService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OrderItemService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "orders.v1.OrderItemService",
+	HandlerType: (*OrderItemServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateOrderItem",
+			Handler:    _OrderItemService_CreateOrderItem_Handler,
+		},
+		{
+			MethodName: "GetOrderItem",
+			Handler:    _OrderItemService_GetOrderItem_Handler,
+		},
+		{
+			MethodName: "ListOrderItems",
+			Handler:    _OrderItemService_ListOrderItems_Handler,
+		},
+		{
+			MethodName: "UpdateOrderItem",
+			Handler:    _OrderItemService_UpdateOrderItem_Handler,
+		},
+		{
+			MethodName: "PatchOrderItem",
+			Handler:    _OrderItemService_PatchOrderItem_Handler,
+		},
+		{
+			MethodName: "DeleteOrderItem",
+			Handler:    _OrderItemService_DeleteOrderItem_Handler,
+		},
+		{
+			MethodName: "BulkOrderItems",
+			Handler:    _OrderItemService_BulkOrderItems_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "orders/v1/order_item.proto",
+}
@@ -0,0 +1,154 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	workbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// ContentType is the media type of the produced workbook
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// StreamWriter writes a single-sheet XLSX workbook row by row, nothing but the
+// current row is kept in memory so it can export tables of any size.
+type StreamWriter struct {
+	zip    *zip.Writer
+	sheet  *bufio.Writer
+	row    int
+	closed bool
+}
+
+// NewStreamWriter starts a workbook with one sheet named sheetName on w
+func NewStreamWriter(w io.Writer, sheetName string) (*StreamWriter, error) {
+	zw := zip.NewWriter(w)
+
+	var escaped strings.Builder
+	if err := xml.EscapeText(&escaped, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escaped.String())},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last part, so it stays open while rows are streamed
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(sheetHeader); err != nil {
+		return nil, err
+	}
+
+	return &StreamWriter{zip: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends one row, numbers are written as numeric cells and everything else as text
+func (s *StreamWriter) WriteRow(cells ...interface{}) error {
+	if s.closed {
+		return errors.New("xlsx: write to closed stream writer")
+	}
+	s.row++
+	if _, err := fmt.Fprintf(s.sheet, `<row r="%d">`, s.row); err != nil {
+		return err
+	}
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(s.row)
+		var err error
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case int:
+			_, err = fmt.Fprintf(s.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case int64:
+			_, err = fmt.Fprintf(s.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			_, err = fmt.Fprintf(s.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case time.Time:
+			err = s.writeString(ref, v.Format(time.RFC3339))
+		default:
+			err = s.writeString(ref, fmt.Sprint(v))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Flush pushes the buffered rows to the underlying writer
+func (s *StreamWriter) Flush() error {
+	if err := s.sheet.Flush(); err != nil {
+		return err
+	}
+	return s.zip.Flush()
+}
+
+// Close finishes the sheet and the zip archive, it does not close the underlying writer
+func (s *StreamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if _, err := s.sheet.WriteString(sheetFooter); err != nil {
+		return err
+	}
+	if err := s.sheet.Flush(); err != nil {
+		return err
+	}
+	return s.zip.Close()
+}
+
+func (s *StreamWriter) writeString(ref, value string) error {
+	if _, err := fmt.Fprintf(s.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref); err != nil {
+		return err
+	}
+	if err := xml.EscapeText(s.sheet, []byte(value)); err != nil {
+		return err
+	}
+	_, err := s.sheet.WriteString(`</t></is></c>`)
+	return err
+}
+
+// columnName converts a zero-based column index to its letters (0 -> A, 26 -> AA)
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
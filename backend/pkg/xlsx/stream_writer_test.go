@@ -0,0 +1,77 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readPart unzips the part name of a workbook
+func readPart(t *testing.T, workbook []byte, name string) string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatalf("workbook is not a zip archive: %v", err)
+	}
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	part, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(part)
+}
+
+func TestStreamWriterWritesTypedCells(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewStreamWriter(&out, "Orders & <Items>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+	if err := w.WriteRow("id", "name", "price"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(1, nil, int64(2), 1.5, created, `<a href="x">&`); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow("late"); err == nil {
+		t.Error("write after Close accepted")
+	}
+
+	if workbook := readPart(t, out.Bytes(), "xl/workbook.xml"); !strings.Contains(workbook, `<sheet name="Orders &amp; &lt;Items&gt;"`) {
+		t.Errorf("sheet name not escaped in\n%s", workbook)
+	}
+	want := sheetHeader +
+		`<row r="1">` +
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>` +
+		`<c r="B1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>` +
+		`<c r="C1" t="inlineStr"><is><t xml:space="preserve">price</t></is></c>` +
+		`</row><row r="2">` +
+		`<c r="A2"><v>1</v></c>` +
+		`<c r="C2"><v>2</v></c>` +
+		`<c r="D2"><v>1.5</v></c>` +
+		`<c r="E2" t="inlineStr"><is><t xml:space="preserve">2023-03-01T08:00:00Z</t></is></c>` +
+		`<c r="F2" t="inlineStr"><is><t xml:space="preserve">&lt;a href=&#34;x&#34;&gt;&amp;</t></is></c>` +
+		`</row>` + sheetFooter
+	if sheet := readPart(t, out.Bytes(), "xl/worksheets/sheet1.xml"); sheet != want {
+		t.Errorf("sheet1.xml =\n%s\nwant\n%s", sheet, want)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", index, got, want)
+		}
+	}
+}